@@ -94,6 +94,16 @@ func (b *BufferManager) FlushAll(txnum int32) {
 
 }
 
+//FlushDirty 将脏页列表中所有事务修改过的缓存页都刷新到磁盘中，数据库关闭的时候调用
+func (b *BufferManager) FlushDirty() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, buffer := range b.dirtylist {
+		buffer.Flush()
+		delete(b.dirtylist, key)
+	}
+}
+
 //Pin 将给定磁盘文件的区块数据分配给缓存页面,相当于内存分配，new
 /*
 	情况一：要读取的数据已经被缓存在某个页面中了，这样缓存器就设置这个页面pin，增加引用计数
@@ -174,7 +184,10 @@ func (b *BufferManager) tryPin(blk *fm.BlockId) *Buffer {
 	if cacheItem, ok := b.lruCache.Get(blk.HashCode()); ok {
 		//得到了缓存页
 		buffer := cacheItem.(*Buffer)
-
+		if !buffer.IsPinned() {
+			//预读取的页面没有被pin过，现在开始被占用了
+			b.numAvailable--
+		}
 		buffer.Pin() //增加引用计数，获得到之后，就需要增加引用计数，把当前page占用了
 		return buffer
 	}
@@ -210,6 +223,14 @@ func (b *BufferManager) chooseUnpinBuffer() *Buffer {
 		b.freelist.Remove(elem) //把这个给删除掉
 		return buffer
 	}
+	//空闲列表为空的时候，预读取上来但是一直没有被使用的页面也可以被替换
+	for key, elem := range b.lruCache.Items() {
+		buffer := elem.Value.(*container.CacheItem).Value().(*Buffer)
+		if !buffer.IsPinned() {
+			b.lruCache.Remove(key)
+			return buffer
+		}
+	}
 	//说明全部的buffer都被使用了
 	return nil
 }
@@ -242,6 +263,27 @@ func (b *BufferManager) asyncPreRead(blk *fm.BlockId) {
 
 }
 
+//Discard 文件被删除之前调用，丢弃缓存中这个文件的区块，避免旧的数据被写回去重新创建出这个文件，或者之后创建的同名文件读到旧的数据
+//只处理没有被pin的缓存页，预读取上来的页面从LRU缓存中移到空闲列表
+func (b *BufferManager) Discard(fileName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, buffer := range b.bufferPool {
+		if buffer.blk == nil || buffer.blk.FileName() != fileName || buffer.IsPinned() {
+			continue
+		}
+		buffer.txnum = -1 //不需要再写回磁盘
+		key := buffer.blk.HashCode()
+		if b.dirtylist[key] == buffer {
+			delete(b.dirtylist, key)
+		}
+		if elem, ok := b.lruCache.Items()[key]; ok && elem.Value.(*container.CacheItem).Value().(*Buffer) == buffer {
+			b.lruCache.Remove(key)
+			b.freelist.PushFront(buffer)
+		}
+	}
+}
+
 //AddToDirty 把当前的buff添加到脏页列表中
 func (b *BufferManager) AddToDirty(buff *Buffer) {
 	_, ok := b.dirtylist[buff.blk.HashCode()]
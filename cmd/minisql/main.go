@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"miniSQL/db"
+	"os"
+	"path/filepath"
+)
+
+/*
+	minisql 交互式的命令行工具
+	用法：minisql [-blocksize 4096] [-buffers 64] [-history ~/.minisql_history] DIR
+	SQL语句以;结束，可以跨越多行，以.开头的是元命令，输入.help查看所有的元命令
+*/
+
+func main() {
+	blockSize := flag.Uint64("blocksize", db.DEFAULT_BLOCK_SIZE, "block size of the data directory")
+	numBuffers := flag.Uint("buffers", db.DEFAULT_NUM_BUFFERS, "number of buffers in the buffer pool")
+	historyFile := flag.String("history", defaultHistoryFile(), "file to keep the command history in, empty to disable")
+	verbose := flag.Bool("verbose", false, "print the debug output of the storage engine")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] DIR\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	out := os.Stdout
+	if !*verbose {
+		//事务和锁管理器会往标准输出打印调试信息，这里把它们丢弃掉，只保留命令行自己的输出
+		if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+			os.Stdout = devNull
+		}
+	}
+
+	d, err := db.Open(flag.Arg(0), db.Options{BlockSize: *blockSize, NumBuffers: uint32(*numBuffers)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open %s: %v\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+	s := newShell(d, out)
+	if *historyFile != "" {
+		s.loadHistory(*historyFile)
+	}
+	err = s.run(os.Stdin, isTerminal(os.Stdin))
+	s.close()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".minisql_history")
+}
+
+//isTerminal 从终端输入的时候才需要输出提示符
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
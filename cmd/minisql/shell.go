@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"miniSQL/comm"
+	"miniSQL/db"
+	"miniSQL/parser"
+	"miniSQL/planner"
+	rm "miniSQL/record_manager"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	PROMPT          = "minisql> "
+	TX_PROMPT       = "minisql*> " //处在显式开启的事务中
+	CONTINUE_PROMPT = "    ...> "
+	MAX_HISTORY     = 1000 //最多保留的历史记录条数
+)
+
+//catalogTables 元数据表，.tables的时候不显示出来
+var catalogTables = map[string]bool{
+	"tblcat":  true,
+	"fldcat":  true,
+	"idxcat":  true,
+	"viewcat": true,
+	"defcat":  true,
+}
+
+//shell 交互式的命令行，每一行要么是以.开头的元命令，要么是SQL语句的一部分，SQL语句以;结束，可以跨越多行
+type shell struct {
+	db          *db.DB
+	sess        *db.Session
+	out         io.Writer
+	pending     strings.Builder //还没有遇到;的语句
+	explain     bool            //执行查询之前是否输出查询树
+	timer       bool            //是否输出每条语句的执行时间
+	history     []string
+	historyFile string //历史记录保存的文件，为空就不保存
+	quit        bool
+}
+
+func newShell(d *db.DB, out io.Writer) *shell {
+	return &shell{
+		db:      d,
+		sess:    d.NewSession(),
+		out:     out,
+		history: make([]string, 0),
+	}
+}
+
+//run 从in中一行一行的读取并执行，prompt为true的时候会输出提示符
+func (s *shell) run(in io.Reader, prompt bool) error {
+	scanner := bufio.NewScanner(in)
+	for !s.quit {
+		if prompt {
+			if s.pending.Len() == 0 && s.sess.InTransaction() {
+				fmt.Fprint(s.out, TX_PROMPT)
+			} else if s.pending.Len() == 0 {
+				fmt.Fprint(s.out, PROMPT)
+			} else {
+				fmt.Fprint(s.out, CONTINUE_PROMPT)
+			}
+		}
+		if !scanner.Scan() {
+			break
+		}
+		s.feedLine(scanner.Text())
+	}
+	if prompt {
+		fmt.Fprintln(s.out)
+	}
+	return scanner.Err()
+}
+
+//feedLine 处理一行输入
+func (s *shell) feedLine(line string) {
+	trimmed := strings.TrimSpace(line)
+	if s.pending.Len() == 0 {
+		if trimmed == "" {
+			return
+		}
+		if strings.HasPrefix(trimmed, ".") {
+			s.addHistory(trimmed)
+			if err := s.runMeta(trimmed); err != nil {
+				fmt.Fprintf(s.out, "Error: %v\n", err)
+			}
+			return
+		}
+	}
+	//按照;把语句切分开，没有结束的部分留到下一行继续
+	s.pending.WriteString(line)
+	s.pending.WriteString("\n")
+	stmts, rest := parser.SplitStatements(s.pending.String())
+	s.pending.Reset()
+	if strings.TrimSpace(rest) != "" {
+		s.pending.WriteString(rest)
+	}
+	for _, stmt := range stmts {
+		s.addHistory(stmt + ";")
+		s.execute(stmt)
+	}
+}
+
+//execute 执行一条完整的SQL语句，没有执行BEGIN的时候每条语句都在单独的事务中执行
+func (s *shell) execute(sql string) {
+	start := time.Now()
+	stmt, err := parser.NewSQLParser(sql).ParseStatement()
+	if err == nil {
+		if data, ok := stmt.(*parser.QueryData); ok {
+			err = s.executeQuery(data)
+		} else {
+			err = s.executeUpdate(stmt)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "Error: %v\n", err)
+	}
+	if s.timer {
+		fmt.Fprintf(s.out, "Time: %.3f ms\n", float64(time.Since(start).Microseconds())/1000)
+	}
+}
+
+func (s *shell) executeQuery(data *parser.QueryData) error {
+	if s.explain {
+		plan, err := s.sess.Plan(data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(s.out, planner.Explain(plan))
+	}
+	header, rows, err := s.collectRows(data)
+	if err != nil {
+		return err
+	}
+	printTable(s.out, header, rows)
+	return nil
+}
+
+func (s *shell) executeUpdate(stmt parser.Statement) error {
+	count, err := s.sess.Execute(stmt)
+	if err != nil {
+		return err
+	}
+	switch stmt.(type) {
+	case *parser.InsertData, *parser.DeleteData, *parser.UpdateData:
+		fmt.Fprintf(s.out, "OK, %d %s affected\n", count, plural(count, "row"))
+	default:
+		fmt.Fprintln(s.out, "OK")
+	}
+	return nil
+}
+
+//close 退出的时候回滚没有提交的事务
+func (s *shell) close() error {
+	return s.sess.Close()
+}
+
+//column 结果中的一列
+type column struct {
+	name    string
+	numeric bool //数字右对齐
+}
+
+//collectRows 在会话中执行查询，把所有的记录都读取出来转化成字符串
+func (s *shell) collectRows(data *parser.QueryData) ([]column, [][]string, error) {
+	result, err := s.sess.Query(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	sch := result.Schema()
+	header := make([]column, 0, len(result.Fields()))
+	for _, field := range result.Fields() {
+		header = append(header, column{name: field, numeric: rm.IsNumeric(sch.Type(field))})
+	}
+	rows := make([][]string, 0)
+	for result.Next() {
+		row := make([]string, len(header))
+		for i, val := range result.Row() {
+			row[i] = val.ToString()
+		}
+		rows = append(rows, row)
+	}
+	if err := result.Close(); err != nil {
+		return nil, nil, err
+	}
+	return header, rows, nil
+}
+
+//printTable 按照对齐的表格输出查询结果
+func printTable(out io.Writer, header []column, rows [][]string) {
+	widths := make([]int, len(header))
+	for i, col := range header {
+		widths[i] = utf8.RuneCountInString(col.name)
+	}
+	for _, row := range rows {
+		for i, val := range row {
+			if w := utf8.RuneCountInString(val); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	separator := "+"
+	for _, w := range widths {
+		separator += strings.Repeat("-", w+2) + "+"
+	}
+	printRow := func(vals []string, alignRight bool) {
+		line := "|"
+		for i, val := range vals {
+			padding := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(val))
+			if alignRight && header[i].numeric {
+				line += " " + padding + val + " |"
+			} else {
+				line += " " + val + padding + " |"
+			}
+		}
+		fmt.Fprintln(out, line)
+	}
+	names := make([]string, len(header))
+	for i, col := range header {
+		names[i] = col.name
+	}
+	fmt.Fprintln(out, separator)
+	printRow(names, false)
+	fmt.Fprintln(out, separator)
+	for _, row := range rows {
+		printRow(row, true)
+	}
+	if len(rows) > 0 {
+		fmt.Fprintln(out, separator)
+	}
+	fmt.Fprintf(out, "(%d %s)\n", len(rows), plural(len(rows), "row"))
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+const helpText = `.explain on|off   输出查询语句的查询树
+.help             显示帮助信息
+.history          显示历史记录
+.indexes TABLE    显示表上的索引
+.quit             退出
+.read FILE        执行文件中的SQL语句
+.schema [TABLE]   显示表或者视图的定义
+.tables           显示所有的表和视图
+.timer on|off     输出每条语句的执行时间
+`
+
+//runMeta 执行以.开头的元命令
+func (s *shell) runMeta(line string) error {
+	args := strings.Fields(line)
+	switch args[0] {
+	case ".help":
+		fmt.Fprint(s.out, helpText)
+	case ".quit", ".exit":
+		s.quit = true
+	case ".explain":
+		return setFlag(&s.explain, args)
+	case ".timer":
+		return setFlag(&s.timer, args)
+	case ".tables":
+		return s.printTables()
+	case ".schema":
+		if len(args) > 2 {
+			return fmt.Errorf("usage: .schema [TABLE]")
+		}
+		name := ""
+		if len(args) == 2 {
+			name = args[1]
+		}
+		return s.printSchema(name)
+	case ".indexes":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: .indexes TABLE")
+		}
+		return s.printIndexes(args[1])
+	case ".read":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: .read FILE")
+		}
+		return s.readFile(args[1])
+	case ".history":
+		for i, h := range s.history {
+			fmt.Fprintf(s.out, "%5d  %s\n", i+1, h)
+		}
+	default:
+		return fmt.Errorf("unknown command %s, enter .help for usage", args[0])
+	}
+	return nil
+}
+
+func setFlag(flag *bool, args []string) error {
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		return fmt.Errorf("usage: %s on|off", args[0])
+	}
+	*flag = args[1] == "on"
+	return nil
+}
+
+//catalogQuery 在元数据表上执行一条查询语句，把结果按照字符串返回
+func (s *shell) catalogQuery(sql string, args ...string) ([][]string, error) {
+	constants := make([]*comm.Constant, 0, len(args))
+	for i := range args {
+		constants = append(constants, comm.NewConstantString(&args[i]))
+	}
+	stmt, err := parser.NewSQLParserWithArgs(sql, constants).ParseStatement()
+	if err != nil {
+		return nil, err
+	}
+	_, rows, err := s.collectRows(stmt.(*parser.QueryData))
+	return rows, err
+}
+
+//printTables 输出tblcat中所有的用户表以及viewcat中的视图
+func (s *shell) printTables() error {
+	rows, err := s.catalogQuery("select tblname from tblcat")
+	if err != nil {
+		return err
+	}
+	views, err := s.catalogQuery("select viewname from viewcat")
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0)
+	for _, row := range append(rows, views...) {
+		if !catalogTables[row[0]] {
+			names = append(names, row[0])
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(s.out, name)
+	}
+	return nil
+}
+
+//printSchema 根据fldcat和viewcat中的内容还原出建表语句
+func (s *shell) printSchema(name string) error {
+	var names []string
+	if name != "" {
+		names = []string{name}
+	} else {
+		rows, err := s.catalogQuery("select tblname from tblcat")
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if !catalogTables[row[0]] {
+				names = append(names, row[0])
+			}
+		}
+		views, err := s.catalogQuery("select viewname from viewcat")
+		if err != nil {
+			return err
+		}
+		for _, row := range views {
+			names = append(names, row[0])
+		}
+		sort.Strings(names)
+	}
+	for _, n := range names {
+		fields, err := s.catalogQuery("select fldname,type,length from fldcat where tblname = ?", n)
+		if err != nil {
+			return err
+		}
+		if len(fields) > 0 {
+			defs := make([]string, 0, len(fields))
+			for _, f := range fields {
+				typ, _ := strconv.Atoi(f[1])
+				length, _ := strconv.Atoi(f[2])
+				defs = append(defs, f[0]+" "+strings.ToLower(rm.TypeName(rm.FIELD_TYPE(typ), length)))
+			}
+			fmt.Fprintf(s.out, "create table %s (%s);\n", n, strings.Join(defs, ", "))
+			continue
+		}
+		views, err := s.catalogQuery("select viewdef from viewcat where viewname = ?", n)
+		if err != nil {
+			return err
+		}
+		if len(views) == 0 {
+			return fmt.Errorf("%w: %s", planner.ErrTableNotFound, n)
+		}
+		fmt.Fprintf(s.out, "create view %s as %s;\n", n, views[0][0])
+	}
+	return nil
+}
+
+//printIndexes 输出idxcat中建立在给定表上的索引
+func (s *shell) printIndexes(table string) error {
+	rows, err := s.catalogQuery("select indexName,fieldName from idxcat where tableName = ?", table)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		fmt.Fprintf(s.out, "%s on %s (%s)\n", row[0], table, row[1])
+	}
+	return nil
+}
+
+//readFile 执行文件中的所有语句
+func (s *shell) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := s.run(f, false); err != nil {
+		return err
+	}
+	if stmt := strings.TrimSpace(s.pending.String()); stmt != "" {
+		s.pending.Reset()
+		return fmt.Errorf("%s: statement is not terminated by ;", path)
+	}
+	return nil
+}
+
+//loadHistory 读取之前保存的历史记录
+func (s *shell) loadHistory(path string) {
+	s.historyFile = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			s.history = append(s.history, line)
+		}
+	}
+	if len(s.history) > MAX_HISTORY {
+		s.history = s.history[len(s.history)-MAX_HISTORY:]
+	}
+}
+
+//addHistory 记录一条历史记录，多行的语句会合并成一行
+func (s *shell) addHistory(entry string) {
+	entry = strings.ReplaceAll(strings.TrimSpace(entry), "\n", " ")
+	s.history = append(s.history, entry)
+	if len(s.history) > MAX_HISTORY {
+		s.history = s.history[1:]
+	}
+	if s.historyFile == "" {
+		return
+	}
+	f, err := os.OpenFile(s.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, entry)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/db"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestShell(t *testing.T) (*shell, *bytes.Buffer) {
+	d, err := db.Open(filepath.Join(t.TempDir(), "shell_test"), db.Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	t.Cleanup(func() { d.Close() })
+	out := &bytes.Buffer{}
+	return newShell(d, out), out
+}
+
+func TestShellStatements(t *testing.T) {
+	s, out := newTestShell(t)
+	input := `create table student (name varchar(16),
+	gradyear int);
+insert into student (name,gradyear) values ("tom",2020); insert into student (name,gradyear) values ("a;b",2021);
+select name,gradyear from student;
+`
+	assert.Nil(t, s.run(strings.NewReader(input), false))
+	expected := `OK
+OK, 1 row affected
+OK, 1 row affected
++------+----------+
+| name | gradyear |
++------+----------+
+| tom  |     2020 |
+| a;b  |     2021 |
++------+----------+
+(2 rows)
+`
+	assert.Equal(t, expected, out.String())
+
+	out.Reset()
+	s.feedLine("select name from teacher;")
+	assert.True(t, strings.HasPrefix(out.String(), "Error: table not found"))
+}
+
+func TestShellMetaCommands(t *testing.T) {
+	s, out := newTestShell(t)
+	sqlFile := filepath.Join(t.TempDir(), "school.sql")
+	script := `create table student (name varchar(16),majorId int);
+create table dept (dname varchar(10),did int);
+create view names as select name from student;
+create index majorIdx on student (majorId);
+`
+	assert.Nil(t, os.WriteFile(sqlFile, []byte(script), 0644))
+	input := ".read " + sqlFile + "\n.tables\n.schema student\n.schema names\n.indexes student\n.explain on\nselect name from student;\n.nothing\n"
+	assert.Nil(t, s.run(strings.NewReader(input), false))
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, []string{"OK", "OK", "OK", "OK", "dept", "names", "student",
+		"create table student (name varchar(16), majorId int);",
+		"create view names as SELECT name FROM student;",
+		"majorIdx on student (majorId)"}, lines[:10])
+	assert.True(t, strings.HasPrefix(lines[10], "Project(name)"))
+	assert.True(t, strings.HasPrefix(lines[12], "    Table(student)"))
+	assert.Contains(t, out.String(), "(0 rows)")
+	assert.Contains(t, out.String(), "Error: unknown command .nothing")
+
+	//历史记录中多行语句合并成一行
+	assert.Equal(t, ".read "+sqlFile, s.history[0])
+	assert.Equal(t, "create table student (name varchar(16),majorId int);", s.history[1])
+}
+
+func TestShellTransaction(t *testing.T) {
+	s, out := newTestShell(t)
+	input := `create table student (name varchar(16), gradyear int);
+begin;
+insert into student (name,gradyear) values ("tom",2020);
+rollback;
+select name from student;
+`
+	assert.Nil(t, s.run(strings.NewReader(input), false))
+	expected := `OK
+OK
+OK, 1 row affected
+OK
++------+
+| name |
++------+
+(0 rows)
+`
+	assert.Equal(t, expected, out.String())
+	assert.False(t, s.sess.InTransaction())
+}
+
+func TestShellNull(t *testing.T) {
+	s, out := newTestShell(t)
+	input := `create table student (name varchar(16), gradyear int);
+insert into student (name) values ('tom');
+select name, gradyear from student where gradyear is null;
+`
+	assert.Nil(t, s.run(strings.NewReader(input), false))
+	expected := `OK
+OK, 1 row affected
++------+----------+
+| name | gradyear |
++------+----------+
+| tom  |     NULL |
++------+----------+
+(1 row)
+`
+	assert.Equal(t, expected, out.String())
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"miniSQL/db"
+	"miniSQL/server"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+/*
+	minisqld 以服务的形式运行数据库，客户端通过网络协议连接
+	用法：minisqld [-pg 127.0.0.1:5432] [-mysql 127.0.0.1:3306] [-user NAME -password PASS] [-http 127.0.0.1:8080] DIR
+*/
+
+const SHUTDOWN_TIMEOUT = 10 * time.Second //退出的时候等待HTTP请求结束的最长时间
+
+func main() {
+	blockSize := flag.Uint64("blocksize", db.DEFAULT_BLOCK_SIZE, "block size of the data directory")
+	numBuffers := flag.Uint("buffers", db.DEFAULT_NUM_BUFFERS, "number of buffers in the buffer pool")
+	pgAddr := flag.String("pg", "127.0.0.1:5432", "address to serve the PostgreSQL protocol on, empty to disable")
+	mysqlAddr := flag.String("mysql", "127.0.0.1:3306", "address to serve the MySQL protocol on, empty to disable")
+	user := flag.String("user", "", "MySQL user name, empty to accept any client")
+	password := flag.String("password", "", "MySQL password of -user")
+	httpAddr := flag.String("http", "", "address to serve the HTTP/JSON API on, empty to disable")
+	idleTimeout := flag.Duration("idle-timeout", server.DEFAULT_IDLE_TIMEOUT, "roll back HTTP transactions idle for longer than this")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] DIR\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	d, err := db.Open(flag.Arg(0), db.Options{BlockSize: *blockSize, NumBuffers: uint32(*numBuffers)})
+	if err != nil {
+		log.Fatalf("open %s: %v", flag.Arg(0), err)
+	}
+	servers := make([]*server.Server, 0)
+	errs := make(chan error, 1)
+	serve := func(name string, addr string, s *server.Server) {
+		if addr == "" {
+			return
+		}
+		servers = append(servers, s)
+		go func() {
+			log.Printf("serving %s protocol on %s", name, addr)
+			errs <- s.ListenAndServe(addr)
+		}()
+	}
+	serve("PostgreSQL", *pgAddr, server.NewPGServer(d))
+	var users map[string]string
+	if *user != "" {
+		users = map[string]string{*user: *password}
+	}
+	serve("MySQL", *mysqlAddr, server.NewMySQLServer(d, users))
+	var httpServer *http.Server
+	var httpHandler *server.HTTPHandler
+	if *httpAddr != "" {
+		httpHandler = server.NewHTTPHandler(d, *idleTimeout)
+		httpServer = &http.Server{Addr: *httpAddr, Handler: httpHandler}
+		go func() {
+			log.Printf("serving HTTP on %s", *httpAddr)
+			errs <- httpServer.ListenAndServe()
+		}()
+	}
+	if len(servers) == 0 && httpServer == nil {
+		log.Fatal("no protocol enabled")
+	}
+
+	//收到退出信号之后，关闭所有的服务，再关闭数据库
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case sig := <-signals:
+		log.Printf("received %v, shutting down", sig)
+	case err := <-errs:
+		log.Printf("server stopped: %v", err)
+	}
+	for _, s := range servers {
+		s.Close()
+	}
+	if httpServer != nil {
+		//先等正在处理的请求结束，再回滚还没有提交的HTTP事务
+		ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Printf("shutdown HTTP server: %v", err)
+			httpServer.Close()
+		}
+		cancel()
+		httpHandler.Close()
+	}
+	if err := d.Close(); err != nil {
+		log.Printf("close database: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package comm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+/*
+	常量之间的算术运算，表达式在执行的时候使用这些方法计算结果
+	整数，定点数和浮点数可以进行算术运算，字符串的拼接使用CONCAT函数
+	两个操作数的类型不同的时候先转换成范围更大的类型：整数和定点数运算的结果是定点数，有一个操作数是浮点数的时候结果是浮点数
+	整数运算的结果超出64位的时候返回ErrIntegerOverflow，不会溢出成错误的值
+	计算出错的时候返回的错误都包装了ErrArithmetic，调用者可以据此区分表达式的错误和其他的错误
+	有一个操作数是NULL的时候结果也是NULL
+*/
+
+var (
+	ErrArithmetic      = errors.New("arithmetic error")
+	ErrDivisionByZero  = fmt.Errorf("%w: division by zero", ErrArithmetic)
+	ErrIntegerOverflow = fmt.Errorf("%w: integer out of range", ErrArithmetic)
+)
+
+//numericOp 一个算术运算在三种数值类型上的实现，ints的结果溢出的时候第二个返回值是false
+type numericOp struct {
+	ints     func(a, b int) (int, bool)
+	floats   func(a, b float64) float64
+	decimals func(a, b Decimal) (Decimal, error)
+}
+
+//Add 两个数相加
+func (c *Constant) Add(obj *Constant) (*Constant, error) {
+	return c.arithmetic("+", obj, numericOp{
+		ints: func(a, b int) (int, bool) {
+			v := a + b
+			return v, (a^v)&(b^v) >= 0
+		},
+		floats:   func(a, b float64) float64 { return a + b },
+		decimals: Decimal.Add,
+	})
+}
+
+//Sub 两个数相减
+func (c *Constant) Sub(obj *Constant) (*Constant, error) {
+	return c.arithmetic("-", obj, numericOp{
+		ints: func(a, b int) (int, bool) {
+			v := a - b
+			return v, (a^b)&(a^v) >= 0
+		},
+		floats:   func(a, b float64) float64 { return a - b },
+		decimals: Decimal.Sub,
+	})
+}
+
+//Mul 两个数相乘
+func (c *Constant) Mul(obj *Constant) (*Constant, error) {
+	return c.arithmetic("*", obj, numericOp{
+		ints: func(a, b int) (int, bool) {
+			if a == 0 || b == 0 {
+				return 0, true
+			}
+			v := a * b
+			return v, v/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64)
+		},
+		floats:   func(a, b float64) float64 { return a * b },
+		decimals: Decimal.Mul,
+	})
+}
+
+//Div 除法，整数除法的结果向零取整
+func (c *Constant) Div(obj *Constant) (*Constant, error) {
+	if !c.IsNull() && obj.isZero() {
+		return nil, ErrDivisionByZero
+	}
+	return c.arithmetic("/", obj, numericOp{
+		ints: func(a, b int) (int, bool) {
+			return a / b, !(a == math.MinInt64 && b == -1)
+		},
+		floats:   func(a, b float64) float64 { return a / b },
+		decimals: Decimal.Div,
+	})
+}
+
+//Mod 取余数，结果的符号和被除数相同
+func (c *Constant) Mod(obj *Constant) (*Constant, error) {
+	if !c.IsNull() && obj.isZero() {
+		return nil, ErrDivisionByZero
+	}
+	return c.arithmetic("%", obj, numericOp{
+		ints: func(a, b int) (int, bool) {
+			return a % b, true
+		},
+		floats:   math.Mod,
+		decimals: Decimal.Mod,
+	})
+}
+
+//isZero 除数是不是0
+func (c *Constant) isZero() bool {
+	return c.IsNumber() && c.CompareTo(&Constant{Ival: new(int)}) == 0
+}
+
+//Negate 取负数
+func (c *Constant) Negate() (*Constant, error) {
+	switch {
+	case c.IsNull():
+		return c, nil
+	case c.Ival != nil:
+		if *c.Ival == math.MinInt64 {
+			return nil, fmt.Errorf("%w: -(%d)", ErrIntegerOverflow, *c.Ival)
+		}
+		v := -*c.Ival
+		return NewConstantInt(&v), nil
+	case c.Fval != nil:
+		v := -*c.Fval
+		return NewConstantFloat(&v), nil
+	case c.Dval != nil:
+		v := c.Dval.Negate()
+		return NewConstantDecimal(&v), nil
+	}
+	return nil, fmt.Errorf("%w: cannot negate %q", ErrArithmetic, c.ToString())
+}
+
+func (c *Constant) arithmetic(op string, obj *Constant, f numericOp) (*Constant, error) {
+	if c.IsNull() || obj.IsNull() {
+		return NewNullConstant(), nil
+	}
+	if !c.IsNumber() || !obj.IsNumber() {
+		return nil, fmt.Errorf("%w: cannot apply %s to %q and %q", ErrArithmetic, op, c.ToString(), obj.ToString())
+	}
+	switch {
+	case c.Ival != nil && obj.Ival != nil:
+		v, ok := f.ints(*c.Ival, *obj.Ival)
+		if !ok {
+			return nil, fmt.Errorf("%w: %d %s %d", ErrIntegerOverflow, *c.Ival, op, *obj.Ival)
+		}
+		return NewConstantInt(&v), nil
+	case c.Fval != nil || obj.Fval != nil:
+		v := f.floats(c.AsFloat(), obj.AsFloat())
+		return NewConstantFloat(&v), nil
+	}
+	v, err := f.decimals(c.AsDecimal(), obj.AsDecimal())
+	if err != nil {
+		return nil, err
+	}
+	return NewConstantDecimal(&v), nil
+}
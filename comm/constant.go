@@ -2,14 +2,20 @@ package comm
 
 import (
 	"hash/fnv"
+	"math"
 	"math/big"
 	"strconv"
+	"strings"
 )
 
 //Constant 用户可以不用指定string或者int类型数据的插入,这个可以表示一个常量
+//INTEGER和BIGINT都使用Ival，BOOLEAN使用Bval，DOUBLE使用Fval，DECIMAL使用Dval
 type Constant struct {
 	Ival *int
 	Sval *string
+	Bval *bool
+	Fval *float64
+	Dval *Decimal
 }
 
 //NewConstantInt 构造当前位int类型的对象
@@ -28,15 +34,70 @@ func NewConstantString(val *string) *Constant {
 	}
 }
 
+//NewConstantBool 构造一个布尔类型的常量
+func NewConstantBool(val *bool) *Constant {
+	return &Constant{Bval: val}
+}
+
+//NewConstantFloat 构造一个浮点数常量
+func NewConstantFloat(val *float64) *Constant {
+	return &Constant{Fval: val}
+}
+
+//NewConstantDecimal 构造一个定点数常量
+func NewConstantDecimal(val *Decimal) *Constant {
+	return &Constant{Dval: val}
+}
+
+//NewNullConstant 构造一个NULL，外连接中没有匹配上的一边使用NULL填充
+func NewNullConstant() *Constant {
+	return &Constant{}
+}
+
+//IsNull 所有的值都没有的时候是NULL，预处理语句中还没有绑定值的参数槽也是这种形式
+func (c *Constant) IsNull() bool {
+	return c.Ival == nil && c.Sval == nil && c.Bval == nil && c.Fval == nil && c.Dval == nil
+}
+
+//IsNumber 是不是整数，浮点数或者定点数，这些类型之间可以互相运算和比较
+func (c *Constant) IsNumber() bool {
+	return c.Ival != nil || c.Fval != nil || c.Dval != nil
+}
+
+//Set 把val的值复制到当前的常量中，预处理语句绑定参数的时候使用
+func (c *Constant) Set(val *Constant) {
+	c.Ival, c.Sval, c.Bval, c.Fval, c.Dval = val.Ival, val.Sval, val.Bval, val.Fval, val.Dval
+}
+
 //ToString 将该Constant存储的值按照字符串的形式显示
 func (c *Constant) ToString() string {
-	if c.Ival != nil {
+	switch {
+	case c.IsNull():
+		return "NULL"
+	case c.Ival != nil:
 		//当前是int类型
 		return strconv.FormatInt((int64)(*c.Ival), 10) //将他转化string类型
+	case c.Bval != nil:
+		if *c.Bval {
+			return "TRUE"
+		}
+		return "FALSE"
+	case c.Fval != nil:
+		return FormatFloat(*c.Fval)
+	case c.Dval != nil:
+		return c.Dval.String()
 	}
 	return *c.Sval
 }
 
+//FormatFloat 浮点数使用能够准确表示它的最短形式，很大或者很小的数使用科学计数法
+func FormatFloat(f float64) string {
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-4 || abs >= 1e15) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
 //AsInt 将当前的Constant类型作为int类型返回
 func (c *Constant) AsInt() int {
 	return *c.Ival
@@ -47,27 +108,141 @@ func (c *Constant) AsString() string {
 	return *c.Sval
 }
 
-//Equal	判断两个Constant类型是否相同
+//AsBool 将当前Constant类型作为bool类型返回
+func (c *Constant) AsBool() bool {
+	return *c.Bval
+}
+
+//AsFloat 将数值转换成浮点数返回，整数和定点数也可以转换
+func (c *Constant) AsFloat() float64 {
+	switch {
+	case c.Ival != nil:
+		return float64(*c.Ival)
+	case c.Dval != nil:
+		return c.Dval.Float64()
+	}
+	return *c.Fval
+}
+
+//AsDecimal 将整数或者定点数作为定点数返回
+func (c *Constant) AsDecimal() Decimal {
+	if c.Ival != nil {
+		return NewDecimal(int64(*c.Ival), 0)
+	}
+	return *c.Dval
+}
+
+//Equal	判断两个Constant类型是否相同，不同类型的数值按照大小比较
 func (c *Constant) Equal(obj *Constant) bool {
 	//判断两个Constant类型是否相同
-	if c.Ival != nil && obj.Ival != nil {
-		return *c.Ival == *obj.Ival
+	if c.IsNull() || obj.IsNull() || c.kind() != obj.kind() {
+		return false
+	}
+	return c.CompareTo(obj) == 0
+}
+
+//Comparable 两个值是不是可以比较，不同类型的数值之间可以比较，其他的类型必须相同
+func (c *Constant) Comparable(obj *Constant) bool {
+	return c.kind() == obj.kind()
+}
+
+const (
+	kindNull = iota
+	kindBool
+	kindNumber
+	kindString
+)
+
+//kind 比较的时候使用的大类，不同的数值类型属于同一个大类
+func (c *Constant) kind() int {
+	switch {
+	case c.IsNull():
+		return kindNull
+	case c.Bval != nil:
+		return kindBool
+	case c.Sval != nil:
+		return kindString
 	}
-	if c.Sval != nil && obj.Sval != nil {
-		return *c.Sval == *obj.Sval
+	return kindNumber
+}
+
+//CompareTo 比较两个Constant的大小，小于返回-1，相等返回0，大于返回1
+//数值按照大小比较，字符串按照字典序比较，FALSE排在TRUE的前面
+//不同大类之间按照NULL，布尔值，数值，字符串的顺序排列，所以整数总是排在字符串的前面，NULL排在最前面
+func (c *Constant) CompareTo(obj *Constant) int {
+	switch {
+	case c.kind() != obj.kind():
+		return compareOrdered(c.kind(), obj.kind())
+	case c.IsNull():
+		return 0
+	case c.Sval != nil:
+		return strings.Compare(*c.Sval, *obj.Sval)
+	case c.Bval != nil:
+		return compareOrdered(boolToInt(*c.Bval), boolToInt(*obj.Bval))
+	case c.Ival != nil && obj.Ival != nil:
+		return compareOrdered(*c.Ival, *obj.Ival)
+	case c.Fval != nil || obj.Fval != nil:
+		a, b := c.AsFloat(), obj.AsFloat()
+		if a < b {
+			return -1
+		}
+		if a > b {
+			return 1
+		}
+		return 0
+	default:
+		return c.AsDecimal().Cmp(obj.AsDecimal())
 	}
-	return false
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func compareOrdered(a, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+//Integral 数值是整数的时候返回这个整数，Equal相等的数值哈希值也要相同，所以值是整数的浮点数和定点数按照整数计算
+func (c *Constant) Integral() (int64, bool) {
+	switch {
+	case c.Ival != nil:
+		return int64(*c.Ival), true
+	case c.Dval != nil:
+		d := c.Dval.Normalize()
+		return d.Unscaled, d.Scale == 0
+	case c.Fval != nil:
+		f := *c.Fval
+		if f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			return int64(f), true
+		}
+	}
+	return 0, false
 }
 
 //HashCode 获得他的一个哈希值
 func (c *Constant) HashCode() uint32 {
 	var bytes []byte
 	h := fnv.New32a()
-	if c.Ival != nil {
+	if v, ok := c.Integral(); ok {
 		//将数值转化成字节数组，然后再进行编码
-		s := big.NewInt(int64(*c.Ival)) //转化成一个Int类型的变量
-		bytes = s.Bytes()               //将他转化成一个字节数组
-	} else {
+		s := big.NewInt(v) //转化成一个Int类型的变量
+		bytes = s.Bytes()  //将他转化成一个字节数组
+	} else if c.IsNumber() {
+		//不是整数的数值使用浮点数的二进制表示
+		bytes = big.NewInt(int64(math.Float64bits(c.AsFloat()))).Bytes()
+	} else if c.Bval != nil {
+		bytes = []byte{byte(boolToInt(*c.Bval))}
+	} else if c.Sval != nil {
 		bytes = []byte(*c.Sval) //如果是字符串类型，就可以直接将他转化成一个字节数组
 	}
 	h.Write(bytes) //写入到这个对象中去
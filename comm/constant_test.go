@@ -2,6 +2,7 @@ package comm
 
 import (
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
@@ -42,3 +43,145 @@ func TestConstant(t *testing.T) {
 	assert.False(t, cInt.Equal(cStr))
 
 }
+
+func TestConstantCompareTo(t *testing.T) {
+	one, two := 1, 2
+	a, b := "a", "b"
+	assert.Equal(t, -1, NewConstantInt(&one).CompareTo(NewConstantInt(&two)))
+	assert.Equal(t, 1, NewConstantInt(&two).CompareTo(NewConstantInt(&one)))
+	assert.Equal(t, 0, NewConstantInt(&one).CompareTo(NewConstantInt(&one)))
+	assert.Equal(t, -1, NewConstantString(&a).CompareTo(NewConstantString(&b)))
+	assert.Equal(t, 0, NewConstantString(&b).CompareTo(NewConstantString(&b)))
+	assert.Equal(t, -1, NewConstantInt(&two).CompareTo(NewConstantString(&a)))
+	assert.Equal(t, 1, NewConstantString(&a).CompareTo(NewConstantInt(&two)))
+}
+
+func TestConstantArithmetic(t *testing.T) {
+	seven, two, zero := 7, 2, 0
+	a, b, z := NewConstantInt(&seven), NewConstantInt(&two), NewConstantInt(&zero)
+	s := "abc"
+	str := NewConstantString(&s)
+
+	cases := []struct {
+		f      func(*Constant) (*Constant, error)
+		expect int
+	}{
+		{a.Add, 9}, {a.Sub, 5}, {a.Mul, 14}, {a.Div, 3}, {a.Mod, 1},
+	}
+	for _, c := range cases {
+		val, err := c.f(b)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expect, val.AsInt())
+	}
+	neg, err := a.Negate()
+	assert.Nil(t, err)
+	assert.Equal(t, -7, neg.AsInt())
+	//整数除法向零取整，余数的符号和被除数相同
+	val, _ := neg.Div(b)
+	assert.Equal(t, -3, val.AsInt())
+	val, _ = neg.Mod(b)
+	assert.Equal(t, -1, val.AsInt())
+
+	_, err = a.Div(z)
+	assert.ErrorIs(t, err, ErrDivisionByZero)
+	_, err = a.Mod(z)
+	assert.ErrorIs(t, err, ErrArithmetic)
+	_, err = a.Add(str)
+	assert.ErrorIs(t, err, ErrArithmetic)
+	_, err = str.Negate()
+	assert.ErrorIs(t, err, ErrArithmetic)
+
+	//整数运算超出64位的时候返回错误，不会溢出成错误的值
+	max, min, minusOne := math.MaxInt64, math.MinInt64, -1
+	big, small, m := NewConstantInt(&max), NewConstantInt(&min), NewConstantInt(&minusOne)
+	_, err = big.Add(a)
+	assert.ErrorIs(t, err, ErrIntegerOverflow)
+	_, err = small.Sub(a)
+	assert.ErrorIs(t, err, ErrIntegerOverflow)
+	_, err = big.Mul(b)
+	assert.ErrorIs(t, err, ErrIntegerOverflow)
+	_, err = m.Mul(small)
+	assert.ErrorIs(t, err, ErrIntegerOverflow)
+	_, err = small.Div(m)
+	assert.ErrorIs(t, err, ErrIntegerOverflow)
+	_, err = small.Negate()
+	assert.ErrorIs(t, err, ErrIntegerOverflow)
+	val, err = big.Add(m)
+	assert.Nil(t, err)
+	assert.Equal(t, math.MaxInt64-1, val.AsInt())
+	val, err = small.Mod(m)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, val.AsInt())
+}
+
+func TestNullConstant(t *testing.T) {
+	null := NewNullConstant()
+	one := 1
+	name := "a"
+	assert.True(t, null.IsNull())
+	assert.False(t, NewConstantInt(&one).IsNull())
+	assert.Equal(t, "NULL", null.ToString())
+	assert.False(t, null.Equal(NewNullConstant()))
+	//NULL排在最前面
+	assert.Equal(t, 0, null.CompareTo(NewNullConstant()))
+	assert.Equal(t, -1, null.CompareTo(NewConstantInt(&one)))
+	assert.Equal(t, 1, NewConstantString(&name).CompareTo(null))
+	assert.Equal(t, NewNullConstant().HashCode(), null.HashCode())
+	//NULL参与运算的结果还是NULL，NULL除以0也不会出错
+	for _, f := range []func(*Constant) (*Constant, error){null.Add, null.Div, NewConstantInt(&one).Mul} {
+		val, err := f(NewNullConstant())
+		assert.Nil(t, err)
+		assert.True(t, val.IsNull())
+	}
+	zero := 0
+	val, err := null.Mod(NewConstantInt(&zero))
+	assert.Nil(t, err)
+	assert.True(t, val.IsNull())
+	val, err = null.Negate()
+	assert.Nil(t, err)
+	assert.True(t, val.IsNull())
+}
+
+func TestNumericConstant(t *testing.T) {
+	two, yes, no := 2, true, false
+	half, three := 0.5, 3.0
+	price := NewDecimal(1250, 2)
+	i, f, d := NewConstantInt(&two), NewConstantFloat(&half), NewConstantDecimal(&price)
+	assert.Equal(t, "0.5", f.ToString())
+	assert.Equal(t, "12.50", d.ToString())
+	assert.Equal(t, "TRUE", NewConstantBool(&yes).ToString())
+
+	//整数和定点数运算的结果是定点数，有浮点数的时候结果是浮点数
+	val, err := i.Mul(d)
+	assert.Nil(t, err)
+	assert.Equal(t, "25.00", val.ToString())
+	val, err = d.Add(f)
+	assert.Nil(t, err)
+	assert.Equal(t, 13.0, val.AsFloat())
+	val, err = d.Div(i)
+	assert.Nil(t, err)
+	assert.Equal(t, "6.250000", val.ToString())
+	val, err = d.Mod(i)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.50", val.ToString())
+	val, err = f.Negate()
+	assert.Nil(t, err)
+	assert.Equal(t, -0.5, val.AsFloat())
+	_, err = d.Div(NewConstantFloat(new(float64)))
+	assert.ErrorIs(t, err, ErrDivisionByZero)
+	_, err = NewConstantBool(&yes).Add(i)
+	assert.ErrorIs(t, err, ErrArithmetic)
+
+	//不同类型的数值按照大小比较，相等的数值哈希值相同
+	whole := NewDecimal(300, 2)
+	assert.True(t, NewConstantFloat(&three).Equal(NewConstantDecimal(&whole)))
+	assert.Equal(t, NewConstantFloat(&three).HashCode(), NewConstantDecimal(&whole).HashCode())
+	three3 := 3
+	assert.Equal(t, NewConstantInt(&three3).HashCode(), NewConstantDecimal(&whole).HashCode())
+	assert.Equal(t, NewConstantFloat(&half).HashCode(), NewConstantDecimal(&Decimal{Unscaled: 5, Scale: 1}).HashCode())
+	assert.Equal(t, -1, f.CompareTo(i))
+	assert.Equal(t, 1, d.CompareTo(i))
+	assert.Equal(t, -1, NewConstantBool(&no).CompareTo(NewConstantBool(&yes)))
+	assert.False(t, NewConstantBool(&yes).Equal(NewConstantInt(new(int))))
+	assert.False(t, NewConstantBool(&no).IsNull())
+}
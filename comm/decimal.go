@@ -0,0 +1,215 @@
+package comm
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+/*
+	定点数，值是Unscaled / 10^Scale，例如12.50的Unscaled是1250，Scale是2
+	DECIMAL最多有18位有效数字，这样Unscaled总是可以使用一个int64保存
+	运算的中间结果使用big.Int计算，最后的结果超过18位的时候返回ErrDecimalOverflow
+	小数位数需要减少的时候四舍五入，0.5向远离0的方向进位
+*/
+
+const (
+	MAX_DECIMAL_PRECISION = 18 //DECIMAL最多的有效数字位数
+	DECIMAL_DIV_SCALE     = 4  //除法结果的小数位数比被除数多4位
+)
+
+var ErrDecimalOverflow = fmt.Errorf("%w: decimal overflow", ErrArithmetic)
+
+//maxUnscaled 18位数字能表示的最大值加一，也就是10^18
+var maxUnscaled = pow10(MAX_DECIMAL_PRECISION)
+
+//Decimal 定点数
+type Decimal struct {
+	Unscaled int64 //去掉小数点之后的整数
+	Scale    int   //小数点后面的位数
+}
+
+//NewDecimal 构造一个定点数
+func NewDecimal(unscaled int64, scale int) Decimal {
+	return Decimal{Unscaled: unscaled, Scale: scale}
+}
+
+//ParseDecimal 解析12.5，-0.25这样的字符串，小数位数就是小数点后面数字的个数
+func ParseDecimal(s string) (Decimal, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	intPart, fracPart := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		intPart, fracPart = digits[:i], digits[i+1:]
+	}
+	if intPart+fracPart == "" || strings.ContainsAny(intPart+fracPart, "+-") {
+		return Decimal{}, fmt.Errorf("%w: invalid decimal %q", ErrArithmetic, s)
+	}
+	v, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("%w: invalid decimal %q", ErrArithmetic, s)
+	}
+	if strings.HasPrefix(s, "-") {
+		v.Neg(v)
+	}
+	return decimalFromBig(v, len(fracPart))
+}
+
+//DecimalFromFloat 把浮点数转换成定点数，使用能精确还原这个浮点数的最短的十进制形式
+func DecimalFromFloat(f float64) (Decimal, error) {
+	d, err := ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil || d.Scale <= MAX_DECIMAL_PRECISION {
+		return d, err
+	}
+	return d.Rescale(MAX_DECIMAL_PRECISION)
+}
+
+//decimalFromBig 检查结果有没有超过18位数字
+func decimalFromBig(v *big.Int, scale int) (Decimal, error) {
+	if new(big.Int).Abs(v).Cmp(maxUnscaled) >= 0 {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return Decimal{Unscaled: v.Int64(), Scale: scale}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) bigInt() *big.Int {
+	return big.NewInt(d.Unscaled)
+}
+
+//String 按照小数位数显示，12.50不会显示成12.5
+func (d Decimal) String() string {
+	s := strconv.FormatInt(d.Unscaled, 10)
+	sign := ""
+	if d.Unscaled < 0 {
+		sign, s = "-", s[1:]
+	}
+	if d.Scale == 0 {
+		return sign + s
+	}
+	if len(s) <= d.Scale {
+		s = strings.Repeat("0", d.Scale-len(s)+1) + s
+	}
+	return sign + s[:len(s)-d.Scale] + "." + s[len(s)-d.Scale:]
+}
+
+//Float64 转换成浮点数，和DOUBLE一起运算的时候使用
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+//Precision 有效数字的位数，整数部分是0的时候不计算在内
+func (d Decimal) Precision() int {
+	s := strconv.FormatInt(d.Unscaled, 10)
+	return len(strings.TrimPrefix(s, "-"))
+}
+
+//IntegerDigits 整数部分的位数，写入DECIMAL(p,s)字段的时候不能超过p-s
+func (d Decimal) IntegerDigits() int {
+	if n := d.Precision() - d.Scale; n > 0 && d.Unscaled != 0 {
+		return n
+	}
+	return 0
+}
+
+//Rescale 把小数位数变成scale，位数减少的时候四舍五入
+func (d Decimal) Rescale(scale int) (Decimal, error) {
+	if scale >= d.Scale {
+		return decimalFromBig(new(big.Int).Mul(d.bigInt(), pow10(scale-d.Scale)), scale)
+	}
+	return decimalFromBig(roundDiv(d.bigInt(), pow10(d.Scale-scale)), scale)
+}
+
+//Normalize 去掉小数末尾的0，值相同的定点数结果相同
+func (d Decimal) Normalize() Decimal {
+	for d.Scale > 0 && d.Unscaled%10 == 0 {
+		d.Unscaled /= 10
+		d.Scale--
+	}
+	return d
+}
+
+//Cmp 比较两个定点数的大小，小于返回-1，相等返回0，大于返回1
+func (d Decimal) Cmp(obj Decimal) int {
+	a, b := align(d, obj)
+	return a.Cmp(b)
+}
+
+//align 把两个定点数变成相同的小数位数，返回去掉小数点之后的整数
+func align(a, b Decimal) (*big.Int, *big.Int) {
+	x, y := a.bigInt(), b.bigInt()
+	if a.Scale < b.Scale {
+		x.Mul(x, pow10(b.Scale-a.Scale))
+	} else {
+		y.Mul(y, pow10(a.Scale-b.Scale))
+	}
+	return x, y
+}
+
+//roundDiv 除法，结果四舍五入
+func roundDiv(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	//余数的两倍不小于除数的时候进位，进位的方向和商的符号相同
+	if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(new(big.Int).Abs(b)) >= 0 {
+		if a.Sign()*b.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+//Add 加法，结果的小数位数是两个操作数中较多的那个
+func (d Decimal) Add(obj Decimal) (Decimal, error) {
+	a, b := align(d, obj)
+	return decimalFromBig(a.Add(a, b), maxInt(d.Scale, obj.Scale))
+}
+
+//Sub 减法，结果的小数位数是两个操作数中较多的那个
+func (d Decimal) Sub(obj Decimal) (Decimal, error) {
+	a, b := align(d, obj)
+	return decimalFromBig(a.Sub(a, b), maxInt(d.Scale, obj.Scale))
+}
+
+//Mul 乘法，结果的小数位数是两个操作数的小数位数之和，超过18位的时候四舍五入
+func (d Decimal) Mul(obj Decimal) (Decimal, error) {
+	v, scale := new(big.Int).Mul(d.bigInt(), obj.bigInt()), d.Scale+obj.Scale
+	if scale > MAX_DECIMAL_PRECISION {
+		v, scale = roundDiv(v, pow10(scale-MAX_DECIMAL_PRECISION)), MAX_DECIMAL_PRECISION
+	}
+	return decimalFromBig(v, scale)
+}
+
+//Div 除法，结果的小数位数比被除数多DECIMAL_DIV_SCALE位，四舍五入，调用者保证除数不是0
+func (d Decimal) Div(obj Decimal) (Decimal, error) {
+	scale := d.Scale + DECIMAL_DIV_SCALE
+	if scale > MAX_DECIMAL_PRECISION {
+		scale = MAX_DECIMAL_PRECISION
+	}
+	//d / obj = (d.Unscaled * 10^(scale - d.Scale + obj.Scale) / obj.Unscaled) / 10^scale
+	v := new(big.Int).Mul(d.bigInt(), pow10(scale-d.Scale+obj.Scale))
+	return decimalFromBig(roundDiv(v, obj.bigInt()), scale)
+}
+
+//Mod 取余数，结果的符号和被除数相同，调用者保证除数不是0
+func (d Decimal) Mod(obj Decimal) (Decimal, error) {
+	a, b := align(d, obj)
+	return decimalFromBig(a.Rem(a, b), maxInt(d.Scale, obj.Scale))
+}
+
+//Negate 取负数
+func (d Decimal) Negate() Decimal {
+	return Decimal{Unscaled: -d.Unscaled, Scale: d.Scale}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
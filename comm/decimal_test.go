@@ -0,0 +1,46 @@
+package comm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecimal(t *testing.T) {
+	for _, s := range []string{"12.50", "-0.05", "7", "0.000", "-123456789.123456789"} {
+		d, err := ParseDecimal(s)
+		assert.Nil(t, err)
+		assert.Equal(t, s, d.String())
+	}
+	for _, s := range []string{"", ".", "1.2.3", "--1", "1e5", "1234567890123456789"} {
+		_, err := ParseDecimal(s)
+		assert.ErrorIs(t, err, ErrArithmetic, s)
+	}
+
+	d, _ := ParseDecimal("2.345")
+	//四舍五入，0.5向远离0的方向进位
+	r, err := d.Rescale(2)
+	assert.Nil(t, err)
+	assert.Equal(t, "2.35", r.String())
+	r, _ = d.Negate().Rescale(2)
+	assert.Equal(t, "-2.35", r.String())
+	r, _ = d.Rescale(5)
+	assert.Equal(t, "2.34500", r.String())
+	assert.Equal(t, 0, r.Cmp(d))
+	assert.Equal(t, NewDecimal(469, 2), NewDecimal(46900, 4).Normalize())
+	assert.Equal(t, 1, d.IntegerDigits())
+	assert.Equal(t, 0, NewDecimal(5, 2).IntegerDigits())
+
+	one, _ := ParseDecimal("1")
+	three, _ := ParseDecimal("3")
+	q, err := one.Div(three)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.3333", q.String())
+	q, _ = NewDecimal(-10, 1).Div(three)
+	assert.Equal(t, "-0.33333", q.String())
+
+	big := NewDecimal(999999999999999999, 0)
+	_, err = big.Add(one)
+	assert.ErrorIs(t, err, ErrDecimalOverflow)
+	_, err = big.Rescale(1)
+	assert.ErrorIs(t, err, ErrDecimalOverflow)
+}
@@ -191,6 +191,11 @@ func (c *LRUCache) Contain(key string) bool {
 	return ok //判断某一个区块是否存在
 }
 
+//Value 返回缓存项中保存的值
+func (i *CacheItem) Value() interface{} {
+	return i.value
+}
+
 func (c *LRUCache) Items() map[string]*list.Element {
 	return c.cache
 }
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"errors"
+	bm "miniSQL/buffer_manager"
+	fm "miniSQL/file_manager"
+	lm "miniSQL/log_manager"
+	mm "miniSQL/metadata_manager"
+	"miniSQL/planner"
+	tx "miniSQL/transaction"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+/*
+	DB 把文件管理器，日志管理器，缓存管理器，元数据管理器以及查询规划器全部组装在一起
+	之前每个使用者都需要自己按照顺序构造这些对象，现在只需要调用Open即可
+	启动的时候：先执行一次恢复，把上一次没有完成的事务全部回滚掉，如果是一个新的数据库，就需要创建元数据表
+	关闭的时候：把脏页刷新到磁盘中，写入一个checkpoint，最后关闭打开的文件
+*/
+
+var (
+	ErrClosed             = errors.New("database is closed")
+	ErrActiveTransactions = errors.New("database has active transactions")
+)
+
+//DB 一个打开的数据库实例
+type DB struct {
+	dir           string
+	fm            *fm.FileManager
+	lm            *lm.LogManager
+	bm            *bm.BufferManager
+	mdm           *mm.MetaDataManager
+	queryPlanner  *planner.BasicQueryPlan
+	updatePlanner *planner.BasicUpdatePlanner
+	planner       *planner.Planner
+	closed        bool
+	mu            sync.RWMutex
+	active        int        //还没有提交或者回滚的事务数量
+	activeMu      sync.Mutex //事务在持有mu的时候结束，所以active使用单独的锁
+}
+
+//Open 打开dir目录下的数据库，如果目录不存在就会创建一个新的数据库
+func Open(dir string, opts Options) (*DB, error) {
+	opts = opts.withDefaults()
+	fileManager, err := fm.NewFileManager(dir, opts.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	//目录可能是使用者自己提前创建好的，所以还需要检查元数据表是否已经存在
+	isNew := fileManager.IsNew() || !fileExists(filepath.Join(dir, "tblcat.tbl"))
+	logManager, err := lm.NewLogManager(fileManager, opts.LogFile)
+	if err != nil {
+		fileManager.Close()
+		return nil, err
+	}
+	bufferManager := bm.NewBufferManager(fileManager, logManager, opts.NumBuffers)
+	d := &DB{
+		dir: dir,
+		fm:  fileManager,
+		lm:  logManager,
+		bm:  bufferManager,
+	}
+	//启动的时候使用一个单独的事务来完成恢复和元数据的初始化
+	startTx := d.NewTx()
+	if !isNew {
+		//旧的数据库，需要把上次没有commit的事务都回滚掉
+		if err := startTx.Recover(); err != nil {
+			startTx.RollBack()
+			fileManager.Close()
+			return nil, err
+		}
+	}
+	d.mdm, err = mm.NewMetaDataManager(isNew, startTx)
+	if err != nil {
+		startTx.RollBack()
+		fileManager.Close()
+		return nil, err
+	}
+	startTx.Commit()
+	d.queryPlanner = planner.NewBasicQueryPlan(d.mdm)
+	d.updatePlanner = planner.NewBasicUpdatePlanner(d.mdm)
+	d.planner = planner.NewPlanner(d.queryPlanner, d.updatePlanner)
+	return d, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+//Dir 返回数据库所在的目录
+func (d *DB) Dir() string {
+	return d.dir
+}
+
+//NewTx 开启一个新的事务，在数据库关闭之前必须提交或者回滚
+func (d *DB) NewTx() *tx.Transaction {
+	t := tx.NewTransaction(d.fm, d.lm, d.bm)
+	d.activeMu.Lock()
+	d.active++
+	d.activeMu.Unlock()
+	t.OnFinish(func() {
+		d.activeMu.Lock()
+		d.active--
+		d.activeMu.Unlock()
+	})
+	return t
+}
+
+//MetaDataManager 返回元数据管理器
+func (d *DB) MetaDataManager() *mm.MetaDataManager {
+	return d.mdm
+}
+
+//QueryPlanner 返回查询规划器
+func (d *DB) QueryPlanner() *planner.BasicQueryPlan {
+	return d.queryPlanner
+}
+
+//UpdatePlanner 返回修改规划器
+func (d *DB) UpdatePlanner() *planner.BasicUpdatePlanner {
+	return d.updatePlanner
+}
+
+//Planner 返回封装了查询和修改规划器的Planner，可以直接执行SQL语句
+func (d *DB) Planner() *planner.Planner {
+	return d.planner
+}
+
+//Exec 在一个单独的事务中执行一条修改语句，执行成功就提交，失败就回滚，返回受影响的记录数
+func (d *DB) Exec(sql string) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return 0, ErrClosed
+	}
+	t := d.NewTx()
+	count, err := d.planner.ExecuteUpdate(sql, t)
+	if err != nil {
+		t.RollBack()
+		return 0, err
+	}
+	t.Commit()
+	return count, nil
+}
+
+//checkOpen 检查数据库是否已经关闭
+func (d *DB) checkOpen() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+//Close 关闭数据库，所有的事务都必须在关闭之前提交或者回滚
+//还有没结束的事务时返回ErrActiveTransactions，数据库保持打开，否则恢复遇到checkpoint就停下来，这些事务的修改不会被回滚
+func (d *DB) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	d.activeMu.Lock()
+	active := d.active
+	d.activeMu.Unlock()
+	if active > 0 {
+		return ErrActiveTransactions
+	}
+	d.closed = true
+	//先把数据刷新到磁盘中，然后再写入checkpoint，下一次启动的时候恢复到这里就可以结束了
+	d.bm.FlushDirty()
+	lsn, err := tx.WriteCheckPointToLog(d.lm)
+	if err != nil {
+		return err
+	}
+	if err := d.lm.FlushByLSN(lsn); err != nil {
+		return err
+	}
+	return d.fm.Close()
+}
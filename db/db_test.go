@@ -0,0 +1,81 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+//scanStudents 把student表中的名字都读取出来
+func scanStudents(t *testing.T, d *DB) []string {
+	tx := d.NewTx()
+	defer tx.Commit()
+	scan, sch, err := d.Planner().ExecuteQuery("select name,gradyear from student", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "gradyear"}, sch.Fields())
+	defer scan.Close()
+	names := make([]string, 0)
+	for scan.Next() {
+		names = append(names, scan.GetString("name"))
+	}
+	return names
+}
+
+func TestOpenExecReopen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "db_test")
+	d, err := Open(dir, Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	_, err = d.Exec("create table student (name varchar(16),majorId int,gradyear int)")
+	assert.Nil(t, err)
+	for _, sql := range []string{
+		"insert into student (name,majorId,gradyear) values (\"tom\",10,2020)",
+		"insert into student (name,majorId,gradyear) values (\"jim\",20,2021)",
+		"insert into student (name,majorId,gradyear) values (\"amy\",10,2022)",
+	} {
+		n, err := d.Exec(sql)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+	}
+	n, err := d.Exec("delete from student where name = \"jim\"")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"tom", "amy"}, scanStudents(t, d))
+	assert.Nil(t, d.Close())
+	_, err = d.Exec("delete from student")
+	assert.Equal(t, ErrClosed, err)
+
+	//重新打开数据库之后，已经提交的数据都还在
+	d, err = Open(dir, Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tom", "amy"}, scanStudents(t, d))
+	assert.Nil(t, d.Close())
+}
+
+func TestExecSyntaxError(t *testing.T) {
+	d, err := Open(filepath.Join(t.TempDir(), "db_test"), DefaultOptions())
+	assert.Nil(t, err)
+	defer d.Close()
+	_, err = d.Exec("selec name from student")
+	assert.NotNil(t, err)
+}
+
+func TestCloseWithActiveTransaction(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "db_test")
+	d, err := Open(dir, Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	_, err = d.Exec("create table student (name varchar(16),majorId int,gradyear int)")
+	assert.Nil(t, err)
+	tx := d.NewTx()
+	_, err = d.Planner().ExecuteUpdate("insert into student (name,majorId,gradyear) values (\"tom\",10,2020)", tx)
+	assert.Nil(t, err)
+	//还有没结束的事务，不能写入checkpoint，数据库保持打开
+	assert.Equal(t, ErrActiveTransactions, d.Close())
+	assert.Nil(t, d.checkOpen())
+	assert.Nil(t, tx.RollBack())
+	assert.Nil(t, d.Close())
+
+	d, err = Open(dir, Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, scanStudents(t, d))
+	assert.Nil(t, d.Close())
+}
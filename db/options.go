@@ -0,0 +1,37 @@
+package db
+
+const (
+	DEFAULT_BLOCK_SIZE  = 4096      //默认的区块大小
+	DEFAULT_NUM_BUFFERS = 64        //默认的缓存池中的缓存页数量
+	DEFAULT_LOG_FILE    = "logfile" //默认的日志文件名
+)
+
+//Options 打开数据库时可以指定的参数，没有设置的字段会使用默认值
+type Options struct {
+	BlockSize  uint64 //一个区块的大小，同一个数据目录必须一直使用同一个区块大小
+	NumBuffers uint32 //缓存池中的缓存页数量
+	LogFile    string //日志文件的名字
+}
+
+//DefaultOptions 返回默认的配置
+func DefaultOptions() Options {
+	return Options{
+		BlockSize:  DEFAULT_BLOCK_SIZE,
+		NumBuffers: DEFAULT_NUM_BUFFERS,
+		LogFile:    DEFAULT_LOG_FILE,
+	}
+}
+
+//withDefaults 把没有设置的字段填充成默认值
+func (o Options) withDefaults() Options {
+	if o.BlockSize == 0 {
+		o.BlockSize = DEFAULT_BLOCK_SIZE
+	}
+	if o.NumBuffers == 0 {
+		o.NumBuffers = DEFAULT_NUM_BUFFERS
+	}
+	if o.LogFile == "" {
+		o.LogFile = DEFAULT_LOG_FILE
+	}
+	return o
+}
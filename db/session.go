@@ -0,0 +1,365 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"miniSQL/comm"
+	"miniSQL/parser"
+	"miniSQL/planner"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+)
+
+/*
+	Session 一个客户端的会话，调用者不需要再自己管理transaction.Transaction
+	没有执行BEGIN的时候处在自动提交模式，每条语句都在自己的事务中执行，执行成功就提交，失败就回滚
+	执行BEGIN之后，语句都在同一个事务中执行，直到COMMIT或者ROLLBACK
+	事务中的语句执行失败的时候，只撤销这一条语句的修改，事务还可以继续使用；
+	如果是获取锁超时导致语句被中止，事务可能已经处在不一致的状态，整个事务都会被回滚
+	SAVEPOINT记录当前的日志编号，ROLLBACK TO会撤销这个编号之后当前事务写入的日志
+	Session不是并发安全的，一个会话同一时刻只能执行一条语句
+*/
+
+var (
+	ErrAborted           = errors.New("statement aborted")
+	ErrInTransaction     = errors.New("there is already a transaction in progress")
+	ErrNoTransaction     = errors.New("there is no transaction in progress")
+	ErrReadOnly          = errors.New("cannot execute statement in a read-only transaction")
+	ErrSavepointNotFound = errors.New("savepoint does not exist")
+)
+
+//savepoint 事务中的一个保存点
+type savepoint struct {
+	name string
+	lsn  uint64
+}
+
+//Session 一个会话，通过DB.NewSession创建
+type Session struct {
+	db         *DB
+	tx         *tx.Transaction //显式开启的事务，nil表示自动提交
+	readOnly   bool
+	savepoints []savepoint //按照创建的顺序保存，同名的保存点以最后创建的为准
+}
+
+//NewSession 创建一个处在自动提交模式的会话
+func (d *DB) NewSession() *Session {
+	return &Session{db: d}
+}
+
+//InTransaction 当前是否有显式开启的事务
+func (s *Session) InTransaction() bool {
+	return s.tx != nil
+}
+
+//ReadOnly 当前显式开启的事务是否是只读的
+func (s *Session) ReadOnly() bool {
+	return s.tx != nil && s.readOnly
+}
+
+//Begin 显式开启一个事务
+func (s *Session) Begin(readOnly bool) error {
+	if s.tx != nil {
+		return ErrInTransaction
+	}
+	if err := s.db.checkOpen(); err != nil {
+		return err
+	}
+	s.tx = s.db.NewTx()
+	s.readOnly = readOnly
+	return nil
+}
+
+//Commit 提交当前的事务，没有事务的时候什么都不做
+func (s *Session) Commit() error {
+	return s.end(true)
+}
+
+//Rollback 回滚当前的事务，没有事务的时候什么都不做
+func (s *Session) Rollback() error {
+	return s.end(false)
+}
+
+//Close 关闭会话，没有提交的事务会被回滚
+func (s *Session) Close() error {
+	return s.Rollback()
+}
+
+func (s *Session) end(commit bool) error {
+	if s.tx == nil {
+		return nil
+	}
+	t := s.tx
+	s.tx = nil
+	s.readOnly = false
+	s.savepoints = nil
+	return protect(func() error {
+		if commit {
+			t.Commit()
+			return nil
+		}
+		return t.RollBack()
+	})
+}
+
+//Savepoint 在当前事务中创建一个保存点
+func (s *Session) Savepoint(name string) error {
+	if s.tx == nil {
+		return fmt.Errorf("%w: SAVEPOINT can only be used in transaction blocks", ErrNoTransaction)
+	}
+	s.savepoints = append(s.savepoints, savepoint{name: name, lsn: s.tx.Savepoint()})
+	return nil
+}
+
+//RollbackTo 撤销保存点之后的所有修改，这个保存点仍然保留，在它之后创建的保存点会被删除
+func (s *Session) RollbackTo(name string) error {
+	if s.tx == nil {
+		return fmt.Errorf("%w: ROLLBACK TO SAVEPOINT can only be used in transaction blocks", ErrNoTransaction)
+	}
+	for i := len(s.savepoints) - 1; i >= 0; i-- {
+		if s.savepoints[i].name != name {
+			continue
+		}
+		lsn := s.savepoints[i].lsn
+		s.savepoints = s.savepoints[:i+1]
+		err := protect(func() error {
+			s.tx.RollBackTo(lsn)
+			return nil
+		})
+		if err != nil {
+			s.end(false)
+		}
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrSavepointNotFound, name)
+}
+
+//Execute 执行一条事务控制语句或者修改语句，返回受影响的记录数，查询语句需要使用Query
+func (s *Session) Execute(stmt parser.Statement) (int, error) {
+	switch data := stmt.(type) {
+	case *parser.BeginData:
+		return 0, s.Begin(data.ReadOnly())
+	case *parser.CommitData:
+		return 0, s.Commit()
+	case *parser.RollbackData:
+		if data.Savepoint() != "" {
+			return 0, s.RollbackTo(data.Savepoint())
+		}
+		return 0, s.Rollback()
+	case *parser.SavepointData:
+		return 0, s.Savepoint(data.Name())
+	case *parser.QueryData:
+		return 0, planner.ErrNotUpdate
+	}
+	if s.ReadOnly() {
+		return 0, ErrReadOnly
+	}
+	st, err := s.start()
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = protect(func() (err error) {
+		count, err = s.db.planner.ExecuteStatement(stmt, st.tx)
+		return err
+	})
+	if err := st.finish(err); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+//Query 执行一条查询语句，语句在返回的Rows关闭之后才结束，自动提交的事务也是在这个时候提交
+func (s *Session) Query(data *parser.QueryData) (*Rows, error) {
+	return s.query(func(t *tx.Transaction) (planner.Plan, error) {
+		return s.db.queryPlanner.CreatePlan(data, t)
+	})
+}
+
+//query 在语句的事务中使用createPlan生成查询计划，并打开查询计划
+func (s *Session) query(createPlan func(t *tx.Transaction) (planner.Plan, error)) (*Rows, error) {
+	st, err := s.start()
+	if err != nil {
+		return nil, err
+	}
+	r := &Rows{st: st}
+	err = protect(func() error {
+		plan, err := createPlan(st.tx)
+		if err != nil {
+			return err
+		}
+		scan, err := plan.Open()
+		if err != nil {
+			return err
+		}
+		r.scan, r.schema = scan.(query.Scan), plan.Schema()
+		return nil
+	})
+	if err != nil {
+		st.finish(err)
+		return nil, err
+	}
+	r.fields = r.schema.Fields()
+	return r, nil
+}
+
+//Plan 在会话的事务中为查询语句生成查询计划，但是不执行它
+func (s *Session) Plan(data *parser.QueryData) (planner.Plan, error) {
+	st, err := s.start()
+	if err != nil {
+		return nil, err
+	}
+	var plan planner.Plan
+	err = protect(func() (err error) {
+		plan, err = s.db.queryPlanner.CreatePlan(data, st.tx)
+		return err
+	})
+	if err := st.finish(err); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+//Describe 不执行查询语句，只返回结果的表结构
+func (s *Session) Describe(data *parser.QueryData) (rm.SchemaInterface, error) {
+	plan, err := s.Plan(data)
+	if err != nil {
+		return nil, err
+	}
+	return plan.Schema(), nil
+}
+
+//statement 会话中正在执行的一条语句
+type statement struct {
+	sess       *Session
+	tx         *tx.Transaction
+	autocommit bool
+	lsn        uint64 //语句开始之前的日志编号，语句失败的时候回滚到这里
+}
+
+func (s *Session) start() (*statement, error) {
+	if err := s.db.checkOpen(); err != nil {
+		return nil, err
+	}
+	if s.tx == nil {
+		return &statement{sess: s, tx: s.db.NewTx(), autocommit: true}, nil
+	}
+	return &statement{sess: s, tx: s.tx, lsn: s.tx.Savepoint()}, nil
+}
+
+//finish 语句执行结束，根据err提交或者回滚，返回语句最终的错误
+func (st *statement) finish(err error) error {
+	switch {
+	case st.autocommit && err == nil:
+		err = protect(func() error {
+			st.tx.Commit()
+			return nil
+		})
+	case st.autocommit:
+		protect(st.tx.RollBack)
+	case errors.Is(err, ErrAborted):
+		//获取锁超时，事务的状态已经不确定了，整个事务回滚
+		st.sess.end(false)
+	case err != nil:
+		//只撤销这一条语句的修改
+		if protect(func() error {
+			st.tx.RollBackTo(st.lsn)
+			return nil
+		}) != nil {
+			st.sess.end(false)
+		}
+	}
+	return err
+}
+
+//Rows 查询语句的结果，按照火山模型一条一条的读取
+type Rows struct {
+	st     *statement
+	scan   query.Scan
+	schema rm.SchemaInterface
+	fields []string
+	row    []*comm.Constant
+	err    error
+	closed bool
+}
+
+//Schema 返回结果的表结构
+func (r *Rows) Schema() rm.SchemaInterface {
+	return r.schema
+}
+
+//Fields 返回结果中每一列的名字
+func (r *Rows) Fields() []string {
+	return r.fields
+}
+
+//Next 读取下一条记录，没有记录或者出错的时候返回false，出错的原因通过Err获得
+func (r *Rows) Next() bool {
+	if r.closed || r.err != nil {
+		return false
+	}
+	hasNext := false
+	r.err = protect(func() error {
+		hasNext = r.scan.Next()
+		if !hasNext {
+			return nil
+		}
+		row := make([]*comm.Constant, len(r.fields))
+		for i, field := range r.fields {
+			row[i] = r.scan.GetVal(field)
+		}
+		r.row = row
+		return nil
+	})
+	return hasNext && r.err == nil
+}
+
+//Row 返回当前记录每一列的值，顺序和Fields相同
+func (r *Rows) Row() []*comm.Constant {
+	return r.row
+}
+
+//Err 返回读取过程中遇到的错误
+func (r *Rows) Err() error {
+	return r.err
+}
+
+//Close 关闭结果并结束语句，返回读取过程中遇到的错误
+func (r *Rows) Close() error {
+	return r.CloseWithError(nil)
+}
+
+//CloseWithError 关闭结果并结束语句，err不为nil的时候说明调用者在读取的过程中出错了，语句会被当作失败处理
+func (r *Rows) CloseWithError(err error) error {
+	if r.closed {
+		return r.err
+	}
+	r.closed = true
+	if r.err == nil {
+		r.err = err
+	}
+	if closeErr := protect(func() error {
+		r.scan.Close()
+		return nil
+	}); r.err == nil {
+		r.err = closeErr
+	}
+	r.err = r.st.finish(r.err)
+	return r.err
+}
+
+//protect 底层在获取锁超时等情况下会直接panic，这里转化成ErrAborted
+//表达式计算出错（例如除以0）或者标量子查询返回了多条记录也会panic，这种错误只是这一条语句失败，直接返回这个错误
+func protect(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok && (errors.Is(e, comm.ErrArithmetic) || errors.Is(e, query.ErrSubqueryRows)) {
+				err = e
+				return
+			}
+			err = fmt.Errorf("%w: %v", ErrAborted, r)
+		}
+	}()
+	return f()
+}
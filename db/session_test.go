@@ -0,0 +1,109 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"miniSQL/parser"
+	"miniSQL/planner"
+	"miniSQL/query"
+	"path/filepath"
+	"testing"
+)
+
+func execSession(t *testing.T, s *Session, sql string) (int, error) {
+	stmt, err := parser.NewSQLParser(sql).ParseStatement()
+	assert.Nil(t, err, sql)
+	return s.Execute(stmt)
+}
+
+func querySession(t *testing.T, s *Session, sql string) []string {
+	stmt, err := parser.NewSQLParser(sql).ParseStatement()
+	assert.Nil(t, err, sql)
+	rows, err := s.Query(stmt.(*parser.QueryData))
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for rows.Next() {
+		names = append(names, rows.Row()[0].AsString())
+	}
+	assert.Nil(t, rows.Close())
+	return names
+}
+
+func TestSession(t *testing.T) {
+	d, err := Open(filepath.Join(t.TempDir(), "session_test"), Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	defer d.Close()
+	s := d.NewSession()
+	//自动提交
+	_, err = execSession(t, s, "create table student (name varchar(16),gradyear int)")
+	assert.Nil(t, err)
+	_, err = execSession(t, s, "insert into student (name,gradyear) values ('tom',2020)")
+	assert.Nil(t, err)
+	assert.False(t, s.InTransaction())
+
+	//ROLLBACK撤销整个事务
+	_, err = execSession(t, s, "begin")
+	assert.Nil(t, err)
+	assert.True(t, s.InTransaction())
+	_, err = execSession(t, s, "begin")
+	assert.ErrorIs(t, err, ErrInTransaction)
+	_, err = execSession(t, s, "insert into student (name,gradyear) values ('jim',2021)")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tom", "jim"}, querySession(t, s, "select name from student"))
+	_, err = execSession(t, s, "rollback")
+	assert.Nil(t, err)
+	assert.False(t, s.InTransaction())
+	assert.Equal(t, []string{"tom"}, querySession(t, s, "select name from student"))
+
+	//保存点
+	_, err = execSession(t, s, "begin")
+	assert.Nil(t, err)
+	_, err = execSession(t, s, "insert into student (name,gradyear) values ('amy',2022)")
+	assert.Nil(t, err)
+	_, err = execSession(t, s, "savepoint a")
+	assert.Nil(t, err)
+	_, err = execSession(t, s, "update student set gradyear=2000 where name='tom'")
+	assert.Nil(t, err)
+	_, err = execSession(t, s, "savepoint b")
+	assert.Nil(t, err)
+	_, err = execSession(t, s, "delete from student where name='amy'")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tom"}, querySession(t, s, "select name from student"))
+	_, err = execSession(t, s, "rollback to b")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tom", "amy"}, querySession(t, s, "select name from student"))
+	_, err = execSession(t, s, "rollback to savepoint a")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tom"}, querySession(t, s, "select name from student where gradyear=2020"))
+	assert.Equal(t, []string{"tom", "amy"}, querySession(t, s, "select name from student"))
+	_, err = execSession(t, s, "rollback to b")
+	assert.ErrorIs(t, err, ErrSavepointNotFound)
+	//事务中失败的语句不会影响事务
+	_, err = execSession(t, s, "insert into student (name,gradyear) values (2023,'bob')")
+	assert.ErrorIs(t, err, planner.ErrTypeMismatch)
+	assert.True(t, s.InTransaction())
+	stmt, err := parser.NewSQLParser("select name from student where gradyear = (select gradyear from student)").ParseStatement()
+	assert.Nil(t, err)
+	rows, err := s.Query(stmt.(*parser.QueryData))
+	assert.Nil(t, err)
+	assert.False(t, rows.Next())
+	assert.ErrorIs(t, rows.Close(), query.ErrSubqueryRows)
+	assert.True(t, s.InTransaction())
+	_, err = execSession(t, s, "commit")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tom", "amy"}, querySession(t, s, "select name from student"))
+	assert.Equal(t, []string{"tom"}, querySession(t, s, "select name from student where gradyear=2020"))
+
+	//只读事务
+	_, err = execSession(t, s, "begin read only")
+	assert.Nil(t, err)
+	_, err = execSession(t, s, "delete from student")
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.Equal(t, []string{"tom", "amy"}, querySession(t, s, "select name from student"))
+	_, err = execSession(t, s, "commit")
+	assert.Nil(t, err)
+
+	_, err = execSession(t, s, "savepoint a")
+	assert.ErrorIs(t, err, ErrNoTransaction)
+	_, err = execSession(t, s, "commit")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,216 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"miniSQL/comm"
+	"miniSQL/parser"
+	"miniSQL/planner"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+)
+
+/*
+	Stmt 预处理语句，SQL语句只在Prepare的时候解析一次，之后每次执行只需要把参数写入到参数槽中
+	参数槽的类型在Prepare的时候根据表结构推断出来，绑定参数的时候会检查类型
+	查询语句的查询计划会被缓存下来，只要统计数据没有发生变化，下一次执行的时候就直接使用缓存的查询计划
+	修改了表结构的事务回滚之后统计数据的版本也会变化，事务中生成的查询计划不会在回滚之后继续使用
+	Stmt属于创建它的会话，和Session一样不是并发安全的
+*/
+
+var (
+	ErrStmtClosed = errors.New("statement is closed")
+)
+
+//Stmt 一条预处理语句，通过Session.Prepare创建
+type Stmt struct {
+	sess    *Session
+	stmt    parser.Statement
+	params  []*comm.Constant //语法树中的参数槽
+	types   []rm.FIELD_TYPE  //每个参数槽的类型，planner.PARAM_UNKNOWN表示接受任意类型
+	plan    planner.Plan     //缓存的查询计划
+	version uint64           //生成查询计划时统计数据的版本
+	closed  bool
+}
+
+//Prepare 解析一条可以带有?或者$n占位符的语句，并推断出每个参数的类型
+func (s *Session) Prepare(sql string) (*Stmt, error) {
+	stmt, params, err := parser.Prepare(sql)
+	if err != nil {
+		return nil, err
+	}
+	ps := &Stmt{sess: s, stmt: stmt, params: params}
+	if parser.IsTxControl(stmt) {
+		return ps, nil
+	}
+	st, err := s.start()
+	if err != nil {
+		return nil, err
+	}
+	err = protect(func() (err error) {
+		ps.types, err = planner.ParamTypes(s.db.mdm, stmt, params, st.tx)
+		return err
+	})
+	if err := st.finish(err); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+//Statement 返回解析得到的语法树
+func (ps *Stmt) Statement() parser.Statement {
+	return ps.stmt
+}
+
+//NumParams 返回语句需要的参数个数
+func (ps *Stmt) NumParams() int {
+	return len(ps.params)
+}
+
+//ParamTypes 返回每个参数的类型
+func (ps *Stmt) ParamTypes() []rm.FIELD_TYPE {
+	return ps.types
+}
+
+//Execute 绑定参数之后执行修改语句或者事务控制语句，返回受影响的记录数
+func (ps *Stmt) Execute(args ...interface{}) (int, error) {
+	if err := ps.bind(args); err != nil {
+		return 0, err
+	}
+	return ps.sess.Execute(ps.stmt)
+}
+
+//Query 绑定参数之后执行查询语句，统计数据没有变化的时候会复用上一次生成的查询计划
+func (ps *Stmt) Query(args ...interface{}) (*Rows, error) {
+	data, ok := ps.stmt.(*parser.QueryData)
+	if !ok {
+		return nil, planner.ErrNotQuery
+	}
+	if err := ps.bind(args); err != nil {
+		return nil, err
+	}
+	return ps.sess.query(func(t *tx.Transaction) (planner.Plan, error) {
+		version := ps.sess.db.mdm.StatVersion()
+		if ps.plan != nil && ps.version == version {
+			planner.Rebind(ps.plan, t)
+			return ps.plan, nil
+		}
+		plan, err := ps.sess.db.queryPlanner.CreatePlan(data, t)
+		if err != nil {
+			return nil, err
+		}
+		ps.plan, ps.version = plan, version
+		return plan, nil
+	})
+}
+
+//Close 关闭预处理语句，释放缓存的查询计划
+func (ps *Stmt) Close() error {
+	ps.closed = true
+	ps.plan = nil
+	return nil
+}
+
+//bind 把参数写入到参数槽中，参数槽被语法树和缓存的查询计划共享，所以不需要重新生成它们
+func (ps *Stmt) bind(args []interface{}) error {
+	if ps.closed {
+		return ErrStmtClosed
+	}
+	if len(args) != len(ps.params) {
+		return fmt.Errorf("%w: expected %d, got %d", parser.ErrArgCount, len(ps.params), len(args))
+	}
+	for i, arg := range args {
+		val, err := toConstant(arg)
+		if err != nil {
+			return fmt.Errorf("parameter %d: %w", i+1, err)
+		}
+		if val, err = bindValue(ps.types[i], val); err != nil {
+			return fmt.Errorf("%w: parameter %d", err, i+1)
+		}
+		ps.params[i].Set(val)
+	}
+	return nil
+}
+
+//bindValue 检查参数的值能不能绑定到fieldType类型的参数槽上，NULL可以绑定到任意类型的参数上
+//DOUBLE和DECIMAL参数可以接受任意数值，浮点数绑定到DECIMAL参数上的时候转换成定点数
+func bindValue(fieldType rm.FIELD_TYPE, val *comm.Constant) (*comm.Constant, error) {
+	if val.IsNull() {
+		return val, nil
+	}
+	ok := true
+	switch fieldType {
+	case rm.INTEGER, rm.BIGINT:
+		ok = val.Ival != nil
+	case rm.BOOLEAN:
+		ok = val.Bval != nil
+	case rm.DOUBLE:
+		ok = val.IsNumber()
+	case rm.DECIMAL:
+		if val.Fval != nil {
+			d, err := comm.DecimalFromFloat(*val.Fval)
+			if err != nil {
+				return nil, err
+			}
+			return comm.NewConstantDecimal(&d), nil
+		}
+		ok = val.IsNumber()
+	case rm.VARCHAR:
+		ok = val.Sval != nil
+	}
+	if !ok {
+		return nil, planner.ErrTypeMismatch
+	}
+	return val, nil
+}
+
+//toConstant 把Go中的值转化成Constant，支持整数、浮点数、布尔值、字符串和comm.Decimal，nil是NULL
+func toConstant(arg interface{}) (*comm.Constant, error) {
+	var i int
+	switch v := arg.(type) {
+	case nil:
+		return comm.NewNullConstant(), nil
+	case *comm.Constant:
+		if v == nil {
+			return nil, planner.ErrTypeMismatch
+		}
+		return v, nil
+	case string:
+		return comm.NewConstantString(&v), nil
+	case bool:
+		return comm.NewConstantBool(&v), nil
+	case float64:
+		return comm.NewConstantFloat(&v), nil
+	case float32:
+		f := float64(v)
+		return comm.NewConstantFloat(&f), nil
+	case comm.Decimal:
+		return comm.NewConstantDecimal(&v), nil
+	case []byte:
+		s := string(v)
+		return comm.NewConstantString(&s), nil
+	case int:
+		i = v
+	case int8:
+		i = int(v)
+	case int16:
+		i = int(v)
+	case int32:
+		i = int(v)
+	case int64:
+		i = int(v)
+	case uint8:
+		i = int(v)
+	case uint16:
+		i = int(v)
+	case uint32:
+		i = int(v)
+	case uint:
+		i = int(v)
+	case uint64:
+		i = int(v)
+	default:
+		return nil, fmt.Errorf("%w: unsupported argument type %T", planner.ErrTypeMismatch, arg)
+	}
+	return comm.NewConstantInt(&i), nil
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"miniSQL/parser"
+	"miniSQL/planner"
+	rm "miniSQL/record_manager"
+	"path/filepath"
+	"testing"
+)
+
+func queryStmt(t *testing.T, ps *Stmt, args ...interface{}) []string {
+	rows, err := ps.Query(args...)
+	assert.Nil(t, err)
+	names := make([]string, 0)
+	for rows.Next() {
+		names = append(names, rows.Row()[0].AsString())
+	}
+	assert.Nil(t, rows.Close())
+	return names
+}
+
+func TestPreparedStatement(t *testing.T) {
+	d, err := Open(filepath.Join(t.TempDir(), "stmt_test"), Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	defer d.Close()
+	s := d.NewSession()
+	_, err = execSession(t, s, "create table student (name varchar(16),gradyear int)")
+	assert.Nil(t, err)
+
+	insert, err := s.Prepare("insert into student (gradyear,name) values ($2,$1)")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, insert.NumParams())
+	assert.Equal(t, []rm.FIELD_TYPE{rm.VARCHAR, rm.INTEGER}, insert.ParamTypes())
+	for i, name := range []string{"tom", "amy", "jim"} {
+		count, err := insert.Execute(name, 2020+i%2)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+	}
+	_, err = insert.Execute(2020, "bob")
+	assert.ErrorIs(t, err, planner.ErrTypeMismatch)
+	_, err = insert.Execute("bob")
+	assert.ErrorIs(t, err, parser.ErrArgCount)
+
+	sel, err := s.Prepare("select name from student where gradyear = ?")
+	assert.Nil(t, err)
+	assert.Equal(t, []rm.FIELD_TYPE{rm.INTEGER}, sel.ParamTypes())
+	assert.Equal(t, []string{"tom", "jim"}, queryStmt(t, sel, 2020))
+	//统计数据没有变化，复用同一个查询计划
+	plan := sel.plan
+	assert.Equal(t, []string{"amy"}, queryStmt(t, sel, int64(2021)))
+	assert.Same(t, plan, sel.plan)
+
+	//创建索引之后统计数据的版本发生变化，需要重新生成查询计划
+	_, err = execSession(t, s, "create index yearIdx on student (gradyear)")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tom", "jim"}, queryStmt(t, sel, 2020))
+	assert.NotSame(t, plan, sel.plan)
+	//参数可以是NULL，和NULL比较的结果不成立
+	assert.Equal(t, []string{}, queryStmt(t, sel, nil))
+
+	//在显式开启的事务中也可以使用
+	assert.Nil(t, s.Begin(false))
+	update, err := s.Prepare("update student set gradyear = ? where name = ?")
+	assert.Nil(t, err)
+	count, err := update.Execute(2021, "tom")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"tom", "amy"}, queryStmt(t, sel, 2021))
+	assert.Nil(t, s.Rollback())
+	assert.Equal(t, []string{"amy"}, queryStmt(t, sel, 2021))
+
+	//回滚了修改表结构的事务之后，事务中按照新的表结构生成的查询计划不能再使用
+	byName, err := s.Prepare("select name from student where name = ?")
+	assert.Nil(t, err)
+	assert.Nil(t, s.Begin(false))
+	_, err = execSession(t, s, "alter table student add nick varchar(8)")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"amy"}, queryStmt(t, byName, "amy"))
+	assert.Nil(t, s.Rollback())
+	assert.Equal(t, []string{"amy"}, queryStmt(t, byName, "amy"))
+
+	//LIMIT和OFFSET也可以使用参数，分页查询的时候复用同一个查询计划
+	page, err := s.Prepare("select name from student order by name limit ? offset ?")
+	assert.Nil(t, err)
+	assert.Equal(t, []rm.FIELD_TYPE{rm.INTEGER, rm.INTEGER}, page.ParamTypes())
+	assert.Equal(t, []string{"amy", "jim"}, queryStmt(t, page, 2, 0))
+	assert.Equal(t, []string{"tom"}, queryStmt(t, page, 2, 2))
+	_, err = page.Query(-1, 0)
+	assert.ErrorIs(t, err, planner.ErrNegativeLimit)
+	_, err = page.Query(nil, 0)
+	assert.ErrorIs(t, err, planner.ErrTypeMismatch)
+
+	//写入DECIMAL字段的算术运算中的参数也是DECIMAL类型，浮点数参数被转换成定点数
+	_, err = execSession(t, s, "create table item (name varchar(8), price decimal(8,2), instock boolean)")
+	assert.Nil(t, err)
+	add, err := s.Prepare("insert into item (name, price, instock) values (?, ? * 2, ?)")
+	assert.Nil(t, err)
+	assert.Equal(t, []rm.FIELD_TYPE{rm.VARCHAR, rm.DECIMAL, rm.BOOLEAN}, add.ParamTypes())
+	_, err = add.Execute("pen", 1.25, true)
+	assert.Nil(t, err)
+	_, err = add.Execute("ink", 3, false)
+	assert.Nil(t, err)
+	_, err = add.Execute("cap", 1, 1)
+	assert.ErrorIs(t, err, planner.ErrTypeMismatch)
+	find, err := s.Prepare("select name from item where price = ? and instock = ?")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pen"}, queryStmt(t, find, 2.5, true))
+	assert.Equal(t, []string{"ink"}, queryStmt(t, find, 6, false))
+
+	_, err = s.Prepare("select name from teacher where id = ?")
+	assert.ErrorIs(t, err, planner.ErrTableNotFound)
+	_, err = sel.Execute(2020)
+	assert.ErrorIs(t, err, planner.ErrNotUpdate)
+	_, err = insert.Query("tom", 2020)
+	assert.ErrorIs(t, err, planner.ErrNotQuery)
+	assert.Nil(t, sel.Close())
+	_, err = sel.Query(2020)
+	assert.ErrorIs(t, err, ErrStmtClosed)
+}
@@ -0,0 +1,261 @@
+package driver
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"miniSQL/comm"
+	"miniSQL/db"
+	"miniSQL/parser"
+	"miniSQL/planner"
+)
+
+var (
+	ErrInTransaction = db.ErrInTransaction
+	ErrNoTransaction = db.ErrNoTransaction
+)
+
+//conn 一个数据库连接，对应一个会话，没有显式开启事务的时候，每条语句都在自己的事务中执行并自动提交
+//除了使用database/sql的事务接口之外，也可以直接执行BEGIN/COMMIT/ROLLBACK/SAVEPOINT语句
+type conn struct {
+	dir    string
+	db     *db.DB
+	sess   *db.Session
+	closed bool
+}
+
+func newConn(dir string, database *db.DB) *conn {
+	return &conn{
+		dir:  dir,
+		db:   database,
+		sess: database.NewSession(),
+	}
+}
+
+//Prepare 在会话中预处理语句，语句只解析一次，查询语句的查询计划也会被复用
+func (c *conn) Prepare(query string) (sqldriver.Stmt, error) {
+	if c.closed {
+		return nil, sqldriver.ErrBadConn
+	}
+	ps, err := c.sess.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{conn: c, ps: ps}, nil
+}
+
+//Close 关闭连接，没有提交的事务会被回滚
+func (c *conn) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.sess.Close()
+	return releaseDB(c.dir)
+}
+
+//Begin 开启一个事务
+func (c *conn) Begin() (sqldriver.Tx, error) {
+	return c.BeginTx(context.Background(), sqldriver.TxOptions{})
+}
+
+//BeginTx 开启一个事务，目前的事务都是可串行化的，所以不支持设置隔离级别
+func (c *conn) BeginTx(ctx context.Context, opts sqldriver.TxOptions) (sqldriver.Tx, error) {
+	if c.closed {
+		return nil, sqldriver.ErrBadConn
+	}
+	if err := c.sess.Begin(opts.ReadOnly); err != nil {
+		return nil, err
+	}
+	return &transaction{conn: c}, nil
+}
+
+//CheckNamedValue 只支持按照位置传入的参数
+func (c *conn) CheckNamedValue(nv *sqldriver.NamedValue) error {
+	if nv.Name != "" {
+		return fmt.Errorf("minisql: named argument %q is not supported", nv.Name)
+	}
+	v, err := sqldriver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	nv.Value = v
+	return nil
+}
+
+//ExecContext 执行一条修改语句
+func (c *conn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	if c.closed {
+		return nil, sqldriver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := parseStatement(query, args)
+	if err != nil {
+		return nil, err
+	}
+	count, err := c.sess.Execute(s)
+	if err != nil {
+		return nil, err
+	}
+	return result(count), nil
+}
+
+//QueryContext 执行一条查询语句，自动提交的时候，事务会在rows关闭的时候提交
+func (c *conn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	if c.closed {
+		return nil, sqldriver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := parseStatement(query, args)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := s.(*parser.QueryData)
+	if !ok {
+		return nil, planner.ErrNotQuery
+	}
+	r, err := c.sess.Query(data)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{rows: r, schema: r.Schema(), fields: r.Fields()}, nil
+}
+
+//parseStatement 解析SQL语句，并把参数绑定到?占位符上
+func parseStatement(query string, args []sqldriver.NamedValue) (parser.Statement, error) {
+	constants := make([]*comm.Constant, 0, len(args))
+	for _, arg := range args {
+		constant, err := toConstant(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		constants = append(constants, constant)
+	}
+	return parser.NewSQLParserWithArgs(query, constants).ParseStatement()
+}
+
+//toConstant 把database/sql传入的参数转化成Constant，nil是NULL
+func toConstant(v sqldriver.Value) (*comm.Constant, error) {
+	switch val := v.(type) {
+	case nil:
+		return comm.NewNullConstant(), nil
+	case int64:
+		i := int(val)
+		return comm.NewConstantInt(&i), nil
+	case float64:
+		return comm.NewConstantFloat(&val), nil
+	case bool:
+		return comm.NewConstantBool(&val), nil
+	case string:
+		return comm.NewConstantString(&val), nil
+	case []byte:
+		s := string(val)
+		return comm.NewConstantString(&s), nil
+	}
+	return nil, fmt.Errorf("minisql: unsupported argument type %T", v)
+}
+
+//stmt 预处理语句
+type stmt struct {
+	conn *conn
+	ps   *db.Stmt
+}
+
+func (s *stmt) Close() error {
+	return s.ps.Close()
+}
+
+//NumInput 返回语句中参数的个数，database/sql会在执行之前检查
+func (s *stmt) NumInput() int {
+	return s.ps.NumParams()
+}
+
+func (s *stmt) Exec(args []sqldriver.Value) (sqldriver.Result, error) {
+	return s.ExecContext(context.Background(), toNamedValues(args))
+}
+
+func (s *stmt) Query(args []sqldriver.Value) (sqldriver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	if s.conn.closed {
+		return nil, sqldriver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	count, err := s.ps.Execute(toValues(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return result(count), nil
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	if s.conn.closed {
+		return nil, sqldriver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r, err := s.ps.Query(toValues(args)...)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{rows: r, schema: r.Schema(), fields: r.Fields()}, nil
+}
+
+func toNamedValues(args []sqldriver.Value) []sqldriver.NamedValue {
+	named := make([]sqldriver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = sqldriver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+func toValues(args []sqldriver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+//transaction 显式开启的事务
+type transaction struct {
+	conn *conn
+}
+
+//Commit 提交事务
+func (t *transaction) Commit() error {
+	if !t.conn.sess.InTransaction() {
+		return ErrNoTransaction
+	}
+	return t.conn.sess.Commit()
+}
+
+//Rollback 回滚事务
+func (t *transaction) Rollback() error {
+	if !t.conn.sess.InTransaction() {
+		return ErrNoTransaction
+	}
+	return t.conn.sess.Rollback()
+}
+
+//result 修改语句的执行结果
+type result int
+
+//LastInsertId 目前没有自增主键
+func (r result) LastInsertId() (int64, error) {
+	return 0, errors.New("minisql: LastInsertId is not supported")
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
@@ -0,0 +1,124 @@
+package driver
+
+import (
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"miniSQL/db"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+	database/sql的驱动，注册的名字是minisql
+	DSN就是数据库所在的目录，后面可以带上参数：/data/school?blocksize=4096&buffers=64&logfile=logfile
+	同一个目录只会打开一个db.DB，所有连接共享它，最后一个连接关闭的时候才会关闭数据库
+*/
+
+const DriverName = "minisql"
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+//Driver 实现了database/sql/driver.Driver接口
+type Driver struct{}
+
+//sharedDB 被多个连接共享的数据库，refs记录了当前有多少个连接在使用
+type sharedDB struct {
+	db   *db.DB
+	refs int
+}
+
+var (
+	dbsMu sync.Mutex
+	dbs   = make(map[string]*sharedDB) //目录->打开的数据库
+)
+
+//Open 打开dsn指定的数据库，并返回一个新的连接
+func (d *Driver) Open(dsn string) (sqldriver.Conn, error) {
+	dir, opts, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	database, err := acquireDB(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newConn(dir, database), nil
+}
+
+//ParseDSN 把dsn解析成数据库目录和打开时的参数
+func ParseDSN(dsn string) (string, db.Options, error) {
+	opts := db.DefaultOptions()
+	dir, rawQuery, _ := strings.Cut(dsn, "?")
+	if dir == "" {
+		return "", opts, fmt.Errorf("minisql: empty data directory in dsn %q", dsn)
+	}
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", opts, fmt.Errorf("minisql: invalid dsn %q: %w", dsn, err)
+	}
+	for key, vals := range params {
+		val := vals[len(vals)-1]
+		switch key {
+		case "blocksize":
+			if opts.BlockSize, err = strconv.ParseUint(val, 10, 64); err != nil {
+				return "", opts, fmt.Errorf("minisql: invalid blocksize %q", val)
+			}
+		case "buffers":
+			n, err := strconv.ParseUint(val, 10, 32)
+			if err != nil {
+				return "", opts, fmt.Errorf("minisql: invalid buffers %q", val)
+			}
+			opts.NumBuffers = uint32(n)
+		case "logfile":
+			opts.LogFile = val
+		default:
+			return "", opts, fmt.Errorf("minisql: unknown dsn parameter %q", key)
+		}
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return dir, opts, nil
+}
+
+//acquireDB 获得目录对应的数据库，如果还没有打开就打开它
+func acquireDB(dir string, opts db.Options) (*db.DB, error) {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+	if s, ok := dbs[dir]; ok {
+		s.refs++
+		return s.db, nil
+	}
+	database, err := db.Open(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	dbs[dir] = &sharedDB{db: database, refs: 1}
+	return database, nil
+}
+
+//releaseDB 连接关闭的时候调用，没有连接使用的时候就关闭数据库
+//还有没结束的事务时数据库关闭失败，这时保留在dbs中，之后打开同一个目录的连接继续使用它，最后一个连接关闭的时候再尝试关闭
+func releaseDB(dir string) error {
+	dbsMu.Lock()
+	defer dbsMu.Unlock()
+	s, ok := dbs[dir]
+	if !ok {
+		return nil
+	}
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	delete(dbs, dir)
+	return nil
+}
@@ -0,0 +1,193 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/db"
+	"miniSQL/parser"
+	"path/filepath"
+	"testing"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	dsn := filepath.Join(t.TempDir(), "driver_test") + "?blocksize=400&buffers=16"
+	database, err := sql.Open(DriverName, dsn)
+	assert.Nil(t, err)
+	//同一时间只使用一个连接，避免两个连接之间因为锁而互相等待
+	database.SetMaxOpenConns(1)
+	return database
+}
+
+func queryNames(t *testing.T, database *sql.DB, query string, args ...interface{}) []string {
+	rows, err := database.Query(query, args...)
+	assert.Nil(t, err)
+	defer rows.Close()
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		var year int
+		assert.Nil(t, rows.Scan(&name, &year))
+		names = append(names, name)
+	}
+	assert.Nil(t, rows.Err())
+	return names
+}
+
+func TestDriverExecQuery(t *testing.T) {
+	database := openTestDB(t)
+	defer database.Close()
+	_, err := database.Exec("create table student (name varchar(16),gradyear int)")
+	assert.Nil(t, err)
+	for _, name := range []string{"tom", "jim", "amy"} {
+		res, err := database.Exec("insert into student (name,gradyear) values (?,?)", name, 2020)
+		assert.Nil(t, err)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(1), n)
+	}
+	res, err := database.Exec("update student set gradyear = ? where name = ?", 2021, "jim")
+	assert.Nil(t, err)
+	n, _ := res.RowsAffected()
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, []string{"jim"}, queryNames(t, database, "select name,gradyear from student where gradyear = ?", 2021))
+
+	rows, err := database.Query("select name,gradyear from student")
+	assert.Nil(t, err)
+	cols, err := rows.Columns()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "gradyear"}, cols)
+	types, err := rows.ColumnTypes()
+	assert.Nil(t, err)
+	assert.Equal(t, "VARCHAR", types[0].DatabaseTypeName())
+	length, ok := types[0].Length()
+	assert.True(t, ok)
+	assert.Equal(t, int64(16), length)
+	assert.Equal(t, "INT", types[1].DatabaseTypeName())
+	rows.Close()
+
+	//参数的个数和占位符的个数不一致
+	_, err = database.Exec("insert into student (name,gradyear) values (?,?)", "bob")
+	assert.ErrorIs(t, err, parser.ErrArgCount)
+	_, err = database.Query("select name from teacher")
+	assert.NotNil(t, err)
+
+	//外连接中没有匹配上的字段是NULL
+	_, err = database.Exec("create table club (member varchar(16))")
+	assert.Nil(t, err)
+	var member sql.NullString
+	assert.Nil(t, database.QueryRow("select member from student left join club on name = member where name = 'tom'").Scan(&member))
+	assert.False(t, member.Valid)
+
+	//参数可以是nil，写入的字段是NULL
+	_, err = database.Exec("insert into student (name,gradyear) values (?,?)", "ann", nil)
+	assert.Nil(t, err)
+	var year sql.NullInt64
+	assert.Nil(t, database.QueryRow("select gradyear from student where name = 'ann'").Scan(&year))
+	assert.False(t, year.Valid)
+	assert.Nil(t, database.QueryRow("select gradyear from student where gradyear is not null and name = 'tom'").Scan(&year))
+	assert.True(t, year.Valid)
+}
+
+func TestDriverPrepare(t *testing.T) {
+	database := openTestDB(t)
+	defer database.Close()
+	_, err := database.Exec("create table student (name varchar(16),gradyear int)")
+	assert.Nil(t, err)
+	insert, err := database.Prepare("insert into student (gradyear,name) values ($2,$1)")
+	assert.Nil(t, err)
+	defer insert.Close()
+	for i, name := range []string{"tom", "jim", "amy"} {
+		_, err := insert.Exec(name, 2020+i%2)
+		assert.Nil(t, err)
+	}
+	_, err = insert.Exec("bob")
+	assert.NotNil(t, err)
+	_, err = insert.Exec(2022, "bob")
+	assert.NotNil(t, err)
+
+	sel, err := database.Prepare("select name,gradyear from student where gradyear = ?")
+	assert.Nil(t, err)
+	defer sel.Close()
+	for _, year := range []int{2020, 2021, 2020} {
+		rows, err := sel.Query(year)
+		assert.Nil(t, err)
+		count := 0
+		for rows.Next() {
+			count++
+		}
+		assert.Nil(t, rows.Close())
+		assert.Equal(t, map[int]int{2020: 2, 2021: 1}[year], count)
+	}
+}
+
+func TestDriverTransaction(t *testing.T) {
+	database := openTestDB(t)
+	defer database.Close()
+	_, err := database.Exec("create table student (name varchar(16),gradyear int)")
+	assert.Nil(t, err)
+
+	tx, err := database.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("insert into student (name,gradyear) values (?,?)", "tom", 2020)
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+
+	tx, err = database.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("insert into student (name,gradyear) values (?,?)", "jim", 2021)
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Rollback())
+
+	assert.Equal(t, []string{"tom"}, queryNames(t, database, "select name,gradyear from student"))
+
+	//只读事务中不能修改数据
+	tx, err = database.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	assert.Nil(t, err)
+	_, err = tx.Exec("delete from student")
+	assert.ErrorIs(t, err, db.ErrReadOnly)
+	assert.Nil(t, tx.Commit())
+
+	//直接执行事务控制语句
+	for _, sql := range []string{
+		"begin",
+		"insert into student (name,gradyear) values ('amy',2022)",
+		"savepoint sp",
+		"insert into student (name,gradyear) values ('bob',2023)",
+		"rollback to sp",
+		"commit",
+	} {
+		_, err = database.Exec(sql)
+		assert.Nil(t, err, sql)
+	}
+	assert.Equal(t, []string{"tom", "amy"}, queryNames(t, database, "select name,gradyear from student"))
+}
+
+func TestParseDSN(t *testing.T) {
+	dir, opts, err := ParseDSN("/tmp/school?blocksize=400&buffers=8")
+	assert.Nil(t, err)
+	assert.Equal(t, "/tmp/school", dir)
+	assert.Equal(t, uint64(400), opts.BlockSize)
+	assert.Equal(t, uint32(8), opts.NumBuffers)
+	_, _, err = ParseDSN("/tmp/school?cache=8")
+	assert.NotNil(t, err)
+	_, _, err = ParseDSN("")
+	assert.NotNil(t, err)
+}
+
+func TestReleaseWithActiveTransaction(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "release_test")
+	d, err := acquireDB(dir, db.Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	tx := d.NewTx()
+	//关闭失败的数据库仍然可以被新的连接使用
+	assert.Equal(t, db.ErrActiveTransactions, releaseDB(dir))
+	d2, err := acquireDB(dir, db.Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	assert.Same(t, d, d2)
+	assert.Nil(t, tx.RollBack())
+	assert.Nil(t, releaseDB(dir))
+	dbsMu.Lock()
+	_, ok := dbs[dir]
+	dbsMu.Unlock()
+	assert.False(t, ok)
+}
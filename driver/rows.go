@@ -0,0 +1,104 @@
+package driver
+
+import (
+	sqldriver "database/sql/driver"
+	"io"
+	"miniSQL/db"
+	rm "miniSQL/record_manager"
+	"reflect"
+)
+
+//rows 把查询得到的记录按照火山模型一条一条的交给database/sql
+type rows struct {
+	rows   *db.Rows //自动提交的时候，语句的事务在关闭的时候提交
+	schema rm.SchemaInterface
+	fields []string
+}
+
+//Columns 返回结果中每一列的名字
+func (r *rows) Columns() []string {
+	return r.fields
+}
+
+//Close 关闭结果，如果是自动提交的事务，就在这里提交
+func (r *rows) Close() error {
+	return r.rows.Close()
+}
+
+//Next 读取下一条记录，没有记录的时候返回io.EOF
+func (r *rows) Next(dest []sqldriver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	for i, val := range r.rows.Row() {
+		if val.IsNull() {
+			dest[i] = nil
+		} else if val.Ival != nil {
+			dest[i] = int64(*val.Ival)
+		} else if val.Bval != nil {
+			dest[i] = *val.Bval
+		} else if val.Fval != nil {
+			dest[i] = *val.Fval
+		} else if val.Dval != nil {
+			//定点数使用字符串返回，避免转换成浮点数丢失精度
+			dest[i] = val.Dval.String()
+		} else {
+			dest[i] = *val.Sval
+		}
+	}
+	return nil
+}
+
+//ColumnTypeDatabaseTypeName 返回列在数据库中的类型名
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	switch r.schema.Type(r.fields[index]) {
+	case rm.INTEGER:
+		return "INT"
+	case rm.VARCHAR:
+		return "VARCHAR"
+	case rm.BOOLEAN:
+		return "BOOLEAN"
+	case rm.BIGINT:
+		return "BIGINT"
+	case rm.DOUBLE:
+		return "DOUBLE"
+	case rm.DECIMAL:
+		return "DECIMAL"
+	}
+	return ""
+}
+
+//ColumnTypeScanType 返回列可以被扫描成的go类型
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	switch r.schema.Type(r.fields[index]) {
+	case rm.INTEGER, rm.BIGINT:
+		return reflect.TypeOf(int64(0))
+	case rm.VARCHAR, rm.DECIMAL:
+		return reflect.TypeOf("")
+	case rm.BOOLEAN:
+		return reflect.TypeOf(false)
+	case rm.DOUBLE:
+		return reflect.TypeOf(float64(0))
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+//ColumnTypeLength 只有varchar类型的列才有长度
+func (r *rows) ColumnTypeLength(index int) (int64, bool) {
+	if r.schema.Type(r.fields[index]) == rm.VARCHAR {
+		return int64(r.schema.Length(r.fields[index])), true
+	}
+	return 0, false
+}
+
+//ColumnTypePrecisionScale 只有decimal类型的列才有精度和小数位数
+func (r *rows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if r.schema.Type(r.fields[index]) != rm.DECIMAL {
+		return 0, 0, false
+	}
+	length := r.schema.Length(r.fields[index])
+	return int64(rm.DecimalPrecision(length)), int64(rm.DecimalScale(length)), true
+}
@@ -1,6 +1,7 @@
 package file_manager
 
 import (
+	"errors"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -62,7 +63,7 @@ func NewFileManager(dirPath string, blockSize uint64) (*FileManager, error) {
 				name := info.Name()
 				if strings.HasPrefix(name, "temp") {
 					//发现当前是一个临时文件，所以就需要将当前这个临时文件进行删除
-					os.Remove(filepath.Join(path, name))
+					os.Remove(path)
 				}
 			}
 			return nil
@@ -160,6 +161,21 @@ func (f *FileManager) Append(fileName string) (BlockId, error) {
 	return *blk, nil
 }
 
+//Remove 删除一个文件，文件不存在的时候不报错，DROP TABLE和DROP INDEX在事务提交之后调用
+func (f *FileManager) Remove(fileName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := filepath.Join(f.DirPath, fileName)
+	if file, ok := f.openFiles[path]; ok {
+		file.Close()
+		delete(f.openFiles, path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 func (f *FileManager) IsNew() bool {
 	return f.isNew
 }
@@ -167,3 +183,18 @@ func (f *FileManager) IsNew() bool {
 func (f *FileManager) BlockSize() uint64 {
 	return f.blockSize
 }
+
+//Close 关闭文件管理器中所有打开过的文件句柄，数据库关闭的时候调用
+func (f *FileManager) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var firstErr error
+	for path, file := range f.openFiles {
+		//大部分句柄在读写完之后就已经关闭了，这里只需要处理还没有关闭的
+		if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) && firstErr == nil {
+			firstErr = err
+		}
+		delete(f.openFiles, path)
+	}
+	return firstErr
+}
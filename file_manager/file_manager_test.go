@@ -2,6 +2,8 @@ package file_manager
 
 import (
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -26,3 +28,21 @@ func TestFileManager(t *testing.T) {
 	assert.Equal(t, val, p2.GetInt(pos2))
 	assert.Equal(t, s, p2.GetString(pos1))
 }
+
+func TestFileManagerPurgeTempFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "purge_test")
+	fm, err := NewFileManager(dir, 400)
+	assert.Nil(t, err)
+	for _, name := range []string{"temp1.tbl", "student.tbl"} {
+		_, err = fm.Append(name)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, fm.Close())
+	//重新打开的时候临时表被删除，普通的表保留
+	_, err = NewFileManager(dir, 400)
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, "temp1.tbl"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "student.tbl"))
+	assert.Nil(t, err)
+}
@@ -5,6 +5,8 @@ go 1.18
 require (
 	github.com/axiomhq/hyperloglog v0.0.0-20230201085229-3ddf4bad03dc
 	github.com/bits-and-blooms/bloom/v3 v3.6.0
+	github.com/go-sql-driver/mysql v1.7.1
+	github.com/lib/pq v1.10.9
 	github.com/stretchr/testify v1.8.4
 	github.com/twmb/murmur3 v1.1.6
 )
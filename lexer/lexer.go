@@ -115,6 +115,12 @@ func (l *Lexer) Scan() (*Token, error) {
 		token := NewToken(COMMA)
 		l.tokenStack = append(l.tokenStack, token)
 		return token, nil
+	case '.':
+		l.Lexeme = "."
+		l.LexemeStack = append(l.LexemeStack, l.Lexeme)
+		token := NewToken(DOT)
+		l.tokenStack = append(l.tokenStack, token)
+		return token, nil
 	case '{':
 		l.Lexeme = "{"
 		l.LexemeStack = append(l.LexemeStack, l.Lexeme) //将当前的l.Lexeme添加到stack中
@@ -140,6 +146,24 @@ func (l *Lexer) Scan() (*Token, error) {
 		token := NewToken(RIGHT_BRACKET)
 		l.tokenStack = append(l.tokenStack, token)
 		return token, nil
+	case '*':
+		l.Lexeme = "*"
+		l.LexemeStack = append(l.LexemeStack, l.Lexeme) //将当前的l.Lexeme添加到stack中
+		token := NewToken(ASTERISK)
+		l.tokenStack = append(l.tokenStack, token)
+		return token, nil
+	case '/':
+		l.Lexeme = "/"
+		l.LexemeStack = append(l.LexemeStack, l.Lexeme) //将当前的l.Lexeme添加到stack中
+		token := NewToken(SLASH)
+		l.tokenStack = append(l.tokenStack, token)
+		return token, nil
+	case '%':
+		l.Lexeme = "%"
+		l.LexemeStack = append(l.LexemeStack, l.Lexeme) //将当前的l.Lexeme添加到stack中
+		token := NewToken(PERCENT)
+		l.tokenStack = append(l.tokenStack, token)
+		return token, nil
 	case '+':
 		l.Lexeme = "+"
 		l.LexemeStack = append(l.LexemeStack, l.Lexeme) //将当前的l.Lexeme添加到stack中
@@ -250,6 +274,13 @@ func (l *Lexer) Scan() (*Token, error) {
 			}
 			//后面的一个仍然是&,就符合条件
 			return word.tag, nil
+		} else if ok, _ := l.ReadCharacter('>'); ok {
+			//<>和!=一样表示不等于
+			l.Lexeme = "<>"
+			l.LexemeStack = append(l.LexemeStack, l.Lexeme)
+			token := NewToken(NE)
+			l.tokenStack = append(l.tokenStack, token)
+			return token, nil
 		} else {
 			//否则就是一个与操作符
 			l.LexemeStack = append(l.LexemeStack, l.Lexeme)
@@ -277,6 +308,32 @@ func (l *Lexer) Scan() (*Token, error) {
 			l.tokenStack = append(l.tokenStack, token)
 			return token, nil
 		}
+	case '?':
+		//参数占位符，执行的时候再用传入的参数替换
+		l.Lexeme = "?"
+		l.LexemeStack = append(l.LexemeStack, l.Lexeme)
+		token := NewToken(PLACEHOLDER)
+		l.tokenStack = append(l.tokenStack, token)
+		return token, nil
+	case '$':
+		//$n形式的占位符，n从1开始，表示使用第n个参数
+		l.Lexeme = "$"
+		for {
+			chars, err := l.reader.Peek(1)
+			if err != nil || !unicode.IsDigit(rune(chars[0])) {
+				break
+			}
+			l.ReadCh()
+			l.Lexeme += string(l.peek)
+		}
+		if l.Lexeme == "$" {
+			//后面没有跟着数字，不是合法的占位符
+			return NewToken(ERROR), nil
+		}
+		l.LexemeStack = append(l.LexemeStack, l.Lexeme)
+		token := NewToken(PLACEHOLDER)
+		l.tokenStack = append(l.tokenStack, token)
+		return token, nil
 	case '"':
 		//对于这个开头的，会循环读取字符，直到读取到下一个“为止
 		for {
@@ -293,6 +350,23 @@ func (l *Lexer) Scan() (*Token, error) {
 			}
 			l.Lexeme += string(l.peek) //将读取到的字符串拼接起来
 		}
+	case '\'':
+		//标准SQL中的字符串使用单引号，字符串中两个连续的单引号表示一个单引号
+		for {
+			err := l.ReadCh()
+			if err != nil {
+				panic("string no end with quota")
+			}
+			if l.peek == '\'' {
+				if ok, _ := l.ReadCharacter('\''); !ok {
+					l.LexemeStack = append(l.LexemeStack, l.Lexeme)
+					token := NewToken(STRING)
+					l.tokenStack = append(l.tokenStack, token)
+					return token, nil
+				}
+			}
+			l.Lexeme += string(l.peek)
+		}
 	}
 	//上面的情况都不是
 
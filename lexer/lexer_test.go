@@ -70,3 +70,88 @@ func TestLexer3(t *testing.T) {
 		assert.Equal(t, tok.Tag, sqlTok.Tag)
 	}
 }
+
+func TestLexerQuotedString(t *testing.T) {
+	sqlLexer := NewLexer("name = 'it''s' AND city = \"NY\" AND id = ?")
+	expected := []struct {
+		tag    Tag
+		lexeme string
+	}{
+		{ID, "name"}, {ASSIGN_OPERATOR, "="}, {STRING, "it's"}, {AND, "AND"},
+		{ID, "city"}, {ASSIGN_OPERATOR, "="}, {STRING, "NY"}, {AND, "AND"},
+		{ID, "id"}, {ASSIGN_OPERATOR, "="}, {PLACEHOLDER, "?"},
+	}
+	for _, e := range expected {
+		tok, err := sqlLexer.Scan()
+		assert.Nil(t, err)
+		assert.Equal(t, e.tag, tok.Tag)
+		assert.Equal(t, e.lexeme, sqlLexer.Lexeme)
+	}
+}
+
+func TestLexerNumberedPlaceholder(t *testing.T) {
+	sqlLexer := NewLexer("a = $1 AND b = $12,$")
+	expected := []struct {
+		tag    Tag
+		lexeme string
+	}{
+		{ID, "a"}, {ASSIGN_OPERATOR, "="}, {PLACEHOLDER, "$1"}, {AND, "AND"},
+		{ID, "b"}, {ASSIGN_OPERATOR, "="}, {PLACEHOLDER, "$12"}, {COMMA, ","},
+	}
+	for _, e := range expected {
+		tok, err := sqlLexer.Scan()
+		assert.Nil(t, err)
+		assert.Equal(t, e.tag, tok.Tag)
+		assert.Equal(t, e.lexeme, sqlLexer.Lexeme)
+	}
+	tok, _ := sqlLexer.Scan()
+	assert.Equal(t, ERROR, tok.Tag)
+}
+
+func TestLexerComparison(t *testing.T) {
+	sqlLexer := NewLexer("a <> 1 OR NOT b <= 2 AND c != 3")
+	expected := []struct {
+		tag    Tag
+		lexeme string
+	}{
+		{ID, "a"}, {NE, "<>"}, {NUM, "1"}, {OR, "OR"},
+		{NOT, "NOT"}, {ID, "b"}, {LE, "<="}, {NUM, "2"}, {AND, "AND"},
+		{ID, "c"}, {NE, "!="}, {NUM, "3"},
+	}
+	for _, e := range expected {
+		tok, err := sqlLexer.Scan()
+		assert.Nil(t, err)
+		assert.Equal(t, e.tag, tok.Tag)
+		assert.Equal(t, e.lexeme, sqlLexer.Lexeme)
+	}
+}
+
+func TestLexerArithmetic(t *testing.T) {
+	sqlLexer := NewLexer("(a+1)*2-b/3%c")
+	expected := []Tag{LEFT_BRACKET, ID, PLUS, NUM, RIGHT_BRACKET, ASTERISK, NUM, MINUS, ID, SLASH, NUM, PERCENT, ID}
+	for _, tag := range expected {
+		tok, err := sqlLexer.Scan()
+		assert.Nil(t, err)
+		assert.Equal(t, tag, tok.Tag)
+	}
+}
+
+func TestLexerJoin(t *testing.T) {
+	sqlLexer := NewLexer("a left outer join b using (id) inner join c on x = y full join d right join e")
+	expected := []Tag{ID, LEFT, OUTER, JOIN, ID, USING, LEFT_BRACKET, ID, RIGHT_BRACKET, INNER, JOIN, ID, ON, ID, ASSIGN_OPERATOR, ID, FULL, JOIN, ID, RIGHT, JOIN, ID}
+	for _, tag := range expected {
+		tok, err := sqlLexer.Scan()
+		assert.Nil(t, err)
+		assert.Equal(t, tag, tok.Tag)
+	}
+}
+
+func TestLexerQualifiedName(t *testing.T) {
+	sqlLexer := NewLexer("select s.name, t.* from student s")
+	expected := []Tag{SELECT, ID, DOT, ID, COMMA, ID, DOT, ASTERISK, FROM, ID, ID}
+	for _, tag := range expected {
+		tok, err := sqlLexer.Scan()
+		assert.Nil(t, err)
+		assert.Equal(t, tag, tok.Tag)
+	}
+}
@@ -52,7 +52,59 @@ const (
 	AS
 	INDEX
 	ON
+	BEGIN
+	COMMIT
+	ROLLBACK
+	SAVEPOINT
+	TO
+	READ
+	ONLY
+	START
+	TRANSACTION
+	WORK
+	ORDER
+	BY
+	ASC
+	DESC
+	GROUP
+	HAVING
+	DISTINCT
+	LIMIT
+	OFFSET
+	NOT
+	JOIN
+	INNER
+	LEFT
+	RIGHT
+	FULL
+	OUTER
+	USING
+	IN
+	EXISTS
+	UNION
+	INTERSECT
+	EXCEPT
+	ALL
+	DROP
+	IF
+	CASCADE
+	ALTER
+	ADD
+	COLUMN
+	RENAME
+	DEFAULT
+	NULL
+	IS
+	BOOLEAN
+	BIGINT
+	DOUBLE
+	DECIMAL
 	COMMA
+	ASTERISK //*，COUNT(*)和乘法中使用
+	SLASH    ///，除法
+	PERCENT  //%，取余数
+	DOT      //.，表名和字段名之间的分隔符
+	PLACEHOLDER //参数占位符 ? 或者 $n
 	//SQL关键字定义结束
 	EOF //文件的结束
 
@@ -84,7 +136,59 @@ func init() {
 	TokenMap[AS] = "AS"
 	TokenMap[INDEX] = "INDEX"
 	TokenMap[ON] = "ON"
+	TokenMap[BEGIN] = "BEGIN"
+	TokenMap[COMMIT] = "COMMIT"
+	TokenMap[ROLLBACK] = "ROLLBACK"
+	TokenMap[SAVEPOINT] = "SAVEPOINT"
+	TokenMap[TO] = "TO"
+	TokenMap[READ] = "READ"
+	TokenMap[ONLY] = "ONLY"
+	TokenMap[START] = "START"
+	TokenMap[TRANSACTION] = "TRANSACTION"
+	TokenMap[WORK] = "WORK"
+	TokenMap[ORDER] = "ORDER"
+	TokenMap[BY] = "BY"
+	TokenMap[ASC] = "ASC"
+	TokenMap[DESC] = "DESC"
+	TokenMap[GROUP] = "GROUP"
+	TokenMap[HAVING] = "HAVING"
+	TokenMap[DISTINCT] = "DISTINCT"
+	TokenMap[LIMIT] = "LIMIT"
+	TokenMap[OFFSET] = "OFFSET"
+	TokenMap[NOT] = "NOT"
+	TokenMap[JOIN] = "JOIN"
+	TokenMap[INNER] = "INNER"
+	TokenMap[LEFT] = "LEFT"
+	TokenMap[RIGHT] = "RIGHT"
+	TokenMap[FULL] = "FULL"
+	TokenMap[OUTER] = "OUTER"
+	TokenMap[USING] = "USING"
+	TokenMap[IN] = "IN"
+	TokenMap[EXISTS] = "EXISTS"
+	TokenMap[UNION] = "UNION"
+	TokenMap[INTERSECT] = "INTERSECT"
+	TokenMap[EXCEPT] = "EXCEPT"
+	TokenMap[ALL] = "ALL"
+	TokenMap[DROP] = "DROP"
+	TokenMap[IF] = "IF"
+	TokenMap[CASCADE] = "CASCADE"
+	TokenMap[ALTER] = "ALTER"
+	TokenMap[ADD] = "ADD"
+	TokenMap[COLUMN] = "COLUMN"
+	TokenMap[RENAME] = "RENAME"
+	TokenMap[DEFAULT] = "DEFAULT"
+	TokenMap[NULL] = "NULL"
+	TokenMap[IS] = "IS"
+	TokenMap[BOOLEAN] = "BOOLEAN"
+	TokenMap[BIGINT] = "BIGINT"
+	TokenMap[DOUBLE] = "DOUBLE"
+	TokenMap[DECIMAL] = "DECIMAL"
 	TokenMap[COMMA] = ","
+	TokenMap[ASTERISK] = "*"
+	TokenMap[SLASH] = "/"
+	TokenMap[PERCENT] = "%"
+	TokenMap[DOT] = "."
+	TokenMap[PLACEHOLDER] = "?"
 	TokenMap[BASIC] = "BASIC"
 	TokenMap[EQ] = "EQ"
 	TokenMap[FALSE] = "FALSE"
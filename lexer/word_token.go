@@ -30,6 +30,8 @@ func GetKeyWords() []*Word {
 	key_words = append(key_words, NewWordToken(">=", GE))
 	//增加SQL语言对应关键字
 	key_words = append(key_words, NewWordToken("AND", AND))
+	key_words = append(key_words, NewWordToken("OR", OR))
+	key_words = append(key_words, NewWordToken("NOT", NOT))
 	key_words = append(key_words, NewWordToken("SELECT", SELECT))
 	key_words = append(key_words, NewWordToken("FROM", FROM))
 	key_words = append(key_words, NewWordToken("WHERE", WHERE))
@@ -47,5 +49,65 @@ func GetKeyWords() []*Word {
 	key_words = append(key_words, NewWordToken("AS", AS))
 	key_words = append(key_words, NewWordToken("INDEX", INDEX))
 	key_words = append(key_words, NewWordToken("ON", ON))
+	//事务控制语句
+	key_words = append(key_words, NewWordToken("BEGIN", BEGIN))
+	key_words = append(key_words, NewWordToken("COMMIT", COMMIT))
+	key_words = append(key_words, NewWordToken("ROLLBACK", ROLLBACK))
+	key_words = append(key_words, NewWordToken("SAVEPOINT", SAVEPOINT))
+	key_words = append(key_words, NewWordToken("TO", TO))
+	key_words = append(key_words, NewWordToken("READ", READ))
+	key_words = append(key_words, NewWordToken("ONLY", ONLY))
+	key_words = append(key_words, NewWordToken("START", START))
+	key_words = append(key_words, NewWordToken("TRANSACTION", TRANSACTION))
+	key_words = append(key_words, NewWordToken("WORK", WORK))
+	//查询语句的子句
+	key_words = append(key_words, NewWordToken("ORDER", ORDER))
+	key_words = append(key_words, NewWordToken("BY", BY))
+	key_words = append(key_words, NewWordToken("ASC", ASC))
+	key_words = append(key_words, NewWordToken("DESC", DESC))
+	key_words = append(key_words, NewWordToken("GROUP", GROUP))
+	key_words = append(key_words, NewWordToken("HAVING", HAVING))
+	key_words = append(key_words, NewWordToken("DISTINCT", DISTINCT))
+	key_words = append(key_words, NewWordToken("LIMIT", LIMIT))
+	key_words = append(key_words, NewWordToken("OFFSET", OFFSET))
+	//FROM中的连接
+	key_words = append(key_words, NewWordToken("JOIN", JOIN))
+	key_words = append(key_words, NewWordToken("INNER", INNER))
+	key_words = append(key_words, NewWordToken("LEFT", LEFT))
+	key_words = append(key_words, NewWordToken("RIGHT", RIGHT))
+	key_words = append(key_words, NewWordToken("FULL", FULL))
+	key_words = append(key_words, NewWordToken("OUTER", OUTER))
+	key_words = append(key_words, NewWordToken("USING", USING))
+	//子查询
+	key_words = append(key_words, NewWordToken("IN", IN))
+	key_words = append(key_words, NewWordToken("EXISTS", EXISTS))
+	//集合运算
+	key_words = append(key_words, NewWordToken("UNION", UNION))
+	key_words = append(key_words, NewWordToken("INTERSECT", INTERSECT))
+	key_words = append(key_words, NewWordToken("EXCEPT", EXCEPT))
+	key_words = append(key_words, NewWordToken("ALL", ALL))
+	//删除表，视图和索引
+	key_words = append(key_words, NewWordToken("DROP", DROP))
+	key_words = append(key_words, NewWordToken("IF", IF))
+	key_words = append(key_words, NewWordToken("CASCADE", CASCADE))
+	//修改表结构
+	key_words = append(key_words, NewWordToken("ALTER", ALTER))
+	key_words = append(key_words, NewWordToken("ADD", ADD))
+	key_words = append(key_words, NewWordToken("COLUMN", COLUMN))
+	key_words = append(key_words, NewWordToken("RENAME", RENAME))
+	key_words = append(key_words, NewWordToken("DEFAULT", DEFAULT))
+	//空值
+	key_words = append(key_words, NewWordToken("NULL", NULL))
+	key_words = append(key_words, NewWordToken("IS", IS))
+	//布尔值和数值类型，BOOL是BOOLEAN的别名，FLOAT是DOUBLE的别名，NUMERIC是DECIMAL的别名
+	key_words = append(key_words, NewWordToken("TRUE", TRUE))
+	key_words = append(key_words, NewWordToken("FALSE", FALSE))
+	key_words = append(key_words, NewWordToken("BOOLEAN", BOOLEAN))
+	key_words = append(key_words, NewWordToken("BOOL", BOOLEAN))
+	key_words = append(key_words, NewWordToken("BIGINT", BIGINT))
+	key_words = append(key_words, NewWordToken("DOUBLE", DOUBLE))
+	key_words = append(key_words, NewWordToken("FLOAT", DOUBLE))
+	key_words = append(key_words, NewWordToken("DECIMAL", DECIMAL))
+	key_words = append(key_words, NewWordToken("NUMERIC", DECIMAL))
 	return key_words
 }
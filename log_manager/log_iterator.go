@@ -16,6 +16,8 @@ type LogIterator struct {
 	p           *fm.Page        //数据的缓冲块
 	currentPos  uint64          //当前遍历的偏移
 	boundary    uint64          //数据的下界
+	lsn         uint64          //上一次Next返回的日志的编号
+	nextLsn     uint64          //下一次Next返回的日志的编号
 }
 
 func NewLogIterator(file *fm.FileManager, blk *fm.BlockId) *LogIterator {
@@ -57,6 +59,8 @@ func (it *LogIterator) Next() []byte {
 	}
 	record := it.p.GetBytes(it.currentPos)            //从缓冲区中读取数据
 	it.currentPos += UINT64_LEN + uint64(len(record)) //下一次读取的位置就是当前位置+8再加上当前数据的长度
+	it.lsn = it.nextLsn
+	it.nextLsn--
 	return record
 
 }
@@ -67,3 +71,8 @@ func (it *LogIterator) Valid() bool {
 	*/
 	return it.currentPos < it.fileManager.BlockSize() || it.blk.Number() > 0
 }
+
+//LSN 返回上一次Next读取到的日志的编号，只对本次启动之后写入的日志有意义
+func (it *LogIterator) LSN() uint64 {
+	return it.lsn
+}
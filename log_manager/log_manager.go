@@ -86,6 +86,8 @@ func NewLogManager(fileManager *fm.FileManager, logFile string) (*LogManager, er
 
 //FlushByLSN LSN- log sequence number,刷新日志
 func (l *LogManager) FlushByLSN(lsn uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	//把给定编号及其之前的日志写入到磁盘
 	//当我们写入给定编号的日志的时候，接口会把同当前日志处与同一区块的日志写入到磁盘中，假设当前的日志是65,
 	//如果66,67,68也处与同一个区块中，那么他们也会写入到磁盘中
@@ -95,8 +97,7 @@ func (l *LogManager) FlushByLSN(lsn uint64) error {
 		if err != nil {
 			return err
 		}
-		//更新写入的编号
-		l.lastestLsn = lsn
+		//缓冲区中的日志全部写入了磁盘，不能用lsn覆盖lastestLsn，否则其他事务在这之后写入的日志编号会重复
 		l.lastSaved2DiskLsn = l.lastestLsn //同样更新上次刷新到磁盘的日志号
 	}
 	return nil
@@ -144,6 +145,17 @@ func (l *LogManager) Append(logRecord []byte) (uint64, error) {
 
 //Iterator 获得日志文件的迭代器，进行遍历日志文件的内容
 func (l *LogManager) Iterator() *LogIterator {
-	l.Flush()                                          //获得迭代器的时候先将缓冲区中的数据刷新到磁盘中,保证数据完全落盘
-	return NewLogIterator(l.fileManager, l.currentBlk) //从最后一个数据块开始读取，往前遍历
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Flush()                                              //获得迭代器的时候先将缓冲区中的数据刷新到磁盘中,保证数据完全落盘
+	it := NewLogIterator(l.fileManager, l.currentBlk) //从最后一个数据块开始读取，往前遍历
+	it.nextLsn = l.lastestLsn                          //第一条读取到的就是最新的日志
+	return it
+}
+
+//LatestLSN 返回最新写入的日志编号
+func (l *LogManager) LatestLSN() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.lastestLsn
 }
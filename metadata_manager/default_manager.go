@@ -0,0 +1,117 @@
+package metadata_manager
+
+import (
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+)
+
+/*
+	ALTER TABLE ADD COLUMN ... DEFAULT给出的默认值保存在defcat中，INSERT没有给出这个字段的时候使用它
+	默认值按照字符串保存，由使用者按照字段的类型解析
+*/
+
+const (
+	//MAX_DEFAULT 默认值转换成字符串之后的最大长度
+	MAX_DEFAULT = 100
+)
+
+//DefaultManager 默认值管理器
+type DefaultManager struct {
+	tblgr *TableManager //表管理器
+}
+
+//NewDefaultManager 创建一个默认值管理器，当前表有三个字段，【tblname,fldname,defval】
+//以前创建的数据库中没有defcat，打开的时候把它创建出来
+func NewDefaultManager(isNew bool, tblgr *TableManager, tx *tx.Transaction) (*DefaultManager, error) {
+	defaultManager := &DefaultManager{
+		tblgr: tblgr,
+	}
+	if !isNew {
+		layout, err := tblgr.GetLayout("defcat", tx)
+		if err != nil {
+			return nil, err
+		}
+		isNew = layout.SlotSize() < 0
+	}
+	if isNew {
+		sch := rm.NewSchema()
+		sch.AddStringField("tblname", MAX_NAME)
+		sch.AddStringField("fldname", MAX_NAME)
+		sch.AddStringField("defval", MAX_DEFAULT)
+		if err := tblgr.CreateTable("defcat", sch, tx); err != nil {
+			return nil, err
+		}
+	}
+	return defaultManager, nil
+}
+
+//SetDefault 设置表中一个字段的默认值，替换原来的默认值
+func (d *DefaultManager) SetDefault(tblName string, fldName string, val string, tx *tx.Transaction) error {
+	if err := d.DropDefaults(tblName, func(field string) bool { return field == fldName }, tx); err != nil {
+		return err
+	}
+	layout, err := d.tblgr.GetLayout("defcat", tx)
+	if err != nil {
+		return err
+	}
+	ts, err := rm.NewTableScan(tx, "defcat", layout)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	ts.Insert()
+	ts.SetString("tblname", tblName)
+	ts.SetString("fldname", fldName)
+	ts.SetString("defval", val)
+	return nil
+}
+
+//GetDefaults 一张表中所有有默认值的字段以及它们的默认值
+func (d *DefaultManager) GetDefaults(tblName string, tx *tx.Transaction) (map[string]string, error) {
+	layout, err := d.tblgr.GetLayout("defcat", tx)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := rm.NewTableScan(tx, "defcat", layout)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Close()
+	defaults := make(map[string]string)
+	for ts.Next() {
+		if ts.GetString("tblname") == tblName {
+			defaults[ts.GetString("fldname")] = ts.GetString("defval")
+		}
+	}
+	return defaults, nil
+}
+
+//DropDefaults 删除一张表中match返回true的字段的默认值
+func (d *DefaultManager) DropDefaults(tblName string, match func(field string) bool, tx *tx.Transaction) error {
+	layout, err := d.tblgr.GetLayout("defcat", tx)
+	if err != nil {
+		return err
+	}
+	_, err = deleteRows(tx, "defcat", layout, func(ts *rm.TableScan) bool {
+		return ts.GetString("tblname") == tblName && match(ts.GetString("fldname"))
+	})
+	return err
+}
+
+//Rename 表或者字段改名之后，默认值跟着改名，fldName为空的时候修改表名
+func (d *DefaultManager) Rename(tblName string, fldName string, newName string, tx *tx.Transaction) error {
+	layout, err := d.tblgr.GetLayout("defcat", tx)
+	if err != nil {
+		return err
+	}
+	_, err = updateRows(tx, "defcat", layout, func(ts *rm.TableScan) bool {
+		return ts.GetString("tblname") == tblName && (fldName == "" || ts.GetString("fldname") == fldName)
+	}, func(ts *rm.TableScan) {
+		if fldName == "" {
+			ts.SetString("tblname", newName)
+		} else {
+			ts.SetString("fldname", newName)
+		}
+	})
+	return err
+}
@@ -88,6 +88,41 @@ func (h *HashIndex) Delete(val *comm.Constant, rid *rm.RID) {
 		}
 	}
 }
+//Clear 删除索引中所有的记录，表中记录的位置改变之后先清空索引再重新填充
+func (h *HashIndex) Clear() error {
+	h.Close()
+	for bucket := 0; bucket < NUM_BUCKETS; bucket++ {
+		tblName := fmt.Sprintf("%s#%d", h.indexName, bucket)
+		//还没有使用过的bucket没有文件，不需要创建出来
+		size, err := h.tx.Size(rm.TableFileName(tblName))
+		if err != nil {
+			return err
+		}
+		if size == 0 {
+			continue
+		}
+		ts, err := rm.NewTableScan(h.tx, tblName, h.layout)
+		if err != nil {
+			return err
+		}
+		for ts.Next() {
+			ts.Delete()
+		}
+		ts.Close()
+	}
+	h.ts = nil
+	return nil
+}
+
+//HashIndexFiles 哈希索引每一个bucket的文件，删除索引的时候使用
+func HashIndexFiles(indexName string) []string {
+	files := make([]string, NUM_BUCKETS)
+	for bucket := 0; bucket < NUM_BUCKETS; bucket++ {
+		files[bucket] = rm.TableFileName(fmt.Sprintf("%s#%d", indexName, bucket))
+	}
+	return files
+}
+
 func HashIndexSearchCost(numblocks int, rpb int) int {
 	//得到他这个索引的搜索代价
 	return numblocks / NUM_BUCKETS
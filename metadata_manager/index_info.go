@@ -20,14 +20,9 @@ func (i *IndexInfo) CreateIndexLayout() *rm.Layout {
 	//这个表中有三个字段，block id dataval
 	sch.AddIntField("block") //这条记录所在的区块号
 	sch.AddIntField("id")    //id就是这条记录在这个区块里面的偏移,在这个block中的第几条记录
-	if i.tableSchema.Type(i.fieldName) == rm.INTEGER {
-		//如果被创建索引的字段是int类型，就添加一个int字段
-		//查找的对应的dataval，如果相同，就把block+id取出来，知道记录在磁盘中的位置
-		sch.AddIntField("dataval") //dataval就是当前所查询的索引字段的取值
-	} else {
-		fldlen := i.tableSchema.Length(i.fieldName) //当前被创建索引的字段的字段的长度
-		sch.AddStringField("dataval", fldlen)
-	}
+	//dataval就是当前所查询的索引字段的取值，类型和长度都和被创建索引的字段相同
+	//查找的对应的dataval，如果相同，就把block+id取出来，知道记录在磁盘中的位置
+	sch.AddField("dataval", i.tableSchema.Type(i.fieldName), i.tableSchema.Length(i.fieldName))
 	return rm.NewLayoutWithSchema(sch)
 }
 
@@ -46,6 +41,16 @@ func NewIndexInfo(indexName string, fieldName string, tableSchema *rm.Schema, tx
 
 }
 
+//IndexName 索引的名字
+func (i *IndexInfo) IndexName() string {
+	return i.indexName
+}
+
+//FieldName 被创建索引的字段
+func (i *IndexInfo) FieldName() string {
+	return i.fieldName
+}
+
 //BlockAccessed 当前会访问几个block块
 func (i *IndexInfo) BlockAccessed() int {
 	rpb := int(i.tx.BlockSize()) / i.indexLayout.SlotSize() //计算一个block中有多少条记录
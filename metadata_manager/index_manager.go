@@ -77,3 +77,59 @@ func (i *IndexManager) GetIndexInfo(tableName string, tx *tx.Transaction) map[st
 	return result
 
 }
+
+//DropIndex 从idxcat中删除一个索引，返回这个索引是否存在
+func (i *IndexManager) DropIndex(indexName string, tx *tx.Transaction) (bool, error) {
+	n, err := deleteRows(tx, "idxcat", i.layout, func(ts *rm.TableScan) bool {
+		return ts.GetString("indexName") == indexName
+	})
+	return n > 0, err
+}
+
+//DropTableIndexes 删除表上的所有索引，返回这些索引的名字
+func (i *IndexManager) DropTableIndexes(tableName string, tx *tx.Transaction) ([]string, error) {
+	names := make([]string, 0)
+	_, err := deleteRows(tx, "idxcat", i.layout, func(ts *rm.TableScan) bool {
+		if ts.GetString("tableName") != tableName {
+			return false
+		}
+		names = append(names, ts.GetString("indexName"))
+		return true
+	})
+	return names, err
+}
+
+//IndexExists 是否有这个名字的索引
+func (i *IndexManager) IndexExists(indexName string, tx *tx.Transaction) (bool, error) {
+	ts, err := rm.NewTableScan(tx, "idxcat", i.layout)
+	if err != nil {
+		return false, err
+	}
+	defer ts.Close()
+	for ts.Next() {
+		if ts.GetString("indexName") == indexName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//RenameTable 表改名之后修改idxcat中索引对应的表名
+func (i *IndexManager) RenameTable(oldName string, newName string, tx *tx.Transaction) error {
+	_, err := updateRows(tx, "idxcat", i.layout, func(ts *rm.TableScan) bool {
+		return ts.GetString("tableName") == oldName
+	}, func(ts *rm.TableScan) {
+		ts.SetString("tableName", newName)
+	})
+	return err
+}
+
+//RenameField 字段改名之后修改idxcat中被索引的字段名
+func (i *IndexManager) RenameField(tableName string, oldName string, newName string, tx *tx.Transaction) error {
+	_, err := updateRows(tx, "idxcat", i.layout, func(ts *rm.TableScan) bool {
+		return ts.GetString("tableName") == tableName && ts.GetString("fieldName") == oldName
+	}, func(ts *rm.TableScan) {
+		ts.SetString("fieldName", newName)
+	})
+	return err
+}
@@ -21,4 +21,5 @@ type Index interface {
 	GetDataRID() *rm.RID
 	Insert(val *comm.Constant, rid *rm.RID)
 	Delete(val *comm.Constant, rid *rm.RID)
+	Clear() error
 }
@@ -1,6 +1,7 @@
 package metadata_manager
 
 import (
+	"fmt"
 	rm "miniSQL/record_manager"
 	tx "miniSQL/transaction"
 )
@@ -9,6 +10,7 @@ import (
 type MetaDataManager struct {
 	tblmgr  *TableManager
 	viewmgr *ViewManager
+	defmgr  *DefaultManager
 	statmgr *StatManager
 	//索引管理器以后再做处理
 	idxMgr *IndexManager //索引管理器
@@ -26,6 +28,10 @@ func NewMetaDataManager(isNew bool, tx *tx.Transaction) (*MetaDataManager, error
 	if err != nil {
 		return nil, err
 	}
+	metaMgr.defmgr, err = NewDefaultManager(isNew, metaMgr.tblmgr, tx) //默认值管理器
+	if err != nil {
+		return nil, err
+	}
 	metaMgr.statmgr, err = NewStatManager(metaMgr.tblmgr, tx) //构造一个统计管理器
 	if err != nil {
 		return nil, err
@@ -41,15 +47,48 @@ func (m *MetaDataManager) CreateTable(tblname string, sch *rm.Schema, tx *tx.Tra
 	if err != nil {
 		return err
 	}
+	if err := reuseFile(tblname, rm.NewLayoutWithSchema(sch), tx); err != nil {
+		return err
+	}
+	m.invalidate(tx)
 	return nil
 }
 
+//invalidate 表结构发生变化之后让之前生成的查询计划失效，事务回滚的时候表结构恢复原样，需要再失效一次
+func (m *MetaDataManager) invalidate(tx *tx.Transaction) {
+	m.statmgr.Invalidate()
+	tx.OnRollBack(m.statmgr.Invalidate)
+}
+
+//forget 和invalidate一样，同时丢弃表的统计数据，回滚之后也丢弃按照回滚之前的表结构计算的统计数据
+func (m *MetaDataManager) forget(tblName string, tx *tx.Transaction) {
+	m.statmgr.Forget(tblName)
+	tx.OnRollBack(func() {
+		m.statmgr.Forget(tblName)
+	})
+}
+
+//reuseFile 同一个事务中删除之后又重新创建的表或者索引，文件还在等待事务提交之后删除
+//这时取消删除，并且按照新的layout清空文件中原来的记录，清空的操作写入日志，回滚的时候原来的记录会恢复
+func reuseFile(tblName string, layout *rm.Layout, tx *tx.Transaction) error {
+	if !tx.CancelRemove(rm.TableFileName(tblName)) {
+		return nil
+	}
+	ts, err := rm.NewTableScan(tx, tblName, layout)
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+	return ts.Clear()
+}
+
 //CreateView 创建一张视图，通过底层的视图管理器来实现
 func (m *MetaDataManager) CreateView(vname string, vdef string, tx *tx.Transaction) error {
 	err := m.viewmgr.CreateView(vname, vdef, tx)
 	if err != nil {
 		return err
 	}
+	m.invalidate(tx)
 	return nil
 }
 
@@ -62,6 +101,11 @@ func (m *MetaDataManager) GetLayout(tblname string, tx *tx.Transaction) (*rm.Lay
 	return layout, nil
 }
 
+//TableExists 是否有这个名字的表，视图不算在内
+func (m *MetaDataManager) TableExists(tblname string, tx *tx.Transaction) (bool, error) {
+	return m.tblmgr.TableExists(tblname, tx)
+}
+
 //GetViewDef 得到创建某张表使用的SQL语句
 func (m *MetaDataManager) GetViewDef(vname string, tx *tx.Transaction) (string, error) {
 	viewDef, err := m.viewmgr.GetViewDef(vname, tx)
@@ -80,12 +124,155 @@ func (m *MetaDataManager) GetStatInfo(tblname string, layout *rm.Layout, tx *tx.
 	return info, nil
 }
 
+//StatVersion 返回统计数据的版本，用来判断缓存的查询计划是否还可以使用
+func (m *MetaDataManager) StatVersion() uint64 {
+	return m.statmgr.Version()
+}
+
 //CreateIndex 通过元数据管理器，就能直接创建一个索引
-func (m *MetaDataManager) CreateIndex(idxName string, tblName string, fieldName string, tx *tx.Transaction) {
+func (m *MetaDataManager) CreateIndex(idxName string, tblName string, fieldName string, tx *tx.Transaction) error {
 	m.idxMgr.CreateIndex(idxName, tblName, fieldName, tx)
+	m.invalidate(tx)
+	layout := m.idxMgr.GetIndexInfo(tblName, tx)[fieldName].CreateIndexLayout()
+	for bucket := 0; bucket < NUM_BUCKETS; bucket++ {
+		if err := reuseFile(fmt.Sprintf("%s#%d", idxName, bucket), layout, tx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 //GetIndexInfo 获得索引的信息
 func (m *MetaDataManager) GetIndexInfo(tableName string, tx *tx.Transaction) map[string]*IndexInfo {
 	return m.idxMgr.GetIndexInfo(tableName, tx)
 }
+
+//DropTable 删除一张表以及它上面的所有索引，事务提交之后删除它们的文件，返回这张表是否存在
+func (m *MetaDataManager) DropTable(tblName string, tx *tx.Transaction) (bool, error) {
+	found, err := m.tblmgr.DropTable(tblName, tx)
+	if err != nil || !found {
+		return false, err
+	}
+	indexes, err := m.idxMgr.DropTableIndexes(tblName, tx)
+	if err != nil {
+		return false, err
+	}
+	if err := m.defmgr.DropDefaults(tblName, func(string) bool { return true }, tx); err != nil {
+		return false, err
+	}
+	files := []string{rm.TableFileName(tblName)}
+	for _, indexName := range indexes {
+		files = append(files, HashIndexFiles(indexName)...)
+	}
+	for _, file := range files {
+		if err := tx.RemoveOnCommit(file); err != nil {
+			return false, err
+		}
+	}
+	m.forget(tblName, tx)
+	return true, nil
+}
+
+//DropView 删除一个视图，返回这个视图是否存在
+func (m *MetaDataManager) DropView(vname string, tx *tx.Transaction) (bool, error) {
+	found, err := m.viewmgr.DropView(vname, tx)
+	if err != nil || !found {
+		return false, err
+	}
+	m.invalidate(tx)
+	return true, nil
+}
+
+//DropIndex 删除一个索引，事务提交之后删除索引的文件，返回这个索引是否存在
+func (m *MetaDataManager) DropIndex(idxName string, tx *tx.Transaction) (bool, error) {
+	found, err := m.idxMgr.DropIndex(idxName, tx)
+	if err != nil || !found {
+		return false, err
+	}
+	for _, file := range HashIndexFiles(idxName) {
+		if err := tx.RemoveOnCommit(file); err != nil {
+			return false, err
+		}
+	}
+	m.invalidate(tx)
+	return true, nil
+}
+
+//ViewDefs 所有视图的名字以及定义它们的sql语句
+func (m *MetaDataManager) ViewDefs(tx *tx.Transaction) (map[string]string, error) {
+	return m.viewmgr.ViewDefs(tx)
+}
+
+//IndexExists 是否有这个名字的索引
+func (m *MetaDataManager) IndexExists(idxName string, tx *tx.Transaction) (bool, error) {
+	return m.idxMgr.IndexExists(idxName, tx)
+}
+
+//AlterLayout 修改表结构之后用新的layout替换元数据，表中的数据由调用者按照新的layout重写，返回这张表是否存在
+//新的layout中没有的字段，它们的默认值也一起删除
+func (m *MetaDataManager) AlterLayout(tblName string, layout *rm.Layout, tx *tx.Transaction) (bool, error) {
+	found, err := m.tblmgr.ReplaceLayout(tblName, layout, tx)
+	if err != nil || !found {
+		return false, err
+	}
+	err = m.defmgr.DropDefaults(tblName, func(field string) bool { return !layout.Schema().HashField(field) }, tx)
+	if err != nil {
+		return false, err
+	}
+	m.forget(tblName, tx)
+	return true, nil
+}
+
+//RenameField 修改表中一个字段的名字，字段上的索引也跟着改名，返回这个字段是否存在
+func (m *MetaDataManager) RenameField(tblName string, oldName string, newName string, tx *tx.Transaction) (bool, error) {
+	found, err := m.tblmgr.RenameField(tblName, oldName, newName, tx)
+	if err != nil || !found {
+		return false, err
+	}
+	if err := m.idxMgr.RenameField(tblName, oldName, newName, tx); err != nil {
+		return false, err
+	}
+	if err := m.defmgr.Rename(tblName, oldName, newName, tx); err != nil {
+		return false, err
+	}
+	m.forget(tblName, tx)
+	return true, nil
+}
+
+//RenameTable 修改表名，表上的索引转移到新的表名下面，旧的表文件在事务提交之后删除
+//记录由调用者从旧的文件复制到新的文件中，返回这张表是否存在
+func (m *MetaDataManager) RenameTable(oldName string, newName string, tx *tx.Transaction) (bool, error) {
+	found, err := m.tblmgr.RenameTable(oldName, newName, tx)
+	if err != nil || !found {
+		return false, err
+	}
+	if err := m.idxMgr.RenameTable(oldName, newName, tx); err != nil {
+		return false, err
+	}
+	if err := m.defmgr.Rename(oldName, "", newName, tx); err != nil {
+		return false, err
+	}
+	if err := tx.RemoveOnCommit(rm.TableFileName(oldName)); err != nil {
+		return false, err
+	}
+	layout, err := m.tblmgr.GetLayout(newName, tx)
+	if err != nil {
+		return false, err
+	}
+	if err := reuseFile(newName, layout, tx); err != nil {
+		return false, err
+	}
+	m.forget(oldName, tx)
+	m.forget(newName, tx)
+	return true, nil
+}
+
+//SetDefault 保存表中一个字段的默认值
+func (m *MetaDataManager) SetDefault(tblName string, fldName string, val string, tx *tx.Transaction) error {
+	return m.defmgr.SetDefault(tblName, fldName, val, tx)
+}
+
+//GetDefaults 一张表中所有有默认值的字段以及它们的默认值
+func (m *MetaDataManager) GetDefaults(tblName string, tx *tx.Transaction) (map[string]string, error) {
+	return m.defmgr.GetDefaults(tblName, tx)
+}
@@ -88,6 +88,13 @@ func TestMetaDataManager2(t *testing.T) {
 	v, err := mdm.GetViewDef("viewA", tx)
 	assert.Nil(t, err)
 	assert.Equal(t, viewDef, v)
+	//视图不算作表
+	exists, err := mdm.TableExists("viewA", tx)
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	exists, err = mdm.TableExists("tblcat", tx)
+	assert.Nil(t, err)
+	assert.True(t, exists)
 	tx.Commit()
 }
 
@@ -52,7 +52,12 @@ func (s *StatInfo) RecordsOutput() int {
 
 //DistinctValue 返回当前表中的某个字段有多少个不同的值
 func (s *StatInfo) DistinctValue(fldName string) int {
-	return int(s.fldData[fldName].Estimate()) //从hyperloglog中返回当前数据的基数
+	sketch, ok := s.fldData[fldName]
+	if !ok {
+		//统计的时候表是空的，没有这个字段的数据，按照只有一个值来估计，避免出现除0
+		return 1
+	}
+	return int(sketch.Estimate()) //从hyperloglog中返回当前数据的基数
 }
 
 //StatManager 状态管理器，管理当前数据库的状态,他只在系统启动的时候创建，在创建的时候，会调用refreshStatistics来创建统计数据并存储在内存中
@@ -61,6 +66,7 @@ type StatManager struct {
 	tblgr      *TableManager
 	tableStats map[string]*StatInfo //管理每张表的信息
 	numCalls   int                  //调用当前StatManager的次数
+	version    uint64               //统计数据的版本，每次重新计算统计数据或者表结构发生变化的时候加1
 	lock       sync.Mutex
 }
 
@@ -81,6 +87,7 @@ func NewStatManager(tblgr *TableManager, tx *tx.Transaction) (*StatManager, erro
 func (s *StatManager) refreshStatistics(tx *tx.Transaction) error {
 	s.tableStats = make(map[string]*StatInfo)
 	s.numCalls = 0
+	s.version++
 	layout, _ := s.tblgr.GetLayout("tblcat", tx)     //获得tblcat表，通过这张表，可以得到每张表的名字
 	tcat, _ := rm.NewTableScan(tx, "tblcat", layout) //对tblcat表进行读写操作
 	for tcat.Next() {
@@ -149,3 +156,25 @@ func (s *StatManager) GetStatInfo(tblname string, layout *rm.Layout, tx *tx.Tran
 	}
 	return si, nil
 }
+
+//Version 返回当前统计数据的版本，版本没有变化的时候，之前根据统计数据生成的查询计划仍然可以继续使用
+func (s *StatManager) Version() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.version
+}
+
+//Invalidate 创建或者删除表，视图和索引之后调用，让之前生成的查询计划失效
+func (s *StatManager) Invalidate() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.version++
+}
+
+//Forget 删除一张表之后调用，丢弃这张表的统计数据，之后创建的同名表重新统计
+func (s *StatManager) Forget(tblname string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.tableStats, tblname)
+	s.version++
+}
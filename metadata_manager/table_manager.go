@@ -55,7 +55,12 @@ func NewTableManager(isNew bool, tx *tx.Transaction) (*TableManager, error) {
 
 //CreateTable 创建一张表，并添加到tbcat和fldcat两张表进行管理,在创建表之前首先保证tblcat和fldcat两张元数据表存在
 func (t *TableManager) CreateTable(tblName string, schema *rm.Schema, tx *tx.Transaction) error {
-	layout := rm.NewLayoutWithSchema(schema)
+	return t.createTable(tblName, rm.NewLayoutWithSchema(schema), tx)
+}
+
+//createTable 按照给定的layout把表登记到tblcat和fldcat中，修改表结构的时候字段的偏移不一定是连续的
+func (t *TableManager) createTable(tblName string, layout *rm.Layout, tx *tx.Transaction) error {
+	schema := layout.Schema()
 	tcat, err := rm.NewTableScan(tx, "tblcat", t.tcatLayout) //开辟一张表
 	if err != nil {
 		return err
@@ -125,3 +130,98 @@ func (t *TableManager) GetLayout(tblName string, tx *tx.Transaction) (*rm.Layout
 	return rm.NewLayout(sch, offsets, size), nil
 
 }
+
+//TableExists tblcat中是否有这张表
+func (t *TableManager) TableExists(tblName string, tx *tx.Transaction) (bool, error) {
+	ts, err := rm.NewTableScan(tx, "tblcat", t.tcatLayout)
+	if err != nil {
+		return false, err
+	}
+	defer ts.Close()
+	for ts.Next() {
+		if ts.GetString("tblname") == tblName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//DropTable 从tblcat和fldcat中删除一张表的记录，返回这张表是否存在，表的文件由调用者登记在事务提交之后删除
+func (t *TableManager) DropTable(tblName string, tx *tx.Transaction) (bool, error) {
+	n, err := deleteRows(tx, "tblcat", t.tcatLayout, func(ts *rm.TableScan) bool {
+		return ts.GetString("tblname") == tblName
+	})
+	if err != nil || n == 0 {
+		return false, err
+	}
+	_, err = deleteRows(tx, "fldcat", t.fcatLayout, func(ts *rm.TableScan) bool {
+		return ts.GetString("tblname") == tblName
+	})
+	return err == nil, err
+}
+
+//ReplaceLayout 用新的layout替换一张表在tblcat和fldcat中的记录，返回这张表是否存在
+func (t *TableManager) ReplaceLayout(tblName string, layout *rm.Layout, tx *tx.Transaction) (bool, error) {
+	found, err := t.DropTable(tblName, tx)
+	if err != nil || !found {
+		return false, err
+	}
+	return true, t.createTable(tblName, layout, tx)
+}
+
+//RenameTable 修改表名，表的结构不变，返回这张表是否存在
+func (t *TableManager) RenameTable(oldName string, newName string, tx *tx.Transaction) (bool, error) {
+	layout, err := t.GetLayout(oldName, tx)
+	if err != nil {
+		return false, err
+	}
+	found, err := t.DropTable(oldName, tx)
+	if err != nil || !found {
+		return false, err
+	}
+	return true, t.createTable(newName, layout, tx)
+}
+
+//RenameField 修改fldcat中一个字段的名字，字段的偏移不变，所以表中的数据不需要改动，返回这个字段是否存在
+func (t *TableManager) RenameField(tblName string, oldName string, newName string, tx *tx.Transaction) (bool, error) {
+	n, err := updateRows(tx, "fldcat", t.fcatLayout, func(ts *rm.TableScan) bool {
+		return ts.GetString("tblname") == tblName && ts.GetString("fldname") == oldName
+	}, func(ts *rm.TableScan) {
+		ts.SetString("fldname", newName)
+	})
+	return n > 0, err
+}
+
+//updateRows 使用update修改元数据表中match返回true的记录，返回修改的记录数
+func updateRows(tx *tx.Transaction, tblName string, layout *rm.Layout, match func(ts *rm.TableScan) bool, update func(ts *rm.TableScan)) (int, error) {
+	ts, err := rm.NewTableScan(tx, tblName, layout)
+	if err != nil {
+		return 0, err
+	}
+	defer ts.Close()
+	n := 0
+	for ts.Next() {
+		if match(ts) {
+			update(ts)
+			n++
+		}
+	}
+	return n, nil
+}
+
+//deleteRows 删除元数据表中match返回true的记录，返回删除的记录数
+func deleteRows(tx *tx.Transaction, tblName string, layout *rm.Layout, match func(ts *rm.TableScan) bool) (int, error) {
+	ts, err := rm.NewTableScan(tx, tblName, layout)
+	if err != nil {
+		return 0, err
+	}
+	defer ts.Close()
+	n := 0
+	for ts.Next() {
+		if match(ts) {
+			ts.Delete()
+			n++
+		}
+	}
+	return n, nil
+}
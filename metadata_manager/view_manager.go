@@ -44,7 +44,7 @@ func (v *ViewManager) CreateView(vname string, vdef string, tx *tx.Transaction)
 		return err
 	}
 	//对这张视图表进行处理
-	ts, err := rm.NewTableScan(tx, vname, layout)
+	ts, err := rm.NewTableScan(tx, "viewcat", layout)
 	if err != nil {
 		return err
 	}
@@ -65,7 +65,7 @@ func (v *ViewManager) GetViewDef(vname string, tx *tx.Transaction) (string, erro
 		return "", err
 	}
 	//对这张视图表进行处理
-	ts, err := rm.NewTableScan(tx, vname, layout)
+	ts, err := rm.NewTableScan(tx, "viewcat", layout)
 	if err != nil {
 		return "", err
 	}
@@ -81,3 +81,33 @@ func (v *ViewManager) GetViewDef(vname string, tx *tx.Transaction) (string, erro
 	}
 	return result, nil
 }
+
+//DropView 从viewcat中删除一个视图，返回这个视图是否存在
+func (v *ViewManager) DropView(vname string, tx *tx.Transaction) (bool, error) {
+	layout, err := v.tblgr.GetLayout("viewcat", tx)
+	if err != nil {
+		return false, err
+	}
+	n, err := deleteRows(tx, "viewcat", layout, func(ts *rm.TableScan) bool {
+		return ts.GetString("viewname") == vname
+	})
+	return n > 0, err
+}
+
+//ViewDefs 所有视图的名字以及定义它们的sql语句
+func (v *ViewManager) ViewDefs(tx *tx.Transaction) (map[string]string, error) {
+	layout, err := v.tblgr.GetLayout("viewcat", tx)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := rm.NewTableScan(tx, "viewcat", layout)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Close()
+	defs := make(map[string]string)
+	for ts.Next() {
+		defs[ts.GetString("viewname")] = ts.GetString("viewdef")
+	}
+	return defs, nil
+}
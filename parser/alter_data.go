@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"miniSQL/comm"
+	rm "miniSQL/record_manager"
+)
+
+//AlterAction ALTER TABLE对表做的修改
+type AlterAction int
+
+const (
+	ALTER_ADD_COLUMN AlterAction = iota
+	ALTER_DROP_COLUMN
+	ALTER_RENAME_COLUMN
+	ALTER_RENAME_TABLE
+)
+
+//AlterData ALTER TABLE name (ADD [COLUMN] fielddef [DEFAULT constant] | DROP [COLUMN] field
+//| RENAME [COLUMN] field TO newname | RENAME TO newname)
+type AlterData struct {
+	tableName  string
+	action     AlterAction
+	fieldName  string         //新增，删除或者重命名的字段
+	newName    string         //字段或者表的新名字
+	schema     *rm.Schema     //新增字段的类型
+	defaultVal *comm.Constant //新增字段的默认值，没有DEFAULT的时候为nil
+}
+
+func NewAddColumnData(tableName string, sch *rm.Schema, defaultVal *comm.Constant) *AlterData {
+	return &AlterData{
+		tableName:  tableName,
+		action:     ALTER_ADD_COLUMN,
+		fieldName:  sch.Fields()[0],
+		schema:     sch,
+		defaultVal: defaultVal,
+	}
+}
+
+func NewDropColumnData(tableName string, fieldName string) *AlterData {
+	return &AlterData{
+		tableName: tableName,
+		action:    ALTER_DROP_COLUMN,
+		fieldName: fieldName,
+	}
+}
+
+func NewRenameColumnData(tableName string, fieldName string, newName string) *AlterData {
+	return &AlterData{
+		tableName: tableName,
+		action:    ALTER_RENAME_COLUMN,
+		fieldName: fieldName,
+		newName:   newName,
+	}
+}
+
+func NewRenameTableData(tableName string, newName string) *AlterData {
+	return &AlterData{
+		tableName: tableName,
+		action:    ALTER_RENAME_TABLE,
+		newName:   newName,
+	}
+}
+
+func (a *AlterData) TableName() string {
+	return a.tableName
+}
+
+func (a *AlterData) Action() AlterAction {
+	return a.action
+}
+
+func (a *AlterData) FieldName() string {
+	return a.fieldName
+}
+
+func (a *AlterData) NewName() string {
+	return a.newName
+}
+
+func (a *AlterData) Schema() *rm.Schema {
+	return a.schema
+}
+
+func (a *AlterData) Default() *comm.Constant {
+	return a.defaultVal
+}
+
+func (a *AlterData) ToString() string {
+	result := "ALTER TABLE " + a.tableName + " "
+	switch a.action {
+	case ALTER_ADD_COLUMN:
+		result += "ADD COLUMN " + a.fieldName + " " + rm.TypeName(a.schema.Type(a.fieldName), a.schema.Length(a.fieldName))
+		if a.defaultVal != nil {
+			result += " DEFAULT " + a.defaultVal.ToString()
+		}
+	case ALTER_DROP_COLUMN:
+		result += "DROP COLUMN " + a.fieldName
+	case ALTER_RENAME_COLUMN:
+		result += "RENAME COLUMN " + a.fieldName + " TO " + a.newName
+	case ALTER_RENAME_TABLE:
+		result += "RENAME TO " + a.newName
+	}
+	return result
+}
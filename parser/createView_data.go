@@ -19,6 +19,11 @@ func (v *CreateViewData) ViewName() string {
 	return v.viewName
 }
 
+//Query 视图定义中的查询
+func (v *CreateViewData) Query() *QueryData {
+	return v.queryData
+}
+
 //ViewDef 获得创建这张表的定义语句
 func (v *CreateViewData) ViewDef() string {
 	return v.queryData.ToString()
@@ -0,0 +1,67 @@
+package parser
+
+//DropKind 删除的对象的类型
+type DropKind int
+
+const (
+	DROP_TABLE DropKind = iota
+	DROP_VIEW
+	DROP_INDEX
+)
+
+//String 对象类型在SQL中的写法
+func (k DropKind) String() string {
+	switch k {
+	case DROP_VIEW:
+		return "VIEW"
+	case DROP_INDEX:
+		return "INDEX"
+	}
+	return "TABLE"
+}
+
+//DropData DROP TABLE | VIEW | INDEX [IF EXISTS] name [CASCADE]
+//IF EXISTS的时候对象不存在不报错，CASCADE的时候同时删除依赖这张表或者视图的视图
+type DropData struct {
+	kind     DropKind
+	name     string
+	ifExists bool
+	cascade  bool
+}
+
+func NewDropData(kind DropKind, name string, ifExists bool, cascade bool) *DropData {
+	return &DropData{
+		kind:     kind,
+		name:     name,
+		ifExists: ifExists,
+		cascade:  cascade,
+	}
+}
+
+func (d *DropData) Kind() DropKind {
+	return d.kind
+}
+
+func (d *DropData) Name() string {
+	return d.name
+}
+
+func (d *DropData) IfExists() bool {
+	return d.ifExists
+}
+
+func (d *DropData) Cascade() bool {
+	return d.cascade
+}
+
+func (d *DropData) ToString() string {
+	result := "DROP " + d.kind.String() + " "
+	if d.ifExists {
+		result += "IF EXISTS "
+	}
+	result += d.name
+	if d.cascade {
+		result += " CASCADE"
+	}
+	return result
+}
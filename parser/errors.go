@@ -2,8 +2,18 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
-	ErrSyntax = errors.New("you have an error in your SQL syntax")
+	ErrSyntax   = errors.New("you have an error in your SQL syntax")
+	ErrArgCount = errors.New("number of arguments does not match number of placeholders")
 )
+
+//syntaxError 把解析过程中遇到的错误包装成ErrSyntax，已经是ErrSyntax或者ErrArgCount的错误直接返回
+func syntaxError(err error) error {
+	if errors.Is(err, ErrSyntax) || errors.Is(err, ErrArgCount) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrSyntax, err)
+}
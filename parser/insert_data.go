@@ -1,21 +1,32 @@
 package parser
 
 import (
-	"miniSQL/comm"
+	"miniSQL/query"
 )
 
 //InsertData 这个解析出来就是相当于抽象语法树
+//写入的记录可以是VALUES后面的多行值，也可以来自一个查询，两者只有一个
 type InsertData struct {
 	tableName string
 	fields    []string
-	values    []*comm.Constant
+	rows      [][]*query.Expression //每一行写入的值，可以是常量或者常量之间的运算，不能使用字段
+	query     *QueryData            //INSERT ... SELECT中的查询，使用VALUES的时候为nil
 }
 
-func NewInsertData(tblName string, fields []string, values []*comm.Constant) *InsertData {
+func NewInsertData(tblName string, fields []string, rows [][]*query.Expression) *InsertData {
 	return &InsertData{
 		tableName: tblName,
 		fields:    fields,
-		values:    values,
+		rows:      rows,
+	}
+}
+
+//NewInsertSelectData 写入查询结果中的每一条记录，查询结果的列和fields按照位置对应
+func NewInsertSelectData(tblName string, fields []string, data *QueryData) *InsertData {
+	return &InsertData{
+		tableName: tblName,
+		fields:    fields,
+		query:     data,
 	}
 }
 
@@ -27,6 +38,18 @@ func (d *InsertData) Fields() []string {
 	return d.fields
 }
 
-func (d *InsertData) Vals() []*comm.Constant {
-	return d.values
+//Vals 第一行写入的值，INSERT ... SELECT的时候为nil
+func (d *InsertData) Vals() []*query.Expression {
+	if len(d.rows) == 0 {
+		return nil
+	}
+	return d.rows[0]
+}
+
+func (d *InsertData) Rows() [][]*query.Expression {
+	return d.rows
+}
+
+func (d *InsertData) Query() *QueryData {
+	return d.query
 }
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"miniSQL/query"
+	"strings"
+)
+
+/*
+	FROM中用逗号分隔的每一项是一个TableRef，它从一张表开始，依次和后面JOIN的表进行连接
+	FROM a LEFT JOIN b ON x = y JOIN c USING (id), d
+	解析成两个TableRef：a带着两个连接，b使用LEFT JOIN，c使用INNER JOIN；d没有连接
+	每张表都可以有一个别名，FROM student s 或者 FROM student AS s，之后使用s.name引用这张表中的字段
+	连接条件可以是ON后面的条件，也可以是USING中两边同名的字段，USING中的字段在结果中只出现一次
+	表也可以是一个子查询，FROM (SELECT ...) AS t，这时必须有别名，表名为空
+*/
+
+//JoinType 连接的类型
+type JoinType int
+
+const (
+	INNER_JOIN JoinType = iota
+	LEFT_JOIN           //保留左边所有的记录，右边没有匹配的使用NULL填充
+	RIGHT_JOIN          //保留右边所有的记录
+	FULL_JOIN           //保留两边所有的记录
+)
+
+//String 连接类型在SQL中的写法
+func (t JoinType) String() string {
+	switch t {
+	case LEFT_JOIN:
+		return "LEFT JOIN"
+	case RIGHT_JOIN:
+		return "RIGHT JOIN"
+	case FULL_JOIN:
+		return "FULL JOIN"
+	}
+	return "JOIN"
+}
+
+//JoinData 和左边已经连接好的结果进行连接的一张表
+type JoinData struct {
+	joinType JoinType
+	table    string
+	alias    string           //表的别名，没有的时候为空
+	on       *query.Predicate //ON后面的条件，使用USING的时候为空的条件
+	using    []string         //USING中的字段
+	query    *QueryData       //FROM中的子查询，不是子查询的时候为nil
+}
+
+func NewJoinData(joinType JoinType, table string, alias string, on *query.Predicate, using []string) *JoinData {
+	if on == nil {
+		on = query.NewPredicate()
+	}
+	return &JoinData{
+		joinType: joinType,
+		table:    table,
+		alias:    alias,
+		on:       on,
+		using:    using,
+	}
+}
+
+func (j *JoinData) JoinType() JoinType {
+	return j.joinType
+}
+
+func (j *JoinData) TableName() string {
+	return j.table
+}
+
+func (j *JoinData) Alias() string {
+	return j.alias
+}
+
+//RangeName 在查询中引用这张表使用的名字，有别名的时候是别名
+func (j *JoinData) RangeName() string {
+	return rangeName(j.table, j.alias)
+}
+
+//Query 连接的是子查询的时候返回子查询，否则返回nil
+func (j *JoinData) Query() *QueryData {
+	return j.query
+}
+
+func (j *JoinData) SetQuery(data *QueryData) {
+	j.query = data
+}
+
+func (j *JoinData) On() *query.Predicate {
+	return j.on
+}
+
+func (j *JoinData) Using() []string {
+	return j.using
+}
+
+//ToString 连接转化成字符串，视图的定义需要能够被重新解析
+func (j *JoinData) ToString() string {
+	result := j.joinType.String() + " " + tableString(j.table, j.alias, j.query)
+	if len(j.using) > 0 {
+		return result + " USING (" + strings.Join(j.using, ", ") + ")"
+	}
+	return result + " ON " + j.on.ToString()
+}
+
+//TableRef FROM中用逗号分隔的一项
+type TableRef struct {
+	table string
+	alias string
+	joins []*JoinData
+	query *QueryData
+}
+
+func NewTableRef(table string, alias string, joins []*JoinData) *TableRef {
+	return &TableRef{
+		table: table,
+		alias: alias,
+		joins: joins,
+	}
+}
+
+func (t *TableRef) TableName() string {
+	return t.table
+}
+
+func (t *TableRef) Alias() string {
+	return t.alias
+}
+
+func (t *TableRef) RangeName() string {
+	return rangeName(t.table, t.alias)
+}
+
+func (t *TableRef) Query() *QueryData {
+	return t.query
+}
+
+func (t *TableRef) SetQuery(data *QueryData) {
+	t.query = data
+}
+
+func (t *TableRef) Joins() []*JoinData {
+	return t.joins
+}
+
+func (t *TableRef) ToString() string {
+	result := tableString(t.table, t.alias, t.query)
+	for _, join := range t.joins {
+		result += " " + join.ToString()
+	}
+	return result
+}
+
+func rangeName(table string, alias string) string {
+	if alias != "" {
+		return alias
+	}
+	return table
+}
+
+func tableString(table string, alias string, data *QueryData) string {
+	if data != nil {
+		return "(" + data.ToString() + ") AS " + alias
+	}
+	if alias != "" {
+		return table + " AS " + alias
+	}
+	return table
+}
@@ -13,8 +13,14 @@ import (
 )
 
 type SQLParser struct {
-	sqlLexer *lexer.Lexer //判断当前的组合序列是否符合某种语法规则
-
+	sqlLexer       *lexer.Lexer       //判断当前的组合序列是否符合某种语法规则
+	args           []*comm.Constant   //绑定到?占位符上的参数，按照出现的顺序依次使用
+	argIdx         int                //下一个?占位符使用的参数下标，使用$n的时候是出现过的最大的n
+	prepare        bool               //为true的时候不替换占位符，而是为每个占位符创建一个参数槽
+	params         []*comm.Constant   //每个占位符对应的参数槽，执行之前再把值绑定上去
+	style          string             //语句中使用的占位符的形式，?和$n不能混合使用
+	aggregates     []*query.Aggregate //当前查询中出现的聚合函数
+	allowAggregate bool               //当前的子句中是否可以使用聚合函数，WHERE中不能使用
 }
 
 func NewSQLParser(s string) *SQLParser {
@@ -23,13 +29,50 @@ func NewSQLParser(s string) *SQLParser {
 	}
 }
 
+//NewSQLParserWithArgs 构造一个带有参数的解析器，语句中的?会依次被args中的值替换，$n会被第n个参数替换
+func NewSQLParserWithArgs(s string, args []*comm.Constant) *SQLParser {
+	return &SQLParser{
+		sqlLexer: lexer.NewLexer(s),
+		args:     args,
+	}
+}
+
+//Prepare 解析一条带有占位符的语句，返回语法树和每个占位符对应的参数槽
+//参数槽是语法树中的Constant对象，执行之前把值写入到参数槽中，同一个语法树就可以使用不同的参数反复执行
+//?按照出现的顺序对应参数槽，$n对应第n个参数槽，同一个$n出现多次的时候共享同一个参数槽
+func Prepare(s string) (Statement, []*comm.Constant, error) {
+	p := &SQLParser{
+		sqlLexer: lexer.NewLexer(s),
+		prepare:  true,
+	}
+	stmt, err := p.ParseStatement()
+	if err != nil {
+		return nil, nil, err
+	}
+	return stmt, p.params, nil
+}
+
 /*
 	bfd范式
 	FIELD -> ID
-	CONSTANT -> STRING | NUM
-	EXPRESSION -> FIELD | CONSTANT
-	TERM -> EXPRESSION EQ EXPRESSION
-	PREDICATE -> TERM (AND PREDICATE)?
+	QUALIFIEDFIELD -> (ID DOT)? FIELD
+	AGGREGATE -> ID LEFT_BRACKET (ASTERISK | QUALIFIEDFIELD) RIGHT_BRACKET
+	COLUMN -> QUALIFIEDFIELD | AGGREGATE
+	CONSTANT -> STRING | NUM | REAL | TRUE | FALSE | NULL | ? | $n
+	FUNCTION -> ID LEFT_BRACKET EXPRESSION (COMMA EXPRESSION)* RIGHT_BRACKET
+	EXPRESSION -> PRODUCT ((PLUS | MINUS) PRODUCT)*
+	PRODUCT -> UNARY ((ASTERISK | SLASH | PERCENT) UNARY)*
+	UNARY -> MINUS UNARY | PRIMARY
+	SUBQUERY -> LEFT_BRACKET QUERY RIGHT_BRACKET
+	PRIMARY -> COLUMN | FUNCTION | CONSTANT | SUBQUERY | LEFT_BRACKET EXPRESSION RIGHT_BRACKET
+	SELECTITEM -> ASTERISK | ID DOT ASTERISK | EXPRESSION (AS ID)?
+	TERM -> EXPRESSION OP EXPRESSION | EXPRESSION (NOT)? IN SUBQUERY | EXPRESSION IS (NOT)? NULL | EXPRESSION
+	PREDICATE -> CONJUNCT (OR CONJUNCT)*
+	JOINTYPE -> (INNER)? JOIN | (LEFT | RIGHT | FULL) (OUTER)? JOIN
+	TABLE -> ID ((AS)? ID)? | SUBQUERY (AS)? ID
+	JOIN -> JOINTYPE TABLE (ON PREDICATE | USING LEFT_BRACKET ID (COMMA ID)* RIGHT_BRACKET)
+	TABLEREF -> TABLE (JOIN)*
+	FROMLIST -> TABLEREF (COMMA TABLEREF)*
 */
 
 //Field 解析当前的field，并返回当前的field的token对应的字符串
@@ -45,7 +88,126 @@ func (p *SQLParser) Field() (*lexer.Token, string, error) {
 	return token, p.sqlLexer.Lexeme, nil
 }
 
-//Constant 当前是一个常数，CONSTANT -> STRING | NUM，返回对应的constant数
+//QualifiedField QUALIFIEDFIELD -> (ID DOT)? FIELD，带有表名或者表的别名的字段返回t.col的形式
+func (p *SQLParser) QualifiedField() (string, error) {
+	_, field, err := p.Field()
+	if err != nil {
+		return "", err
+	}
+	return p.qualify(field)
+}
+
+//qualify 已经读取了一个ID，后面跟着点的时候它是表名，再读取字段名
+func (p *SQLParser) qualify(name string) (string, error) {
+	if !p.matchTag(lexer.DOT) {
+		return name, nil
+	}
+	_, field, err := p.Field()
+	if err != nil {
+		return "", err
+	}
+	return name + "." + field, nil
+}
+
+//Column COLUMN -> QUALIFIEDFIELD | AGGREGATE，聚合函数返回它的结果对应的字段名，例如count(*)
+//聚合函数的名字不是关键字，只有后面跟着左括号的时候才作为聚合函数，这样count，max等仍然可以作为字段名
+func (p *SQLParser) Column() (string, error) {
+	_, field, err := p.Field()
+	if err != nil {
+		return "", err
+	}
+	if !query.IsAggregateFn(field) || !p.matchTag(lexer.LEFT_BRACKET) {
+		return p.qualify(field)
+	}
+	if !p.allowAggregate {
+		return "", fmt.Errorf("%w: aggregate function %s is not allowed here", ErrSyntax, field)
+	}
+	arg := "*"
+	if !p.matchTag(lexer.ASTERISK) {
+		if arg, err = p.QualifiedField(); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+	}
+	if err := p.checkWordTag(lexer.RIGHT_BRACKET); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+	agg := query.NewAggregate(field, arg)
+	if agg.Fn() != query.AGG_COUNT && arg == "*" {
+		return "", fmt.Errorf("%w: %s(*) is not supported", ErrSyntax, agg.Fn())
+	}
+	for _, exist := range p.aggregates {
+		if exist.Name() == agg.Name() {
+			return agg.Name(), nil
+		}
+	}
+	p.aggregates = append(p.aggregates, agg)
+	return agg.Name(), nil
+}
+
+//SelectList SELECTLIST -> SELECTITEM (COMMA SELECTITEM)*
+//返回每一项的表达式和它在结果中的名字，没有别名的时候使用表达式的字符串形式作为名字
+//*和t.*作为名字为*和t.*的字段，由planner展开成对应的表中所有的字段
+//没有别名的t.col使用col作为名字，和其他项的名字冲突的时候才保留t.col
+func (p *SQLParser) SelectList() ([]*query.Expression, []string, error) {
+	exprs := make([]*query.Expression, 0)
+	names := make([]string, 0)
+	qualified := make([]bool, 0) //对应的项是否是没有别名的t.col
+	for {
+		expr, err := p.selectItem()
+		if err != nil {
+			return nil, nil, err
+		}
+		name := expr.ToString()
+		_, col := query.SplitFieldName(name)
+		//*和t.*不能使用别名
+		if col != "*" && p.matchTag(lexer.AS) {
+			if _, name, err = p.Field(); err != nil {
+				return nil, nil, err
+			}
+		}
+		isQualified := expr.IsFieldName() && name == expr.AsFieldName() && col != name && col != "*"
+		if isQualified {
+			name = col
+		}
+		exprs = append(exprs, expr)
+		names = append(names, name)
+		qualified = append(qualified, isQualified)
+		if !p.matchTag(lexer.COMMA) {
+			break
+		}
+	}
+	count := make(map[string]int)
+	for _, name := range names {
+		count[name]++
+	}
+	for i := range names {
+		if qualified[i] && count[names[i]] > 1 {
+			names[i] = exprs[i].AsFieldName()
+		}
+	}
+	return exprs, names, nil
+}
+
+//selectItem 选择的一项是*，t.*或者一个表达式
+func (p *SQLParser) selectItem() (*query.Expression, error) {
+	if p.matchTag(lexer.ASTERISK) {
+		return query.NewExpressionWithFieldName("*"), nil
+	}
+	if p.matchTag(lexer.ID) {
+		table := p.sqlLexer.Lexeme
+		if p.matchTag(lexer.DOT) {
+			if p.matchTag(lexer.ASTERISK) {
+				return query.NewExpressionWithFieldName(table + ".*"), nil
+			}
+			p.sqlLexer.ReverseScan()
+		}
+		p.sqlLexer.ReverseScan()
+	}
+	return p.Expression()
+}
+
+//Constant 当前是一个常数，CONSTANT -> STRING | NUM | REAL | TRUE | FALSE | NULL，返回对应的constant数
+//带小数点的数字是定点数，小数位数就是小数点后面数字的个数
 func (p *SQLParser) Constant() (*comm.Constant, error) {
 	token, err := p.sqlLexer.Scan()
 	if err != nil {
@@ -56,35 +218,174 @@ func (p *SQLParser) Constant() (*comm.Constant, error) {
 	case lexer.STRING:
 		s := strings.Clone(p.sqlLexer.Lexeme) //把当前的字符串保存起来
 		return comm.NewConstantString(&s), nil
-		break
 	case lexer.NUM:
 		v, err := strconv.Atoi(p.sqlLexer.Lexeme) //转化成整数
 		if err != nil {
 			return nil, errors.New("string is not number")
 		}
 		return comm.NewConstantInt(&v), nil
-		break
+	case lexer.REAL:
+		v, err := comm.ParseDecimal(p.sqlLexer.Lexeme)
+		if err != nil {
+			return nil, err
+		}
+		return comm.NewConstantDecimal(&v), nil
+	case lexer.TRUE, lexer.FALSE:
+		v := token.Tag == lexer.TRUE
+		return comm.NewConstantBool(&v), nil
+	case lexer.NULL:
+		return comm.NewNullConstant(), nil
+	case lexer.PLACEHOLDER:
+		return p.Placeholder()
 	default:
-		panic("token is not string ")
+		return nil, errors.New("token is not a constant")
 	}
-	panic("token is not string ")
 }
 
-//Expression EXPRESSION -> FIELD | CONSTANT
+//Placeholder 处理?和$n两种占位符，prepare的时候返回参数槽，否则直接使用绑定的参数
+func (p *SQLParser) Placeholder() (*comm.Constant, error) {
+	lexeme := p.sqlLexer.Lexeme
+	style := lexeme[:1]
+	if p.style != "" && p.style != style {
+		return nil, fmt.Errorf("%w: cannot mix ? and $n placeholders", ErrSyntax)
+	}
+	p.style = style
+	idx := p.argIdx
+	if style == "?" {
+		p.argIdx++
+	} else {
+		n, err := strconv.Atoi(lexeme[1:])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: invalid placeholder %s", ErrSyntax, lexeme)
+		}
+		idx = n - 1
+		if n > p.argIdx {
+			p.argIdx = n
+		}
+	}
+	if p.prepare {
+		for len(p.params) <= idx {
+			p.params = append(p.params, &comm.Constant{})
+		}
+		return p.params[idx], nil
+	}
+	if idx >= len(p.args) {
+		return nil, ErrArgCount
+	}
+	return p.args[idx], nil
+}
+
+//arithOps 算术运算符的token对应的运算符
+var arithOps = map[lexer.Tag]string{
+	lexer.PLUS:     query.ARITH_ADD,
+	lexer.MINUS:    query.ARITH_SUB,
+	lexer.ASTERISK: query.ARITH_MUL,
+	lexer.SLASH:    query.ARITH_DIV,
+	lexer.PERCENT:  query.ARITH_MOD,
+}
+
+//Expression EXPRESSION -> PRODUCT ((PLUS | MINUS) PRODUCT)*，加减法的优先级比乘除法低
 func (p *SQLParser) Expression() (*query.Expression, error) {
+	return p.binaryExpression(p.product, lexer.PLUS, lexer.MINUS)
+}
+
+//product PRODUCT -> UNARY ((ASTERISK | SLASH | PERCENT) UNARY)*
+func (p *SQLParser) product() (*query.Expression, error) {
+	return p.binaryExpression(p.unary, lexer.ASTERISK, lexer.SLASH, lexer.PERCENT)
+}
+
+//binaryExpression 解析使用tags中的运算符连接起来的操作数，运算符是左结合的
+func (p *SQLParser) binaryExpression(operand func() (*query.Expression, error), tags ...lexer.Tag) (*query.Expression, error) {
+	lhs, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, err := p.sqlLexer.Scan()
+		matched := false
+		for _, tag := range tags {
+			if err == nil && tok.Tag == tag {
+				matched = true
+			}
+		}
+		if !matched {
+			p.sqlLexer.ReverseScan()
+			return lhs, nil
+		}
+		rhs, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		lhs = query.NewArithmeticExpression(arithOps[tok.Tag], lhs, rhs)
+	}
+}
+
+//unary UNARY -> MINUS UNARY | PRIMARY，负的数值常量直接合并成一个常量
+func (p *SQLParser) unary() (*query.Expression, error) {
+	if !p.matchTag(lexer.MINUS) {
+		return p.primary()
+	}
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if operand.IsConstant() && operand.AsConstant().IsNumber() && !p.isParam(operand.AsConstant()) {
+		v, _ := operand.AsConstant().Negate()
+		return query.NewExpressionWithConstant(v), nil
+	}
+	return query.NewNegateExpression(operand), nil
+}
+
+//isParam 判断常量是不是预处理语句中的参数槽，参数槽的值在执行的时候才绑定，不能在解析的时候计算
+func (p *SQLParser) isParam(c *comm.Constant) bool {
+	for _, param := range p.params {
+		if param == c {
+			return true
+		}
+	}
+	return false
+}
+
+//primary PRIMARY -> COLUMN | FUNCTION | CONSTANT | SUBQUERY | LEFT_BRACKET EXPRESSION RIGHT_BRACKET
+func (p *SQLParser) primary() (*query.Expression, error) {
 	tok, err := p.sqlLexer.Scan()
 	if err != nil {
 		return nil, err
 	}
-	//判断当前的类型，只可能是field和constant两种类型
-	if tok.Tag == lexer.ID {
-		p.sqlLexer.ReverseScan() //回退
-		_, str, err := p.Field() //调用当前的field接口，解析出来field
+	switch tok.Tag {
+	case lexer.LEFT_BRACKET:
+		if p.isSubquery() {
+			data, err := p.subquery()
+			if err != nil {
+				return nil, err
+			}
+			return query.NewSubqueryExpression(query.NewSubquery(data)), nil
+		}
+		expr, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkWordTag(lexer.RIGHT_BRACKET); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case lexer.ID:
+		//函数名和聚合函数名后面跟着左括号的时候才是函数调用
+		name := p.sqlLexer.Lexeme
+		if !query.IsAggregateFn(name) && p.matchTag(lexer.LEFT_BRACKET) {
+			fn := query.LookupFunction(name)
+			if fn == nil {
+				return nil, fmt.Errorf("%w: unknown function %s", ErrSyntax, name)
+			}
+			return p.functionCall(fn)
+		}
+		p.sqlLexer.ReverseScan()
+		str, err := p.Column() //字段或者聚合函数
 		if err != nil {
 			return nil, err
 		}
 		return query.NewExpressionWithFieldName(str), nil //使用字符串来初始化当前的表达式
-	} else {
+	default:
 		p.sqlLexer.ReverseScan()
 		constant, err := p.Constant()
 		if err != nil {
@@ -94,7 +395,50 @@ func (p *SQLParser) Expression() (*query.Expression, error) {
 	}
 }
 
-//TERM  -> EXPRESSION EQ EXPRESSION
+//functionCall 函数名和左括号已经读取了，读取参数和右括号
+func (p *SQLParser) functionCall(fn *query.Function) (*query.Expression, error) {
+	args, err := p.ExpressionList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkWordTag(lexer.RIGHT_BRACKET); err != nil {
+		return nil, err
+	}
+	if !fn.CheckArgCount(len(args)) {
+		return nil, fmt.Errorf("%w: wrong number of arguments for %s", ErrSyntax, fn.Name())
+	}
+	return query.NewFunctionExpression(fn, args), nil
+}
+
+//ExpressionList EXPRESSIONLIST -> EXPRESSION (COMMA EXPRESSION)*
+func (p *SQLParser) ExpressionList() ([]*query.Expression, error) {
+	exprs := make([]*query.Expression, 0)
+	for {
+		expr, err := p.Expression()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.matchTag(lexer.COMMA) {
+			return exprs, nil
+		}
+	}
+}
+
+//TERM  -> EXPRESSION OP EXPRESSION | EXPRESSION IS (NOT)? NULL | EXPRESSION
+//OP    -> = | == | <> | != | < | <= | > | >=
+//只有一个表达式的时候是BOOLEAN类型的条件，例如 WHERE instock，等价于 instock = TRUE，表达式的类型在创建计划的时候检查
+
+//termOps 比较运算符的token对应的操作符
+var termOps = map[lexer.Tag]string{
+	lexer.ASSIGN_OPERATOR:  query.OP_EQ,
+	lexer.EQ:               query.OP_EQ,
+	lexer.NE:               query.OP_NE,
+	lexer.LESS_OPERATOR:    query.OP_LT,
+	lexer.LE:               query.OP_LE,
+	lexer.GREATER_OPERATOR: query.OP_GT,
+	lexer.GE:               query.OP_GE,
+}
 
 func (p *SQLParser) Term() (*query.Term, error) {
 	//进行完左边的解析之后
@@ -102,85 +446,559 @@ func (p *SQLParser) Term() (*query.Term, error) {
 	if err != nil {
 		return nil, err
 	}
-	//就需要继续读取到一个=
+	if p.matchTag(lexer.NOT) {
+		if err := p.checkWordTag(lexer.IN); err != nil {
+			return nil, err
+		}
+		in, err := p.in(lhs)
+		if err != nil {
+			return nil, err
+		}
+		return query.NewNotTerm(query.NewPredicateWithTerm(in)), nil
+	}
+	if p.matchTag(lexer.IN) {
+		return p.in(lhs)
+	}
+	if p.matchTag(lexer.IS) {
+		not := p.matchTag(lexer.NOT)
+		if err := p.checkWordTag(lexer.NULL); err != nil {
+			return nil, err
+		}
+		return query.NewIsNullTerm(lhs, not), nil
+	}
+	//就需要继续读取到一个比较运算符
 	tok, err := p.sqlLexer.Scan()
+	op, ok := "", false
+	if err == nil {
+		op, ok = termOps[tok.Tag]
+	}
+	if !ok {
+		//后面没有比较运算符，条件到这里结束，读取到的token放回去
+		p.sqlLexer.ReverseScan()
+		yes := true
+		return query.NewTermWithOp(lhs, query.OP_EQ, query.NewExpressionWithConstant(comm.NewConstantBool(&yes))), nil
+	}
+	rhs, err := p.Expression()
 	if err != nil {
 		return nil, err
 	}
-	if tok.Tag != lexer.ASSIGN_OPERATOR {
-		return nil, errors.New("should have = in the middle of term")
+	return query.NewTermWithOp(lhs, op, rhs), nil
+}
+
+//in IN已经读取了，读取后面的子查询
+func (p *SQLParser) in(lhs *query.Expression) (*query.Term, error) {
+	if err := p.checkWordTag(lexer.LEFT_BRACKET); err != nil {
+		return nil, err
 	}
-	rhs, err := p.Expression()
+	if !p.isSubquery() {
+		return nil, fmt.Errorf("%w: IN requires a subquery", ErrSyntax)
+	}
+	data, err := p.subquery()
 	if err != nil {
 		return nil, err
 	}
-	return query.NewTerm(lhs, rhs), nil
+	return query.NewInTerm(lhs, query.NewSubquery(data)), nil
 }
 
-//predicate->term (and predicate),条件里面包含条件,递归的调用这个函数
+//isSubquery 左括号已经读取了，后面是SELECT的时候是子查询
+func (p *SQLParser) isSubquery() bool {
+	if p.matchTag(lexer.SELECT) {
+		p.sqlLexer.ReverseScan()
+		return true
+	}
+	return false
+}
 
-//Predicate 构造一个条件出来
-func (p *SQLParser) Predicate() (*query.Predicate, error) {
-	term, err := p.Term()
+//subquery 左括号已经读取了，读取括号中的查询和右括号，子查询中的聚合函数和外层的查询是分开的
+func (p *SQLParser) subquery() (*QueryData, error) {
+	aggregates, allowAggregate := p.aggregates, p.allowAggregate
+	data, err := p.query()
+	p.aggregates, p.allowAggregate = aggregates, allowAggregate
 	if err != nil {
 		return nil, err
 	}
-	pred := query.NewPredicateWithTerm(term)
-	//tok, err := p.sqlLexer.Scan() //往后一个op，判断是否是=，如果到最后了，就说明条件读取完了
-	//if err != nil && tok.Tag != lexer.EOF {
-	//	panic(err)
-	//}
-	ok, err := p.isMatchTag(lexer.AND)
+	if err := p.checkWordTag(lexer.RIGHT_BRACKET); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+/*
+	条件按照优先级从低到高为OR、AND、NOT，括号可以改变优先级：
+	PREDICATE -> CONJUNCT (OR CONJUNCT)*
+	CONJUNCT  -> FACTOR (AND FACTOR)*
+	FACTOR    -> NOT FACTOR | EXISTS SUBQUERY | ( PREDICATE ) | TERM
+	AND连接的条件直接合并到同一个Predicate中，这样planner可以把每一个term单独下推
+*/
+
+//Predicate 构造一个条件出来，条件不完整的时候返回ErrSyntax
+func (p *SQLParser) Predicate() (*query.Predicate, error) {
+	pred, err := p.disjunct()
+	if err != nil {
+		return nil, syntaxError(err)
+	}
+	return pred, nil
+}
+
+//disjunct 使用OR连接起来的一组条件
+func (p *SQLParser) disjunct() (*query.Predicate, error) {
+	pred, err := p.conjunct()
 	if err != nil {
 		return nil, err
 	}
-	//如果当前的是条件是=，就需要递归的调用这个函数，并且把这个predicate进行扩充
-	if ok {
-		qp, err := p.Predicate()
+	disjuncts := []*query.Predicate{pred}
+	for p.matchTag(lexer.OR) {
+		next, err := p.conjunct()
 		if err != nil {
-			if err == io.EOF {
-				return pred, nil
-			}
 			return nil, err
 		}
-		if qp != nil {
-			pred.ConjoinWith(qp)
+		disjuncts = append(disjuncts, next)
+	}
+	if len(disjuncts) == 1 {
+		return pred, nil
+	}
+	return query.NewPredicateWithTerm(query.NewOrTerm(disjuncts...)), nil
+}
+
+//conjunct 使用AND连接起来的一组条件
+func (p *SQLParser) conjunct() (*query.Predicate, error) {
+	pred, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.matchTag(lexer.AND) {
+		next, err := p.factor()
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		p.sqlLexer.ReverseScan() //如果不是=的话，就需要把操作符放回去,之后还能继续读取
+		pred.ConjoinWith(next)
 	}
 	return pred, nil
 }
 
-//Query ->select selectlist from tablelist (where predicate)解析出sql语句的各个信息
+//factor 一个比较，或者NOT以及括号中的条件
+func (p *SQLParser) factor() (*query.Predicate, error) {
+	if p.matchTag(lexer.NOT) {
+		inner, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return query.NewPredicateWithTerm(query.NewNotTerm(inner)), nil
+	}
+	if p.matchTag(lexer.EXISTS) {
+		if err := p.checkWordTag(lexer.LEFT_BRACKET); err != nil {
+			return nil, err
+		}
+		if !p.isSubquery() {
+			return nil, fmt.Errorf("%w: EXISTS requires a subquery", ErrSyntax)
+		}
+		data, err := p.subquery()
+		if err != nil {
+			return nil, err
+		}
+		return query.NewPredicateWithTerm(query.NewExistsTerm(query.NewSubquery(data))), nil
+	}
+	if p.matchTag(lexer.LEFT_BRACKET) && !p.isArithmeticBracket() {
+		inner, err := p.disjunct()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkWordTag(lexer.RIGHT_BRACKET); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+	term, err := p.Term()
+	if err != nil {
+		return nil, err
+	}
+	return query.NewPredicateWithTerm(term), nil
+}
+
+//isArithmeticBracket 左括号已经读取了，向后查看到对应的右括号为止，判断括号中是条件还是算术表达式
+//括号中出现了比较运算符或者AND，OR，NOT，IN，IS，EXISTS的时候是条件，例如 (a = 1 OR b = 2)，否则是算术表达式，例如 (a + 1) * 2 > 3
+//子查询中的比较不算在内，例如 (SELECT max(a) FROM t WHERE b = 1) + 1 > 3 中的括号是算术表达式
+//是算术表达式的时候把左括号也放回去，由Term重新解析
+func (p *SQLParser) isArithmeticBracket() bool {
+	depth, scanned, isPredicate := 1, 0, false
+	subquery := 0 //正在跳过的子查询所在的括号深度
+	for depth > 0 && !isPredicate {
+		tok, err := p.sqlLexer.Scan()
+		scanned++
+		if err != nil || tok.Tag == lexer.EOF {
+			break
+		}
+		_, isCompare := termOps[tok.Tag]
+		switch {
+		case tok.Tag == lexer.LEFT_BRACKET:
+			depth++
+		case tok.Tag == lexer.RIGHT_BRACKET:
+			if depth--; depth < subquery {
+				subquery = 0
+			}
+		case tok.Tag == lexer.SELECT && subquery == 0:
+			subquery = depth
+		case subquery > 0:
+		case isCompare || tok.Tag == lexer.AND || tok.Tag == lexer.OR || tok.Tag == lexer.NOT ||
+			tok.Tag == lexer.IN || tok.Tag == lexer.IS || tok.Tag == lexer.EXISTS:
+			isPredicate = true
+		}
+	}
+	for i := 0; i < scanned; i++ {
+		p.sqlLexer.ReverseScan()
+	}
+	if !isPredicate {
+		p.sqlLexer.ReverseScan()
+	}
+	return !isPredicate
+}
+
+/*
+	查询可以是多个SELECT之间的集合运算，INTERSECT的优先级比UNION和EXCEPT高，同一优先级的从左到右结合：
+	QUERY   -> SETTERM ((UNION | EXCEPT) (ALL)? SETTERM)* (ORDER BY SORTKEYLIST)? (LIMIT CONSTANT (OFFSET CONSTANT)?)?
+	SETTERM -> OPERAND (INTERSECT (ALL)? OPERAND)*
+	OPERAND -> SELECT | SUBQUERY
+	SELECT  -> select (distinct)? selectlist from tablelist (where predicate)? (group by idlist)? (having predicate)?
+	第一个操作数必须是SELECT，不能带括号
+	ORDER BY和LIMIT作用在整个集合运算的结果上，只有一个SELECT的时候ORDER BY中还可以使用聚合函数
+*/
+
+//Query 解析出sql语句的各个信息
 func (p *SQLParser) Query() (*QueryData, error) {
+	data, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	//查询语句的所有子句都已经解析完了，后面不能再有其他的内容
+	if err := p.checkEnd(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+//checkEnd 语句已经解析完了，后面不能再有其他的内容
+func (p *SQLParser) checkEnd() error {
+	if tok, _ := p.sqlLexer.Scan(); tok.Tag != lexer.EOF {
+		p.sqlLexer.ReverseScan()
+		return fmt.Errorf("%w: unexpected %q", ErrSyntax, p.sqlLexer.Lexeme)
+	}
+	p.sqlLexer.ReverseScan()
+	return nil
+}
+
+//query 解析一个查询，子查询后面还有右括号，所以不检查是否已经到了结尾
+func (p *SQLParser) query() (*QueryData, error) {
+	data, err := p.selectQuery()
+	if err != nil {
+		return nil, err
+	}
+	if data, err = p.intersect(data); err != nil {
+		return nil, err
+	}
+	for {
+		var op SetOp
+		if p.matchTag(lexer.UNION) {
+			op = UNION
+		} else if p.matchTag(lexer.EXCEPT) {
+			op = EXCEPT
+		} else {
+			break
+		}
+		all := p.matchTag(lexer.ALL)
+		rhs, err := p.operand()
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+		if rhs, err = p.intersect(rhs); err != nil {
+			return nil, err
+		}
+		data = NewSetQueryData(op, all, data, rhs)
+	}
+	//集合运算的ORDER BY只能使用结果中的字段，不能使用聚合函数
+	p.allowAggregate = data.SetOperation() == nil
+	defer func() { p.allowAggregate = false }()
+	if p.matchTag(lexer.ORDER) {
+		if err := p.checkWordTag(lexer.BY); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		keys, err := p.SortKeyList()
+		if err != nil {
+			return nil, err
+		}
+		data.SetOrderBy(keys)
+	}
+	if p.matchTag(lexer.LIMIT) {
+		limit, err := p.Constant()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		var offset *comm.Constant
+		if p.matchTag(lexer.OFFSET) {
+			if offset, err = p.Constant(); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+			}
+		}
+		data.SetLimit(limit, offset)
+	}
+	//ORDER BY中可能出现了新的聚合函数
+	if data.SetOperation() == nil {
+		data.SetAggregates(p.aggregates)
+	}
+	return data, nil
+}
+
+//intersect 已经读取了一个操作数data，INTERSECT的优先级更高，先和后面的操作数结合
+func (p *SQLParser) intersect(data *QueryData) (*QueryData, error) {
+	for p.matchTag(lexer.INTERSECT) {
+		all := p.matchTag(lexer.ALL)
+		rhs, err := p.operand()
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+		data = NewSetQueryData(INTERSECT, all, data, rhs)
+	}
+	return data, nil
+}
+
+//operand 集合运算右边的操作数，可以是括号中的查询，括号中的查询可以有自己的ORDER BY和LIMIT
+func (p *SQLParser) operand() (*QueryData, error) {
+	if p.matchTag(lexer.LEFT_BRACKET) {
+		return p.subquery()
+	}
+	return p.selectQuery()
+}
+
+//selectQuery 解析一个SELECT到HAVING为止的部分
+func (p *SQLParser) selectQuery() (*QueryData, error) {
+	p.aggregates = nil
 	//读取当前的关键字
 	if err := p.checkWordTag(lexer.SELECT); err != nil {
 		return nil, err
 	}
-	//把字段筛选出来
-	fields := p.IDList()
+	distinct := p.matchTag(lexer.DISTINCT)
+	//把字段筛选出来，选择的字段中可以使用聚合函数
+	p.allowAggregate = true
+	columns, fields, err := p.SelectList()
+	if err != nil {
+		return nil, syntaxError(err)
+	}
+	p.allowAggregate = false
 	if err := p.checkWordTag(lexer.FROM); err != nil {
 		return nil, err
 
 	}
-	tables := p.IDList()
+	from, err := p.FromList()
+	if err != nil {
+		return nil, syntaxError(err)
+	}
 	pred := query.NewPredicate()
 	//检查是否有WHERE关键字
-	ok, err := p.isMatchTag(lexer.WHERE)
+	if p.matchTag(lexer.WHERE) {
+		pred, err = p.Predicate() //当前有where的关键词，就需要获得对应的predicate对象
+		if err != nil {
+			return nil, err
+		}
+	}
+	data := NewQueryData(fields, nil, pred)
+	data.SetFrom(from)
+	data.SetColumns(columns)
+	data.SetDistinct(distinct)
+	if p.matchTag(lexer.GROUP) {
+		if err := p.checkWordTag(lexer.BY); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		groupBy := make([]string, 0)
+		for {
+			field, err := p.QualifiedField()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+			}
+			groupBy = append(groupBy, field)
+			if !p.matchTag(lexer.COMMA) {
+				break
+			}
+		}
+		data.SetGroupBy(groupBy)
+	}
+	//HAVING中可以使用聚合函数
+	if p.matchTag(lexer.HAVING) {
+		p.allowAggregate = true
+		having, err := p.Predicate()
+		p.allowAggregate = false
+		if err != nil {
+			return nil, err
+		}
+		data.SetHaving(having)
+	}
+	data.SetAggregates(p.aggregates)
+	return data, nil
+}
+
+//SortKeyList SORTKEYLIST -> COLUMN (ASC | DESC)? (COMMA SORTKEYLIST)?
+//FromList 解析FROM后面用逗号分隔的每一项，每一项是一张表以及和它连接的表
+func (p *SQLParser) FromList() ([]*TableRef, error) {
+	from := make([]*TableRef, 0)
+	for {
+		table, derived, alias, err := p.table()
+		if err != nil {
+			return nil, err
+		}
+		joins := make([]*JoinData, 0)
+		for {
+			joinType, ok, err := p.joinType()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			join, err := p.join(joinType)
+			if err != nil {
+				return nil, err
+			}
+			joins = append(joins, join)
+		}
+		ref := NewTableRef(table, alias, joins)
+		ref.SetQuery(derived)
+		from = append(from, ref)
+		if !p.matchTag(lexer.COMMA) {
+			return from, nil
+		}
+	}
+}
+
+//joinType 读取JOIN以及它前面的连接类型，INNER和OUTER可以省略，后面不是连接的时候返回false
+func (p *SQLParser) joinType() (JoinType, bool, error) {
+	joinType := INNER_JOIN
+	switch {
+	case p.matchTag(lexer.JOIN):
+		return INNER_JOIN, true, nil
+	case p.matchTag(lexer.INNER):
+	case p.matchTag(lexer.LEFT):
+		joinType = LEFT_JOIN
+		p.matchTag(lexer.OUTER)
+	case p.matchTag(lexer.RIGHT):
+		joinType = RIGHT_JOIN
+		p.matchTag(lexer.OUTER)
+	case p.matchTag(lexer.FULL):
+		joinType = FULL_JOIN
+		p.matchTag(lexer.OUTER)
+	default:
+		return INNER_JOIN, false, nil
+	}
+	if err := p.checkWordTag(lexer.JOIN); err != nil {
+		return INNER_JOIN, false, err
+	}
+	return joinType, true, nil
+}
+
+//join 解析JOIN后面的表以及ON或者USING连接条件
+func (p *SQLParser) join(joinType JoinType) (*JoinData, error) {
+	table, derived, alias, err := p.table()
 	if err != nil {
 		return nil, err
 	}
-	if ok {
-		pred, err = p.Predicate() //当前有where的关键词，就需要获得对应的predicate对象
-		if err != nil {
+	var join *JoinData
+	if p.matchTag(lexer.USING) {
+		if err := p.checkWordTag(lexer.LEFT_BRACKET); err != nil {
 			return nil, err
 		}
+		using := make([]string, 0)
+		for {
+			_, field, err := p.Field()
+			if err != nil {
+				return nil, err
+			}
+			using = append(using, field)
+			if !p.matchTag(lexer.COMMA) {
+				break
+			}
+		}
+		if err := p.checkWordTag(lexer.RIGHT_BRACKET); err != nil {
+			return nil, err
+		}
+		join = NewJoinData(joinType, table, alias, nil, using)
 	} else {
-		p.sqlLexer.ReverseScan() //把当前读取到的关键字放回去
+		if err := p.checkWordTag(lexer.ON); err != nil {
+			return nil, err
+		}
+		on, err := p.Predicate()
+		if err != nil {
+			return nil, err
+		}
+		join = NewJoinData(joinType, table, alias, on, nil)
 	}
-	return NewQueryData(fields, tables, pred), nil
+	join.SetQuery(derived)
+	return join, nil
+}
 
+//table TABLE -> ID ((AS)? ID)? | SUBQUERY (AS)? ID，FROM中的子查询必须有别名
+func (p *SQLParser) table() (string, *QueryData, string, error) {
+	if p.matchTag(lexer.LEFT_BRACKET) {
+		if !p.isSubquery() {
+			return "", nil, "", fmt.Errorf("%w: expected a subquery", ErrSyntax)
+		}
+		derived, err := p.subquery()
+		if err != nil {
+			return "", nil, "", err
+		}
+		alias, err := p.tableAlias()
+		if err != nil {
+			return "", nil, "", err
+		}
+		if alias == "" {
+			return "", nil, "", fmt.Errorf("%w: subquery in FROM must have an alias", ErrSyntax)
+		}
+		return "", derived, alias, nil
+	}
+	_, table, err := p.Field()
+	if err != nil {
+		return "", nil, "", err
+	}
+	alias, err := p.tableAlias()
+	return table, nil, alias, err
+}
+
+//tableAlias 表名后面的别名，AS可以省略，没有别名的时候返回空字符串
+func (p *SQLParser) tableAlias() (string, error) {
+	if p.matchTag(lexer.AS) {
+		_, alias, err := p.Field()
+		return alias, err
+	}
+	if p.matchTag(lexer.ID) {
+		return p.sqlLexer.Lexeme, nil
+	}
+	return "", nil
+}
+
+func (p *SQLParser) SortKeyList() ([]*query.SortKey, error) {
+	keys := make([]*query.SortKey, 0)
+	for {
+		field, err := p.Column()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		desc := false
+		if p.matchTag(lexer.DESC) {
+			desc = true
+		} else {
+			p.matchTag(lexer.ASC)
+		}
+		keys = append(keys, query.NewSortKey(field, desc))
+		if !p.matchTag(lexer.COMMA) {
+			return keys, nil
+		}
+	}
+}
+
+//matchTag 如果下一个token是wordTag就读取它并返回true，否则把读取到的token放回去
+func (p *SQLParser) matchTag(wordTag lexer.Tag) bool {
+	tok, err := p.sqlLexer.Scan()
+	if err == nil && tok.Tag == wordTag {
+		return true
+	}
+	p.sqlLexer.ReverseScan()
+	return false
 }
 
 //IDList 将ID全筛选出来
@@ -205,28 +1023,7 @@ func (p *SQLParser) IDList() []string {
 	return l
 }
 
-func (p *SQLParser) CostantList() []*comm.Constant {
-	l := make([]*comm.Constant, 0)
-	constant, _ := p.Constant() //获得一个ID
-	l = append(l, constant)     //把当前的字段添加到字段列表中
-	//如果为空的话，也可以返回了
-	tok, err := p.sqlLexer.Scan() //往后面读取
-	if err != nil && err != io.EOF {
-		panic(err)
-	}
-	if tok.Tag == lexer.EOF {
-		return l
-	}
-	if tok.Tag == lexer.COMMA {
-		constList := p.CostantList()
-		l = append(l, constList...)
-	} else {
-		p.sqlLexer.ReverseScan() //把读取到的字符回退，保证下次能够读取到
-	}
-	return l
-}
-
-//UpdateCmd 对于表的修改的语句主要有:INSERT | DELETE | MODIFY | CREATE,除了这几个之外的话，就是语法错误
+//UpdateCmd 对于表的修改的语句主要有:INSERT | DELETE | MODIFY | CREATE | DROP | ALTER,除了这几个之外的话，就是语法错误
 func (p *SQLParser) UpdateCmd() (interface{}, error) {
 	tok, err := p.sqlLexer.Scan()
 	if err != nil {
@@ -244,6 +1041,10 @@ func (p *SQLParser) UpdateCmd() (interface{}, error) {
 		//当前是create,进入到create的分支中
 		//p.sqlLexer.ReverseScan()
 		return p.Create()
+	} else if tok.Tag == lexer.DROP {
+		return p.Drop()
+	} else if tok.Tag == lexer.ALTER {
+		return p.Alter()
 	}
 	return nil, ErrSyntax
 }
@@ -268,8 +1069,124 @@ func (p *SQLParser) Create() (interface{}, error) {
 	return nil, ErrSyntax
 }
 
+//Drop DROP (TABLE | VIEW | INDEX) (IF EXISTS)? ID CASCADE?
+//没有对象依赖索引，所以DROP INDEX后面不能有CASCADE
+func (p *SQLParser) Drop() (interface{}, error) {
+	if err := p.checkWordTag(lexer.DROP); err != nil {
+		return nil, syntaxError(err)
+	}
+	tok, err := p.sqlLexer.Scan()
+	if err != nil {
+		return nil, ErrSyntax
+	}
+	var kind DropKind
+	switch tok.Tag {
+	case lexer.TABLE:
+		kind = DROP_TABLE
+	case lexer.VIEW:
+		kind = DROP_VIEW
+	case lexer.INDEX:
+		kind = DROP_INDEX
+	default:
+		return nil, fmt.Errorf("%w: DROP must be followed by TABLE, VIEW or INDEX", ErrSyntax)
+	}
+	ifExists := p.matchTag(lexer.IF)
+	if ifExists {
+		if err := p.checkWordTag(lexer.EXISTS); err != nil {
+			return nil, syntaxError(err)
+		}
+	}
+	if err := p.checkWordTag(lexer.ID); err != nil {
+		return nil, syntaxError(err)
+	}
+	name := p.sqlLexer.Lexeme
+	cascade := kind != DROP_INDEX && p.matchTag(lexer.CASCADE)
+	if err := p.checkEnd(); err != nil {
+		return nil, err
+	}
+	return NewDropData(kind, name, ifExists, cascade), nil
+}
+
+//Alter ALTER TABLE ID (ADD COLUMN? FIELDDEF (DEFAULT MINUS? CONSTANT)? | DROP COLUMN? ID
+//| RENAME COLUMN? ID TO ID | RENAME TO ID)
+func (p *SQLParser) Alter() (interface{}, error) {
+	if err := p.checkWordTag(lexer.ALTER); err != nil {
+		return nil, syntaxError(err)
+	}
+	if err := p.checkWordTag(lexer.TABLE); err != nil {
+		return nil, syntaxError(err)
+	}
+	if err := p.checkWordTag(lexer.ID); err != nil {
+		return nil, syntaxError(err)
+	}
+	tblName := p.sqlLexer.Lexeme
+	tok, err := p.sqlLexer.Scan()
+	if err != nil {
+		return nil, ErrSyntax
+	}
+	var data *AlterData
+	switch tok.Tag {
+	case lexer.ADD:
+		p.matchTag(lexer.COLUMN)
+		sch := p.FieldDef()
+		if sch == nil || len(sch.Fields()) == 0 {
+			return nil, fmt.Errorf("%w: ADD COLUMN needs a field name and type", ErrSyntax)
+		}
+		var defaultVal *comm.Constant
+		if p.matchTag(lexer.DEFAULT) {
+			//默认值可以是负数
+			negative := p.matchTag(lexer.MINUS)
+			defaultVal, err = p.Constant()
+			if err != nil {
+				return nil, syntaxError(err)
+			}
+			if negative {
+				if !defaultVal.IsNumber() {
+					return nil, fmt.Errorf("%w: only numbers can be negative", ErrSyntax)
+				}
+				defaultVal, _ = defaultVal.Negate()
+			}
+		}
+		data = NewAddColumnData(tblName, sch, defaultVal)
+	case lexer.DROP:
+		p.matchTag(lexer.COLUMN)
+		if err := p.checkWordTag(lexer.ID); err != nil {
+			return nil, syntaxError(err)
+		}
+		data = NewDropColumnData(tblName, p.sqlLexer.Lexeme)
+	case lexer.RENAME:
+		//RENAME TO是重命名表，否则是重命名字段
+		if p.matchTag(lexer.TO) {
+			if err := p.checkWordTag(lexer.ID); err != nil {
+				return nil, syntaxError(err)
+			}
+			data = NewRenameTableData(tblName, p.sqlLexer.Lexeme)
+			break
+		}
+		p.matchTag(lexer.COLUMN)
+		if err := p.checkWordTag(lexer.ID); err != nil {
+			return nil, syntaxError(err)
+		}
+		fldName := p.sqlLexer.Lexeme
+		if err := p.checkWordTag(lexer.TO); err != nil {
+			return nil, syntaxError(err)
+		}
+		if err := p.checkWordTag(lexer.ID); err != nil {
+			return nil, syntaxError(err)
+		}
+		data = NewRenameColumnData(tblName, fldName, p.sqlLexer.Lexeme)
+	default:
+		return nil, fmt.Errorf("%w: ALTER TABLE must be followed by ADD, DROP or RENAME", ErrSyntax)
+	}
+	if err := p.checkEnd(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 //Insert insert into ID (name,age) values (10,"str"),(20,“name”)
-//insert into ID left_bracket fieldlist right_bracket values left_bracket valuelist right_bracket
+//insert into ID left_bracket fieldlist right_bracket (values row (comma row)* | query)
+//row -> left_bracket valuelist right_bracket，写入的记录也可以来自一个查询，例如 insert into t (a, b) select x, y from s
 func (p *SQLParser) Insert() (interface{}, error) {
 	p.checkWordTag(lexer.INSERT)
 	p.checkWordTag(lexer.INTO)
@@ -279,12 +1196,39 @@ func (p *SQLParser) Insert() (interface{}, error) {
 	//得到他的field集合
 	fields := p.IDList()
 	p.checkWordTag(lexer.RIGHT_BRACKET)
-	p.checkWordTag(lexer.VALUES)
-	p.checkWordTag(lexer.LEFT_BRACKET)
-	values := p.CostantList()
-
-	p.checkWordTag(lexer.RIGHT_BRACKET)
-	return NewInsertData(tblName, fields, values), nil
+	if p.matchTag(lexer.SELECT) {
+		p.sqlLexer.ReverseScan()
+		data, err := p.Query()
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+		return NewInsertSelectData(tblName, fields, data), nil
+	}
+	if err := p.checkWordTag(lexer.VALUES); err != nil {
+		return nil, syntaxError(err)
+	}
+	rows := make([][]*query.Expression, 0)
+	for {
+		if err := p.checkWordTag(lexer.LEFT_BRACKET); err != nil {
+			return nil, syntaxError(err)
+		}
+		values, err := p.ExpressionList() //写入的值可以是表达式，例如 values (10 * 2, "str")
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+		if err := p.checkWordTag(lexer.RIGHT_BRACKET); err != nil {
+			return nil, syntaxError(err)
+		}
+		rows = append(rows, values)
+		if !p.matchTag(lexer.COMMA) {
+			break
+		}
+	}
+	//多行的值之间漏掉了逗号的时候不能只写入第一行
+	if err := p.checkEnd(); err != nil {
+		return nil, err
+	}
+	return NewInsertData(tblName, fields, rows), nil
 }
 
 //checkWordTag 检查tag是否是我们需要的tag,如果不是就panic
@@ -376,9 +1320,18 @@ func (p *SQLParser) fieldType(fieldName string) *rm.Schema {
 	if err != nil {
 		panic(err)
 	}
-	//字段目前只支持两种类型
+	//字段支持INT，BIGINT，BOOLEAN，DOUBLE，DECIMAL(p,s)和VARCHAR(n)
 	if tok.Tag == lexer.INT {
 		schema.AddIntField(fieldName)
+	} else if tok.Tag == lexer.BIGINT {
+		schema.AddField(fieldName, rm.BIGINT, 0)
+	} else if tok.Tag == lexer.BOOLEAN {
+		schema.AddField(fieldName, rm.BOOLEAN, 0)
+	} else if tok.Tag == lexer.DOUBLE {
+		schema.AddField(fieldName, rm.DOUBLE, 0)
+	} else if tok.Tag == lexer.DECIMAL {
+		precision, scale := p.decimalType()
+		schema.AddDecimalField(fieldName, precision, scale)
 	} else if tok.Tag == lexer.VARCHAR {
 		//如果是varchar的话，后面还有括号varchar(255)
 		tok, err = p.sqlLexer.Scan()
@@ -414,6 +1367,38 @@ func (p *SQLParser) fieldType(fieldName string) *rm.Schema {
 	return schema
 }
 
+//decimalType 读取DECIMAL后面的(p,s)，p是有效数字的位数，s是小数位数
+//省略的时候和MySQL一样，精度是10，小数位数是0
+func (p *SQLParser) decimalType() (int, int) {
+	precision, scale := 10, 0
+	if !p.matchTag(lexer.LEFT_BRACKET) {
+		return precision, scale
+	}
+	precision = p.typeArg()
+	if p.matchTag(lexer.COMMA) {
+		scale = p.typeArg()
+	}
+	if err := p.checkWordTag(lexer.RIGHT_BRACKET); err != nil {
+		panic(err)
+	}
+	if precision < 1 || precision > comm.MAX_DECIMAL_PRECISION || scale > precision {
+		panic(fmt.Sprintf("invalid DECIMAL(%d,%d), precision must be between 1 and %d", precision, scale, comm.MAX_DECIMAL_PRECISION))
+	}
+	return precision, scale
+}
+
+//typeArg 读取类型后面括号中的一个数字
+func (p *SQLParser) typeArg() int {
+	if err := p.checkWordTag(lexer.NUM); err != nil {
+		panic(err)
+	}
+	n, err := strconv.Atoi(p.sqlLexer.Lexeme)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
 //CreateView 创建一个视图,CREATE VIEW VIEW_NAME AS QUERY
 func (p *SQLParser) CreateView() (interface{}, error) {
 	if err := p.checkWordTag(lexer.ID); err != nil {
@@ -473,14 +1458,38 @@ func (p *SQLParser) Delete() (interface{}, error) {
 	return NewDeleteData(tableName, pred), nil
 }
 
+//Update update ID set field = expression (, field = expression)* (where predicate)?
 func (p *SQLParser) Update() (interface{}, error) {
 	p.checkWordTag(lexer.UPDATE)
 	p.checkWordTag(lexer.ID)
 	tableName := p.sqlLexer.Lexeme
 	p.checkWordTag(lexer.SET)
-	_, fldName, _ := p.Field()
-	p.checkWordTag(lexer.ASSIGN_OPERATOR)
-	newVal, _ := p.Expression()
+	//SET field = expression (COMMA field = expression)*，同一个字段只能赋值一次
+	fields := make([]string, 0)
+	newVals := make([]*query.Expression, 0)
+	assigned := make(map[string]bool)
+	for {
+		_, fldName, err := p.Field()
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+		if assigned[fldName] {
+			return nil, fmt.Errorf("%w: multiple assignments to same field %s", ErrSyntax, fldName)
+		}
+		assigned[fldName] = true
+		if err := p.checkWordTag(lexer.ASSIGN_OPERATOR); err != nil {
+			return nil, syntaxError(err)
+		}
+		newVal, err := p.Expression() //新的值可以使用这条记录中的字段，例如 set age = age + 1
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+		fields = append(fields, fldName)
+		newVals = append(newVals, newVal)
+		if !p.matchTag(lexer.COMMA) {
+			break
+		}
+	}
 
 	pred := query.NewPredicate()
 	//如果当前匹配是WHERE的话，就需要获得相应的SQL语句
@@ -494,5 +1503,84 @@ func (p *SQLParser) Update() (interface{}, error) {
 			return nil, err
 		}
 	}
-	return NewUpdateData(tableName, fldName, newVal, pred), nil
+	return NewUpdateData(tableName, fields, newVals, pred), nil
+}
+
+//TxControl 事务控制语句
+//BEGIN [TRANSACTION | WORK] [READ ONLY] | START TRANSACTION [READ ONLY]
+//COMMIT [TRANSACTION | WORK] | ROLLBACK [TRANSACTION | WORK] [TO [SAVEPOINT] ID] | SAVEPOINT ID
+func (p *SQLParser) TxControl() (Statement, error) {
+	tok, err := p.sqlLexer.Scan()
+	if err != nil {
+		return nil, ErrSyntax
+	}
+	var stmt Statement
+	switch tok.Tag {
+	case lexer.BEGIN, lexer.START:
+		if tok.Tag == lexer.START {
+			if err := p.checkWordTag(lexer.TRANSACTION); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+			}
+		} else {
+			p.skipTransactionWord()
+		}
+		readOnly, err := p.isMatchTag(lexer.READ)
+		if err != nil {
+			return nil, err
+		}
+		if readOnly {
+			if err := p.checkWordTag(lexer.ONLY); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+			}
+		}
+		stmt = NewBeginData(readOnly)
+	case lexer.COMMIT:
+		p.skipTransactionWord()
+		stmt = NewCommitData()
+	case lexer.ROLLBACK:
+		p.skipTransactionWord()
+		ok, err := p.isMatchTag(lexer.TO)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			stmt = NewRollbackData("")
+			break
+		}
+		//SAVEPOINT关键字可以省略
+		tok, err := p.sqlLexer.Scan()
+		if err != nil {
+			return nil, ErrSyntax
+		}
+		if tok.Tag != lexer.SAVEPOINT {
+			p.sqlLexer.ReverseScan()
+		}
+		if err := p.checkWordTag(lexer.ID); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		stmt = NewRollbackData(p.sqlLexer.Lexeme)
+	case lexer.SAVEPOINT:
+		if err := p.checkWordTag(lexer.ID); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSyntax, err)
+		}
+		stmt = NewSavepointData(p.sqlLexer.Lexeme)
+	default:
+		return nil, ErrSyntax
+	}
+	//事务控制语句后面不能再有其他的内容
+	if tok, _ := p.sqlLexer.Scan(); tok.Tag != lexer.EOF {
+		return nil, ErrSyntax
+	}
+	return stmt, nil
+}
+
+//skipTransactionWord 跳过可以省略的TRANSACTION或者WORK
+func (p *SQLParser) skipTransactionWord() {
+	tok, err := p.sqlLexer.Scan()
+	if err != nil {
+		return
+	}
+	if tok.Tag != lexer.TRANSACTION && tok.Tag != lexer.WORK {
+		p.sqlLexer.ReverseScan()
+	}
 }
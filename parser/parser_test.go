@@ -3,6 +3,8 @@ package parser
 import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"miniSQL/comm"
+	rm "miniSQL/record_manager"
 	"testing"
 )
 
@@ -61,6 +63,41 @@ func TestCreate(t *testing.T) {
 	assert.Equal(t, expectfield, fields)
 }
 
+func TestCreateTypes(t *testing.T) {
+	stmt, err := NewSQLParser("create table account (id bigint, active boolean, ok bool, rate double, ratio float, " +
+		"balance decimal(12,2), total numeric(5), amount decimal)").ParseStatement()
+	assert.Nil(t, err)
+	sch := stmt.(*CreateTableData).schema
+	types := map[string]string{
+		"id": "BIGINT", "active": "BOOLEAN", "ok": "BOOLEAN", "rate": "DOUBLE", "ratio": "DOUBLE",
+		"balance": "DECIMAL(12,2)", "total": "DECIMAL(5,0)", "amount": "DECIMAL(10,0)",
+	}
+	for field, expected := range types {
+		assert.Equal(t, expected, rm.TypeName(sch.Type(field), sch.Length(field)), field)
+	}
+
+	for _, sql := range []string{
+		"create table t (a decimal(19,2))",
+		"create table t (a decimal(0))",
+		"create table t (a decimal(4,5))",
+		"create table t (a decimal(4,)",
+		"create table t (a decimal(x))",
+	} {
+		_, err := NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+
+	//带小数点的数字是定点数，TRUE和FALSE是布尔常量
+	stmt, err = NewSQLParser("insert into account (id, active, balance) values (1, true, 12.50), (-2, FALSE, -0.05)").ParseStatement()
+	assert.Nil(t, err)
+	rows := stmt.(*InsertData).Rows()
+	assert.Equal(t, comm.NewDecimal(1250, 2), *rows[0][2].AsConstant().Dval)
+	assert.Equal(t, comm.NewDecimal(-5, 2), *rows[1][2].AsConstant().Dval)
+	assert.True(t, rows[0][1].AsConstant().AsBool())
+	assert.False(t, rows[1][1].AsConstant().AsBool())
+	assert.Equal(t, "-0.05", rows[1][2].ToString())
+}
+
 func TestInsert(t *testing.T) {
 	//sql := "INSERT INTO PERSON (NAME,ID) VALUES (1,\"20\")"
 	sql := "INSERT INTO Customers (CustomerName, ContactName, Address, City, PostalCode, Country) " +
@@ -70,6 +107,34 @@ func TestInsert(t *testing.T) {
 	it = it.(*InsertData)
 }
 
+func TestInsertRows(t *testing.T) {
+	stmt, err := NewSQLParser("insert into student (name, gradyear) values ('amy', 2020), ('bob', 2019 + 1)").ParseStatement()
+	assert.Nil(t, err)
+	data := stmt.(*InsertData)
+	assert.Equal(t, 2, len(data.Rows()))
+	assert.Equal(t, "amy", data.Vals()[0].AsConstant().AsString())
+	assert.Equal(t, "2019+1", data.Rows()[1][1].ToString())
+	assert.Nil(t, data.Query())
+
+	stmt, err = NewSQLParser("insert into student (name, gradyear) select name, year from alumni where year > 2000 union select dname, 0 from dept").ParseStatement()
+	assert.Nil(t, err)
+	data = stmt.(*InsertData)
+	assert.Equal(t, []string{"name", "gradyear"}, data.Fields())
+	assert.Equal(t, 0, len(data.Rows()))
+	assert.Nil(t, data.Vals())
+	assert.Equal(t, UNION, data.Query().SetOperation().Op())
+
+	for _, sql := range []string{
+		"insert into student (name) values ('amy'),",
+		"insert into student (name) values ('amy') ('bob')",
+		"insert into student (name) values ('amy'), 'bob'",
+		"insert into student (name) select from alumni",
+	} {
+		_, err = NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
 func TestView(t *testing.T) {
 	//TODO 解析字符串的时候，需要把字符串中间的下划线包括进去
 	sql := "CREATE VIEW employeeview AS SELECT employeeid, firstname, lastname, salary FROM employees WHERE salary = 50000"
@@ -110,3 +175,590 @@ func TestUpdate(t *testing.T) {
 	dd := it.(*UpdateData)
 	assert.NotNil(t, dd)
 }
+
+func TestUpdateFields(t *testing.T) {
+	it, err := NewSQLParser("UPDATE PERSON SET AGE = AGE + 1, NAME = 'x', PRED = AGE WHERE PRED = 10").UpdateCmd()
+	assert.Nil(t, err)
+	dd := it.(*UpdateData)
+	assert.Equal(t, []string{"AGE", "NAME", "PRED"}, dd.Fields())
+	assert.Equal(t, 3, len(dd.NewValues()))
+	assert.Equal(t, "AGE", dd.TargetField())
+
+	for _, sql := range []string{
+		"UPDATE PERSON SET AGE = 1, AGE = 2",
+		"UPDATE PERSON SET AGE = 1,",
+		"UPDATE PERSON SET AGE = 1 NAME = 'x'",
+	} {
+		_, err = NewSQLParser(sql).UpdateCmd()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
+func TestParseStatement(t *testing.T) {
+	stmt, err := NewSQLParser("select name from student where id = 1").ParseStatement()
+	assert.Nil(t, err)
+	assert.True(t, IsQuery(stmt))
+	assert.Equal(t, []string{"name"}, stmt.(*QueryData).Fields())
+
+	stmt, err = NewSQLParser("insert into student (name,id) values (\"tom\",1)").ParseStatement()
+	assert.Nil(t, err)
+	assert.False(t, IsQuery(stmt))
+	_, ok := stmt.(*InsertData)
+	assert.True(t, ok)
+
+	stmt, err = NewSQLParser("create table student (name varchar(16),id int)").ParseStatement()
+	assert.Nil(t, err)
+	_, ok = stmt.(*CreateTableData)
+	assert.True(t, ok)
+
+	_, err = NewSQLParser("selec name from student").ParseStatement()
+	assert.NotNil(t, err)
+}
+
+func TestParsePlaceholder(t *testing.T) {
+	name := "tom \"the cat\""
+	year := 2020
+	args := []*comm.Constant{comm.NewConstantString(&name), comm.NewConstantInt(&year)}
+	stmt, err := NewSQLParserWithArgs("insert into student (name,gradyear) values (?,?)", args).ParseStatement()
+	assert.Nil(t, err)
+	vals := stmt.(*InsertData).Vals()
+	assert.Equal(t, name, vals[0].AsConstant().AsString())
+	assert.Equal(t, year, vals[1].AsConstant().AsInt())
+
+	stmt, err = NewSQLParserWithArgs("select name from student where gradyear = ?", args[1:]).ParseStatement()
+	assert.Nil(t, err)
+	assert.Equal(t, "gradyear=2020", stmt.(*QueryData).Pred().ToString())
+
+	_, err = NewSQLParserWithArgs("insert into student (name,gradyear) values (?,?)", args[:1]).ParseStatement()
+	assert.ErrorIs(t, err, ErrArgCount)
+	_, err = NewSQLParserWithArgs("select name from student where gradyear = ?", args).ParseStatement()
+	assert.ErrorIs(t, err, ErrArgCount)
+}
+
+func TestPrepare(t *testing.T) {
+	stmt, params, err := Prepare("insert into student (name,gradyear) values ($2,$1)")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(params))
+	vals := stmt.(*InsertData).Vals()
+	assert.Same(t, params[1], vals[0].AsConstant())
+	assert.Same(t, params[0], vals[1].AsConstant())
+
+	//参数槽绑定上值之后，语法树中看到的就是新的值
+	year := 2021
+	params[0].Ival = &year
+	assert.Equal(t, 2021, vals[1].AsConstant().AsInt())
+
+	stmt, params, err = Prepare("select name from student where gradyear = ? and name = ?")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(params))
+	terms := stmt.(*QueryData).Pred().Terms()
+	assert.Same(t, params[0], terms[0].Rhs().AsConstant())
+	assert.Same(t, params[1], terms[1].Rhs().AsConstant())
+
+	_, params, err = Prepare("delete from student where gradyear = $1 and majorid = $1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(params))
+
+	_, _, err = Prepare("select name from student where gradyear = ? and name = $1")
+	assert.ErrorIs(t, err, ErrSyntax)
+
+	name := "amy"
+	args := []*comm.Constant{comm.NewConstantString(&name), comm.NewConstantInt(&year)}
+	stmt, err = NewSQLParserWithArgs("insert into student (name,gradyear) values ($1,$2)", args).ParseStatement()
+	assert.Nil(t, err)
+	assert.Equal(t, "amy", stmt.(*InsertData).Vals()[0].AsConstant().AsString())
+	_, err = NewSQLParserWithArgs("select name from student where gradyear = $1", args).ParseStatement()
+	assert.ErrorIs(t, err, ErrArgCount)
+}
+
+func TestParseOrderBy(t *testing.T) {
+	data, err := NewSQLParser("select name from student where gradyear = 2020 order by majorid desc, name asc, id").Query()
+	assert.Nil(t, err)
+	keys := data.OrderBy()
+	assert.Equal(t, 3, len(keys))
+	assert.Equal(t, "majorid", keys[0].Field())
+	assert.True(t, keys[0].Desc())
+	assert.False(t, keys[1].Desc())
+	assert.Equal(t, "SELECT name FROM student WHERE gradyear=2020 ORDER BY majorid DESC, name, id", data.ToString())
+
+	data, err = NewSQLParser("select name from student order by name").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(data.OrderBy()))
+	assert.Equal(t, "", data.Pred().ToString())
+
+	_, err = NewSQLParser("select name from student order name").ParseStatement()
+	assert.ErrorIs(t, err, ErrSyntax)
+	_, err = NewSQLParser("select name from student where id = 1 name").ParseStatement()
+	assert.ErrorIs(t, err, ErrSyntax)
+}
+
+func TestParseGroupBy(t *testing.T) {
+	data, err := NewSQLParser("select deptId, COUNT(*), max(grade) from student where gradyear = 2020 group by deptId having count(*) = 2 order by max(grade) desc").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"deptId", "count(*)", "max(grade)"}, data.Fields())
+	assert.Equal(t, []string{"deptId"}, data.GroupBy())
+	assert.Equal(t, 2, len(data.Aggregates()))
+	assert.Equal(t, "count(*)=2", data.Having().ToString())
+	assert.Equal(t, "max(grade)", data.OrderBy()[0].Field())
+	assert.True(t, data.IsAggregate())
+	sql := "SELECT deptId, count(*), max(grade) FROM student WHERE gradyear=2020 GROUP BY deptId HAVING count(*)=2 ORDER BY max(grade) DESC"
+	assert.Equal(t, sql, data.ToString())
+	//视图的定义需要能够重新解析
+	again, err := NewSQLParser(sql).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, sql, again.ToString())
+
+	//没有左括号的时候count仍然是一个普通的字段
+	data, err = NewSQLParser("select count from student").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"count"}, data.Fields())
+	assert.False(t, data.IsAggregate())
+
+	_, err = NewSQLParser("select name from student where count(*) = 1").ParseStatement()
+	assert.ErrorIs(t, err, ErrSyntax)
+	_, err = NewSQLParser("select sum(*) from student").ParseStatement()
+	assert.ErrorIs(t, err, ErrSyntax)
+	_, err = NewSQLParser("select name from student group name").ParseStatement()
+	assert.ErrorIs(t, err, ErrSyntax)
+
+	data, err = NewSQLParser("select distinct majorid, gradyear from student order by gradyear").Query()
+	assert.Nil(t, err)
+	assert.True(t, data.Distinct())
+	assert.Equal(t, []string{"majorid", "gradyear"}, data.Fields())
+	assert.Equal(t, "SELECT DISTINCT majorid, gradyear FROM student ORDER BY gradyear", data.ToString())
+}
+
+func TestParsePredicate(t *testing.T) {
+	//NOT的优先级最高，其次是AND，最后是OR
+	pred, err := NewSQLParser("a = 1 OR b > 2 AND NOT c <= 3").Predicate()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pred.Terms()))
+	assert.Equal(t, "(a=1 OR b>2 AND NOT (c<=3))", pred.ToString())
+
+	//AND连接的条件合并到同一个Predicate中，括号中的OR作为一个整体
+	pred, err = NewSQLParser("a >= 1 AND (b <> 2 OR c != 3) AND d < e").Predicate()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(pred.Terms()))
+	assert.Equal(t, "a>=1 AND (b<>2 OR c<>3) AND d<e", pred.ToString())
+
+	//ToString的结果可以被重新解析，视图的定义依赖这一点
+	data, err := NewSQLParser("select name from student where not (gradyear < 2020 or majorid == 10) and sid > 3").Query()
+	assert.Nil(t, err)
+	reparsed, err := NewSQLParser(data.ToString()).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, data.ToString(), reparsed.ToString())
+
+	//IS NULL和NULL常量
+	pred, err = NewSQLParser("a IS NULL OR (b + 1 IS NOT NULL) AND c = NULL").Predicate()
+	assert.Nil(t, err)
+	assert.Equal(t, "(a IS NULL OR b+1 IS NOT NULL AND c=NULL)", pred.ToString())
+	data, err = NewSQLParser("select name from student where not (gradyear is null) and majorid is not null").Query()
+	assert.Nil(t, err)
+	reparsed, err = NewSQLParser(data.ToString()).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, data.ToString(), reparsed.ToString())
+
+	for _, sql := range []string{
+		"select name from student where (a = 1",
+		"select name from student where a = 1 or",
+		"select name from student where a is 1",
+		"select name from student where a is not",
+		"select name from student where not",
+		"select name from student where a ! 1",
+	} {
+		_, err = NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	//*，/，%的优先级高于+和-，同级的运算符从左到右结合
+	cases := map[string]string{
+		"a + b * 2":         "a+(b*2)",
+		"a - b - c":         "(a-b)-c",
+		"(a + 1) * -b % 3":  "((a+1)*(-b))%3",
+		"-5 + a":            "-5+a",
+		"upper(name)":       "upper(name)",
+		"CONCAT(a, 'x', 1)": "concat(a, \"x\", 1)",
+		"price * 1.05":      "price*1.05",
+		"-2.50 + a":         "-2.50+a",
+	}
+	for sql, expected := range cases {
+		e, err := NewSQLParser(sql).Expression()
+		assert.Nil(t, err, sql)
+		assert.Equal(t, expected, e.ToString(), sql)
+	}
+
+	//选择列表中可以使用表达式和别名，ToString的结果可以被重新解析
+	data, err := NewSQLParser("select name, age + 1 as next, length(name) from t where (age + 1) * 2 > 30 and (a = 1 or b = 2)").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "next", "length(name)"}, data.Fields())
+	assert.Equal(t, "age+1", data.Columns()[1].ToString())
+	assert.Equal(t, 2, len(data.Pred().Terms()))
+	reparsed, err := NewSQLParser(data.ToString()).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, data.ToString(), reparsed.ToString())
+
+	stmt, err := NewSQLParser("insert into t (name, age) values (concat('a', 'b'), 2 * 3)").ParseStatement()
+	assert.Nil(t, err)
+	assert.Equal(t, "concat(\"a\", \"b\")", stmt.(*InsertData).Vals()[0].ToString())
+	assert.Equal(t, "2*3", stmt.(*InsertData).Vals()[1].ToString())
+
+	stmt, err = NewSQLParser("update t set age = age + 1 where name = 'tom'").ParseStatement()
+	assert.Nil(t, err)
+	assert.Equal(t, "age+1", stmt.(*UpdateData).NewValue().ToString())
+
+	for _, sql := range []string{
+		"select nothing(a) from t",
+		"select upper(a, b) from t",
+		"select a + from t",
+		"select (a + 1 from t",
+		"select a as from t",
+	} {
+		_, err = NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
+func TestParseJoin(t *testing.T) {
+	data, err := NewSQLParser("select name, amount from customer left outer join orders on cid = ocid and amount > 10 " +
+		"join item using (oid, sku), region right join city using (rid) where amount > 100").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"customer", "orders", "item", "region", "city"}, data.Tables())
+	from := data.From()
+	assert.Equal(t, 2, len(from))
+	assert.Equal(t, "customer", from[0].TableName())
+	joins := from[0].Joins()
+	assert.Equal(t, 2, len(joins))
+	assert.Equal(t, LEFT_JOIN, joins[0].JoinType())
+	assert.Equal(t, "orders", joins[0].TableName())
+	assert.Equal(t, "cid=ocid AND amount>10", joins[0].On().ToString())
+	assert.Equal(t, INNER_JOIN, joins[1].JoinType())
+	assert.Equal(t, []string{"oid", "sku"}, joins[1].Using())
+	assert.Equal(t, RIGHT_JOIN, from[1].Joins()[0].JoinType())
+	assert.Equal(t, "amount>100", data.Pred().ToString())
+
+	//ToString的结果可以被重新解析，视图的定义依赖这一点
+	assert.Equal(t, "SELECT name, amount FROM customer LEFT JOIN orders ON cid=ocid AND amount>10 JOIN item USING (oid, sku), "+
+		"region RIGHT JOIN city USING (rid) WHERE amount>100", data.ToString())
+	reparsed, err := NewSQLParser(data.ToString()).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, data.ToString(), reparsed.ToString())
+
+	data, err = NewSQLParser("select a from x full join y on (a = b or a = c) inner join z on c = d").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, FULL_JOIN, data.From()[0].Joins()[0].JoinType())
+	assert.Equal(t, INNER_JOIN, data.From()[0].Joins()[1].JoinType())
+	assert.Equal(t, "SELECT a FROM x FULL JOIN y ON (a=b OR a=c) JOIN z ON c=d", data.ToString())
+
+	for _, sql := range []string{
+		"select a from x join y",
+		"select a from x left y on a = b",
+		"select a from x join y using ()",
+		"select a from x join y using (a",
+		"select a from x join on a = b",
+		"select a from x full outer join y on",
+	} {
+		_, err = NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
+func TestParseQualifiedName(t *testing.T) {
+	data, err := NewSQLParser("select s.name, t.name, s.age as years, max(t.age) from student s join teacher as t on s.tid = t.id " +
+		"where s.age > 18 group by s.name, t.name order by s.name").Query()
+	assert.Nil(t, err)
+	//两个name冲突，保留表名
+	assert.Equal(t, []string{"s.name", "t.name", "years", "max(t.age)"}, data.Fields())
+	assert.Equal(t, "s", data.From()[0].RangeName())
+	assert.Equal(t, "student", data.From()[0].TableName())
+	assert.Equal(t, "t", data.From()[0].Joins()[0].Alias())
+	assert.Equal(t, "s.tid=t.id", data.From()[0].Joins()[0].On().ToString())
+	assert.Equal(t, []string{"s.name", "t.name"}, data.GroupBy())
+	assert.Equal(t, "s.name", data.OrderBy()[0].Field())
+	assert.Equal(t, "t.age", data.Aggregates()[0].Field())
+	assert.Equal(t, "SELECT s.name, t.name, s.age AS years, max(t.age) FROM student AS s JOIN teacher AS t ON s.tid=t.id "+
+		"WHERE s.age>18 GROUP BY s.name, t.name ORDER BY s.name", data.ToString())
+	reparsed, err := NewSQLParser(data.ToString()).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, data.ToString(), reparsed.ToString())
+
+	//没有冲突的时候使用字段名作为结果中的名字
+	data, err = NewSQLParser("select s.name, d.title from student s, dept d").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "title"}, data.Fields())
+	assert.Equal(t, "s.name", data.Columns()[0].AsFieldName())
+
+	data, err = NewSQLParser("select *, s.* from student s").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"*", "s.*"}, data.Fields())
+	assert.Equal(t, "SELECT *, s.* FROM student AS s", data.ToString())
+
+	for _, sql := range []string{
+		"select s. from student s",
+		"select s.name from student as",
+		"select name from student s t",
+	} {
+		_, err = NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+	//*不能使用别名
+	_, err = NewSQLParser("select s.* as x from student s").ParseStatement()
+	assert.NotNil(t, err)
+}
+
+func TestParseSubquery(t *testing.T) {
+	data, err := NewSQLParser("select name from student where majorid in (select did from dept where dname = 'math') " +
+		"and not exists (select eid from enroll where studentid = sid) and age > (select avg(age) from student)").Query()
+	assert.Nil(t, err)
+	//子查询中的聚合函数不属于外层的查询
+	assert.Equal(t, 0, len(data.Aggregates()))
+	subs := data.Pred().Subqueries()
+	assert.Equal(t, 3, len(subs))
+	assert.Equal(t, "SELECT did FROM dept WHERE dname=\"math\"", subs[0].Data().ToString())
+	assert.Equal(t, 1, len(subs[2].Data().(*QueryData).Aggregates()))
+	assert.Equal(t, "SELECT name FROM student WHERE majorid IN (SELECT did FROM dept WHERE dname=\"math\") AND "+
+		"NOT (EXISTS (SELECT eid FROM enroll WHERE studentid=sid)) AND age>(SELECT avg(age) FROM student)", data.ToString())
+	reparsed, err := NewSQLParser(data.ToString()).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, data.ToString(), reparsed.ToString())
+
+	data, err = NewSQLParser("select name from student where majorid not in (select did from dept)").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, "NOT (majorid IN (SELECT did FROM dept))", data.Pred().ToString())
+
+	//括号中的子查询是算术表达式的一部分
+	data, err = NewSQLParser("select name, (select max(age) from student) - age as diff from student " +
+		"where ((select min(age) from student where age > 1) + 1) * 2 < age").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "diff"}, data.Fields())
+	assert.Equal(t, 1, len(data.Columns()[1].Subqueries()))
+	assert.Equal(t, 1, len(data.Pred().Subqueries()))
+
+	data, err = NewSQLParser("select t.name from (select name, age from student where age > 18) as t join " +
+		"(select did from dept) d on t.age = d.did").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(data.Tables()))
+	assert.Equal(t, "t", data.From()[0].RangeName())
+	assert.Equal(t, "SELECT name, age FROM student WHERE age>18", data.From()[0].Query().ToString())
+	assert.Equal(t, "d", data.From()[0].Joins()[0].RangeName())
+	assert.Equal(t, "SELECT t.name AS name FROM (SELECT name, age FROM student WHERE age>18) AS t JOIN "+
+		"(SELECT did FROM dept) AS d ON t.age=d.did", data.ToString())
+	reparsed, err = NewSQLParser(data.ToString()).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, data.ToString(), reparsed.ToString())
+
+	for _, sql := range []string{
+		"select a from (select b from c)",
+		"select a from x where a in (1)",
+		"select a from x where exists (a = 1)",
+		"select a from x where a in (select b from c",
+		"select a from x where not in (select b from c)",
+	} {
+		_, err = NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
+func TestParseSetOperation(t *testing.T) {
+	data, err := NewSQLParser("select sid, name from student where age > 18 union all select eid, name from teacher " +
+		"except select sid, name from alumni order by name desc limit 5").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sid", "name"}, data.Fields())
+	set := data.SetOperation()
+	assert.Equal(t, EXCEPT, set.Op())
+	assert.False(t, set.All())
+	//UNION和EXCEPT从左到右结合
+	assert.Equal(t, UNION, set.Lhs().SetOperation().Op())
+	assert.True(t, set.Lhs().SetOperation().All())
+	assert.Equal(t, 1, len(data.OrderBy()))
+	assert.Equal(t, 5, data.Limit().AsInt())
+	assert.Equal(t, "SELECT sid, name FROM student WHERE age>18 UNION ALL SELECT eid, name FROM teacher "+
+		"EXCEPT SELECT sid, name FROM alumni ORDER BY name DESC LIMIT 5", data.ToString())
+
+	//INTERSECT的优先级比UNION高
+	data, err = NewSQLParser("select a from x union select b from y intersect all select c from z").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, UNION, data.SetOperation().Op())
+	assert.Equal(t, INTERSECT, data.SetOperation().Rhs().SetOperation().Op())
+	assert.Equal(t, "SELECT a FROM x UNION (SELECT b FROM y INTERSECT ALL SELECT c FROM z)", data.ToString())
+	reparsed, err := NewSQLParser(data.ToString()).Query()
+	assert.Nil(t, err)
+	assert.Equal(t, data.ToString(), reparsed.ToString())
+
+	//每一个操作数的聚合函数是分开的，括号中的操作数可以有自己的ORDER BY和LIMIT
+	data, err = NewSQLParser("select max(a) from x intersect (select count(b) from y order by count(b) limit 1)").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(data.Aggregates()))
+	assert.Equal(t, 1, len(data.SetOperation().Lhs().Aggregates()))
+	assert.Equal(t, 1, len(data.SetOperation().Rhs().Aggregates()))
+	assert.Equal(t, 1, data.SetOperation().Rhs().Limit().AsInt())
+
+	//子查询中的集合运算
+	data, err = NewSQLParser("select name from student where sid in (select sid from enroll union select sid from alumni)").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, UNION, data.Pred().Subqueries()[0].Data().(*QueryData).SetOperation().Op())
+
+	for _, sql := range []string{
+		"select a from x union",
+		"select a from x union all",
+		"select a from x intersect y",
+		"select a from x union select b from y order by max(b)",
+		"(select a from x) union select b from y",
+	} {
+		_, err = NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
+func TestParseDrop(t *testing.T) {
+	for sql, want := range map[string]*DropData{
+		"drop table student":                   NewDropData(DROP_TABLE, "student", false, false),
+		"DROP TABLE IF EXISTS student CASCADE": NewDropData(DROP_TABLE, "student", true, true),
+		"drop view if exists adults":           NewDropData(DROP_VIEW, "adults", true, false),
+		"drop view adults cascade":             NewDropData(DROP_VIEW, "adults", false, true),
+		"drop index ageidx":                    NewDropData(DROP_INDEX, "ageidx", false, false),
+	} {
+		stmt, err := NewSQLParser(sql).ParseStatement()
+		if assert.Nil(t, err, sql) {
+			assert.Equal(t, want, stmt.(*DropData), sql)
+		}
+	}
+	assert.Equal(t, "DROP TABLE IF EXISTS student CASCADE", NewDropData(DROP_TABLE, "student", true, true).ToString())
+
+	//查询中引用的表和视图，包括子查询中的
+	data, err := NewSQLParser("select a from x join (select b from y) as t on a = b where a in (select c from z) " +
+		"union select d from w").Query()
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"x", "y", "z", "w"}, data.ReferencedTables())
+
+	for _, sql := range []string{
+		"drop student",
+		"drop table",
+		"drop table if student",
+		"drop index ageidx cascade",
+		"drop table a, b",
+	} {
+		_, err = NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
+func TestParseAlter(t *testing.T) {
+	sch := rm.NewSchema()
+	sch.AddStringField("email", 20)
+	dflt := "none"
+	age := rm.NewSchema()
+	age.AddIntField("age")
+	minus := -1
+	price := rm.NewSchema()
+	price.AddDecimalField("price", 8, 2)
+	half := comm.NewDecimal(-5, 1)
+	for sql, want := range map[string]*AlterData{
+		"alter table student add email varchar(20)":                       NewAddColumnData("student", sch, nil),
+		"alter table student add column email varchar(20) default 'none'": NewAddColumnData("student", sch, comm.NewConstantString(&dflt)),
+		"alter table student add age int default -1":                      NewAddColumnData("student", age, comm.NewConstantInt(&minus)),
+		"alter table student add price decimal(8,2) default -0.5":         NewAddColumnData("student", price, comm.NewConstantDecimal(&half)),
+		"alter table student drop column age":                             NewDropColumnData("student", "age"),
+		"ALTER TABLE student DROP age":                                    NewDropColumnData("student", "age"),
+		"alter table student rename column age to years":                  NewRenameColumnData("student", "age", "years"),
+		"alter table student rename age to years":                         NewRenameColumnData("student", "age", "years"),
+		"alter table student rename to pupil":                             NewRenameTableData("student", "pupil"),
+	} {
+		stmt, err := NewSQLParser(sql).ParseStatement()
+		if assert.Nil(t, err, sql) {
+			assert.Equal(t, want, stmt.(*AlterData), sql)
+		}
+	}
+	assert.Equal(t, "ALTER TABLE student ADD COLUMN email VARCHAR(20) DEFAULT none",
+		NewAddColumnData("student", sch, comm.NewConstantString(&dflt)).ToString())
+	assert.Equal(t, "ALTER TABLE student RENAME COLUMN age TO years", NewRenameColumnData("student", "age", "years").ToString())
+	assert.Equal(t, "ALTER TABLE student ADD COLUMN price DECIMAL(8,2) DEFAULT -0.5",
+		NewAddColumnData("student", price, comm.NewConstantDecimal(&half)).ToString())
+
+	for _, sql := range []string{
+		"alter student add age int",
+		"alter table student",
+		"alter table student add",
+		"alter table student add age int default",
+		"alter table student drop",
+		"alter table student rename age",
+		"alter table student rename to",
+		"alter table student modify age int",
+		"alter table student drop age, name",
+	} {
+		_, err := NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	data, err := NewSQLParser("select name from student order by name limit 10 offset 20").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, 10, data.Limit().AsInt())
+	assert.Equal(t, 20, data.Offset().AsInt())
+	assert.Equal(t, "SELECT name FROM student ORDER BY name LIMIT 10 OFFSET 20", data.ToString())
+
+	data, err = NewSQLParser("select name from student limit 5").Query()
+	assert.Nil(t, err)
+	assert.Equal(t, 5, data.Limit().AsInt())
+	assert.Nil(t, data.Offset())
+
+	stmt, params, err := Prepare("select name from student where gradyear = ? limit ? offset ?")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(params))
+	assert.Same(t, params[1], stmt.(*QueryData).Limit())
+	assert.Same(t, params[2], stmt.(*QueryData).Offset())
+
+	_, err = NewSQLParser("select name from student limit").ParseStatement()
+	assert.ErrorIs(t, err, ErrSyntax)
+	_, err = NewSQLParser("select name from student limit 5 offset").ParseStatement()
+	assert.ErrorIs(t, err, ErrSyntax)
+}
+
+func TestSplitStatements(t *testing.T) {
+	stmts, rest := SplitStatements("insert into t (a) values (\"x;y\");; delete from t;\n select a from 't;'")
+	assert.Equal(t, []string{"insert into t (a) values (\"x;y\")", "delete from t"}, stmts)
+	assert.Equal(t, "\n select a from 't;'", rest)
+}
+
+func TestTxControl(t *testing.T) {
+	stmt, err := NewSQLParser("begin").ParseStatement()
+	assert.Nil(t, err)
+	assert.False(t, stmt.(*BeginData).ReadOnly())
+	stmt, err = NewSQLParser("BEGIN READ ONLY;").ParseStatement()
+	assert.Nil(t, err)
+	assert.True(t, stmt.(*BeginData).ReadOnly())
+	assert.True(t, IsTxControl(stmt))
+
+	stmt, err = NewSQLParser("start transaction read only").ParseStatement()
+	assert.Nil(t, err)
+	assert.True(t, stmt.(*BeginData).ReadOnly())
+	stmt, err = NewSQLParser("begin work").ParseStatement()
+	assert.Nil(t, err)
+	assert.False(t, stmt.(*BeginData).ReadOnly())
+	stmt, err = NewSQLParser("rollback transaction to sp").ParseStatement()
+	assert.Nil(t, err)
+	assert.Equal(t, "sp", stmt.(*RollbackData).Savepoint())
+
+	stmt, err = NewSQLParser("commit").ParseStatement()
+	assert.Nil(t, err)
+	assert.IsType(t, &CommitData{}, stmt)
+	stmt, err = NewSQLParser("rollback").ParseStatement()
+	assert.Nil(t, err)
+	assert.Equal(t, "", stmt.(*RollbackData).Savepoint())
+	stmt, err = NewSQLParser("savepoint sp").ParseStatement()
+	assert.Nil(t, err)
+	assert.Equal(t, "sp", stmt.(*SavepointData).Name())
+	stmt, err = NewSQLParser("rollback to sp").ParseStatement()
+	assert.Nil(t, err)
+	assert.Equal(t, "sp", stmt.(*RollbackData).Savepoint())
+	stmt, err = NewSQLParser("rollback to savepoint sp").ParseStatement()
+	assert.Nil(t, err)
+	assert.Equal(t, "sp", stmt.(*RollbackData).Savepoint())
+
+	for _, sql := range []string{"begin read", "commit now", "start", "commit work work", "savepoint", "rollback to", "rollback sp"} {
+		_, err = NewSQLParser(sql).ParseStatement()
+		assert.ErrorIs(t, err, ErrSyntax, sql)
+	}
+}
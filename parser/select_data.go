@@ -1,6 +1,10 @@
 package parser
 
-import "miniSQL/query"
+import (
+	"miniSQL/comm"
+	"miniSQL/query"
+	"strings"
+)
 
 /*
 	SQL解析完之后，会创建一个QueryData对象，我们接下来就是需要根据这个对象构建出合适的查询规划器Planner
@@ -12,9 +16,19 @@ import "miniSQL/query"
 
 //QueryData 保存query查询解析出来的结果,在预处理器在中会对这里面的字段和表进行检查是否存在
 type QueryData struct {
-	fields []string
-	tables []string
-	pred   *query.Predicate //这个是条件
+	fields     []string            //结果中每一列的名字，有别名的时候是别名
+	columns    []*query.Expression //每一列对应的表达式，和fields一一对应
+	tables     []string            //FROM中用到的所有表
+	from       []*TableRef         //FROM中用逗号分隔的每一项以及它们的连接
+	pred       *query.Predicate    //这个是条件
+	orderBy    []*query.SortKey    //ORDER BY的排序字段，没有的时候为空
+	groupBy    []string            //GROUP BY的分组字段
+	having     *query.Predicate    //HAVING条件，没有的时候为nil
+	aggregates []*query.Aggregate  //选择的字段，HAVING以及ORDER BY中出现的所有聚合函数
+	distinct   bool                //SELECT DISTINCT，需要去掉重复的记录
+	limit      *comm.Constant      //LIMIT最多返回的记录数，没有的时候为nil
+	offset     *comm.Constant      //OFFSET跳过的记录数，没有的时候为nil
+	set        *SetOperation       //集合运算的两个查询，不是集合运算的时候为nil
 }
 
 func NewQueryData(fields []string, tables []string, pred *query.Predicate) *QueryData {
@@ -29,31 +43,179 @@ func (q *QueryData) Fields() []string {
 	return q.fields
 }
 
+//Columns 每一列对应的表达式，没有设置的时候每一列就是同名的字段
+func (q *QueryData) Columns() []*query.Expression {
+	if q.columns != nil {
+		return q.columns
+	}
+	columns := make([]*query.Expression, len(q.fields))
+	for i, field := range q.fields {
+		columns[i] = query.NewExpressionWithFieldName(field)
+	}
+	return columns
+}
+
+func (q *QueryData) SetColumns(columns []*query.Expression) {
+	q.columns = columns
+}
+
 func (q *QueryData) Tables() []string {
 	return q.tables
 }
 
+//ReferencedTables 查询中用到的所有表和视图，包括集合运算的两边，FROM中的子查询以及条件和表达式中的子查询用到的
+//删除表或者视图的时候用它找出依赖它们的视图，同一个名字可能出现多次
+func (q *QueryData) ReferencedTables() []string {
+	if q.set != nil {
+		return append(q.set.lhs.ReferencedTables(), q.set.rhs.ReferencedTables()...)
+	}
+	tables := append([]string{}, q.Tables()...)
+	subs := q.pred.Subqueries()
+	for _, column := range q.Columns() {
+		subs = append(subs, column.Subqueries()...)
+	}
+	if q.having != nil {
+		subs = append(subs, q.having.Subqueries()...)
+	}
+	for _, ref := range q.From() {
+		if ref.Query() != nil {
+			tables = append(tables, ref.Query().ReferencedTables()...)
+		}
+		for _, join := range ref.Joins() {
+			if join.Query() != nil {
+				tables = append(tables, join.Query().ReferencedTables()...)
+			}
+			subs = append(subs, join.On().Subqueries()...)
+		}
+	}
+	for _, sub := range subs {
+		tables = append(tables, sub.Data().(*QueryData).ReferencedTables()...)
+	}
+	return tables
+}
+
+//From FROM中用逗号分隔的每一项，没有设置的时候每一项就是一张没有连接的表
+func (q *QueryData) From() []*TableRef {
+	if q.from != nil {
+		return q.from
+	}
+	from := make([]*TableRef, len(q.tables))
+	for i, table := range q.tables {
+		from[i] = NewTableRef(table, "", nil)
+	}
+	return from
+}
+
+//SetFrom 设置FROM中的每一项，同时把连接中用到的表也加入到Tables中，FROM中的子查询没有表名，不加入
+func (q *QueryData) SetFrom(from []*TableRef) {
+	q.from = from
+	q.tables = make([]string, 0, len(from))
+	for _, ref := range from {
+		if ref.Query() == nil {
+			q.tables = append(q.tables, ref.TableName())
+		}
+		for _, join := range ref.Joins() {
+			if join.Query() == nil {
+				q.tables = append(q.tables, join.TableName())
+			}
+		}
+	}
+}
+
 func (q *QueryData) Pred() *query.Predicate {
 	return q.pred
 }
 
+func (q *QueryData) OrderBy() []*query.SortKey {
+	return q.orderBy
+}
+
+//SetOrderBy 设置ORDER BY的排序字段
+func (q *QueryData) SetOrderBy(keys []*query.SortKey) {
+	q.orderBy = keys
+}
+
+func (q *QueryData) GroupBy() []string {
+	return q.groupBy
+}
+
+func (q *QueryData) SetGroupBy(fields []string) {
+	q.groupBy = fields
+}
+
+func (q *QueryData) Having() *query.Predicate {
+	return q.having
+}
+
+func (q *QueryData) SetHaving(pred *query.Predicate) {
+	q.having = pred
+}
+
+func (q *QueryData) Aggregates() []*query.Aggregate {
+	return q.aggregates
+}
+
+func (q *QueryData) SetAggregates(aggs []*query.Aggregate) {
+	q.aggregates = aggs
+}
+
+func (q *QueryData) Distinct() bool {
+	return q.distinct
+}
+
+func (q *QueryData) SetDistinct(distinct bool) {
+	q.distinct = distinct
+}
+
+func (q *QueryData) Limit() *comm.Constant {
+	return q.limit
+}
+
+func (q *QueryData) Offset() *comm.Constant {
+	return q.offset
+}
+
+//SetLimit 设置LIMIT和OFFSET，它们可以是整数常量也可以是占位符对应的参数槽
+func (q *QueryData) SetLimit(limit *comm.Constant, offset *comm.Constant) {
+	q.limit = limit
+	q.offset = offset
+}
+
+//SetOperation 集合运算的两个查询，不是集合运算的时候为nil
+func (q *QueryData) SetOperation() *SetOperation {
+	return q.set
+}
+
+//IsAggregate 是否需要对记录进行分组聚合
+func (q *QueryData) IsAggregate() bool {
+	return len(q.groupBy) > 0 || len(q.aggregates) > 0 || q.having != nil
+}
+
 //ToString 将这个SQL语句转化成字符串的形式
 func (q *QueryData) ToString() string {
+	if q.set != nil {
+		return q.set.ToString() + q.tailString()
+	}
 	result := "SELECT "
+	if q.distinct {
+		result += "DISTINCT "
+	}
 	fieldNum := len(q.fields)
 
-	for i, fldName := range q.fields {
-		result += fldName
+	for i, column := range q.Columns() {
+		result += column.ToString()
+		if column.ToString() != q.fields[i] {
+			result += " AS " + q.fields[i]
+		}
 		if i != fieldNum-1 {
 			result += ", "
 		}
 	}
-	result = result[:len(result)-1]
 	result += " FROM "
-	tableNum := len(q.tables)
-	for i, tableName := range q.tables {
-		result += tableName
-		if i != tableNum-1 {
+	from := q.From()
+	for i, ref := range from {
+		result += ref.ToString()
+		if i != len(from)-1 {
 			result += ", "
 		}
 	}
@@ -62,5 +224,26 @@ func (q *QueryData) ToString() string {
 	if predStr != "" {
 		result += " WHERE " + predStr
 	}
+	if len(q.groupBy) > 0 {
+		result += " GROUP BY " + strings.Join(q.groupBy, ", ")
+	}
+	if q.having != nil {
+		result += " HAVING " + q.having.ToString()
+	}
+	return result + q.tailString()
+}
+
+//tailString ORDER BY和LIMIT部分
+func (q *QueryData) tailString() string {
+	result := ""
+	if len(q.orderBy) > 0 {
+		result += " ORDER BY " + query.SortKeysToString(q.orderBy)
+	}
+	if q.limit != nil {
+		result += " LIMIT " + query.NewExpressionWithConstant(q.limit).ToString()
+		if q.offset != nil {
+			result += " OFFSET " + query.NewExpressionWithConstant(q.offset).ToString()
+		}
+	}
 	return result
 }
@@ -0,0 +1,80 @@
+package parser
+
+import "miniSQL/query"
+
+/*
+	集合运算把两个查询的结果合并起来，两个查询的列数必须相同，对应的列类型相同，结果中列的名字使用左边查询的名字
+	UNION：两边的记录合并起来并去掉重复的记录，UNION ALL保留重复的记录
+	INTERSECT：两边都有的记录，INTERSECT ALL中一条记录在左边出现m次，右边出现n次，结果中出现min(m, n)次
+	EXCEPT：左边有右边没有的记录，EXCEPT ALL中出现max(m - n, 0)次
+	集合运算的QueryData只有字段、ORDER BY和LIMIT，两边的查询保存在SetOperation中
+*/
+
+//SetOp 集合运算的类型
+type SetOp int
+
+const (
+	UNION SetOp = iota
+	INTERSECT
+	EXCEPT
+)
+
+//String 集合运算在SQL中的写法
+func (op SetOp) String() string {
+	switch op {
+	case INTERSECT:
+		return "INTERSECT"
+	case EXCEPT:
+		return "EXCEPT"
+	}
+	return "UNION"
+}
+
+//SetOperation 两个查询之间的集合运算
+type SetOperation struct {
+	op  SetOp
+	all bool //保留重复的记录
+	lhs *QueryData
+	rhs *QueryData
+}
+
+func (s *SetOperation) Op() SetOp {
+	return s.op
+}
+
+func (s *SetOperation) All() bool {
+	return s.all
+}
+
+func (s *SetOperation) Lhs() *QueryData {
+	return s.lhs
+}
+
+func (s *SetOperation) Rhs() *QueryData {
+	return s.rhs
+}
+
+//ToString 右边的操作数是集合运算或者有自己的ORDER BY和LIMIT的时候加上括号，这样可以按照相同的结合方式重新解析
+func (s *SetOperation) ToString() string {
+	result := s.lhs.ToString() + " " + s.op.String()
+	if s.all {
+		result += " ALL"
+	}
+	rhs := s.rhs.ToString()
+	if s.rhs.set != nil || len(s.rhs.orderBy) > 0 || s.rhs.limit != nil {
+		rhs = "(" + rhs + ")"
+	}
+	return result + " " + rhs
+}
+
+//NewSetQueryData 两个查询之间的集合运算，结果中的字段使用左边查询的名字
+func NewSetQueryData(op SetOp, all bool, lhs *QueryData, rhs *QueryData) *QueryData {
+	data := NewQueryData(lhs.Fields(), nil, query.NewPredicate())
+	data.set = &SetOperation{
+		op:  op,
+		all: all,
+		lhs: lhs,
+		rhs: rhs,
+	}
+	return data
+}
@@ -0,0 +1,28 @@
+package parser
+
+import "strings"
+
+//SplitStatements 按照;把多条SQL语句切分开，字符串中的;不算
+//返回所有以;结束的语句（不包含;），以及最后还没有结束的部分，空的语句会被跳过
+func SplitStatements(sql string) ([]string, string) {
+	stmts := make([]string, 0)
+	var quote byte //当前所在的字符串使用的引号，0表示不在字符串中
+	start := 0
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';':
+			if stmt := strings.TrimSpace(sql[start:i]); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+			start = i + 1
+		}
+	}
+	return stmts, sql[start:]
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"miniSQL/lexer"
+)
+
+//Statement 解析完SQL语句之后得到的语法树，只有当前包中的语法树对象才实现了这个接口
+//使用的时候对它进行type switch即可知道是哪一种语句：
+//*QueryData,*InsertData,*DeleteData,*UpdateData,*CreateTableData,*CreateViewData,*CreateIndexData,*DropData,*AlterData
+//以及事务控制语句*BeginData,*CommitData,*RollbackData,*SavepointData
+type Statement interface {
+	statementNode()
+}
+
+func (q *QueryData) statementNode()       {}
+func (d *InsertData) statementNode()      {}
+func (d *DeleteData) statementNode()      {}
+func (m *UpdateData) statementNode()      {}
+func (t *CreateTableData) statementNode() {}
+func (v *CreateViewData) statementNode()  {}
+func (i *CreateIndexData) statementNode() {}
+func (d *DropData) statementNode()        {}
+func (a *AlterData) statementNode()       {}
+func (b *BeginData) statementNode()       {}
+func (c *CommitData) statementNode()      {}
+func (r *RollbackData) statementNode()    {}
+func (s *SavepointData) statementNode()   {}
+
+//IsQuery 判断当前的语句是否是一个查询语句
+func IsQuery(stmt Statement) bool {
+	_, ok := stmt.(*QueryData)
+	return ok
+}
+
+//IsTxControl 判断当前的语句是否是事务控制语句，这些语句由会话来执行，而不是交给规划器
+func IsTxControl(stmt Statement) bool {
+	switch stmt.(type) {
+	case *BeginData, *CommitData, *RollbackData, *SavepointData:
+		return true
+	}
+	return false
+}
+
+//ParseStatement 解析任意一条SQL语句，调用者不需要提前知道他是查询语句还是修改语句
+func (p *SQLParser) ParseStatement() (stmt Statement, err error) {
+	//解析器中有些地方遇到语法错误会直接panic，这里统一转化成语法错误返回给调用者
+	defer func() {
+		if r := recover(); r != nil {
+			stmt = nil
+			if e, ok := r.(error); ok && (errors.Is(e, ErrArgCount) || errors.Is(e, ErrSyntax)) {
+				err = e
+				return
+			}
+			err = fmt.Errorf("%w: %v", ErrSyntax, r)
+		}
+	}()
+	tok, err := p.sqlLexer.Scan()
+	if err != nil {
+		return nil, ErrSyntax
+	}
+	p.sqlLexer.ReverseScan() //先看一下第一个关键字，再放回去
+	if tok.Tag == lexer.SELECT {
+		qd, err := p.Query()
+		if err != nil {
+			return nil, err
+		}
+		stmt = qd
+	} else if tok.Tag == lexer.BEGIN || tok.Tag == lexer.START || tok.Tag == lexer.COMMIT || tok.Tag == lexer.ROLLBACK || tok.Tag == lexer.SAVEPOINT {
+		stmt, err = p.TxControl()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		cmd, err := p.UpdateCmd()
+		if err != nil {
+			return nil, err
+		}
+		stmt = cmd.(Statement)
+	}
+	//传入的参数必须全部被占位符使用掉
+	if !p.prepare && p.argIdx != len(p.args) {
+		return nil, ErrArgCount
+	}
+	return stmt, nil
+}
@@ -0,0 +1,54 @@
+package parser
+
+//BeginData BEGIN [READ ONLY]
+type BeginData struct {
+	readOnly bool //只读事务中不能执行修改语句
+}
+
+func NewBeginData(readOnly bool) *BeginData {
+	return &BeginData{
+		readOnly: readOnly,
+	}
+}
+
+func (b *BeginData) ReadOnly() bool {
+	return b.readOnly
+}
+
+//CommitData COMMIT
+type CommitData struct {
+}
+
+func NewCommitData() *CommitData {
+	return &CommitData{}
+}
+
+//RollbackData ROLLBACK 或者 ROLLBACK TO [SAVEPOINT] name
+type RollbackData struct {
+	savepoint string //为空的时候回滚整个事务，否则回滚到这个保存点
+}
+
+func NewRollbackData(savepoint string) *RollbackData {
+	return &RollbackData{
+		savepoint: savepoint,
+	}
+}
+
+func (r *RollbackData) Savepoint() string {
+	return r.savepoint
+}
+
+//SavepointData SAVEPOINT name
+type SavepointData struct {
+	name string
+}
+
+func NewSavepointData(name string) *SavepointData {
+	return &SavepointData{
+		name: name,
+	}
+}
+
+func (s *SavepointData) Name() string {
+	return s.name
+}
@@ -5,18 +5,19 @@ import (
 )
 
 //UpdateData 这个语法树中记录的就是需要进行update的SQL语句
+//SET中可以有多个赋值，fields[i]被设置成newVals[i]，所有的新值都使用修改之前的记录计算
 type UpdateData struct {
 	tableName string
-	fieldName string
-	newVal    *query.Expression
+	fields    []string
+	newVals   []*query.Expression
 	pred      *query.Predicate
 }
 
-func NewUpdateData(tblName string, fldName string, newVal *query.Expression, pred *query.Predicate) *UpdateData {
+func NewUpdateData(tblName string, fields []string, newVals []*query.Expression, pred *query.Predicate) *UpdateData {
 	return &UpdateData{
 		tableName: tblName,
-		fieldName: fldName,
-		newVal:    newVal,
+		fields:    fields,
+		newVals:   newVals,
 		pred:      pred,
 	}
 
@@ -25,12 +26,24 @@ func (m *UpdateData) TableName() string {
 	return m.tableName
 }
 
+//TargetField 第一个赋值的字段
 func (m *UpdateData) TargetField() string {
-	return m.fieldName
+	return m.fields[0]
 }
 
+//NewValue 第一个赋值的新值
 func (m *UpdateData) NewValue() *query.Expression {
-	return m.newVal
+	return m.newVals[0]
+}
+
+//Fields SET中所有被赋值的字段
+func (m *UpdateData) Fields() []string {
+	return m.fields
+}
+
+//NewValues 和Fields一一对应的新值
+func (m *UpdateData) NewValues() []*query.Expression {
+	return m.newVals
 }
 
 func (m *UpdateData) Pred() *query.Predicate {
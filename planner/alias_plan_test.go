@@ -0,0 +1,89 @@
+package planner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestQualifiedName(t *testing.T) {
+	p, tx, _ := newTestPlanner(t, "alias_test")
+	defer tx.Commit()
+	for _, sql := range []string{
+		"create table emp (id int, name varchar(10), boss int, dept int)",
+		"create table dept (id int, title varchar(10))",
+		"insert into emp (id, name, boss, dept) values (1, 'ann', 0, 1)",
+		"insert into emp (id, name, boss, dept) values (2, 'ben', 1, 1)",
+		"insert into emp (id, name, boss, dept) values (3, 'cid', 1, 2)",
+		"insert into emp (id, name, boss, dept) values (4, 'dan', 3, 2)",
+		"insert into dept (id, title) values (1, 'sales')",
+		"insert into dept (id, title) values (2, 'tech')",
+		"create view staff as select e.name, d.title from emp e, dept d where e.dept = d.id",
+	} {
+		_, err := p.ExecuteUpdate(sql, tx)
+		assert.Nil(t, err, sql)
+	}
+
+	cases := []struct {
+		sql    string
+		fields []string
+		rows   []string
+	}{
+		//自连接，同一张表使用不同的别名
+		{"select e.name, m.name as manager from emp e join emp m on e.boss = m.id order by e.id",
+			[]string{"name", "manager"}, []string{"ben ann", "cid ann", "dan cid"}},
+		//没有冲突的字段可以不写表名
+		{"select name, title from emp, dept where dept = dept.id and title = 'tech' order by name",
+			[]string{"name", "title"}, []string{"cid tech", "dan tech"}},
+		{"select e.id, d.id from emp e, dept d where e.dept = d.id and name = 'ben'",
+			[]string{"e.id", "d.id"}, []string{"2 1"}},
+		{"select * from dept order by id", []string{"id", "title"}, []string{"1 sales", "2 tech"}},
+		{"select * from emp e, dept d where e.id = 1 and d.id = 2",
+			[]string{"e.id", "name", "boss", "dept", "d.id", "title"}, []string{"1 ann 0 1 2 tech"}},
+		{"select d.*, e.name from emp e, dept d where e.dept = d.id and e.boss = 3",
+			[]string{"d.id", "title", "name"}, []string{"2 tech dan"}},
+		{"select d.title, count(*), max(e.id) from emp e join dept d on e.dept = d.id group by d.title order by d.title",
+			[]string{"title", "count(*)", "max(e.id)"}, []string{"sales 2 2", "tech 2 4"}},
+		{"select title, name from staff where title = 'sales' order by name", []string{"title", "name"}, []string{"sales ann", "sales ben"}},
+	}
+	for _, c := range cases {
+		scan, sch, err := p.ExecuteQuery(c.sql, tx)
+		if !assert.Nil(t, err, c.sql) {
+			continue
+		}
+		scan.Close()
+		assert.Equal(t, c.fields, sch.Fields(), c.sql)
+		assert.Equal(t, c.rows, collectRows(t, p, c.sql, tx), c.sql)
+	}
+
+	plan, err := p.CreateQueryPlan("select e.name from emp e join emp m on e.boss = m.id", tx)
+	assert.Nil(t, err)
+	explain := Explain(plan)
+	assert.True(t, strings.Contains(explain, "Alias(e)"), explain)
+	assert.True(t, strings.Contains(explain, "Alias(m)"), explain)
+
+	for _, sql := range []string{
+		"select id from emp, dept",
+		"select name from emp e, dept d where id = 1",
+		"select name from emp e join emp m on boss = id",
+	} {
+		_, err = p.CreateQueryPlan(sql, tx)
+		assert.ErrorIs(t, err, ErrAmbiguousField, sql)
+	}
+	for _, sql := range []string{
+		"select x.name from emp e",
+		"select e.title from emp e",
+		"select emp.name from emp e",
+		"select x.* from emp",
+	} {
+		_, err = p.CreateQueryPlan(sql, tx)
+		assert.ErrorIs(t, err, ErrFieldNotFound, sql)
+	}
+	for _, sql := range []string{
+		"select name from emp, emp",
+		"select name from emp e join dept e on boss = title",
+	} {
+		_, err = p.CreateQueryPlan(sql, tx)
+		assert.ErrorIs(t, err, ErrDuplicateAlias, sql)
+	}
+}
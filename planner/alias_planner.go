@@ -0,0 +1,58 @@
+package planner
+
+import (
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+)
+
+//AliasPlan 给FROM中的一张表的字段换上在查询中使用的名字，和其他表同名的字段使用t.col的形式
+//没有同名字段的表不需要AliasPlan，直接使用表本身的计划
+type AliasPlan struct {
+	p      Plan
+	name   string            //表在查询中的名字，有别名的时候是别名
+	names  map[string]string //新的名字对应的底层字段名
+	schema *rm.Schema
+}
+
+//NewAliasPlan fields[i]是p中第i个字段的新名字
+func NewAliasPlan(p Plan, name string, fields []string) *AliasPlan {
+	aliasPlan := &AliasPlan{
+		p:      p,
+		name:   name,
+		names:  make(map[string]string),
+		schema: rm.NewSchema(),
+	}
+	for i, field := range p.Schema().Fields() {
+		aliasPlan.names[fields[i]] = field
+		aliasPlan.schema.AddField(fields[i], p.Schema().Type(field), p.Schema().Length(field))
+	}
+	return aliasPlan
+}
+
+func (a *AliasPlan) Open() (interface{}, error) {
+	s, err := a.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return query.NewAliasScan(s.(query.Scan), a.names), nil
+}
+
+func (a *AliasPlan) BlockAccessed() int {
+	return a.p.BlockAccessed()
+}
+
+func (a *AliasPlan) RecordsOutput() int {
+	return a.p.RecordsOutput()
+}
+
+func (a *AliasPlan) DistinctValues(fldName string) int {
+	return a.p.DistinctValues(a.names[fldName])
+}
+
+func (a *AliasPlan) Schema() rm.SchemaInterface {
+	return a.schema
+}
+
+func (a *AliasPlan) Cost() float64 {
+	return a.p.Cost()
+}
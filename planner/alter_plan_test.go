@@ -0,0 +1,197 @@
+package planner
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/comm"
+	mm "miniSQL/metadata_manager"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+//indexedIDs 通过索引找到field = val的记录，返回这些记录的id字段
+func indexedIDs(t *testing.T, mdm *mm.MetaDataManager, table string, field string, val int, tx *tx.Transaction) []string {
+	ii, ok := mdm.GetIndexInfo(table, tx)[field]
+	if !assert.True(t, ok, field) {
+		return nil
+	}
+	layout, err := mdm.GetLayout(table, tx)
+	assert.Nil(t, err)
+	ts, err := rm.NewTableScan(tx, table, layout)
+	assert.Nil(t, err)
+	defer ts.Close()
+	idx := ii.Open()
+	defer idx.Close()
+	ids := make([]int, 0)
+	idx.BeforeFirst(comm.NewConstantInt(&val))
+	for idx.Next() {
+		ts.Move2Rid(idx.GetDataRID())
+		ids = append(ids, ts.GetInt("id"))
+	}
+	sort.Ints(ids)
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = strconv.Itoa(id)
+	}
+	return result
+}
+
+func TestAlterTable(t *testing.T) {
+	p, t1, db := newTestPlanner(t, "alter_test")
+	for _, sql := range []string{
+		"create table emp (id int, name varchar(10), dept int)",
+		"create table dept (did int, title varchar(10))",
+		"create table one (a int)",
+		"create index empdept on emp (dept)",
+		"create view names as select name from emp where dept = 1",
+	} {
+		_, err := p.ExecuteUpdate(sql, t1)
+		assert.Nil(t, err, sql)
+	}
+	//记录足够多，占用好几个区块
+	for i := 0; i < 30; i++ {
+		_, err := p.ExecuteUpdate(fmt.Sprintf("insert into emp (id, name, dept) values (%d, 'e%d', %d)", i, i, i%3), t1)
+		assert.Nil(t, err)
+	}
+	deptOne := collectRows(t, p, "select id from emp where dept = 1 order by id", t1)
+	t1.Commit()
+
+	t2 := db.newTx()
+	for sql, target := range map[string]error{
+		"alter table nosuch add x int":                     ErrTableNotFound,
+		"alter table names add x int":                      ErrTableNotFound,
+		"alter table fldcat add x int":                     ErrCatalogTable,
+		"alter table emp add name int":                     ErrDuplicateField,
+		"alter table emp add x int default 'a'":            ErrTypeMismatch,
+		"alter table emp drop nosuch":                      ErrFieldNotFound,
+		"alter table emp rename nosuch to x":               ErrFieldNotFound,
+		"alter table emp rename id to name":                ErrDuplicateField,
+		"alter table one drop a":                           ErrLastField,
+		"alter table dept rename to emp":                   ErrTableExists,
+		"alter table dept rename to names":                 ErrTableExists,
+		"create table emp (x int)":                         ErrTableExists,
+		"create table names (x int)":                       ErrTableExists,
+		"create table tblcat (x int)":                      ErrTableExists,
+		"create view emp as select did from dept":          ErrTableExists,
+		"create view names as select did from dept":        ErrTableExists,
+		"create view loop as select a from loop":           ErrRecursiveView,
+		"alter table emp rename to staff":                  ErrDependentObjects,
+		"alter table emp drop name":                        ErrDependentObjects,
+		"alter table emp rename column dept to did":        ErrDependentObjects,
+		"alter table emp add title varchar(5) default 'x'": nil,
+	} {
+		_, err := p.ExecuteUpdate(sql, t2)
+		if target == nil {
+			assert.Nil(t, err, sql)
+		} else {
+			assert.ErrorIs(t, err, target, sql)
+		}
+	}
+	//通过其他视图间接引用自己也不可以
+	_, err := p.ExecuteUpdate("create view wa as select a from wb", t2)
+	assert.Nil(t, err)
+	_, err = p.ExecuteUpdate("create view wb as select a from wa", t2)
+	assert.ErrorIs(t, err, ErrRecursiveView)
+	_, err = p.ExecuteUpdate("drop view wa", t2)
+	assert.Nil(t, err)
+	//视图依赖的字段没有被修改
+	assert.Equal(t, 10, len(collectRows(t, p, "select name from names", t2)))
+
+	//新增的字段在每条记录中都有默认值，没有DEFAULT的时候是NULL，原来的字段不变，索引指向新的记录位置
+	_, err = p.ExecuteUpdate("alter table emp add column sal int default -5", t2)
+	assert.Nil(t, err)
+	_, err = p.ExecuteUpdate("alter table emp add note varchar(4)", t2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"0 e0 0 x -5 NULL", "1 e1 1 x -5 NULL", "29 e29 2 x -5 NULL"},
+		collectRows(t, p, "select id, name, dept, title, sal, note from emp where id = 0 or id = 1 or id = 29 order by id", t2))
+	assert.Equal(t, 30, len(collectRows(t, p, "select id from emp", t2)))
+	assert.Equal(t, deptOne, indexedIDs(t, db.mdm, "emp", "dept", 1, t2))
+	_, err = p.ExecuteUpdate("insert into emp (id, name, dept, sal) values (30, 'new', 2, 9)", t2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"new 9"}, collectRows(t, p, "select name, sal from emp where id = 30", t2))
+	//DEFAULT保存在元数据中，之后的INSERT和INSERT ... SELECT没有给出这个字段的时候使用默认值
+	for _, sql := range []string{
+		"insert into emp (id, name, dept) values (31, 'def', 2)",
+		"insert into emp (id, name, dept) select id + 100, name, dept from emp where id = 29",
+	} {
+		_, err = p.ExecuteUpdate(sql, t2)
+		assert.Nil(t, err, sql)
+	}
+	assert.Equal(t, []string{"31 x -5 NULL", "129 x -5 NULL"},
+		collectRows(t, p, "select id, title, sal, note from emp where id > 30 order by id", t2))
+
+	//删除的字段不能再使用，其他字段的数据不变
+	_, err = p.ExecuteUpdate("alter table emp drop column title", t2)
+	assert.Nil(t, err)
+	_, _, err = p.ExecuteQuery("select title from emp", t2)
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+	assert.Equal(t, []string{"29 e29 2 -5"}, collectRows(t, p, "select id, name, dept, sal from emp where id = 29", t2))
+
+	//索引跟着字段改名
+	_, err = p.ExecuteUpdate("drop view names", t2)
+	assert.Nil(t, err)
+	_, err = p.ExecuteUpdate("alter table emp rename column dept to did", t2)
+	assert.Nil(t, err)
+	assert.Equal(t, deptOne, indexedIDs(t, db.mdm, "emp", "did", 1, t2))
+	assert.Equal(t, []string{"e1"}, collectRows(t, p, "select name from emp where did = 1 and id = 1", t2))
+
+	//表改名之后旧的文件在事务提交之后删除，索引转移到新的表名下面
+	_, err = p.ExecuteUpdate("alter table emp rename to staff", t2)
+	assert.Nil(t, err)
+	_, _, err = p.ExecuteQuery("select id from emp", t2)
+	assert.ErrorIs(t, err, ErrTableNotFound)
+	assert.Equal(t, 33, len(collectRows(t, p, "select id from staff", t2)))
+	assert.Equal(t, deptOne, indexedIDs(t, db.mdm, "staff", "did", 1, t2))
+	assert.True(t, db.exists("emp.tbl"))
+	t2.Commit()
+	assert.False(t, db.exists("emp.tbl"))
+	assert.True(t, db.exists("staff.tbl"))
+
+	//删除有索引的字段的时候也删除索引
+	t3 := db.newTx()
+	_, err = p.ExecuteUpdate("alter table staff drop did", t3)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(db.mdm.GetIndexInfo("staff", t3)))
+	t3.Commit()
+	assert.False(t, db.exists("empdept#*.tbl"))
+
+	//回滚之后表结构和记录都恢复原样
+	t4 := db.newTx()
+	_, err = p.ExecuteUpdate("alter table staff add extra int default 1", t4)
+	assert.Nil(t, err)
+	_, err = p.ExecuteUpdate("alter table staff rename name to ename", t4)
+	assert.Nil(t, err)
+	assert.Nil(t, t4.RollBack())
+	t5 := db.newTx()
+	assert.Equal(t, []string{"2 e2 -5 NULL"}, collectRows(t, p, "select * from staff where id = 2", t5))
+	_, _, err = p.ExecuteQuery("select extra from staff", t5)
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+	t5.Commit()
+
+	//字段改名、表改名之后默认值还在，重新打开之后也在，删除字段的时候默认值一起删除
+	//同一个事务中改名再改回来，表文件不会在提交的时候被删除
+	t6 := db.newTx()
+	p = db.reopen(t, t6)
+	for _, sql := range []string{
+		"alter table staff rename to tmp",
+		"alter table tmp rename to staff",
+		"alter table staff rename sal to salary",
+		"alter table staff drop note",
+		"alter table staff add note varchar(4)",
+		"insert into staff (id, name) values (200, 'r')",
+	} {
+		_, err = p.ExecuteUpdate(sql, t6)
+		assert.Nil(t, err, sql)
+	}
+	t6.Commit()
+	assert.True(t, db.exists("staff.tbl"))
+	assert.False(t, db.exists("tmp.tbl"))
+	t7 := db.newTx()
+	assert.Equal(t, 34, len(collectRows(t, p, "select id from staff", t7)))
+	assert.Equal(t, []string{"2 e2 -5 NULL", "200 r -5 NULL"},
+		collectRows(t, p, "select id, name, salary, note from staff where id = 2 or id = 200 order by id", t7))
+	t7.Commit()
+}
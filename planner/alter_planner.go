@@ -0,0 +1,224 @@
+package planner
+
+import (
+	"fmt"
+	"miniSQL/comm"
+	mm "miniSQL/metadata_manager"
+	"miniSQL/parser"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+	"strings"
+)
+
+//ExecuteAlter 修改表结构
+//ADD COLUMN在事务中把表中的记录按照新的layout重写一遍，RENAME TO把记录复制到新的表文件中，它们都会改变记录的位置，所以要重建表上的索引
+//DROP COLUMN和RENAME COLUMN只修改元数据，字段的偏移不变，删除的字段占用的字节不再使用
+//修改字段之后依赖这张表的视图必须还能使用，否则撤销这次修改并返回ErrDependentObjects
+func (b *BasicUpdatePlanner) ExecuteAlter(data *parser.AlterData, tx *tx.Transaction) error {
+	name := data.TableName()
+	if catalogTables[name] {
+		return fmt.Errorf("%w: %s", ErrCatalogTable, name)
+	}
+	layout, err := b.mdm.GetLayout(name, tx)
+	if err != nil {
+		return err
+	}
+	if layout.SlotSize() < 0 {
+		return fmt.Errorf("%w: %s", ErrTableNotFound, name)
+	}
+	views, err := b.dependentViews(name, tx)
+	if err != nil {
+		return err
+	}
+	if data.Action() == parser.ALTER_RENAME_TABLE {
+		//视图的定义里面写的是旧的表名，改名之后就不能使用了
+		if len(views) > 0 {
+			return fmt.Errorf("%w: view %s depends on table %s", ErrDependentObjects, strings.Join(views, ", "), name)
+		}
+		return b.renameTable(name, data.NewName(), layout, tx)
+	}
+	savepoint := tx.Savepoint()
+	switch data.Action() {
+	case parser.ALTER_ADD_COLUMN:
+		err = b.addColumn(name, data.FieldName(), data.Schema(), data.Default(), layout, tx)
+	case parser.ALTER_DROP_COLUMN:
+		err = b.dropColumn(name, data.FieldName(), layout, tx)
+	case parser.ALTER_RENAME_COLUMN:
+		err = b.renameColumn(name, data.FieldName(), data.NewName(), layout, tx)
+	}
+	if err != nil {
+		tx.RollBackTo(savepoint)
+		return err
+	}
+	for _, view := range views {
+		if err := b.checkView(view, tx); err != nil {
+			tx.RollBackTo(savepoint)
+			return fmt.Errorf("%w: view %s: %v", ErrDependentObjects, view, err)
+		}
+	}
+	return nil
+}
+
+//addColumn 新增的字段放在记录的末尾，先把记录保存到临时表中，再按照新的layout清空表文件，把记录和新字段的默认值写回去
+//没有DEFAULT的时候新字段的值是NULL，有DEFAULT的时候默认值保存在元数据中，之后的INSERT没有给出这个字段的时候也使用它
+func (b *BasicUpdatePlanner) addColumn(name string, field string, sch *rm.Schema, defaultVal *comm.Constant, layout *rm.Layout, tx *tx.Transaction) error {
+	if layout.Schema().HashField(field) {
+		return fmt.Errorf("%w: %s", ErrDuplicateField, field)
+	}
+	if len(layout.Schema().Fields()) >= rm.MAX_FIELDS {
+		return fmt.Errorf("%w: %s already has %d fields", ErrTooManyFields, name, rm.MAX_FIELDS)
+	}
+	newLayout := layout.AddField(field, sch)
+	if defaultVal == nil {
+		defaultVal = comm.NewNullConstant()
+	} else if err := checkAssign(sch, field, query.NewExpressionWithConstant(defaultVal), rm.NewSchema()); err != nil {
+		return err
+	} else if defaultVal, err = convertValue(defaultVal, sch, field); err != nil {
+		return err
+	}
+	fields := layout.Schema().Fields()
+	src, err := rm.NewTableScan(tx, name, layout)
+	if err != nil {
+		return err
+	}
+	temp, err := NewTempTable(tx, layout.Schema()).Open()
+	if err != nil {
+		src.Close()
+		return err
+	}
+	defer temp.Close()
+	_, err = copyRecords(src, temp, layout.Schema(), fields, fields, nil, nil)
+	src.Close()
+	if err != nil {
+		return err
+	}
+
+	if _, err := b.mdm.AlterLayout(name, newLayout, tx); err != nil {
+		return err
+	}
+	if !defaultVal.IsNull() {
+		//保存默认值，之后的INSERT没有给出这个字段的时候使用
+		text := defaultVal.ToString()
+		if len(text) > mm.MAX_DEFAULT {
+			return fmt.Errorf("%w: default of %s, got %d bytes", ErrValueTooLong, field, len(text))
+		}
+		if err := b.mdm.SetDefault(name, field, text, tx); err != nil {
+			return err
+		}
+	}
+	dest, err := rm.NewTableScan(tx, name, newLayout)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+	if err := dest.Clear(); err != nil {
+		return err
+	}
+	temp.BeforeFirst()
+	for temp.Next() {
+		dest.Insert()
+		for _, f := range fields {
+			dest.SetVal(f, temp.GetVal(f))
+		}
+		dest.SetVal(field, defaultVal)
+	}
+	return b.rebuildIndexes(name, newLayout, tx)
+}
+
+//dropColumn 只从元数据中去掉这个字段，字段上的索引也一起删除
+//记录头中NULL标志的位置按照字段的偏移排列，所以要先去掉每条记录中这个字段的NULL标志
+func (b *BasicUpdatePlanner) dropColumn(name string, field string, layout *rm.Layout, tx *tx.Transaction) error {
+	if !layout.Schema().HashField(field) {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	if len(layout.Schema().Fields()) == 1 {
+		return fmt.Errorf("%w: %s", ErrLastField, field)
+	}
+	if ii, ok := b.mdm.GetIndexInfo(name, tx)[field]; ok {
+		if _, err := b.mdm.DropIndex(ii.IndexName(), tx); err != nil {
+			return err
+		}
+	}
+	ts, err := rm.NewTableScan(tx, name, layout)
+	if err != nil {
+		return err
+	}
+	for ts.Next() {
+		ts.DropNullBit(field)
+	}
+	ts.Close()
+	_, err = b.mdm.AlterLayout(name, layout.DropField(field), tx)
+	return err
+}
+
+//renameColumn 修改元数据中的字段名，字段的偏移不变
+func (b *BasicUpdatePlanner) renameColumn(name string, field string, newName string, layout *rm.Layout, tx *tx.Transaction) error {
+	if !layout.Schema().HashField(field) {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	if layout.Schema().HashField(newName) {
+		return fmt.Errorf("%w: %s", ErrDuplicateField, newName)
+	}
+	_, err := b.mdm.RenameField(name, field, newName, tx)
+	return err
+}
+
+//renameTable 把记录复制到新表名对应的文件中，旧的文件在事务提交之后删除
+func (b *BasicUpdatePlanner) renameTable(name string, newName string, layout *rm.Layout, tx *tx.Transaction) error {
+	if err := b.checkNewName(newName, tx); err != nil {
+		return err
+	}
+	if _, err := b.mdm.RenameTable(name, newName, tx); err != nil {
+		return err
+	}
+	src, err := rm.NewTableScan(tx, name, layout)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dest, err := rm.NewTableScan(tx, newName, layout)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+	fields := layout.Schema().Fields()
+	if _, err := copyRecords(src, dest, layout.Schema(), fields, fields, nil, nil); err != nil {
+		return err
+	}
+	return b.rebuildIndexes(newName, layout, tx)
+}
+
+//rebuildIndexes 记录的位置改变之后，清空表上的索引再重新填充
+func (b *BasicUpdatePlanner) rebuildIndexes(name string, layout *rm.Layout, tx *tx.Transaction) error {
+	for field, ii := range b.mdm.GetIndexInfo(name, tx) {
+		idx := ii.Open()
+		if err := idx.Clear(); err != nil {
+			return err
+		}
+		ts, err := rm.NewTableScan(tx, name, layout)
+		if err != nil {
+			return err
+		}
+		for ts.Next() {
+			idx.Insert(ts.GetVal(field), ts.GetRid().(*rm.RID))
+		}
+		ts.Close()
+		idx.Close()
+	}
+	return nil
+}
+
+//checkView 视图的定义是否还能生成查询计划
+func (b *BasicUpdatePlanner) checkView(view string, tx *tx.Transaction) error {
+	viewDef, err := b.mdm.GetViewDef(view, tx)
+	if err != nil {
+		return err
+	}
+	data, err := parser.NewSQLParser(viewDef).Query()
+	if err != nil {
+		return err
+	}
+	_, err = NewBasicQueryPlan(b.mdm).CreatePlan(data, tx)
+	return err
+}
@@ -0,0 +1,131 @@
+package planner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDrop(t *testing.T) {
+	p, t1, db := newTestPlanner(t, "drop_test")
+	for _, sql := range []string{
+		"create table emp (id int, name varchar(10), dept int, sal int)",
+		"create table dept (did int, title varchar(10))",
+		"insert into emp (id, name, dept, sal) values (1, 'ann', 1, 30), (2, 'ben', 2, 10)",
+		"insert into dept (did, title) values (1, 'sales'), (2, 'tech')",
+		"create index empdept on emp (dept)",
+		"create view rich as select name from emp where sal > 20",
+		"create view richer as select name from rich where name <> 'x'",
+		"create view titles as select title from dept where did in (select dept from emp)",
+		"create view depts as select title from dept",
+	} {
+		_, err := p.ExecuteUpdate(sql, t1)
+		assert.Nil(t, err, sql)
+	}
+	t1.Commit()
+	assert.True(t, db.exists("emp.tbl"))
+	assert.True(t, db.exists("empdept#*.tbl"))
+
+	t2 := db.newTx()
+	for sql, target := range map[string]error{
+		"drop table emp":    ErrDependentObjects,
+		"drop view rich":    ErrDependentObjects,
+		"drop table nosuch": ErrTableNotFound,
+		"drop table rich":   ErrTableNotFound,
+		"drop view emp":     ErrViewNotFound,
+		"drop index nosuch": ErrIndexNotFound,
+		"drop table tblcat": ErrCatalogTable,
+		"drop table idxcat": ErrCatalogTable,
+	} {
+		_, err := p.ExecuteUpdate(sql, t2)
+		assert.ErrorIs(t, err, target, sql)
+	}
+	for _, sql := range []string{
+		"drop table if exists nosuch",
+		"drop view if exists nosuch cascade",
+		"drop index if exists nosuch",
+	} {
+		_, err := p.ExecuteUpdate(sql, t2)
+		assert.Nil(t, err, sql)
+	}
+
+	//CASCADE同时删除直接和间接依赖这张表的视图，子查询中引用也是依赖
+	_, err := p.ExecuteUpdate("drop table emp cascade", t2)
+	assert.Nil(t, err)
+	for _, view := range []string{"rich", "richer", "titles"} {
+		_, err = p.CreateQueryPlan("select * from "+view, t2)
+		assert.ErrorIs(t, err, ErrTableNotFound, view)
+	}
+	assert.Equal(t, []string{"sales", "tech"}, collectRows(t, p, "select title from depts order by title", t2))
+	assert.Equal(t, 0, len(db.mdm.GetIndexInfo("emp", t2)))
+	//文件在事务提交之后才删除
+	assert.True(t, db.exists("emp.tbl"))
+	t2.Commit()
+	assert.False(t, db.exists("emp.tbl"))
+	assert.False(t, db.exists("empdept#*.tbl"))
+
+	//同名的表重新创建之后是空的
+	t3 := db.newTx()
+	_, err = p.ExecuteUpdate("create table emp (id int, name varchar(10))", t3)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, collectRows(t, p, "select id, name from emp", t3))
+	_, err = p.ExecuteUpdate("insert into emp (id, name) values (7, 'cid')", t3)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"7 cid"}, collectRows(t, p, "select id, name from emp", t3))
+	_, err = p.ExecuteUpdate("create index deptidx on dept (did)", t3)
+	assert.Nil(t, err)
+	_, err = p.ExecuteUpdate("drop index deptidx", t3)
+	assert.Nil(t, err)
+	t3.Commit()
+	assert.False(t, db.exists("deptidx#*.tbl"))
+
+	//回滚之后表和文件都还在
+	t4 := db.newTx()
+	_, err = p.ExecuteUpdate("drop view depts", t4)
+	assert.Nil(t, err)
+	_, err = p.ExecuteUpdate("drop table dept", t4)
+	assert.Nil(t, err)
+	assert.Nil(t, t4.RollBack())
+	t5 := db.newTx()
+	lsn := t5.Savepoint()
+	_, err = p.ExecuteUpdate("drop table dept cascade", t5)
+	assert.Nil(t, err)
+	t5.RollBackTo(lsn)
+	assert.Equal(t, []string{"sales", "tech"}, collectRows(t, p, "select title from depts order by title", t5))
+	t5.Commit()
+	assert.True(t, db.exists("dept.tbl"))
+
+	//同一个事务中删除之后重新创建，原来的记录被清空，提交之后文件不会被删除
+	t6 := db.newTx()
+	for _, sql := range []string{
+		"create index empid on emp (id)",
+		"drop table emp",
+		"create table emp (id int, title varchar(20), sal int)",
+	} {
+		_, err = p.ExecuteUpdate(sql, t6)
+		assert.Nil(t, err, sql)
+	}
+	assert.Equal(t, []string{}, collectRows(t, p, "select id, title, sal from emp", t6))
+	for _, sql := range []string{
+		"insert into emp (id, title, sal) values (8, 'boss', 99)",
+		"create index empid on emp (id)",
+	} {
+		_, err = p.ExecuteUpdate(sql, t6)
+		assert.Nil(t, err, sql)
+	}
+	t6.Commit()
+	assert.True(t, db.exists("emp.tbl"))
+	t7 := db.newTx()
+	assert.Equal(t, []string{"8 boss 99"}, collectRows(t, p, "select id, title, sal from emp", t7))
+	assert.Equal(t, []string{"8"}, indexedIDs(t, db.mdm, "emp", "id", 8, t7))
+
+	//回滚到重新创建之前的保存点，文件仍然在提交之后删除
+	_, err = p.ExecuteUpdate("drop table emp", t7)
+	assert.Nil(t, err)
+	lsn = t7.Savepoint()
+	_, err = p.ExecuteUpdate("create table emp (id int)", t7)
+	assert.Nil(t, err)
+	t7.RollBackTo(lsn)
+	t7.Commit()
+	assert.False(t, db.exists("emp.tbl"))
+	assert.False(t, db.exists("empid#*.tbl"))
+}
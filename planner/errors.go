@@ -0,0 +1,32 @@
+package planner
+
+import (
+	"errors"
+)
+
+var (
+	ErrTableNotFound    = errors.New("table not found")
+	ErrFieldNotFound    = errors.New("field not found")
+	ErrNotQuery         = errors.New("statement is not a query")
+	ErrNotUpdate        = errors.New("statement is not an update command")
+	ErrTypeMismatch     = errors.New("value type does not match field type")
+	ErrNotGrouped       = errors.New("field must appear in the GROUP BY clause or be used in an aggregate function")
+	ErrNegativeLimit    = errors.New("LIMIT and OFFSET must not be negative")
+	ErrNotSelected      = errors.New("for SELECT DISTINCT, ORDER BY field must appear in the select list")
+	ErrAmbiguousField   = errors.New("field reference is ambiguous")
+	ErrDuplicateAlias   = errors.New("table name specified more than once")
+	ErrSubqueryColumns  = errors.New("subquery must return only one column")
+	ErrSetColumns       = errors.New("each UNION, INTERSECT or EXCEPT query must have the same number of columns")
+	ErrColumnCount      = errors.New("number of values does not match number of columns")
+	ErrViewNotFound     = errors.New("view not found")
+	ErrIndexNotFound    = errors.New("index not found")
+	ErrIndexExists      = errors.New("index already exists")
+	ErrDependentObjects = errors.New("cannot drop or alter because other objects depend on it")
+	ErrCatalogTable     = errors.New("cannot drop or alter a system catalog table")
+	ErrDuplicateField   = errors.New("field already exists")
+	ErrTableExists      = errors.New("table or view already exists")
+	ErrRecursiveView    = errors.New("view definition references the view itself")
+	ErrLastField        = errors.New("cannot drop the only field of a table")
+	ErrTooManyFields    = errors.New("too many fields in a table")
+	ErrValueTooLong     = errors.New("value too long for field")
+)
@@ -0,0 +1,181 @@
+package planner
+
+import (
+	"fmt"
+	"miniSQL/comm"
+	"miniSQL/parser"
+	"miniSQL/query"
+	"strings"
+)
+
+//Explain 把查询树按照缩进的形式输出，每一个节点都带上估算出来的块访问数，输出记录数以及成本
+//Project(name)  blocks=1 rows=3 cost=4.50
+//  Select(gradyear=2020)  blocks=1 rows=3 cost=3.20
+//    Table(student)  blocks=1 rows=9 cost=1.90
+func Explain(p Plan) string {
+	var sb strings.Builder
+	explain(&sb, p, 0)
+	return sb.String()
+}
+
+func explain(sb *strings.Builder, p Plan, depth int) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	switch plan := p.(type) {
+	case *TablePlan:
+		fmt.Fprintf(sb, "Table(%s)", plan.tblName)
+	case *SelectPlan:
+		fmt.Fprintf(sb, "Select(%s)", plan.pred.ToString())
+	case *ProjectPlan:
+		fmt.Fprintf(sb, "Project(%s)", strings.Join(plan.schema.Fields(), ", "))
+	case *ExtendPlan:
+		columns := make([]string, len(plan.fields))
+		for i, field := range plan.fields {
+			columns[i] = plan.exprs[i].ToString() + " AS " + field
+		}
+		fmt.Fprintf(sb, "Extend(%s)", strings.Join(columns, ", "))
+	case *SortPlan:
+		fmt.Fprintf(sb, "Sort(%s)", query.SortKeysToString(plan.keys))
+	case *ProductPlan:
+		sb.WriteString("Product")
+	case *AliasPlan:
+		fmt.Fprintf(sb, "Alias(%s)", plan.name)
+	case *OuterRefPlan:
+		fmt.Fprintf(sb, "OuterRef(%s)", strings.Join(plan.schema.Fields(), ", "))
+	case *SemiJoinPlan:
+		name := "SemiJoin"
+		if plan.anti {
+			name = "AntiJoin"
+		}
+		keys := make([]string, len(plan.outerKeys))
+		for i := range plan.outerKeys {
+			keys[i] = plan.outerKeys[i].ToString() + "=" + plan.innerKeys[i].ToString()
+		}
+		fmt.Fprintf(sb, "%s(%s)", name, strings.Join(keys, " AND "))
+	case *JoinPlan:
+		cond := plan.pred.ToString()
+		if len(plan.using) > 0 {
+			cond = "USING " + strings.Join(plan.using, ", ")
+		}
+		fmt.Fprintf(sb, "%s(%s)", joinNames[plan.joinType], cond)
+	case *TopNPlan:
+		fmt.Fprintf(sb, "TopN(%s)", query.SortKeysToString(plan.keys))
+	case *SetOpPlan:
+		name := setOpNames[plan.op]
+		if plan.all {
+			name += "All"
+		}
+		if plan.sorted {
+			fmt.Fprintf(sb, "%s(%s)", name, query.SortKeysToString(plan.lhsKeys))
+		} else {
+			sb.WriteString(name)
+		}
+	case *LimitPlan:
+		sb.WriteString("Limit(" + limitString(plan.limit))
+		if plan.offset != nil {
+			sb.WriteString(" OFFSET " + limitString(plan.offset))
+		}
+		sb.WriteString(")")
+	case *GroupByPlan:
+		aggs := make([]string, 0, len(plan.aggs))
+		for _, agg := range plan.aggs {
+			aggs = append(aggs, agg.Name())
+		}
+		name := "GroupBy"
+		if plan.distinct {
+			name = "Distinct"
+		}
+		if plan.hash {
+			name = "Hash" + name
+		}
+		if plan.distinct {
+			fmt.Fprintf(sb, "%s(%s)", name, strings.Join(plan.groupFields, ", "))
+		} else {
+			fmt.Fprintf(sb, "%s(%s; %s)", name, strings.Join(plan.groupFields, ", "), strings.Join(aggs, ", "))
+		}
+	default:
+		fmt.Fprintf(sb, "%T", p)
+	}
+	fmt.Fprintf(sb, "  blocks=%d rows=%d cost=%.2f\n", p.BlockAccessed(), p.RecordsOutput(), p.Cost())
+	//子查询的计划放在节点的子节点前面，相关子查询在每一条记录上都会执行一次
+	for _, sub := range subPlans(p) {
+		sb.WriteString(strings.Repeat("  ", depth+1))
+		if sub.IsCorrelated() {
+			fmt.Fprintf(sb, "SubPlan(correlated: %s)\n", strings.Join(sub.OuterFields(), ", "))
+		} else {
+			sb.WriteString("SubPlan\n")
+		}
+		explain(sb, sub.Plan().(Plan), depth+2)
+	}
+	for _, child := range childPlans(p) {
+		explain(sb, child, depth+1)
+	}
+}
+
+var joinNames = map[parser.JoinType]string{
+	parser.INNER_JOIN: "Join",
+	parser.LEFT_JOIN:  "LeftJoin",
+	parser.RIGHT_JOIN: "RightJoin",
+	parser.FULL_JOIN:  "FullJoin",
+}
+
+var setOpNames = map[parser.SetOp]string{
+	parser.UNION:     "Union",
+	parser.INTERSECT: "Intersect",
+	parser.EXCEPT:    "Except",
+}
+
+//limitString 预处理语句中还没有绑定值的参数槽输出成?
+func limitString(c *comm.Constant) string {
+	if c.IsNull() {
+		return "?"
+	}
+	return query.NewExpressionWithConstant(c).ToString()
+}
+
+//childPlans 查询树中一个节点的所有子节点
+func childPlans(p Plan) []Plan {
+	switch plan := p.(type) {
+	case *SelectPlan:
+		return []Plan{plan.p}
+	case *ProjectPlan:
+		return []Plan{plan.p}
+	case *ExtendPlan:
+		return []Plan{plan.p}
+	case *AliasPlan:
+		return []Plan{plan.p}
+	case *SortPlan:
+		return []Plan{plan.p}
+	case *TopNPlan:
+		return []Plan{plan.p}
+	case *LimitPlan:
+		return []Plan{plan.p}
+	case *ProductPlan:
+		return plan.planOrders
+	case *JoinPlan:
+		outer, inner := plan.outerInner()
+		return []Plan{outer, inner}
+	case *SemiJoinPlan:
+		return []Plan{plan.p, plan.inner}
+	case *SetOpPlan:
+		return []Plan{plan.lhs, plan.rhs}
+	case *GroupByPlan:
+		if plan.sorted != nil {
+			return []Plan{plan.sorted}
+		}
+		return []Plan{plan.p}
+	}
+	return nil
+}
+
+//subPlans 查询树中一个节点的条件和表达式中的子查询
+func subPlans(p Plan) []*query.Subquery {
+	switch plan := p.(type) {
+	case *SelectPlan:
+		return plan.pred.Subqueries()
+	case *ExtendPlan:
+		return exprsSubqueries(plan.exprs)
+	case *JoinPlan:
+		return plan.pred.Subqueries()
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package planner
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/comm"
+	"strings"
+	"testing"
+)
+
+func TestExpressionPlan(t *testing.T) {
+	p, tx, _ := newTestPlanner(t, "expression_test")
+	defer tx.Commit()
+	_, err := p.ExecuteUpdate("create table course (title varchar(16),deptId int)", tx)
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = p.ExecuteUpdate(fmt.Sprintf("insert into course (title,deptId) values (concat('c', %d), %d * 2 - 1)", i, i), tx)
+		assert.Nil(t, err)
+	}
+
+	//选择列表中的表达式和别名，ORDER BY中可以使用别名
+	sql := "select title, deptId + 1 as next, upper(title) from course where deptId * 2 > 10 order by next desc"
+	assert.Equal(t, []string{"c9 18 C9", "c8 16 C8", "c7 14 C7", "c6 12 C6", "c5 10 C5", "c4 8 C4"}, collectRows(t, p, sql, tx))
+	plan, err := p.CreateQueryPlan(sql, tx)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(Explain(plan), "Extend("), Explain(plan))
+	assert.Equal(t, []string{"c1", "c3", "c5", "c7", "c9"}, collectRows(t, p, "select title from course where deptId % 4 = 1 and length(title) = 2", tx))
+
+	//聚合函数的结果也可以参与运算
+	assert.Equal(t, []string{"11"}, collectRows(t, p, "select count(*) + 1 as n from course", tx))
+
+	n, err := p.ExecuteUpdate("update course set deptId = -deptId + 100 where title = 'c0'", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"c0 101"}, collectRows(t, p, "select title, deptId from course where deptId > 100", tx))
+
+	//创建计划的时候检查类型
+	for _, sql := range []string{
+		"select title + 1 from course",
+		"select upper(deptId) from course",
+		"select title from course where title = deptId * 2",
+		"update course set title = deptId + 1",
+		"insert into course (title,deptId) values ('x', upper('y'))",
+	} {
+		_, err = p.CreateQueryPlan(sql, tx)
+		if strings.HasPrefix(sql, "select") {
+			assert.ErrorIs(t, err, ErrTypeMismatch, sql)
+		} else {
+			_, err = p.ExecuteUpdate(sql, tx)
+			assert.ErrorIs(t, err, ErrTypeMismatch, sql)
+		}
+	}
+	_, err = p.CreateQueryPlan("select nothing + 1 from course", tx)
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+
+	//除数为零在执行的时候返回错误
+	_, err = p.ExecuteUpdate("update course set deptId = deptId / 0", tx)
+	assert.ErrorIs(t, err, comm.ErrDivisionByZero)
+	_, err = p.ExecuteUpdate("insert into course (title,deptId) values ('x', 1 % 0)", tx)
+	assert.ErrorIs(t, err, comm.ErrDivisionByZero)
+}
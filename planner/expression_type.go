@@ -0,0 +1,297 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"miniSQL/comm"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	"strconv"
+)
+
+/*
+	创建计划的时候检查表达式的类型：
+	1.表达式中用到的字段必须存在
+	2.算术运算符的操作数必须是数值，函数的参数必须是函数要求的类型
+	3.比较运算符两边的类型必须相同，不同的数值类型之间可以比较
+	算术运算的结果是操作数中范围最大的类型，按照INTEGER，BIGINT，DECIMAL，DOUBLE的顺序
+	写入字段的值可以转换成范围更大的数值类型，例如整数可以写入DECIMAL字段，定点数可以写入DOUBLE字段，反过来不行
+	4.标量子查询和IN中的子查询只能有一个字段，类型是这个字段的类型
+	还没有绑定值的参数槽类型为PARAM_UNKNOWN，可以和任意的类型一起使用
+	计算出来的字符串字段需要知道最大长度，排序等操作把记录写入临时表的时候使用
+*/
+
+const (
+	intStringLength     = 20 //整数转换成字符串之后的最大长度
+	floatStringLength   = 24 //浮点数转换成字符串之后的最大长度
+	boolStringLength    = 5  //TRUE和FALSE转换成字符串之后的最大长度
+	unknownStringLength = 64 //还没有绑定值的参数槽转换成字符串之后假设的长度
+)
+
+//numericRank 数值类型的范围，不是数值类型的时候返回0
+func numericRank(fieldType rm.FIELD_TYPE) int {
+	switch fieldType {
+	case rm.INTEGER:
+		return 1
+	case rm.BIGINT:
+		return 2
+	case rm.DECIMAL:
+		return 3
+	case rm.DOUBLE:
+		return 4
+	}
+	return 0
+}
+
+//comparableTypes 比较运算两边的类型相同，或者都是数值类型
+func comparableTypes(a rm.FIELD_TYPE, b rm.FIELD_TYPE) bool {
+	return a == b || rm.IsNumeric(a) && rm.IsNumeric(b)
+}
+
+//assignable valType类型的值是否可以写入fieldType类型的字段，INTEGER和BIGINT在内存中都是整数，可以互相写入
+func assignable(valType rm.FIELD_TYPE, fieldType rm.FIELD_TYPE) bool {
+	if valType == fieldType || valType == PARAM_UNKNOWN {
+		return true
+	}
+	if valType == rm.BIGINT && fieldType == rm.INTEGER {
+		return true
+	}
+	return numericRank(valType) > 0 && numericRank(valType) <= numericRank(fieldType)
+}
+
+//exprType 推断表达式结果的类型，结果是字符串的时候同时返回最大长度
+func exprType(e *query.Expression, sch rm.SchemaInterface) (rm.FIELD_TYPE, int, error) {
+	switch {
+	case e.IsConstant():
+		c := e.AsConstant()
+		switch {
+		case c.Ival != nil:
+			return rm.INTEGER, 0, nil
+		case c.Sval != nil:
+			return rm.VARCHAR, len(*c.Sval), nil
+		case c.Bval != nil:
+			return rm.BOOLEAN, 0, nil
+		case c.Fval != nil:
+			return rm.DOUBLE, 0, nil
+		case c.Dval != nil:
+			return rm.DECIMAL, rm.DecimalLength(comm.MAX_DECIMAL_PRECISION, c.Dval.Scale), nil
+		}
+		return PARAM_UNKNOWN, unknownStringLength, nil
+	case e.IsFieldName():
+		if !sch.HashField(e.AsFieldName()) {
+			return 0, 0, fmt.Errorf("%w: %s", ErrFieldNotFound, e.AsFieldName())
+		}
+		return sch.Type(e.AsFieldName()), sch.Length(e.AsFieldName()), nil
+	case e.Subquery() != nil:
+		subSch := e.Subquery().Plan().Schema()
+		if len(subSch.Fields()) != 1 {
+			return 0, 0, fmt.Errorf("%w: %s", ErrSubqueryColumns, e.ToString())
+		}
+		field := subSch.Fields()[0]
+		return subSch.Type(field), subSch.Length(field), nil
+	}
+	types := make([]rm.FIELD_TYPE, len(e.Args()))
+	lengths := make([]int, len(e.Args()))
+	for i, arg := range e.Args() {
+		argType, length, err := exprType(arg, sch)
+		if err != nil {
+			return 0, 0, err
+		}
+		types[i], lengths[i] = argType, length
+	}
+	fn := e.Function()
+	if fn == nil {
+		return arithmeticType(e, types, lengths)
+	}
+	for i, argType := range types {
+		expected := fn.ArgType(i)
+		if expected != query.TYPE_ANY && !assignable(argType, expected) {
+			return 0, 0, fmt.Errorf("%w: wrong argument type in %s", ErrTypeMismatch, e.ToString())
+		}
+	}
+	if fn.ResultType() != rm.VARCHAR {
+		return fn.ResultType(), 0, nil
+	}
+	if fn.Name() != "concat" {
+		//upper，lower，substr的结果不会比第一个参数长
+		return rm.VARCHAR, lengths[0], nil
+	}
+	length := 0
+	for i, argType := range types {
+		length += stringLength(argType, lengths[i])
+	}
+	return rm.VARCHAR, length, nil
+}
+
+//arithmeticType 算术运算的结果是操作数中范围最大的类型，定点数的小数位数和comm中定点数的运算规则相同
+func arithmeticType(e *query.Expression, types []rm.FIELD_TYPE, lengths []int) (rm.FIELD_TYPE, int, error) {
+	resultType, scales := rm.INTEGER, make([]int, len(types))
+	for i, argType := range types {
+		if argType != PARAM_UNKNOWN && !rm.IsNumeric(argType) {
+			return 0, 0, fmt.Errorf("%w: %s requires numeric operands", ErrTypeMismatch, e.ToString())
+		}
+		if numericRank(argType) > numericRank(resultType) {
+			resultType = argType
+		}
+		if argType == rm.DECIMAL {
+			scales[i] = rm.DecimalScale(lengths[i])
+		}
+	}
+	if resultType != rm.DECIMAL {
+		return resultType, 0, nil
+	}
+	scale := scales[0]
+	switch e.Op() {
+	case query.ARITH_ADD, query.ARITH_SUB, query.ARITH_MOD:
+		if scales[1] > scale {
+			scale = scales[1]
+		}
+	case query.ARITH_MUL:
+		scale += scales[1]
+	case query.ARITH_DIV:
+		scale += comm.DECIMAL_DIV_SCALE
+	}
+	if scale > comm.MAX_DECIMAL_PRECISION {
+		scale = comm.MAX_DECIMAL_PRECISION
+	}
+	return rm.DECIMAL, rm.DecimalLength(comm.MAX_DECIMAL_PRECISION, scale), nil
+}
+
+//stringLength 值转换成字符串之后的最大长度
+func stringLength(fieldType rm.FIELD_TYPE, length int) int {
+	switch fieldType {
+	case rm.INTEGER, rm.BIGINT:
+		return intStringLength
+	case rm.DOUBLE:
+		return floatStringLength
+	case rm.BOOLEAN:
+		return boolStringLength
+	case rm.DECIMAL:
+		//符号，小数点和前导的0
+		return rm.DecimalPrecision(length) + 3
+	}
+	return length
+}
+
+//checkPredicate 检查条件中每一个比较两边表达式的类型
+func checkPredicate(pred *query.Predicate, sch rm.SchemaInterface) error {
+	if pred == nil {
+		return nil
+	}
+	for _, term := range pred.Comparisons() {
+		lhsType, _, err := exprType(term.Lhs(), sch)
+		if err != nil {
+			return err
+		}
+		rhsType, _, err := exprType(term.Rhs(), sch)
+		if err != nil {
+			return err
+		}
+		if !comparableTypes(lhsType, rhsType) && lhsType != PARAM_UNKNOWN && rhsType != PARAM_UNKNOWN {
+			return fmt.Errorf("%w: %s", ErrTypeMismatch, term.ToString())
+		}
+	}
+	return nil
+}
+
+//checkAssign 检查写入到字段中的值的类型
+func checkAssign(sch rm.SchemaInterface, field string, val *query.Expression, valSch rm.SchemaInterface) error {
+	if !sch.HashField(field) {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	valType, _, err := exprType(val, valSch)
+	if err != nil {
+		return err
+	}
+	if !assignable(valType, sch.Type(field)) {
+		return fmt.Errorf("%w: %s", ErrTypeMismatch, field)
+	}
+	return nil
+}
+
+//parseDefault 把defcat中保存的默认值按照字段的类型解析出来，默认值是写入时ToString的结果
+func parseDefault(text string, sch rm.SchemaInterface, field string) (*comm.Constant, error) {
+	var val *comm.Constant
+	switch sch.Type(field) {
+	case rm.INTEGER, rm.BIGINT:
+		i, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ival := int(i)
+		val = comm.NewConstantInt(&ival)
+	case rm.BOOLEAN:
+		b := text == "TRUE"
+		val = comm.NewConstantBool(&b)
+	case rm.DOUBLE:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, err
+		}
+		val = comm.NewConstantFloat(&f)
+	case rm.DECIMAL:
+		d, err := comm.ParseDecimal(text)
+		if err != nil {
+			return nil, err
+		}
+		val = comm.NewConstantDecimal(&d)
+	default:
+		val = comm.NewConstantString(&text)
+	}
+	return convertValue(val, sch, field)
+}
+
+//convertValue 写入字段之前把值转换成字段的类型，值的类型已经使用checkAssign检查过了，字符串的长度不能超过字段的长度
+//写入INTEGER字段的整数超出32位的时候返回错误，写入DOUBLE字段的数值转换成浮点数，写入DECIMAL(p,s)字段的值四舍五入到s位小数，整数部分超过p-s位的时候返回错误
+//还没有绑定值的参数槽在检查的时候可以是任意类型，所以这里类型不对的时候也返回错误
+func convertValue(val *comm.Constant, sch rm.SchemaInterface, field string) (*comm.Constant, error) {
+	if val.IsNull() {
+		return val, nil
+	}
+	switch sch.Type(field) {
+	case rm.INTEGER:
+		//INTEGER和BIGINT在内存中都是int，写入INTEGER字段的值必须在32位的范围之内
+		if val.Ival != nil {
+			if *val.Ival < math.MinInt32 || *val.Ival > math.MaxInt32 {
+				return nil, fmt.Errorf("%w: %s INTEGER, got %d", comm.ErrIntegerOverflow, field, *val.Ival)
+			}
+			return val, nil
+		}
+	case rm.BIGINT:
+		if val.Ival != nil {
+			return val, nil
+		}
+	case rm.BOOLEAN:
+		if val.Bval != nil {
+			return val, nil
+		}
+	case rm.DOUBLE:
+		if val.IsNumber() {
+			f := val.AsFloat()
+			return comm.NewConstantFloat(&f), nil
+		}
+	case rm.DECIMAL:
+		if val.Ival != nil || val.Dval != nil {
+			return convertDecimal(val.AsDecimal(), sch.Length(field))
+		}
+	default:
+		if val.Sval != nil {
+			//字符串在记录中占用的字节数是固定的，超过的部分会覆盖后面的字段
+			if len(*val.Sval) > sch.Length(field) {
+				return nil, fmt.Errorf("%w: %s %s, got %d bytes", ErrValueTooLong, field, rm.TypeName(sch.Type(field), sch.Length(field)), len(*val.Sval))
+			}
+			return val, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTypeMismatch, field)
+}
+
+//convertDecimal 把定点数转换成长度为length的DECIMAL字段中保存的形式
+func convertDecimal(d comm.Decimal, length int) (*comm.Constant, error) {
+	precision, scale := rm.DecimalPrecision(length), rm.DecimalScale(length)
+	v, err := d.Rescale(scale)
+	if err != nil || v.IntegerDigits() > precision-scale {
+		return nil, fmt.Errorf("%w: %s does not fit in %s", comm.ErrDecimalOverflow, d.String(), rm.TypeName(rm.DECIMAL, length))
+	}
+	return comm.NewConstantDecimal(&v), nil
+}
@@ -0,0 +1,80 @@
+package planner
+
+import (
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+)
+
+//ExtendPlan 在p的输出上增加计算出来的字段，选择的字段中有表达式或者别名的时候使用
+//放在排序和投影的下面，这样ORDER BY中可以使用别名，投影的时候也只需要按照名字挑选字段
+type ExtendPlan struct {
+	p      Plan
+	fields []string
+	exprs  []*query.Expression
+	schema *rm.Schema
+	cost   float64
+}
+
+//NewExtendPlan 增加名字为fields[i]，值为exprs[i]的字段，表达式的类型需要提前使用exprType检查过
+func NewExtendPlan(p Plan, fields []string, exprs []*query.Expression) *ExtendPlan {
+	extendPlan := &ExtendPlan{
+		p:      p,
+		fields: fields,
+		exprs:  exprs,
+		schema: rm.NewSchema(),
+		cost:   p.Cost() + float64(p.RecordsOutput())*cpuCost, //每条记录都需要计算一次表达式
+	}
+	extendPlan.schema.AddAll(p.Schema())
+	for i, field := range fields {
+		//只有一个参数槽的时候不知道它的类型，按照字符串处理
+		fieldType, length, _ := exprType(exprs[i], p.Schema())
+		if fieldType == PARAM_UNKNOWN {
+			extendPlan.schema.AddStringField(field, length)
+		} else {
+			extendPlan.schema.AddField(field, fieldType, length)
+		}
+	}
+	return extendPlan
+}
+
+func (e *ExtendPlan) Open() (interface{}, error) {
+	resetSubqueries(exprsSubqueries(e.exprs))
+	s, err := e.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	return query.NewExtendScan(s.(query.Scan), e.fields, e.exprs), nil
+}
+
+func (e *ExtendPlan) BlockAccessed() int {
+	return e.p.BlockAccessed()
+}
+
+func (e *ExtendPlan) RecordsOutput() int {
+	return e.p.RecordsOutput()
+}
+
+//DistinctValues 别名和底层的字段相同，计算出来的字段最多每条记录一个不同的值
+func (e *ExtendPlan) DistinctValues(fldName string) int {
+	for i, field := range e.fields {
+		if field != fldName {
+			continue
+		}
+		if e.exprs[i].IsFieldName() {
+			return e.p.DistinctValues(e.exprs[i].AsFieldName())
+		}
+		if e.exprs[i].IsConstant() {
+			return 1
+		}
+		return e.p.RecordsOutput()
+	}
+	return e.p.DistinctValues(fldName)
+}
+
+func (e *ExtendPlan) Schema() rm.SchemaInterface {
+	return e.schema
+}
+
+func (e *ExtendPlan) Cost() float64 {
+	return e.cost
+}
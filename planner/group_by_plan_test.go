@@ -0,0 +1,114 @@
+package planner
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	"strings"
+	"testing"
+)
+
+func TestGroupByPlan(t *testing.T) {
+	p, tx, db := newTestPlanner(t, "group_test")
+	defer tx.Commit()
+	_, err := p.ExecuteUpdate("create table course (title varchar(16),deptId int,credit int)", tx)
+	assert.Nil(t, err)
+	for i, title := range []string{"db", "os", "ml", "ai", "go"} {
+		_, err = p.ExecuteUpdate(fmt.Sprintf("insert into course (title,deptId,credit) values ('%s',%d,%d)", title, 10*(i%2), i+1), tx)
+		assert.Nil(t, err)
+	}
+
+	rows := collectRows(t, p, "select deptId, count(*), sum(credit), avg(credit), min(title), max(title) from course group by deptId order by deptId", tx)
+	assert.Equal(t, []string{"0 3 9 3 db ml", "10 2 6 3 ai os"}, rows)
+	rows = collectRows(t, p, "select deptId from course group by deptId having count(*) = 2", tx)
+	assert.Equal(t, []string{"10"}, rows)
+	rows = collectRows(t, p, "select count(credit), max(credit) from course where deptId = 10", tx)
+	assert.Equal(t, []string{"2 4"}, rows)
+	//没有GROUP BY的时候即使没有记录也返回一条结果，SUM没有值的时候是NULL
+	rows = collectRows(t, p, "select count(*), sum(credit) from course where deptId = 20", tx)
+	assert.Equal(t, []string{"0 NULL"}, rows)
+	rows = collectRows(t, p, "select deptId, count(*) from course where deptId = 20 group by deptId", tx)
+	assert.Equal(t, []string{}, rows)
+
+	plan, err := p.CreateQueryPlan("select deptId, count(*) from course group by deptId", tx)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(Explain(plan), "GroupBy(deptId; count(*))"))
+	assert.Equal(t, 2, plan.RecordsOutput())
+
+	_, err = p.CreateQueryPlan("select title, count(*) from course group by deptId", tx)
+	assert.ErrorIs(t, err, ErrNotGrouped)
+	_, err = p.CreateQueryPlan("select deptId from course group by deptId order by credit", tx)
+	assert.ErrorIs(t, err, ErrNotGrouped)
+	_, err = p.CreateQueryPlan("select sum(title) from course", tx)
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = p.CreateQueryPlan("select max(grade) from course", tx)
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+
+	//组数超过哈希表容量的时候写入分区，结果和排序分组相同
+	const numRecords = 300
+	for i := 0; i < numRecords; i++ {
+		_, err = p.ExecuteUpdate(fmt.Sprintf("insert into course (title,deptId,credit) values ('c%03d',%d,%d)", i, 100+i%97, i), tx)
+		assert.Nil(t, err)
+	}
+	tablePlan, err := NewTablePlan(tx, "course", db.mdm)
+	assert.Nil(t, err)
+	aggs := []*query.Aggregate{query.NewAggregate("count", "*"), query.NewAggregate("sum", "credit"), query.NewAggregate("max", "title")}
+	types := []rm.FIELD_TYPE{rm.INTEGER, rm.INTEGER, rm.VARCHAR}
+	groupFields := []string{"deptId"}
+
+	sorted, err := NewSortPlan(tx, tablePlan, []*query.SortKey{query.NewSortKey("deptId", false)}).Open()
+	assert.Nil(t, err)
+	expected := groupResults(query.NewGroupByScan(sorted.(query.Scan), groupFields, aggs, types))
+	assert.Equal(t, 99, len(expected))
+
+	src, err := tablePlan.Open()
+	assert.Nil(t, err)
+	partitions := 0
+	newTemp := func() query.TempTable {
+		partitions++
+		return NewTempTable(tx, tablePlan.Schema())
+	}
+	hashScan := query.NewHashGroupByScan(src.(query.Scan), tablePlan.Schema().Fields(), groupFields, aggs, types, 10, 10, 3, newTemp)
+	actual := groupResults(hashScan)
+	assert.True(t, partitions > 3, "partitions %d", partitions)
+	assert.Equal(t, expected, actual)
+}
+
+func TestDistinctPlan(t *testing.T) {
+	p, tx, _ := newTestPlanner(t, "distinct_test")
+	defer tx.Commit()
+	_, err := p.ExecuteUpdate("create table course (title varchar(16),deptId int,credit int)", tx)
+	assert.Nil(t, err)
+	for i, title := range []string{"db", "os", "ml", "ai", "go", "db"} {
+		_, err = p.ExecuteUpdate(fmt.Sprintf("insert into course (title,deptId,credit) values ('%s',%d,%d)", title, 10*(i%2), 3), tx)
+		assert.Nil(t, err)
+	}
+
+	rows := collectRows(t, p, "select distinct deptId, credit from course order by deptId desc", tx)
+	assert.Equal(t, []string{"10 3", "0 3"}, rows)
+	rows = collectRows(t, p, "select distinct title from course where deptId = 0 order by title", tx)
+	assert.Equal(t, []string{"db", "go", "ml"}, rows)
+	rows = collectRows(t, p, "select distinct credit from course", tx)
+	assert.Equal(t, []string{"3"}, rows)
+	rows = collectRows(t, p, "select distinct deptId, count(*) from course group by deptId order by deptId", tx)
+	assert.Equal(t, []string{"0 3", "10 3"}, rows)
+
+	plan, err := p.CreateQueryPlan("select distinct deptId from course", tx)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(Explain(plan), "Distinct(deptId)"), Explain(plan))
+	assert.True(t, plan.(*GroupByPlan).Distinct())
+
+	_, err = p.CreateQueryPlan("select distinct title from course order by deptId", tx)
+	assert.ErrorIs(t, err, ErrNotSelected)
+}
+
+//groupResults 把分组的结果按照分组字段的值保存下来
+func groupResults(s query.Scan) map[int]string {
+	defer s.Close()
+	results := make(map[int]string)
+	for s.Next() {
+		results[s.GetInt("deptId")] = fmt.Sprintf("%d %d %s", s.GetInt("count(*)"), s.GetInt("sum(credit)"), s.GetString("max(title)"))
+	}
+	return results
+}
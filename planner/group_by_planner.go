@@ -0,0 +1,247 @@
+package planner
+
+import (
+	"math"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+)
+
+/*
+	GroupByPlan 实现GROUP BY和聚合函数，有两种执行方式：
+	1.排序分组：先使用SortPlan按照分组字段排序，再由GroupByScan依次读取每一组
+	2.哈希聚合：HashGroupByScan在内存中使用哈希表保存每一组，组数超过内存容量的时候把记录分区写入到临时表中
+	组数使用分组字段的DistinctValues来估算，哈希聚合的组数放不下内存的时候需要额外的读写分区
+	如果ORDER BY只使用了分组字段，排序分组的时候按照ORDER BY的顺序排序，这样得到的结果已经是有序的，不需要再排序一次
+	创建计划的时候比较两种方式的成本，选择成本较小的一种
+
+	SELECT DISTINCT就是把所有选择的字段作为分组字段，并且没有聚合函数的GROUP BY，由NewDistinctPlan创建
+	不同记录的数量同样根据统计信息中每个字段的HyperLogLog基数来估算，哈希表按照这个数量预先分配空间
+*/
+
+type GroupByPlan struct {
+	tx          *tx.Transaction
+	p           Plan
+	sorted      *SortPlan //排序分组时按照分组字段排序，哈希聚合以及没有分组字段的时候为nil
+	groupFields []string
+	aggs        []*query.Aggregate
+	types       []rm.FIELD_TYPE //每个聚合函数参数的类型
+	schema      *rm.Schema
+	hash        bool //是否使用哈希聚合
+	ordered     bool //结果是否已经按照ORDER BY排好序
+	distinct    bool //是否是SELECT DISTINCT
+	groups      int  //估算出来的组数
+	available   int  //创建计划的时候可以使用的缓存块数
+	cost        float64
+}
+
+//NewGroupByPlan 按照groupFields对p的输出进行分组，计算aggs中的聚合函数，orderBy是查询的ORDER BY，可以为空
+func NewGroupByPlan(tx *tx.Transaction, p Plan, groupFields []string, aggs []*query.Aggregate, orderBy []*query.SortKey) *GroupByPlan {
+	groupByPlan := &GroupByPlan{
+		tx:          tx,
+		p:           p,
+		groupFields: groupFields,
+		aggs:        aggs,
+		types:       make([]rm.FIELD_TYPE, len(aggs)),
+		schema:      rm.NewSchema(),
+		available:   int(tx.AvailableBuffer()),
+	}
+	for _, field := range groupFields {
+		groupByPlan.schema.Add(field, p.Schema())
+	}
+	for i, agg := range aggs {
+		argType, length := rm.INTEGER, 0
+		if agg.Field() != "*" {
+			argType, length = p.Schema().Type(agg.Field()), p.Schema().Length(agg.Field())
+		}
+		groupByPlan.types[i] = argType
+		groupByPlan.schema.AddField(agg.Name(), agg.ResultType(argType), agg.ResultLength(argType, length))
+	}
+	groupByPlan.groups = groupByPlan.estimateGroups()
+	if len(groupFields) == 0 {
+		//整张表是一组，直接读取所有的记录
+		groupByPlan.ordered = true
+		groupByPlan.cost = p.Cost() + float64(p.RecordsOutput())*cpuCost
+		return groupByPlan
+	}
+	keys, ordered := groupByPlan.sortKeys(orderBy)
+	aggCost := float64(p.RecordsOutput()) * cpuCost
+	sorted := NewSortPlan(tx, p, keys)
+	sortCost := sorted.Cost() + aggCost
+	hashCost := p.Cost() + aggCost + groupByPlan.spillCost()
+	if ordered && len(orderBy) > 0 {
+		//哈希聚合之后还需要再对所有的组排序一次
+		groups := float64(groupByPlan.groups)
+		hashCost += groups * math.Log2(groups+1) * cpuCost
+	}
+	if hashCost <= sortCost {
+		groupByPlan.hash = true
+		groupByPlan.cost = hashCost
+		return groupByPlan
+	}
+	groupByPlan.sorted = sorted
+	groupByPlan.ordered = ordered
+	groupByPlan.cost = sortCost
+	return groupByPlan
+}
+
+//NewDistinctPlan 去掉p的输出中重复的记录，orderBy是查询的ORDER BY，可以为空
+func NewDistinctPlan(tx *tx.Transaction, p Plan, orderBy []*query.SortKey) *GroupByPlan {
+	distinctPlan := NewGroupByPlan(tx, p, p.Schema().Fields(), nil, orderBy)
+	distinctPlan.distinct = true
+	return distinctPlan
+}
+
+//sortKeys 排序分组使用的排序字段，ORDER BY只使用了分组字段的时候把它放在最前面，返回的bool表示结果是否满足ORDER BY
+func (g *GroupByPlan) sortKeys(orderBy []*query.SortKey) ([]*query.SortKey, bool) {
+	isGroupField := make(map[string]bool)
+	for _, field := range g.groupFields {
+		isGroupField[field] = true
+	}
+	keys := make([]*query.SortKey, 0, len(g.groupFields))
+	used := make(map[string]bool)
+	ordered := true
+	for _, key := range orderBy {
+		if !isGroupField[key.Field()] {
+			ordered = false
+			break
+		}
+		if !used[key.Field()] {
+			keys = append(keys, key)
+			used[key.Field()] = true
+		}
+	}
+	if !ordered {
+		keys, used = keys[:0], make(map[string]bool)
+	}
+	for _, field := range g.groupFields {
+		if !used[field] {
+			keys = append(keys, query.NewSortKey(field, false))
+		}
+	}
+	return keys, ordered
+}
+
+//estimateGroups 组数等于每个分组字段不同取值数量的乘积，但是不会超过记录数
+func (g *GroupByPlan) estimateGroups() int {
+	if len(g.groupFields) == 0 {
+		return 1
+	}
+	records := g.p.RecordsOutput()
+	groups := 1
+	for _, field := range g.groupFields {
+		groups *= g.p.DistinctValues(field)
+		if groups >= records {
+			groups = records
+			break
+		}
+	}
+	if groups < 1 {
+		return 1
+	}
+	return groups
+}
+
+//memoryGroups 哈希表中最多可以保存多少组，按照每个可用的缓存块存放一个区块的结果来计算
+func (g *GroupByPlan) memoryGroups() int {
+	perBlock := int(g.tx.BlockSize()) / rm.NewLayoutWithSchema(g.schema).SlotSize()
+	if perBlock < 1 {
+		perBlock = 1
+	}
+	available := int(g.tx.AvailableBuffer())
+	if available < 1 {
+		available = 1
+	}
+	return available * perBlock
+}
+
+//fanOut 每一次最多分成多少个分区，每个分区需要一个缓存块，读取记录还需要一个
+func (g *GroupByPlan) fanOut() int {
+	fanOut := int(g.tx.AvailableBuffer()) - 1
+	if fanOut < 2 {
+		return 2
+	}
+	return fanOut
+}
+
+//spillCost 组数放不下内存的时候，每一层分区都需要把放不下的记录写入临时表再读取出来
+func (g *GroupByPlan) spillCost() float64 {
+	capacity := g.memoryGroups()
+	if g.groups <= capacity {
+		return 0
+	}
+	fanOut := g.fanOut()
+	levels := math.Ceil(math.Log(float64(g.groups)/float64(capacity)) / math.Log(float64(fanOut)))
+	if levels < 1 {
+		levels = 1
+	}
+	return 2 * float64(g.p.BlockAccessed()) * levels * ioCost
+}
+
+func (g *GroupByPlan) Open() (interface{}, error) {
+	if g.hash {
+		src, err := g.p.Open()
+		if err != nil {
+			return nil, err
+		}
+		newTemp := func() query.TempTable {
+			return NewTempTable(g.tx, g.p.Schema())
+		}
+		capacity := g.memoryGroups()
+		return query.NewHashGroupByScan(src.(query.Scan), g.p.Schema().Fields(), g.groupFields, g.aggs, g.types,
+			capacity, int(math.Min(float64(g.groups), float64(capacity))), g.fanOut(), newTemp), nil
+	}
+	var child Plan = g.p
+	if g.sorted != nil {
+		child = g.sorted
+	}
+	src, err := child.Open()
+	if err != nil {
+		return nil, err
+	}
+	return query.NewGroupByScan(src.(query.Scan), g.groupFields, g.aggs, g.types), nil
+}
+
+//Hash 是否使用哈希聚合
+func (g *GroupByPlan) Hash() bool {
+	return g.hash
+}
+
+//Distinct 是否是SELECT DISTINCT
+func (g *GroupByPlan) Distinct() bool {
+	return g.distinct
+}
+
+//Ordered 结果是否已经按照创建计划时传入的ORDER BY排好序
+func (g *GroupByPlan) Ordered() bool {
+	return g.ordered
+}
+
+func (g *GroupByPlan) BlockAccessed() int {
+	if g.sorted != nil {
+		return g.sorted.BlockAccessed()
+	}
+	return g.p.BlockAccessed()
+}
+
+//RecordsOutput 每一组输出一条记录
+func (g *GroupByPlan) RecordsOutput() int {
+	return g.groups
+}
+
+func (g *GroupByPlan) DistinctValues(fldName string) int {
+	for _, field := range g.groupFields {
+		if field == fldName {
+			return int(math.Min(float64(g.p.DistinctValues(fldName)), float64(g.groups)))
+		}
+	}
+	return g.groups
+}
+
+func (g *GroupByPlan) Schema() rm.SchemaInterface {
+	return g.schema
+}
+
+func (g *GroupByPlan) Cost() float64 {
+	return g.cost
+}
@@ -0,0 +1,87 @@
+package planner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bm "miniSQL/buffer_manager"
+	fm "miniSQL/file_manager"
+	lm "miniSQL/log_manager"
+	mm "miniSQL/metadata_manager"
+	"miniSQL/query"
+	tx "miniSQL/transaction"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//testDB 规划器测试使用的数据库，每个测试在自己的临时目录中创建
+type testDB struct {
+	dir  string
+	fmgr *fm.FileManager
+	lmgr *lm.LogManager
+	bmgr *bm.BufferManager
+	mdm  *mm.MetaDataManager
+}
+
+//newTestPlanner 在临时目录中创建数据库name，返回规划器以及创建元数据表的事务，由调用者提交这个事务
+//需要多个事务、重新打开数据库或者检查数据库文件的测试使用返回的testDB
+func newTestPlanner(t *testing.T, name string) (*Planner, *tx.Transaction, *testDB) {
+	db := &testDB{dir: filepath.Join(t.TempDir(), name)}
+	var err error
+	db.fmgr, err = fm.NewFileManager(db.dir, 400)
+	require.Nil(t, err)
+	db.lmgr, err = lm.NewLogManager(db.fmgr, "logfile")
+	require.Nil(t, err)
+	db.bmgr = bm.NewBufferManager(db.fmgr, db.lmgr, 8)
+	tx1 := db.newTx()
+	db.mdm, err = mm.NewMetaDataManager(true, tx1)
+	require.Nil(t, err)
+	return NewPlanner(NewBasicQueryPlan(db.mdm), NewBasicUpdatePlanner(db.mdm)), tx1, db
+}
+
+//newTx 在测试数据库上开始一个新的事务
+func (d *testDB) newTx() *tx.Transaction {
+	return tx.NewTransaction(d.fmgr, d.lmgr, d.bmgr)
+}
+
+//reopen 像重新打开数据库一样从元数据表中读取元数据，返回使用新的元数据管理器的规划器
+func (d *testDB) reopen(t *testing.T, tx *tx.Transaction) *Planner {
+	var err error
+	d.mdm, err = mm.NewMetaDataManager(false, tx)
+	require.Nil(t, err)
+	return NewPlanner(NewBasicQueryPlan(d.mdm), NewBasicUpdatePlanner(d.mdm))
+}
+
+//exists 数据库目录中有没有和pattern匹配的文件
+func (d *testDB) exists(pattern string) bool {
+	files, _ := filepath.Glob(filepath.Join(d.dir, pattern))
+	return len(files) > 0
+}
+
+//collectRows 执行查询，返回每条记录的字段值，字段值之间用空格分开
+func collectRows(t *testing.T, p *Planner, sql string, tx *tx.Transaction) []string {
+	plan, err := p.CreateQueryPlan(sql, tx)
+	if !assert.Nil(t, err, sql) {
+		return nil
+	}
+	return planRows(t, plan)
+}
+
+//planRows 打开查询计划，返回每条记录的字段值，字段值之间用空格分开
+func planRows(t *testing.T, plan Plan) []string {
+	s, err := plan.Open()
+	if !assert.Nil(t, err) {
+		return nil
+	}
+	scan := s.(query.Scan)
+	defer scan.Close()
+	rows := make([]string, 0)
+	for scan.Next() {
+		vals := make([]string, 0)
+		for _, field := range plan.Schema().Fields() {
+			vals = append(vals, scan.GetVal(field).ToString())
+		}
+		rows = append(rows, strings.Join(vals, " "))
+	}
+	return rows
+}
@@ -0,0 +1,84 @@
+package planner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"miniSQL/comm"
+	"miniSQL/parser"
+	rm "miniSQL/record_manager"
+	"testing"
+)
+
+func TestInsertRows(t *testing.T) {
+	p, tx, db := newTestPlanner(t, "insert_test")
+	defer tx.Commit()
+	for _, sql := range []string{
+		"create table emp (id int, name varchar(10), dept int)",
+		"create table archive (aid int, aname varchar(10))",
+		"create index empdept on emp (dept)",
+	} {
+		_, err := p.ExecuteUpdate(sql, tx)
+		assert.Nil(t, err, sql)
+	}
+
+	//一条语句写入多行
+	n, err := p.ExecuteUpdate("insert into emp (id, name, dept) values (1, 'ann', 1), (2, 'ben', 1), (3, 'cid', 2 * 1)", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"1 ann 1", "2 ben 1", "3 cid 2"}, collectRows(t, p, "select id, name, dept from emp order by id", tx))
+
+	//写入查询的结果
+	n, err = p.ExecuteUpdate("insert into archive (aname, aid) select name, id + 10 from emp where dept = 1", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"11 ann", "12 ben"}, collectRows(t, p, "select aid, aname from archive order by aid", tx))
+
+	//查询读取的就是要写入的表，新写入的记录不会再被读取到
+	n, err = p.ExecuteUpdate("insert into emp (id, name, dept) select id + 3, name, dept from emp", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 6, len(collectRows(t, p, "select id from emp", tx)))
+	//多行写入和INSERT ... SELECT写入的记录都加入到了表上的索引中
+	idx := db.mdm.GetIndexInfo("emp", tx)["dept"].Open()
+	key := 1
+	idx.BeforeFirst(comm.NewConstantInt(&key))
+	rows := 0
+	for idx.Next() {
+		rows++
+	}
+	idx.Close()
+	assert.Equal(t, 4, rows)
+	n, err = p.ExecuteUpdate("insert into archive (aid, aname) select aid, aname from archive where aid in (select id + 10 from emp)", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	for sql, target := range map[string]error{
+		"insert into emp (id, name) values (1, 'x'), (2)":         ErrColumnCount,
+		"insert into emp (id, name) values (1, 'x', 3)":           ErrColumnCount,
+		"insert into emp (id, name) select id from emp":           ErrColumnCount,
+		"insert into emp (id, name) values (1, 'x'), ('y', 2)":    ErrTypeMismatch,
+		"insert into emp (id, name) select name, id from emp":     ErrTypeMismatch,
+		"insert into emp (id, title) select id, name from emp":    ErrFieldNotFound,
+		"insert into emp (id, name) select id, title from emp":    ErrFieldNotFound,
+		"insert into nothing (id, name) select id, name from emp": ErrTableNotFound,
+		//字符串超过字段的长度
+		"insert into emp (id, name) values (7, 'toolongvalue')":                               ErrValueTooLong,
+		"insert into archive (aid, aname) select id, concat(name, name, name, name) from emp": ErrValueTooLong,
+	} {
+		_, err = p.ExecuteUpdate(sql, tx)
+		assert.ErrorIs(t, err, target, sql)
+	}
+	//出错的语句一行都没有写入
+	assert.Equal(t, 6, len(collectRows(t, p, "select id from emp", tx)))
+
+	//预处理语句中每一行的参数槽都按照对应的字段推断类型
+	stmt, params, err := parser.Prepare("insert into emp (id, name) values (?, ?), (?, 'x')")
+	assert.Nil(t, err)
+	types, err := ParamTypes(db.mdm, stmt, params, tx)
+	assert.Nil(t, err)
+	assert.Equal(t, []rm.FIELD_TYPE{rm.INTEGER, rm.VARCHAR, rm.INTEGER}, types)
+	stmt, params, err = parser.Prepare("insert into archive (aname, aid) select ?, id from emp where dept = ?")
+	assert.Nil(t, err)
+	types, err = ParamTypes(db.mdm, stmt, params, tx)
+	assert.Nil(t, err)
+	assert.Equal(t, []rm.FIELD_TYPE{rm.VARCHAR, rm.INTEGER}, types)
+}
@@ -28,5 +28,17 @@ type Plan interface {
 }
 
 type QueryPlanner interface {
-	CreatePlan(data *parser.QueryData, tx *tx.Transaction) Plan
+	CreatePlan(data *parser.QueryData, tx *tx.Transaction) (Plan, error)
+}
+
+//UpdatePlanner 执行各种修改语句，返回受影响的记录数
+type UpdatePlanner interface {
+	ExecuteInsert(data *parser.InsertData, tx *tx.Transaction) (int, error)
+	ExecuteDelete(data *parser.DeleteData, tx *tx.Transaction) (int, error)
+	ExecuteModify(data *parser.UpdateData, tx *tx.Transaction) (int, error)
+	ExecuteCreateTable(data *parser.CreateTableData, tx *tx.Transaction) error
+	ExecuteCreateView(data *parser.CreateViewData, tx *tx.Transaction) error
+	ExecuteCreateIndex(data *parser.CreateIndexData, tx *tx.Transaction) error
+	ExecuteDrop(data *parser.DropData, tx *tx.Transaction) error
+	ExecuteAlter(data *parser.AlterData, tx *tx.Transaction) error
 }
@@ -0,0 +1,89 @@
+package planner
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestJoinPlan(t *testing.T) {
+	p, tx, _ := newTestPlanner(t, "join_test")
+	defer tx.Commit()
+	for _, sql := range []string{
+		"create table customer (cid int, cname varchar(10))",
+		"create table orders (ocid int, amount int)",
+		"create table address (cid int, city varchar(10))",
+	} {
+		_, err := p.ExecuteUpdate(sql, tx)
+		assert.Nil(t, err)
+	}
+	for i, name := range []string{"amy", "bob", "cat"} {
+		_, err := p.ExecuteUpdate(fmt.Sprintf("insert into customer (cid, cname) values (%d, '%s')", i+1, name), tx)
+		assert.Nil(t, err)
+	}
+	for _, order := range [][2]int{{1, 100}, {1, 200}, {3, 300}, {9, 900}} {
+		_, err := p.ExecuteUpdate(fmt.Sprintf("insert into orders (ocid, amount) values (%d, %d)", order[0], order[1]), tx)
+		assert.Nil(t, err)
+	}
+	for _, sql := range []string{
+		"insert into address (cid, city) values (2, 'rome')",
+		"insert into address (cid, city) values (4, 'oslo')",
+	} {
+		_, err := p.ExecuteUpdate(sql, tx)
+		assert.Nil(t, err)
+	}
+
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{"select cname, amount from customer join orders on cid = ocid order by amount", []string{"amy 100", "amy 200", "cat 300"}},
+		{"select cname, amount from customer inner join orders on cid = ocid and amount > 100 order by amount", []string{"amy 200", "cat 300"}},
+		//所有的客户以及他们可能有的订单
+		{"select cname, amount from customer left join orders on cid = ocid order by cname, amount", []string{"amy 100", "amy 200", "bob NULL", "cat 300"}},
+		//ON条件只影响匹配，不会过滤掉左边的记录
+		{"select cname, amount from customer left outer join orders on cid = ocid and amount > 250 order by cname", []string{"amy NULL", "bob NULL", "cat 300"}},
+		//WHERE条件在连接之后执行，会过滤掉被NULL填充的记录
+		{"select cname, amount from customer left join orders on cid = ocid where amount > 150 order by amount", []string{"amy 200", "cat 300"}},
+		{"select cname, amount from customer right join orders on cid = ocid order by amount", []string{"amy 100", "amy 200", "cat 300", "NULL 900"}},
+		{"select cname, amount from customer full join orders on cid = ocid order by amount", []string{"bob NULL", "amy 100", "amy 200", "cat 300", "NULL 900"}},
+		//USING中的字段只出现一次
+		{"select cid, cname, city from customer full join address using (cid) order by cid", []string{"1 amy NULL", "2 bob rome", "3 cat NULL", "4 NULL oslo"}},
+		{"select cid, city from customer join address using (cid)", []string{"2 rome"}},
+		{"select cname, city, amount from customer left join address using (cid) left join orders on cid = ocid where cname <> 'amy' order by cname",
+			[]string{"bob rome NULL", "cat NULL 300"}},
+		//逗号和JOIN可以混合使用，customer和address都有cid字段，需要使用表名区分
+		{"select cname, city from customer left join orders on customer.cid = ocid, address where amount = 300", []string{"cat rome", "cat oslo"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.rows, collectRows(t, p, c.sql, tx), c.sql)
+	}
+
+	//RIGHT JOIN把右边的表作为外层，输出的字段顺序不变
+	plan, err := p.CreateQueryPlan("select cname, amount from customer right join orders on cid = ocid", tx)
+	assert.Nil(t, err)
+	explain := Explain(plan)
+	assert.True(t, strings.Contains(explain, "RightJoin(cid=ocid)"), explain)
+	assert.True(t, strings.Index(explain, "Table(orders)") < strings.Index(explain, "Table(customer)"), explain)
+	plan, err = p.CreateQueryPlan("select cname from customer full join address using (cid)", tx)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(Explain(plan), "FullJoin(USING cid)"), Explain(plan))
+	assert.Equal(t, []string{"cname"}, plan.Schema().Fields())
+	for _, ok := plan.(*JoinPlan); !ok; _, ok = plan.(*JoinPlan) {
+		plan = childPlans(plan)[0]
+	}
+	joinPlan := plan.(*JoinPlan)
+	assert.Equal(t, []string{"cid", "cname", "city"}, joinPlan.Schema().Fields())
+	assert.True(t, joinPlan.RecordsOutput() >= 3)
+
+	for _, sql := range []string{
+		"select cname from customer join orders on cid = nothing",
+		"select cname from customer left join orders using (ocid)",
+	} {
+		_, err = p.CreateQueryPlan(sql, tx)
+		assert.ErrorIs(t, err, ErrFieldNotFound, sql)
+	}
+	_, err = p.CreateQueryPlan("select cname from customer left join orders on cname = ocid", tx)
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
@@ -0,0 +1,172 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"miniSQL/parser"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+)
+
+/*
+	JoinPlan 实现FROM中使用JOIN写出的外连接以及使用USING的连接，ON条件的内连接转化成Product上的Select
+	和ProductPlan一样使用嵌套循环，调换内外层可以减少访问的块数，但是外连接调换内外层的时候必须保持语义不变：
+	1.INNER JOIN两边是对称的，选择成本低的顺序
+	2.LEFT JOIN以左边为外层，RIGHT JOIN以右边为外层，需要保留的一边作为外层，只需要扫描一趟
+	3.FULL JOIN两边都需要保留，以左边为外层，第二趟再以右边为外层找出没有匹配上的记录
+	ON条件只在连接的时候使用，不能和WHERE条件合并，否则外连接中被NULL填充的记录会被WHERE过滤掉
+*/
+
+type JoinPlan struct {
+	lhs      Plan
+	rhs      Plan
+	joinType parser.JoinType
+	pred     *query.Predicate
+	using    []string
+	swapped  bool //为true的时候右边作为外层
+	schema   *rm.Schema
+	records  int
+	cost     float64
+}
+
+//NewJoinPlan 连接lhs和rhs，需要先使用checkJoin检查连接条件
+func NewJoinPlan(lhs Plan, rhs Plan, joinType parser.JoinType, pred *query.Predicate, using []string) *JoinPlan {
+	joinPlan := &JoinPlan{
+		lhs:      lhs,
+		rhs:      rhs,
+		joinType: joinType,
+		pred:     pred,
+		using:    using,
+		schema:   rm.NewSchema(),
+	}
+	for _, field := range lhs.Schema().Fields() {
+		if joinPlan.isUsing(field) && rhs.Schema().Length(field) > lhs.Schema().Length(field) {
+			//USING中的字段只出现一次，字符串取两边中较大的长度
+			joinPlan.schema.Add(field, rhs.Schema())
+		} else {
+			joinPlan.schema.Add(field, lhs.Schema())
+		}
+	}
+	for _, field := range rhs.Schema().Fields() {
+		if !joinPlan.isUsing(field) {
+			joinPlan.schema.Add(field, rhs.Schema())
+		}
+	}
+	switch joinType {
+	case parser.INNER_JOIN:
+		joinPlan.swapped = loopCost(rhs, lhs) < loopCost(lhs, rhs)
+	case parser.RIGHT_JOIN:
+		joinPlan.swapped = true
+	}
+	joinPlan.records = joinPlan.estimateRecords()
+	joinPlan.cost = lhs.Cost() + rhs.Cost() + float64(joinPlan.BlockAccessed())*ioCost + float64(lhs.RecordsOutput()*rhs.RecordsOutput())*cpuCost
+	if joinType == parser.FULL_JOIN {
+		//第二趟需要再比较一遍所有的组合
+		joinPlan.cost += float64(lhs.RecordsOutput()*rhs.RecordsOutput()) * cpuCost
+	}
+	return joinPlan
+}
+
+//loopCost 以outer为外层，inner为内层进行嵌套循环的成本
+func loopCost(outer Plan, inner Plan) float64 {
+	return float64(outer.BlockAccessed()+outer.RecordsOutput()*inner.BlockAccessed()) * ioCost
+}
+
+//estimateRecords 匹配上的记录数是笛卡尔积乘以连接条件的选择率，外连接至少输出需要保留的一边的所有记录
+func (j *JoinPlan) estimateRecords() int {
+	selectivity := predicateSelectivity(j.pred, j)
+	for _, field := range j.using {
+		selectivity /= math.Max(math.Max(float64(j.lhs.DistinctValues(field)), float64(j.rhs.DistinctValues(field))), 1)
+	}
+	records := int(float64(j.lhs.RecordsOutput()*j.rhs.RecordsOutput()) * selectivity)
+	if (j.joinType == parser.LEFT_JOIN || j.joinType == parser.FULL_JOIN) && records < j.lhs.RecordsOutput() {
+		records = j.lhs.RecordsOutput()
+	}
+	if (j.joinType == parser.RIGHT_JOIN || j.joinType == parser.FULL_JOIN) && records < j.rhs.RecordsOutput() {
+		records = j.rhs.RecordsOutput()
+	}
+	return records
+}
+
+func (j *JoinPlan) isUsing(field string) bool {
+	for _, f := range j.using {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+//outerInner 按照执行的顺序返回外层和内层
+func (j *JoinPlan) outerInner() (Plan, Plan) {
+	if j.swapped {
+		return j.rhs, j.lhs
+	}
+	return j.lhs, j.rhs
+}
+
+func (j *JoinPlan) Open() (interface{}, error) {
+	resetSubqueries(j.pred.Subqueries())
+	outerPlan, innerPlan := j.outerInner()
+	outer, err := outerPlan.Open()
+	if err != nil {
+		return nil, err
+	}
+	inner, err := innerPlan.Open()
+	if err != nil {
+		outer.(query.Scan).Close()
+		return nil, err
+	}
+	//需要保留的一边已经被放在了外层，只有FULL JOIN还需要保留内层
+	preserveOuter := j.joinType != parser.INNER_JOIN
+	preserveInner := j.joinType == parser.FULL_JOIN
+	return query.NewJoinScan(outer.(query.Scan), inner.(query.Scan), j.pred, j.using, preserveOuter, preserveInner), nil
+}
+
+func (j *JoinPlan) BlockAccessed() int {
+	outer, inner := j.outerInner()
+	blocks := outer.BlockAccessed() + outer.RecordsOutput()*inner.BlockAccessed()
+	if j.joinType == parser.FULL_JOIN {
+		blocks += inner.BlockAccessed() + inner.RecordsOutput()*outer.BlockAccessed()
+	}
+	return blocks
+}
+
+func (j *JoinPlan) RecordsOutput() int {
+	return j.records
+}
+
+//DistinctValues 被NULL填充的记录最多增加一个不同的值，这里忽略不计
+func (j *JoinPlan) DistinctValues(fldName string) int {
+	if j.lhs.Schema().HashField(fldName) {
+		return j.lhs.DistinctValues(fldName)
+	}
+	return j.rhs.DistinctValues(fldName)
+}
+
+func (j *JoinPlan) Schema() rm.SchemaInterface {
+	return j.schema
+}
+
+func (j *JoinPlan) Cost() float64 {
+	return j.cost
+}
+
+//checkJoin 检查连接条件，ON条件中的字段必须在两边的表中，USING中的字段两边都要有并且类型相同，INTEGER和BIGINT看作相同的类型
+func checkJoin(lhs Plan, rhs Plan, join *parser.JoinData) error {
+	for _, field := range join.Using() {
+		for _, sch := range []rm.SchemaInterface{lhs.Schema(), rhs.Schema()} {
+			if !sch.HashField(field) {
+				return fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+			}
+		}
+		lhsType, rhsType := lhs.Schema().Type(field), rhs.Schema().Type(field)
+		if !assignable(lhsType, rhsType) || !assignable(rhsType, lhsType) {
+			return fmt.Errorf("%w: %s", ErrTypeMismatch, field)
+		}
+	}
+	sch := rm.NewSchema()
+	sch.AddAll(lhs.Schema())
+	sch.AddAll(rhs.Schema())
+	return checkPredicate(join.On(), sch)
+}
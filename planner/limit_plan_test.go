@@ -0,0 +1,59 @@
+package planner
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/query"
+	"strings"
+	"testing"
+)
+
+func TestLimitPlan(t *testing.T) {
+	p, tx, _ := newTestPlanner(t, "limit_test")
+	defer tx.Commit()
+	_, err := p.ExecuteUpdate("create table course (title varchar(16),deptId int)", tx)
+	assert.Nil(t, err)
+	const numRecords = 200
+	for i := 0; i < numRecords; i++ {
+		_, err = p.ExecuteUpdate(fmt.Sprintf("insert into course (title,deptId) values ('c%03d',%d)", i, i*37%101), tx)
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, []string{"c000", "c001", "c002"}, collectRows(t, p, "select title from course limit 3", tx))
+	assert.Equal(t, []string{"c198", "c199"}, collectRows(t, p, "select title from course limit 5 offset 198", tx))
+	assert.Equal(t, []string{}, collectRows(t, p, "select title from course limit 0", tx))
+	assert.Equal(t, []string{}, collectRows(t, p, "select title from course limit 5 offset 300", tx))
+
+	//ORDER BY和LIMIT一起使用的时候使用TopN，结果和完整排序之后取前面几条相同
+	sorted := collectRows(t, p, "select title from course order by deptId desc, title", tx)
+	assert.Equal(t, sorted[4:9], collectRows(t, p, "select title from course order by deptId desc, title limit 5 offset 4", tx))
+	plan, err := p.CreateQueryPlan("select title from course order by deptId desc limit 5 offset 4", tx)
+	assert.Nil(t, err)
+	explain := Explain(plan)
+	assert.True(t, strings.HasPrefix(explain, "Limit(5 OFFSET 4)"), explain)
+	assert.True(t, strings.Contains(explain, "TopN(deptId DESC)"), explain)
+	assert.Equal(t, 5, plan.RecordsOutput())
+	//n超过内存可以存放的记录数的时候退化成外部排序
+	assert.Equal(t, sorted[150:], collectRows(t, p, "select title from course order by deptId desc, title limit 100 offset 150", tx))
+
+	//读取到足够的记录之后就关闭底层的scan，释放占用的缓存块
+	available := tx.AvailableBuffer()
+	plan, err = p.CreateQueryPlan("select title from course limit 2", tx)
+	assert.Nil(t, err)
+	s, err := plan.Open()
+	assert.Nil(t, err)
+	scan := s.(*query.LimitScan)
+	assert.True(t, scan.Next())
+	assert.True(t, scan.Next())
+	assert.True(t, tx.AvailableBuffer() < available)
+	assert.False(t, scan.Next())
+	assert.Equal(t, available, tx.AvailableBuffer())
+	scan.BeforeFirst()
+	assert.True(t, scan.Next())
+	assert.Equal(t, "c000", scan.GetString("title"))
+	scan.Close()
+	assert.Equal(t, available, tx.AvailableBuffer())
+
+	_, err = p.CreateQueryPlan("select title from course limit 'a'", tx)
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
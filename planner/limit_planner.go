@@ -0,0 +1,100 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"miniSQL/comm"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+)
+
+//LimitPlan 实现LIMIT和OFFSET，limit和offset可能是预处理语句中的参数槽，打开的时候才读取它们的值
+type LimitPlan struct {
+	p      Plan
+	limit  *comm.Constant
+	offset *comm.Constant //没有OFFSET的时候为nil
+}
+
+func NewLimitPlan(p Plan, limit *comm.Constant, offset *comm.Constant) *LimitPlan {
+	return &LimitPlan{
+		p:      p,
+		limit:  limit,
+		offset: offset,
+	}
+}
+
+//knownInt 常量已经有整数值的时候返回它，预处理语句还没有绑定参数的时候返回false
+func knownInt(c *comm.Constant) (int, bool) {
+	if c == nil || c.Ival == nil {
+		return 0, false
+	}
+	return *c.Ival, true
+}
+
+//limitValues 读取LIMIT和OFFSET的值，它们必须是非负的整数
+func limitValues(limit *comm.Constant, offset *comm.Constant) (int, int, error) {
+	values := make([]int, 2)
+	for i, c := range []*comm.Constant{limit, offset} {
+		if c == nil {
+			continue
+		}
+		if c.Ival == nil {
+			return 0, 0, fmt.Errorf("%w: LIMIT and OFFSET must be integers", ErrTypeMismatch)
+		}
+		if *c.Ival < 0 {
+			return 0, 0, fmt.Errorf("%w: %d", ErrNegativeLimit, *c.Ival)
+		}
+		values[i] = *c.Ival
+	}
+	return values[0], values[1], nil
+}
+
+func (l *LimitPlan) Open() (interface{}, error) {
+	limit, offset, err := limitValues(l.limit, l.offset)
+	if err != nil {
+		return nil, err
+	}
+	open := func() (query.Scan, error) {
+		s, err := l.p.Open()
+		if err != nil {
+			return nil, err
+		}
+		return s.(query.Scan), nil
+	}
+	s, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return query.NewLimitScan(s, open, limit, offset), nil
+}
+
+func (l *LimitPlan) BlockAccessed() int {
+	return l.p.BlockAccessed()
+}
+
+//RecordsOutput 跳过offset条之后最多limit条记录
+func (l *LimitPlan) RecordsOutput() int {
+	records := l.p.RecordsOutput()
+	if offset, ok := knownInt(l.offset); ok {
+		records -= offset
+	}
+	if limit, ok := knownInt(l.limit); ok && limit < records {
+		records = limit
+	}
+	if records < 0 {
+		return 0
+	}
+	return records
+}
+
+func (l *LimitPlan) DistinctValues(fldName string) int {
+	return int(math.Min(float64(l.p.DistinctValues(fldName)), float64(l.RecordsOutput())))
+}
+
+func (l *LimitPlan) Schema() rm.SchemaInterface {
+	return l.p.Schema()
+}
+
+func (l *LimitPlan) Cost() float64 {
+	return l.p.Cost()
+}
@@ -0,0 +1,76 @@
+package planner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNull(t *testing.T) {
+	p, t1, db := newTestPlanner(t, "null_test")
+	for _, sql := range []string{
+		"create table emp (id int, name varchar(10), dept int, sal int)",
+		"create table dept (did int, title varchar(10))",
+		//INSERT中没有给出的字段是NULL
+		"insert into emp (id, name, dept) values (1, 'ann', 1)",
+		"insert into emp (id, name, dept, sal) values (2, 'ben', 1, 10), (3, NULL, NULL, 20), (4, 'dan', 2, NULL)",
+		"insert into emp (id, name, dept, sal) values (5, 'eve', NULL, 30)",
+		"insert into dept (did, title) values (1, 'sales'), (NULL, 'none')",
+	} {
+		_, err := p.ExecuteUpdate(sql, t1)
+		assert.Nil(t, err, sql)
+	}
+
+	for sql, want := range map[string][]string{
+		"select id, name, dept, sal from emp where id = 1":              {"1 ann 1 NULL"},
+		"select id from emp where sal is null order by id":              {"1", "4"},
+		"select id from emp where sal is not null and dept is not null": {"2"},
+		//和NULL比较的结果是UNKNOWN，NOT之后还是UNKNOWN
+		"select id from emp where sal = null":                                 {},
+		"select id from emp where not (dept = 1) order by id":                 {"4"},
+		"select id from emp where dept = 1 or sal > 15 order by id":           {"1", "2", "3", "5"},
+		"select id from emp where not (dept = 1 or sal > 15)":                 {},
+		"select id from emp where (sal + 1) is null order by id":              {"1", "4"},
+		"select id from emp where dept in (select did from dept) order by id": {"1", "2"},
+		//子查询的结果中有NULL的时候NOT IN不成立
+		"select id from emp where dept not in (select did from dept)":                                   {},
+		"select id from emp where dept not in (select did from dept where did is not null) order by id": {"4"},
+		//聚合函数跳过NULL，所有的NULL在同一组，排序的时候NULL在最前面
+		"select count(*), count(sal), sum(sal), avg(sal), min(name), max(sal) from emp": {"5 3 60 20 ann 30"},
+		"select dept, count(*), max(sal) from emp group by dept order by dept":          {"NULL 2 30", "1 2 10", "2 1 NULL"},
+		"select distinct dept from emp order by dept":                                   {"NULL", "1", "2"},
+		"select sum(sal) from emp where dept = 2":                                       {"NULL"},
+		//外连接用NULL填充，NULL和NULL不能连接上
+		"select id, title from emp join dept on dept = did order by id":       {"1 sales", "2 sales"},
+		"select title from dept left join emp on did = dept where id is null": {"none"},
+	} {
+		assert.Equal(t, want, collectRows(t, p, sql, t1), sql)
+	}
+
+	//视图的定义中使用IS NULL，修改字段为NULL之后通过索引查找
+	for _, sql := range []string{
+		"create view unpaid as select name from emp where sal is null",
+		"create index empdept on emp (dept)",
+		"update emp set sal = null, dept = 2 where id = 2",
+		"update emp set name = 'cid' where name is null",
+	} {
+		_, err := p.ExecuteUpdate(sql, t1)
+		assert.Nil(t, err, sql)
+	}
+	assert.Equal(t, []string{"ann", "ben", "dan"}, collectRows(t, p, "select name from unpaid order by name", t1))
+	assert.Equal(t, []string{"2", "4"}, indexedIDs(t, db.mdm, "emp", "dept", 2, t1))
+	assert.Equal(t, []string{"ben", "dan"}, collectRows(t, p, "select name from emp where dept = 2 order by name", t1))
+	assert.Equal(t, []string{"cid"}, collectRows(t, p, "select name from emp where id = 3", t1))
+
+	//新增的字段可以使用DEFAULT NULL，删除字段之后其他字段的NULL标志不变
+	for _, sql := range []string{
+		"drop view unpaid",
+		"alter table emp add bonus int default null",
+		"alter table emp drop column name",
+	} {
+		_, err := p.ExecuteUpdate(sql, t1)
+		assert.Nil(t, err, sql)
+	}
+	assert.Equal(t, []string{"1 1 NULL NULL", "3 NULL 20 NULL", "5 NULL 30 NULL"},
+		collectRows(t, p, "select id, dept, sal, bonus from emp where id <> 2 and id <> 4 order by id", t1))
+	t1.Commit()
+}
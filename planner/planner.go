@@ -0,0 +1,101 @@
+package planner
+
+import (
+	"fmt"
+	"miniSQL/parser"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+)
+
+/*
+	Planner 是对查询规划器和修改规划器的一层封装，使用者只需要传入SQL语句即可
+	解析SQL语句得到Statement，如果是查询语句就交给QueryPlanner，否则就根据语句的类型交给UpdatePlanner中对应的方法
+	REPL，服务端，driver等使用者都通过这个对象来执行SQL语句
+*/
+
+type Planner struct {
+	queryPlanner  QueryPlanner
+	updatePlanner UpdatePlanner
+}
+
+//NewPlanner 使用一个查询规划器和一个修改规划器来构造Planner
+func NewPlanner(queryPlanner QueryPlanner, updatePlanner UpdatePlanner) *Planner {
+	return &Planner{
+		queryPlanner:  queryPlanner,
+		updatePlanner: updatePlanner,
+	}
+}
+
+//CreateQueryPlan 根据查询语句构造出对应的查询计划
+func (p *Planner) CreateQueryPlan(sql string, tx *tx.Transaction) (Plan, error) {
+	stmt, err := parser.NewSQLParser(sql).ParseStatement()
+	if err != nil {
+		return nil, err
+	}
+	data, ok := stmt.(*parser.QueryData)
+	if !ok {
+		return nil, ErrNotQuery
+	}
+	return p.queryPlanner.CreatePlan(data, tx)
+}
+
+//ExecuteQuery 执行一条查询语句，返回已经打开的scan以及结果中每一列的信息，使用完之后调用者需要关闭scan
+func (p *Planner) ExecuteQuery(sql string, tx *tx.Transaction) (query.Scan, rm.SchemaInterface, error) {
+	plan, err := p.CreateQueryPlan(sql, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return openPlan(plan)
+}
+
+//ExecuteQueryData 执行一个已经解析好的查询语句
+func (p *Planner) ExecuteQueryData(data *parser.QueryData, tx *tx.Transaction) (query.Scan, rm.SchemaInterface, error) {
+	plan, err := p.queryPlanner.CreatePlan(data, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return openPlan(plan)
+}
+
+func openPlan(plan Plan) (query.Scan, rm.SchemaInterface, error) {
+	s, err := plan.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.(query.Scan), plan.Schema(), nil
+}
+
+//ExecuteUpdate 执行一条修改语句，返回受影响的记录数，create和drop语句返回0
+func (p *Planner) ExecuteUpdate(sql string, tx *tx.Transaction) (int, error) {
+	stmt, err := parser.NewSQLParser(sql).ParseStatement()
+	if err != nil {
+		return 0, err
+	}
+	return p.ExecuteStatement(stmt, tx)
+}
+
+//ExecuteStatement 执行一个已经解析好的修改语句
+func (p *Planner) ExecuteStatement(stmt parser.Statement, tx *tx.Transaction) (int, error) {
+	switch data := stmt.(type) {
+	case *parser.InsertData:
+		return p.updatePlanner.ExecuteInsert(data, tx)
+	case *parser.DeleteData:
+		return p.updatePlanner.ExecuteDelete(data, tx)
+	case *parser.UpdateData:
+		return p.updatePlanner.ExecuteModify(data, tx)
+	case *parser.CreateTableData:
+		return 0, p.updatePlanner.ExecuteCreateTable(data, tx)
+	case *parser.CreateViewData:
+		return 0, p.updatePlanner.ExecuteCreateView(data, tx)
+	case *parser.CreateIndexData:
+		return 0, p.updatePlanner.ExecuteCreateIndex(data, tx)
+	case *parser.DropData:
+		return 0, p.updatePlanner.ExecuteDrop(data, tx)
+	case *parser.AlterData:
+		return 0, p.updatePlanner.ExecuteAlter(data, tx)
+	case *parser.QueryData:
+		return 0, ErrNotUpdate
+	}
+	return 0, fmt.Errorf("%w: unknown statement %T", ErrNotUpdate, stmt)
+}
@@ -0,0 +1,98 @@
+package planner
+
+import (
+	"github.com/stretchr/testify/assert"
+	bm "miniSQL/buffer_manager"
+	"miniSQL/comm"
+	fm "miniSQL/file_manager"
+	lm "miniSQL/log_manager"
+	mm "miniSQL/metadata_manager"
+	tx "miniSQL/transaction"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlannerExecute(t *testing.T) {
+	fmgr, _ := fm.NewFileManager(filepath.Join(t.TempDir(), "planner_test"), 400)
+	lmgr, _ := lm.NewLogManager(fmgr, "logfile")
+	bmgr := bm.NewBufferManager(fmgr, lmgr, 16)
+	tx := tx.NewTransaction(fmgr, lmgr, bmgr)
+	defer tx.Commit()
+	mdm, err := mm.NewMetaDataManager(true, tx)
+	assert.Nil(t, err)
+	p := NewPlanner(NewBasicQueryPlan(mdm), NewBasicUpdatePlanner(mdm))
+
+	n, err := p.ExecuteUpdate("create table course (title varchar(16),deptId int)", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+	for _, sql := range []string{
+		"insert into course (title,deptId) values (\"db\",10)",
+		"insert into course (title,deptId) values (\"os\",20)",
+		"insert into course (title,deptId) values (\"ml\",10)",
+	} {
+		n, err = p.ExecuteUpdate(sql, tx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+	}
+	n, err = p.ExecuteUpdate("update course set deptId = 30 where deptId = 10", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	n, err = p.ExecuteUpdate("delete from course where title = \"os\"", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	_, err = p.ExecuteUpdate("create index deptIdx on course (deptId)", tx)
+	assert.Nil(t, err)
+	_, err = p.ExecuteUpdate("create index deptIdx on course (title)", tx)
+	assert.ErrorIs(t, err, ErrIndexExists)
+
+	scan, sch, err := p.ExecuteQuery("select title,deptId from course", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"title", "deptId"}, sch.Fields())
+	titles := make([]string, 0)
+	for scan.Next() {
+		assert.Equal(t, 30, scan.GetInt("deptId"))
+		titles = append(titles, scan.GetString("title"))
+	}
+	scan.Close()
+	assert.Equal(t, []string{"db", "ml"}, titles)
+
+	plan, err := p.CreateQueryPlan("select title from course where deptId = 30", tx)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(Explain(plan)), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "Project(title)"))
+	assert.True(t, strings.HasPrefix(lines[1], "  Select(deptId=30)"))
+	assert.True(t, strings.HasPrefix(lines[2], "    Table(course)"))
+
+	//写入和删除记录的时候同时修改表上的索引
+	_, err = p.ExecuteUpdate("insert into course (title,deptId) values (\"go\",30)", tx)
+	assert.Nil(t, err)
+	_, err = p.ExecuteUpdate("delete from course where title = \"db\"", tx)
+	assert.Nil(t, err)
+	idx := mdm.GetIndexInfo("course", tx)["deptId"].Open()
+	key := 30
+	idx.BeforeFirst(comm.NewConstantInt(&key))
+	rows := 0
+	for idx.Next() {
+		rows++
+	}
+	idx.Close()
+	assert.Equal(t, 2, rows)
+
+	//语句的类型不对，或者表和字段不存在的时候都需要返回错误
+	_, _, err = p.ExecuteQuery("delete from course", tx)
+	assert.ErrorIs(t, err, ErrNotQuery)
+	_, err = p.ExecuteUpdate("select title from course", tx)
+	assert.ErrorIs(t, err, ErrNotUpdate)
+	_, _, err = p.ExecuteQuery("select title from teacher", tx)
+	assert.ErrorIs(t, err, ErrTableNotFound)
+	_, _, err = p.ExecuteQuery("select name from course", tx)
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+	_, err = p.ExecuteUpdate("insert into teacher (name) values (\"tom\")", tx)
+	assert.ErrorIs(t, err, ErrTableNotFound)
+	_, err = p.ExecuteUpdate("insert into course (title,deptId) values (10,\"db\")", tx)
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+	_, err = p.ExecuteUpdate("update course set deptId = \"ten\"", tx)
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
@@ -0,0 +1,222 @@
+package planner
+
+import (
+	"miniSQL/comm"
+	mm "miniSQL/metadata_manager"
+	"miniSQL/parser"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+)
+
+/*
+	预处理语句只解析一次，每次执行的时候把参数写入到语法树中的参数槽上
+	参数槽的类型根据它对应的字段在表中的类型推断出来：
+	insert语句中和字段一一对应，update语句中和被修改的字段对应，where条件中和比较运算符另一边的表达式的类型相同
+	算术运算符的操作数是整数，结果写入数值字段或者和数值比较的时候是这个数值类型，函数的参数是函数要求的类型
+	子查询中的参数槽在子查询自己的查询计划上推断，IN的左边和子查询的字段类型相同
+	推断不出来的参数槽（例如两边都是参数）类型为PARAM_UNKNOWN，执行的时候接受任意类型的值
+*/
+
+const (
+	PARAM_UNKNOWN rm.FIELD_TYPE = -1 //参数槽的类型无法推断
+)
+
+//ParamTypes 推断出每个参数槽的类型，同时会检查语句中使用的表和字段是否存在
+func ParamTypes(mdm *mm.MetaDataManager, stmt parser.Statement, params []*comm.Constant, tx *tx.Transaction) ([]rm.FIELD_TYPE, error) {
+	types := make([]rm.FIELD_TYPE, len(params))
+	for i := range types {
+		types[i] = PARAM_UNKNOWN
+	}
+	b := NewBasicQueryPlan(mdm)
+	switch data := stmt.(type) {
+	case *parser.QueryData:
+		plan, s, err := b.createPlan(data, nil, tx)
+		if err != nil {
+			return nil, err
+		}
+		queryParamTypes(types, params, plan, s)
+	case *parser.InsertData:
+		tablePlan, err := NewTablePlan(tx, data.TableName(), mdm)
+		if err != nil {
+			return nil, err
+		}
+		sch := tablePlan.Schema()
+		rows := data.Rows()
+		if data.Query() != nil {
+			plan, s, err := b.createPlan(data.Query(), nil, tx)
+			if err != nil {
+				return nil, err
+			}
+			queryParamTypes(types, params, plan, s)
+			//选择的列写入到对应的字段中，例如 insert into t (a) select ? from s
+			rows = [][]*query.Expression{s.data.Columns()}
+		}
+		for _, row := range rows {
+			for i, field := range data.Fields() {
+				if i < len(row) && sch.HashField(field) {
+					exprParamType(types, params, row[i], sch.Type(field))
+				}
+			}
+		}
+	case *parser.UpdateData:
+		tablePlan, err := NewTablePlan(tx, data.TableName(), mdm)
+		if err != nil {
+			return nil, err
+		}
+		s, pred, vals, err := b.resolveTable(tablePlan, data.TableName(), data.Pred(), data.NewValues(), tx)
+		if err != nil {
+			return nil, err
+		}
+		sch := tablePlan.Schema()
+		for i, field := range data.Fields() {
+			if sch.HashField(field) {
+				exprParamType(types, params, vals[i], sch.Type(field))
+			}
+		}
+		predParamTypes(types, params, pred, sch)
+		subqueryParamTypes(types, params, s)
+	case *parser.DeleteData:
+		tablePlan, err := NewTablePlan(tx, data.TableName(), mdm)
+		if err != nil {
+			return nil, err
+		}
+		s, pred, _, err := b.resolveTable(tablePlan, data.TableName(), data.Pred(), nil, tx)
+		if err != nil {
+			return nil, err
+		}
+		predParamTypes(types, params, pred, tablePlan.Schema())
+		subqueryParamTypes(types, params, s)
+	}
+	return types, nil
+}
+
+//queryParamTypes 推断查询中的参数槽的类型，s是查询的scope，plan是为它创建的查询计划
+func queryParamTypes(types []rm.FIELD_TYPE, params []*comm.Constant, plan Plan, s *scope) {
+	data := s.data
+	sch, pred := treeSchema(plan), data.Pred()
+	for _, ref := range data.From() {
+		for _, join := range ref.Joins() {
+			joined := query.NewPredicate()
+			joined.ConjoinWith(pred)
+			joined.ConjoinWith(join.On())
+			pred = joined
+		}
+	}
+	for _, column := range data.Columns() {
+		operandTypes(types, params, column)
+	}
+	setParamType(types, params, data.Limit(), rm.INTEGER)
+	setParamType(types, params, data.Offset(), rm.INTEGER)
+	if data.Having() != nil {
+		having := query.NewPredicate()
+		having.ConjoinWith(pred)
+		having.ConjoinWith(data.Having())
+		pred = having
+	}
+	predParamTypes(types, params, pred, sch)
+	subqueryParamTypes(types, params, s)
+}
+
+//subqueryParamTypes 推断s中的子查询以及FROM中的子查询里的参数槽的类型
+func subqueryParamTypes(types []rm.FIELD_TYPE, params []*comm.Constant, s *scope) {
+	for sub, inner := range s.subs {
+		queryParamTypes(types, params, sub.Plan().(Plan), inner)
+	}
+	for _, r := range s.ranges {
+		if r.derived != nil {
+			queryParamTypes(types, params, r.plan, r.derived)
+		}
+	}
+}
+
+//predParamTypes 比较运算符两边的类型相同
+func predParamTypes(types []rm.FIELD_TYPE, params []*comm.Constant, pred *query.Predicate, sch rm.SchemaInterface) {
+	for _, term := range pred.Comparisons() {
+		lhs, rhs := term.Lhs(), term.Rhs()
+		if rhsType, _, err := exprType(rhs, sch); err == nil {
+			exprParamType(types, params, lhs, rhsType)
+		}
+		if lhsType, _, err := exprType(lhs, sch); err == nil {
+			exprParamType(types, params, rhs, lhsType)
+		}
+		operandTypes(types, params, lhs)
+		operandTypes(types, params, rhs)
+	}
+}
+
+//exprParamType e的值需要是fieldType类型，e本身是参数槽的时候记录下它的类型，同时推断e中运算符和函数的参数
+//结果需要是数值类型的算术运算，操作数也使用这个类型，例如写入DECIMAL字段的 ? * 2 中的参数槽是DECIMAL类型
+func exprParamType(types []rm.FIELD_TYPE, params []*comm.Constant, e *query.Expression, fieldType rm.FIELD_TYPE) {
+	if e.IsConstant() && fieldType != PARAM_UNKNOWN {
+		setParamType(types, params, e.AsConstant(), fieldType)
+	}
+	if e.Function() == nil && len(e.Args()) > 0 && rm.IsNumeric(fieldType) {
+		for _, arg := range e.Args() {
+			exprParamType(types, params, arg, fieldType)
+		}
+		return
+	}
+	operandTypes(types, params, e)
+}
+
+//operandTypes 算术运算符的操作数是整数，函数的参数是函数要求的类型
+func operandTypes(types []rm.FIELD_TYPE, params []*comm.Constant, e *query.Expression) {
+	for i, arg := range e.Args() {
+		argType := rm.INTEGER
+		if e.Function() != nil {
+			argType = e.Function().ArgType(i)
+		}
+		if argType == query.TYPE_ANY {
+			argType = PARAM_UNKNOWN
+		}
+		exprParamType(types, params, arg, argType)
+	}
+}
+
+//setParamType 如果val是一个参数槽，就记录下它的类型
+func setParamType(types []rm.FIELD_TYPE, params []*comm.Constant, val *comm.Constant, fieldType rm.FIELD_TYPE) {
+	for i, param := range params {
+		if param == val {
+			types[i] = fieldType
+		}
+	}
+}
+
+//Rebind 让缓存下来的查询计划在另外一个事务中执行，查询树中TablePlan，SortPlan，TopNPlan和GroupByPlan持有事务
+func Rebind(p Plan, tx *tx.Transaction) {
+	switch plan := p.(type) {
+	case *TablePlan:
+		plan.tx = tx
+	case *SortPlan:
+		plan.tx = tx
+	case *TopNPlan:
+		plan.tx = tx
+	case *GroupByPlan:
+		plan.tx = tx
+	}
+	for _, child := range childPlans(p) {
+		Rebind(child, tx)
+	}
+	for _, sub := range subPlans(p) {
+		Rebind(sub.Plan().(Plan), tx)
+	}
+}
+
+//treeSchema 查询树中所有节点的字段，WHERE条件和HAVING条件中的字段分别出现在不同的节点中
+func treeSchema(p Plan) *rm.Schema {
+	sch := rm.NewSchema()
+	var walk func(p Plan)
+	walk = func(p Plan) {
+		for _, field := range p.Schema().Fields() {
+			if !sch.HashField(field) {
+				sch.Add(field, p.Schema())
+			}
+		}
+		for _, child := range childPlans(p) {
+			walk(child)
+		}
+	}
+	walk(p)
+	return sch
+}
@@ -101,7 +101,7 @@ func TestQueryPlan(t *testing.T) {
 	p := parser.NewSQLParser(queryStr)                //传入当前的SQL语句，并获得对应的SQL解析流
 	queryData, _ := p.Query()                         //获得对应的抽象语法树
 	testPlanner := NewBasicQueryPlan(mdm)             //构造一个BasicQuery对象
-	testPlan := testPlanner.CreatePlan(queryData, tx) //获得执行计划
+	testPlan, _ := testPlanner.CreatePlan(queryData, tx) //获得执行计划
 	testInterface, _ := testPlan.Open()               //启动执行计划
 	testScan, ok := testInterface.(query.Scan)        //将他转化成Scan类型的对象
 	println(ok)
@@ -109,5 +109,6 @@ func TestQueryPlan(t *testing.T) {
 		//调用每个算子的next方法，并输出结果
 		fmt.Printf("name: %s\n", testScan.GetString("name"))
 	}
-
+	testScan.Close()
+	tx.Commit() //将当前这个事务提交，释放锁
 }
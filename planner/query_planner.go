@@ -1,8 +1,12 @@
 package planner
 
 import (
+	"fmt"
 	mm "miniSQL/metadata_manager"
+	"miniSQL/comm"
 	"miniSQL/parser"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
 	tx "miniSQL/transaction"
 )
 
@@ -18,32 +22,47 @@ func NewBasicQueryPlan(mdm *mm.MetaDataManager) *BasicQueryPlan {
 }
 
 //CreatePlan 创建一个查询计划
-func (b *BasicQueryPlan) CreatePlan(data *parser.QueryData, tx *tx.Transaction) Plan {
-	//1.直接创建QueryData 对象中的表
+func (b *BasicQueryPlan) CreatePlan(data *parser.QueryData, tx *tx.Transaction) (Plan, error) {
+	p, _, err := b.createPlan(data, nil, tx)
+	return p, err
+}
+
+//createPlan 创建查询计划，同时返回查询的scope，其中有把字段都换成了规范名字的查询，预处理语句根据它推断参数的类型
+//parent是外层查询的scope，创建子查询的计划时使用，子查询中可以引用外层查询的字段
+func (b *BasicQueryPlan) createPlan(data *parser.QueryData, parent *scope, tx *tx.Transaction) (Plan, *scope, error) {
+	if data.SetOperation() != nil {
+		return b.setPlan(data, parent, tx)
+	}
+	s, err := b.newScope(data, parent, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.data, err = s.resolveQuery(data); err != nil {
+		return nil, nil, err
+	}
+	//子查询引用了外层的哪些字段在创建子查询的计划时才知道，所以先为子查询创建计划
+	if err := b.bindSubqueries(s, querySubqueries(s.data), tx); err != nil {
+		return nil, nil, err
+	}
+	p, err := b.queryPlan(s, s.data, tx)
+	return p, s, err
+}
+
+//fromPlan FROM中的每一项先和它后面JOIN的表连接起来，再把所有的项执行Product
+func (s *scope) fromPlan(data *parser.QueryData) (Plan, error) {
 	plans := make([]Plan, 0)
-	tables := data.Tables() //从查询的抽象语法树中获得对应的表名
-	for _, tblname := range tables {
-		//遍历当前的表名
-		//获得该表对应的视图的SQL语句
-		viewdef, err := b.mdm.GetViewDef(tblname, tx) //从视图管理器中查询当前是否有这个表名
-		if err != nil {
-			return nil
-		}
-		if viewdef != "" {
-			//当前并不存在相应的视图
-			//直接创建表对应的视图
-			parsers := parser.NewSQLParser(viewdef) //获得这个视图对应的SQL语句
-			viewData, err := parsers.Query()        //重新获得它对应的抽象语法树结构
-			if err != nil {
-				return nil
+	ranges := s.ranges
+	for _, ref := range data.From() {
+		pl := s.plan(ranges[0])
+		ranges = ranges[1:]
+		for _, join := range ref.Joins() {
+			var err error
+			if pl, err = joinPlan(pl, s.plan(ranges[0]), join); err != nil {
+				return nil, err
 			}
-			//递归的创建对应的表的Planner
-			plans = append(plans, b.CreatePlan(viewData, tx))
-		} else {
-			//管理的视图中并没有当前的这个sql语句
-			pl, _ := NewTablePlan(tx, tblname, b.mdm) //构造一个当前的表Plan
-			plans = append(plans, pl)
+			ranges = ranges[1:]
 		}
+		plans = append(plans, pl)
 	}
 	//将所有的表执行Product（笛卡尔积操作），表的顺序对后续的查询效率有很大的影响，这里我们并没有考虑表的顺序影响
 	//只是按照给定的表依次执行Product操作，后续我们会进行优化
@@ -53,9 +72,215 @@ func (b *BasicQueryPlan) CreatePlan(data *parser.QueryData, tx *tx.Transaction)
 	for _, nextPlan := range pps {
 		p = NewProductPlan(p, nextPlan) //将所有的表执行Product（笛卡尔积操作）
 	}
+	return p, nil
+}
+
+//queryPlan 根据字段已经换成了规范名字的查询创建查询计划
+func (b *BasicQueryPlan) queryPlan(s *scope, data *parser.QueryData, tx *tx.Transaction) (Plan, error) {
+	//1.直接创建QueryData 对象中的表
+	p, err := s.fromPlan(data)
+	if err != nil {
+		return nil, err
+	}
+	if s.outer != nil {
+		//相关子查询从OuterRefPlan中读取外层查询的字段
+		p = NewProductPlan(p, NewOuterRefPlan(s.outer, s.outerSch))
+	}
 	//再执行Select算子
-	p = NewSelectPlan(p, data.Pred())
-	//再执行project投影操作,把指定的字段给筛选出来
-	return NewProjectPlan(p, data.Fields())
+	if err := checkPredicate(data.Pred(), p.Schema()); err != nil {
+		return nil, err
+	}
+	if p, err = s.decorrelate(p, data.Pred()); err != nil {
+		return nil, err
+	}
+	ordered := false
+	if data.IsAggregate() {
+		groupPlan, err := createGroupByPlan(p, data, tx)
+		if err != nil {
+			return nil, err
+		}
+		ordered = groupPlan.Ordered()
+		p = groupPlan
+		if data.Having() != nil {
+			if err := checkPredicate(data.Having(), p.Schema()); err != nil {
+				return nil, err
+			}
+			p = NewSelectPlan(p, data.Having())
+		}
+	}
+	//选择的字段中有表达式或者别名的时候，先把它们计算出来，再执行project投影操作,把指定的字段给筛选出来
+	p, err = createExtendPlan(p, data)
+	if err != nil {
+		return nil, err
+	}
+	if data.Distinct() {
+		distinctPlan, err := createDistinctPlan(p, data, tx)
+		if err != nil {
+			return nil, err
+		}
+		p = distinctPlan
+	} else {
+		//排序放在投影之前，ORDER BY中可以使用没有被选择出来的字段
+		if len(data.OrderBy()) > 0 && !ordered {
+			for _, key := range data.OrderBy() {
+				if !p.Schema().HashField(key.Field()) {
+					return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, key.Field())
+				}
+			}
+			p = createSortPlan(p, data, tx)
+		}
+		p = NewProjectPlan(p, data.Fields())
+	}
+	return createLimitPlan(p, data)
+}
+
+//createLimitPlan LIMIT放在最上面，读取到足够的记录之后就不再读取下面的记录
+func createLimitPlan(p Plan, data *parser.QueryData) (Plan, error) {
+	if data.Limit() == nil {
+		return p, nil
+	}
+	for _, c := range []*comm.Constant{data.Limit(), data.Offset()} {
+		//预处理语句中还没有绑定的参数槽在执行的时候再检查
+		if c != nil && !c.IsNull() {
+			if _, _, err := limitValues(c, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewLimitPlan(p, data.Limit(), data.Offset()), nil
+}
+
+//tablePlan 创建FROM中一张表的计划，如果是视图就递归的创建视图定义的查询计划
+func (b *BasicQueryPlan) tablePlan(tblname string, tx *tx.Transaction) (Plan, error) {
+	//获得该表对应的视图的SQL语句
+	viewdef, err := b.mdm.GetViewDef(tblname, tx) //从视图管理器中查询当前是否有这个表名
+	if err != nil {
+		return nil, err
+	}
+	if viewdef == "" {
+		//管理的视图中并没有当前的这个sql语句
+		return NewTablePlan(tx, tblname, b.mdm) //构造一个当前的表Plan
+	}
+	//直接创建表对应的视图
+	parsers := parser.NewSQLParser(viewdef) //获得这个视图对应的SQL语句
+	viewData, err := parsers.Query()        //重新获得它对应的抽象语法树结构
+	if err != nil {
+		return nil, err
+	}
+	//递归的创建对应的表的Planner
+	return b.CreatePlan(viewData, tx)
+}
+
+//joinPlan 把join中的表rhs和左边已经连接好的结果p连接起来
+//使用ON条件的内连接和WHERE条件一样，转化成Product上的Select，外连接和USING使用JoinPlan
+func joinPlan(p Plan, rhs Plan, join *parser.JoinData) (Plan, error) {
+	if err := checkJoin(p, rhs, join); err != nil {
+		return nil, err
+	}
+	if join.JoinType() == parser.INNER_JOIN && len(join.Using()) == 0 {
+		return NewSelectPlan(NewProductPlan(p, rhs), join.On()), nil
+	}
+	return NewJoinPlan(p, rhs, join.JoinType(), join.On(), join.Using()), nil
+}
+
+//createExtendPlan 检查选择的表达式，把表达式和使用了别名的字段作为新的字段计算出来
+func createExtendPlan(p Plan, data *parser.QueryData) (Plan, error) {
+	fields := make([]string, 0)
+	exprs := make([]*query.Expression, 0)
+	for i, column := range data.Columns() {
+		if _, _, err := exprType(column, p.Schema()); err != nil {
+			return nil, err
+		}
+		name := data.Fields()[i]
+		if column.IsFieldName() && column.AsFieldName() == name {
+			continue
+		}
+		fields = append(fields, name)
+		exprs = append(exprs, column)
+	}
+	if len(fields) == 0 {
+		return p, nil
+	}
+	return NewExtendPlan(p, fields, exprs), nil
+}
+
+//createSortPlan 有LIMIT的时候只需要排在最前面的几条记录，使用TopNPlan代替完整的排序
+func createSortPlan(p Plan, data *parser.QueryData, tx *tx.Transaction) Plan {
+	if data.Limit() != nil {
+		return NewTopNPlan(tx, p, data.OrderBy(), data.Limit(), data.Offset())
+	}
+	return NewSortPlan(tx, p, data.OrderBy())
+}
+
+//createDistinctPlan 先投影再去重，去重之后ORDER BY的字段必须是被选择出来的字段，排序放在去重之后
+func createDistinctPlan(p Plan, data *parser.QueryData, tx *tx.Transaction) (Plan, error) {
+	p = NewProjectPlan(p, data.Fields())
+	for _, key := range data.OrderBy() {
+		if !p.Schema().HashField(key.Field()) {
+			return nil, fmt.Errorf("%w: %s", ErrNotSelected, key.Field())
+		}
+	}
+	distinctPlan := NewDistinctPlan(tx, p, data.OrderBy())
+	if len(data.OrderBy()) > 0 && !distinctPlan.Ordered() {
+		return createSortPlan(distinctPlan, data, tx), nil
+	}
+	return distinctPlan, nil
+}
 
+//createGroupByPlan 检查分组字段和聚合函数的参数，选择的字段，HAVING以及ORDER BY中只能使用分组字段和聚合函数
+func createGroupByPlan(p Plan, data *parser.QueryData, tx *tx.Transaction) (*GroupByPlan, error) {
+	sch := p.Schema()
+	for _, field := range data.GroupBy() {
+		if !sch.HashField(field) {
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+		}
+	}
+	for _, agg := range data.Aggregates() {
+		if agg.Field() == "*" {
+			continue
+		}
+		if !sch.HashField(agg.Field()) {
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, agg.Field())
+		}
+		if (agg.Fn() == query.AGG_SUM || agg.Fn() == query.AGG_AVG) && !rm.IsNumeric(sch.Type(agg.Field())) {
+			return nil, fmt.Errorf("%w: %s requires a numeric field", ErrTypeMismatch, agg.Name())
+		}
+	}
+	groupPlan := NewGroupByPlan(tx, p, data.GroupBy(), data.Aggregates(), data.OrderBy())
+	fields := make([]string, 0)
+	for _, column := range data.Columns() {
+		fields = append(fields, column.Fields()...)
+	}
+	if data.Having() != nil {
+		for _, term := range data.Having().Comparisons() {
+			fields = append(fields, term.Lhs().Fields()...)
+			fields = append(fields, term.Rhs().Fields()...)
+		}
+	}
+	for _, key := range data.OrderBy() {
+		//ORDER BY中可以使用选择的字段的别名
+		if !isSelected(data, key.Field()) {
+			fields = append(fields, key.Field())
+		}
+	}
+	for _, field := range fields {
+		if groupPlan.Schema().HashField(field) {
+			continue
+		}
+		if !sch.HashField(field) {
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNotGrouped, field)
+	}
+	return groupPlan, nil
+}
+
+//isSelected 判断name是不是选择的字段在结果中的名字
+func isSelected(data *parser.QueryData, name string) bool {
+	for _, field := range data.Fields() {
+		if field == name {
+			return true
+		}
+	}
+	return false
 }
@@ -5,49 +5,121 @@ import (
 	"miniSQL/query"
 )
 
+/*
+	缩小因子是选择率的倒数，选择率表示满足条件的记录所占的比例：
+	1.field = constant 的选择率是1/V(field)，field = field 的选择率是1/max(V(lhs),V(rhs))
+	2.<> 的选择率是1-1/V，<、<=、>、>= 没有数据分布的信息，按照常见的估算方法认为选择率是1/3
+	3.OR 的选择率是1-(1-s1)(1-s2)...，NOT 的选择率是1-s，AND连接的条件认为是相互独立的，选择率相乘
+	4.两边都是常量的条件直接计算出结果，成立的时候选择率是1，不成立的时候是0
+	5.两边都不是单独的字段的相等比较，例如 a + b = 10，没有办法使用字段的统计信息，选择率认为是1/10
+	6.EXISTS和IN没有子查询结果的统计信息，选择率认为是1/2，估算的时候不执行子查询
+	7.没有NULL值个数的统计信息，IS NULL的选择率认为是1/10，IS NOT NULL是9/10
+*/
+
+const (
+	rangeSelectivity      = 1.0 / 3 //范围比较的选择率
+	expressionSelectivity = 1.0 / 10
+	subquerySelectivity   = 1.0 / 2  //EXISTS和IN的选择率
+	nullSelectivity       = 1.0 / 10 //IS NULL的选择率
+)
+
 //CalculateReductionFactor 根据predicate计算缩小因子
 func CalculateReductionFactor(pred *query.Predicate, plan Plan) int {
-	factor := 1
+	return reductionFactor(predicateSelectivity(pred, plan))
+}
+
+func CalculateReductionFactorForTerm(t *query.Term, plan Plan) int {
+	return reductionFactor(termSelectivity(t, plan))
+}
+
+//reductionFactor 把选择率转换成缩小因子，选择率为0的时候返回最大的因子
+func reductionFactor(selectivity float64) int {
+	if selectivity <= 0 {
+		return math.MaxInt
+	}
+	factor := 1 / selectivity
+	if factor >= math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(math.Round(factor))
+}
+
+//predicateSelectivity AND连接的每一个条件的选择率相乘
+func predicateSelectivity(pred *query.Predicate, plan Plan) float64 {
+	selectivity := 1.0
 	for _, t := range pred.Terms() {
 		//在当前的表达式种一个一个的遍历
-		factor *= CalculateReductionFactorForTerm(t, plan)
+		selectivity *= termSelectivity(t, plan)
 	}
-	return factor
+	return selectivity
 }
 
-func CalculateReductionFactorForTerm(t *query.Term, plan Plan) int {
-	lhsName := ""
-	rhsName := ""
-	if t.Lhs().IsFieldName() && t.Rhs().IsFieldName() {
-		//如果当前的term的=两边都是字段
-		//获得当前的两个字段
-		lhsName = t.Lhs().AsFieldName()
-		rhsName = t.Rhs().AsFieldName()
-		//返回字段最多的值
-		leftDistinct := plan.DistinctValues(lhsName)
-		rightDistinct := plan.DistinctValues(rhsName)
-		if leftDistinct == 0 || rightDistinct == 0 {
-			//如果有一个字段的值=0,直接返回最大的因子即可
-			return math.MaxInt
+func termSelectivity(t *query.Term, plan Plan) float64 {
+	switch t.Kind() {
+	case query.TERM_OR:
+		miss := 1.0
+		for _, child := range t.Children() {
+			miss *= 1 - predicateSelectivity(child, plan)
 		}
+		return 1 - miss
+	case query.TERM_NOT:
+		return 1 - predicateSelectivity(t.Children()[0], plan)
+	case query.TERM_EXISTS:
+		return subquerySelectivity
+	}
+	if t.Op() == query.OP_IN {
+		return subquerySelectivity
+	}
+	if len(t.Subqueries()) > 0 {
+		//和标量子查询的比较
+		return rangeSelectivity
+	}
+	if len(t.Lhs().Fields()) == 0 && len(t.Rhs().Fields()) == 0 {
+		return constantSelectivity(t)
+	}
+	switch t.Op() {
+	case query.OP_EQ:
+		return equalSelectivity(t, plan)
+	case query.OP_NE:
+		return 1 - equalSelectivity(t, plan)
+	case query.OP_IS_NULL:
+		return nullSelectivity
+	case query.OP_IS_NOT_NULL:
+		return 1 - nullSelectivity
+	}
+	return rangeSelectivity
+}
+
+//constantSelectivity 两个常量,where 1=1返回所有的记录，参数还没有绑定或者计算出错的时候也认为所有的记录都满足
+func constantSelectivity(t *query.Term) (selectivity float64) {
+	defer func() {
+		if recover() != nil {
+			selectivity = 1
+		}
+	}()
+	if t.IsSatisfied(nil) {
+		return 1
+	}
+	return 0
+}
 
-		return int(math.Max(float64(leftDistinct), float64(rightDistinct)))
+//equalSelectivity 相等比较的选择率，字段没有任何取值的时候选择率为0
+func equalSelectivity(t *query.Term, plan Plan) float64 {
+	if !t.Lhs().IsFieldName() && !t.Rhs().IsFieldName() {
+		return expressionSelectivity
 	}
-	//其中一个不是字段,则返回这个字段的不同的值
+	distinct := 0
 	if t.Lhs().IsFieldName() {
-		//左边是字段，那么就返回左边的位置值
-		lhsName = t.Lhs().AsFieldName()
-		return plan.DistinctValues(lhsName)
+		distinct = plan.DistinctValues(t.Lhs().AsFieldName())
 	}
 	if t.Rhs().IsFieldName() {
-		//左边是字段，那么就返回左边的位置值
-		rhsName = t.Rhs().AsFieldName()
-		return plan.DistinctValues(rhsName)
+		//如果当前的term的=两边都是字段，使用不同取值较多的字段
+		if v := plan.DistinctValues(t.Rhs().AsFieldName()); v > distinct {
+			distinct = v
+		}
 	}
-	if t.Lhs().AsConstant().Equal(t.Rhs().AsConstant()) {
-		//两个相等的常量,where 1=1返回一条记录即可
-		return 1
-	} else {
-		return math.MaxInt
+	if distinct <= 0 {
+		return 0
 	}
+	return 1 / float64(distinct)
 }
@@ -0,0 +1,404 @@
+package planner
+
+import (
+	"fmt"
+	"miniSQL/parser"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+)
+
+/*
+	FROM中的每一张表是一个范围变量，它的名字是表的别名，没有别名的时候是表名，同一个查询中范围变量的名字不能重复
+	查询中的字段可以写成t.col，也可以只写col，创建查询计划之前先把所有的字段换成规范的名字：
+	1.只有一张表有col字段的时候，规范的名字就是col，这样没有同名字段的查询和以前完全一样
+	2.多张表都有col字段的时候，规范的名字是t.col，这些表使用AliasPlan把字段换成规范的名字，这时只写col会报告歧义的错误
+	3.USING中的字段在连接的结果中只出现一次，右边的表中的这个字段合并到左边拥有这个字段的表上，使用左边的规范名字
+	选择的*展开成所有表的所有字段，t.*展开成表t的所有字段，USING合并的字段只出现一次
+	子查询有自己的scope，它的parent是外层查询的scope，在子查询中找不到的字段再到外层查询中查找
+	子查询中引用的外层字段x换成^x，子查询的FROM中多了一个只有一条记录的OuterRefPlan，它的字段^x的值是外层查询当前记录中x的值
+	外层字段本身是更外一层的^x的时候，子查询中的名字是^^x，这样一层一层的传递进去
+*/
+
+//rangeVar FROM中的一张表
+type rangeVar struct {
+	name    string
+	plan    Plan
+	merged  map[string]*rangeVar //USING中合并到左边的字段，对应左边拥有这个字段的表
+	derived *scope               //FROM中的子查询的scope，是表的时候为nil
+}
+
+//hasField 这张表是否提供了field字段，合并到左边的字段由左边的表提供
+func (r *rangeVar) hasField(field string) bool {
+	_, ok := r.merged[field]
+	return !ok && r.plan.Schema().HashField(field)
+}
+
+//scope 一个查询的FROM中所有的表
+type scope struct {
+	ranges   []*rangeVar
+	aggs     map[string]string //聚合函数原来的名字对应解析之后的名字，例如max(s.age)对应max(age)
+	data     *parser.QueryData //把字段换成规范名字之后的查询
+	parent   *scope            //外层查询的scope，不是子查询的时候为nil
+	outer    *query.OuterRow   //外层查询当前的记录，没有引用外层字段的时候为nil
+	outerSch *rm.Schema        //引用的外层字段^x以及它们的类型
+	subs     map[*query.Subquery]*scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		aggs:     make(map[string]string),
+		parent:   parent,
+		outerSch: rm.NewSchema(),
+		subs:     make(map[*query.Subquery]*scope),
+	}
+}
+
+//newScope 为FROM中的每一张表创建查询计划，并且找出USING中的字段合并到哪一张表上
+func (b *BasicQueryPlan) newScope(data *parser.QueryData, parent *scope, tx *tx.Transaction) (*scope, error) {
+	s := newScope(parent)
+	for _, ref := range data.From() {
+		r, err := s.addRange(b, ref.TableName(), ref.RangeName(), ref.Query(), tx)
+		if err != nil {
+			return nil, err
+		}
+		left := []*rangeVar{r}
+		for _, join := range ref.Joins() {
+			r, err := s.addRange(b, join.TableName(), join.RangeName(), join.Query(), tx)
+			if err != nil {
+				return nil, err
+			}
+			for _, field := range join.Using() {
+				if !r.plan.Schema().HashField(field) {
+					return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+				}
+				owner, err := usingOwner(left, field)
+				if err != nil {
+					return nil, err
+				}
+				r.merged[field] = owner
+			}
+			left = append(left, r)
+		}
+	}
+	return s, nil
+}
+
+//addRange 加入FROM中的一张表，derived不为nil的时候是FROM中的子查询，它不能引用外层查询的字段
+func (s *scope) addRange(b *BasicQueryPlan, table string, name string, derived *parser.QueryData, tx *tx.Transaction) (*rangeVar, error) {
+	if s.lookup(name) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateAlias, name)
+	}
+	var (
+		p     Plan
+		inner *scope
+		err   error
+	)
+	if derived != nil {
+		p, inner, err = b.createPlan(derived, nil, tx)
+	} else {
+		p, err = b.tablePlan(table, tx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := s.addPlan(name, p)
+	r.derived = inner
+	return r, nil
+}
+
+func (s *scope) addPlan(name string, p Plan) *rangeVar {
+	r := &rangeVar{
+		name:   name,
+		plan:   p,
+		merged: make(map[string]*rangeVar),
+	}
+	s.ranges = append(s.ranges, r)
+	return r
+}
+
+//usingOwner USING左边的表中只能有一张表提供这个字段
+func usingOwner(left []*rangeVar, field string) (*rangeVar, error) {
+	var owner *rangeVar
+	for _, r := range left {
+		if !r.hasField(field) {
+			continue
+		}
+		if owner != nil {
+			return nil, fmt.Errorf("%w: %s", ErrAmbiguousField, field)
+		}
+		owner = r
+	}
+	if owner == nil {
+		return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	return owner, nil
+}
+
+func (s *scope) lookup(name string) *rangeVar {
+	for _, r := range s.ranges {
+		if r.name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+//canonical 表r中的字段field在查询中的规范名字
+func (s *scope) canonical(r *rangeVar, field string) string {
+	if owner, ok := r.merged[field]; ok {
+		return s.canonical(owner, field)
+	}
+	count := 0
+	for _, other := range s.ranges {
+		if other.hasField(field) {
+			count++
+		}
+	}
+	if count == 1 {
+		return field
+	}
+	return r.name + "." + field
+}
+
+//plan 表r的查询计划，有字段需要换成t.col的时候在上面加上AliasPlan
+func (s *scope) plan(r *rangeVar) Plan {
+	renamed := false
+	fields := make([]string, 0)
+	for _, field := range r.plan.Schema().Fields() {
+		name := s.canonical(r, field)
+		renamed = renamed || name != field
+		fields = append(fields, name)
+	}
+	if !renamed {
+		return r.plan
+	}
+	return NewAliasPlan(r.plan, r.name, fields)
+}
+
+//resolve 把查询中引用的字段换成规范的名字
+//t.col中t必须是FROM中的表，col必须是t中的字段；只写col的时候如果多张表都有这个字段就是有歧义的
+//找不到的col保持不变，它可能是选择的字段的别名，由后面的检查报告字段不存在
+func (s *scope) resolve(name string) (string, error) {
+	if agg, ok := s.aggs[name]; ok {
+		return agg, nil
+	}
+	resolved, err := s.field(name)
+	if err != nil || resolved != "" {
+		return resolved, err
+	}
+	if qualifier, _ := query.SplitFieldName(name); qualifier != "" {
+		return "", fmt.Errorf("%w: %s", ErrFieldNotFound, name)
+	}
+	return name, nil
+}
+
+//field 先在当前查询的FROM中查找字段，找不到的时候再到外层查询中查找，都找不到的时候返回空字符串
+func (s *scope) field(name string) (string, error) {
+	resolved, err := s.find(name)
+	if err != nil || resolved != "" || s.parent == nil {
+		return resolved, err
+	}
+	outer, err := s.parent.field(name)
+	if err != nil || outer == "" {
+		return "", err
+	}
+	ref := "^" + outer
+	if !s.outerSch.HashField(ref) {
+		fieldType, length := s.parent.fieldType(outer)
+		s.outerSch.AddField(ref, fieldType, length)
+		if s.outer == nil {
+			s.outer = query.NewOuterRow()
+		}
+	}
+	return ref, nil
+}
+
+//find 在当前查询的FROM中查找字段，找不到的时候返回空字符串
+func (s *scope) find(name string) (string, error) {
+	qualifier, field := query.SplitFieldName(name)
+	if qualifier != "" {
+		if r := s.lookup(qualifier); r != nil {
+			if !r.plan.Schema().HashField(field) {
+				return "", fmt.Errorf("%w: %s", ErrFieldNotFound, name)
+			}
+			return s.canonical(r, field), nil
+		}
+		//视图中的字段名本身可能就带有点，例如视图选择了两张表中同名的字段
+		field = name
+	}
+	resolved := ""
+	for _, r := range s.ranges {
+		if !r.plan.Schema().HashField(field) {
+			continue
+		}
+		c := s.canonical(r, field)
+		if resolved != "" && resolved != c {
+			return "", fmt.Errorf("%w: %s", ErrAmbiguousField, name)
+		}
+		resolved = c
+	}
+	return resolved, nil
+}
+
+//fieldType 规范名字为name的字段的类型和长度
+func (s *scope) fieldType(name string) (rm.FIELD_TYPE, int) {
+	for _, r := range s.ranges {
+		if sch := s.plan(r).Schema(); sch.HashField(name) {
+			return sch.Type(name), sch.Length(name)
+		}
+	}
+	return s.outerSch.Type(name), s.outerSch.Length(name)
+}
+
+//refs 子查询中引用的外层字段在外层查询中的名字
+func (s *scope) refs() []string {
+	refs := make([]string, 0)
+	for _, ref := range s.outerSch.Fields() {
+		refs = append(refs, ref[1:])
+	}
+	return refs
+}
+
+//expand 把选择的*和t.*展开成规范的字段名，其他的返回nil
+func (s *scope) expand(column *query.Expression) ([]string, error) {
+	if !column.IsFieldName() {
+		return nil, nil
+	}
+	ranges := s.ranges
+	name := column.AsFieldName()
+	qualifier, field := query.SplitFieldName(name)
+	if field != "*" {
+		return nil, nil
+	}
+	if qualifier != "" {
+		r := s.lookup(qualifier)
+		if r == nil {
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, name)
+		}
+		ranges = []*rangeVar{r}
+	}
+	fields := make([]string, 0)
+	for _, r := range ranges {
+		for _, field := range r.plan.Schema().Fields() {
+			//*中USING合并的字段只出现一次，t.*中仍然包含t中所有的字段
+			if qualifier == "" && !r.hasField(field) {
+				continue
+			}
+			fields = append(fields, s.canonical(r, field))
+		}
+	}
+	return fields, nil
+}
+
+//resolveQuery 返回把所有的字段都换成规范名字，并且展开了*的查询
+func (s *scope) resolveQuery(data *parser.QueryData) (*parser.QueryData, error) {
+	aggs := make([]*query.Aggregate, 0)
+	for _, agg := range data.Aggregates() {
+		field := agg.Field()
+		if field != "*" {
+			var err error
+			if field, err = s.resolve(field); err != nil {
+				return nil, err
+			}
+		}
+		resolved := query.NewAggregate(agg.Fn(), field)
+		s.aggs[agg.Name()] = resolved.Name()
+		if !containsAggregate(aggs, resolved) {
+			aggs = append(aggs, resolved)
+		}
+	}
+	fields := make([]string, 0)
+	columns := make([]*query.Expression, 0)
+	for i, column := range data.Columns() {
+		expanded, err := s.expand(column)
+		if err != nil {
+			return nil, err
+		}
+		if expanded != nil {
+			for _, field := range expanded {
+				fields = append(fields, field)
+				columns = append(columns, query.NewExpressionWithFieldName(field))
+			}
+			continue
+		}
+		if column, err = column.Rename(s.resolve); err != nil {
+			return nil, err
+		}
+		fields = append(fields, data.Fields()[i])
+		columns = append(columns, column)
+	}
+	pred, err := data.Pred().Rename(s.resolve)
+	if err != nil {
+		return nil, err
+	}
+	from := make([]*parser.TableRef, 0)
+	for _, ref := range data.From() {
+		joins := make([]*parser.JoinData, 0)
+		for _, join := range ref.Joins() {
+			on, err := join.On().Rename(s.resolve)
+			if err != nil {
+				return nil, err
+			}
+			var using []string
+			r := s.lookup(join.RangeName())
+			for _, field := range join.Using() {
+				using = append(using, s.canonical(r, field))
+			}
+			resolvedJoin := parser.NewJoinData(join.JoinType(), join.TableName(), join.Alias(), on, using)
+			resolvedJoin.SetQuery(join.Query())
+			joins = append(joins, resolvedJoin)
+		}
+		resolvedRef := parser.NewTableRef(ref.TableName(), ref.Alias(), joins)
+		resolvedRef.SetQuery(ref.Query())
+		from = append(from, resolvedRef)
+	}
+	resolved := parser.NewQueryData(fields, nil, pred)
+	resolved.SetFrom(from)
+	resolved.SetColumns(columns)
+	resolved.SetDistinct(data.Distinct())
+	resolved.SetAggregates(aggs)
+	resolved.SetLimit(data.Limit(), data.Offset())
+	if len(data.GroupBy()) > 0 {
+		groupBy := make([]string, 0)
+		for _, field := range data.GroupBy() {
+			field, err := s.resolve(field)
+			if err != nil {
+				return nil, err
+			}
+			groupBy = append(groupBy, field)
+		}
+		resolved.SetGroupBy(groupBy)
+	}
+	if data.Having() != nil {
+		having, err := data.Having().Rename(s.resolve)
+		if err != nil {
+			return nil, err
+		}
+		resolved.SetHaving(having)
+	}
+	if len(data.OrderBy()) > 0 {
+		keys := make([]*query.SortKey, 0)
+		for _, key := range data.OrderBy() {
+			//ORDER BY中优先使用选择的字段在结果中的名字
+			field := key.Field()
+			if !isSelected(resolved, field) {
+				if field, err = s.resolve(field); err != nil {
+					return nil, err
+				}
+			}
+			keys = append(keys, query.NewSortKey(field, key.Desc()))
+		}
+		resolved.SetOrderBy(keys)
+	}
+	return resolved, nil
+}
+
+func containsAggregate(aggs []*query.Aggregate, agg *query.Aggregate) bool {
+	for _, exist := range aggs {
+		if exist.Name() == agg.Name() {
+			return true
+		}
+	}
+	return false
+}
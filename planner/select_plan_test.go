@@ -0,0 +1,61 @@
+package planner
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/parser"
+	"miniSQL/query"
+	"testing"
+)
+
+func TestSelectPlanPredicate(t *testing.T) {
+	p, tx, db := newTestPlanner(t, "select_test")
+	defer tx.Commit()
+	_, err := p.ExecuteUpdate("create table course (title varchar(16),deptId int)", tx)
+	assert.Nil(t, err)
+	for i := 0; i < 100; i++ {
+		_, err = p.ExecuteUpdate(fmt.Sprintf("insert into course (title,deptId) values ('c%03d',%d)", i, i%10), tx)
+		assert.Nil(t, err)
+	}
+
+	cases := []struct {
+		sql   string
+		count int
+	}{
+		{"select title from course where deptId < 3", 30},
+		{"select title from course where deptId >= 8", 20},
+		{"select title from course where deptId <> 0", 90},
+		{"select title from course where deptId > 2 and deptId <= 4", 20},
+		{"select title from course where deptId = 1 or deptId = 2 or title = 'c099'", 21},
+		{"select title from course where not deptId < 5", 50},
+		{"select title from course where not (deptId = 1 or deptId > 2) and title < 'c050'", 10},
+		{"select title from course where title > 'c095' or (deptId = 0 and title <= 'c010')", 6},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.count, len(collectRows(t, p, c.sql, tx)), c.sql)
+	}
+
+	//选择率：=为1/V，<>为1-1/V，范围比较为1/3，OR为1-(1-s1)(1-s2)，NOT为1-s
+	table, err := NewTablePlan(tx, "course", db.mdm)
+	assert.Nil(t, err)
+	deptIds := table.DistinctValues("deptId")
+	assert.True(t, deptIds > 1)
+	predicate := func(where string) *query.Predicate {
+		pred, err := parser.NewSQLParser(where).Predicate()
+		assert.Nil(t, err)
+		return pred
+	}
+	reduction := func(where string) int {
+		return CalculateReductionFactor(predicate(where), table)
+	}
+	assert.Equal(t, deptIds, reduction("deptId = 1"))
+	assert.Equal(t, 3, reduction("deptId < 5"))
+	assert.Equal(t, 9, reduction("deptId > 1 and title <= 'c050'"))
+	assert.Equal(t, 1, reduction("deptId <> 1"))
+	assert.Equal(t, 1, reduction("not deptId = 1"))
+	assert.Equal(t, 2, reduction("deptId < 2 or deptId > 7"))
+	assert.Equal(t, 1, reduction("1 = 1"))
+	assert.Equal(t, 1, reduction("1 < 2"))
+	assert.Equal(t, table.RecordsOutput(), NewSelectPlan(table, predicate("deptId = 1 or 1 = 1")).RecordsOutput())
+	assert.Equal(t, 0, NewSelectPlan(table, predicate("1 > 2")).RecordsOutput())
+}
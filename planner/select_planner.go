@@ -27,6 +27,7 @@ func NewSelectPlan(p Plan, pred *query.Predicate) *SelectPlan {
 
 //Open 打开当前的selectScan对象
 func (s *SelectPlan) Open() (interface{}, error) {
+	resetSubqueries(s.pred.Subqueries())
 	scan, err := s.p.Open() //打开当前的scan对象，可能是tableScan/projectScan
 
 	if err != nil {
@@ -78,7 +79,6 @@ func (s *SelectPlan) DistinctValues(fldName string) int {
 			return s.p.DistinctValues(fldName)
 		}
 	}
-	return 0
 }
 
 func (s *SelectPlan) Schema() rm.SchemaInterface {
@@ -0,0 +1,100 @@
+package planner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"miniSQL/parser"
+	rm "miniSQL/record_manager"
+	"strings"
+	"testing"
+)
+
+func TestSetOperation(t *testing.T) {
+	p, tx, db := newTestPlanner(t, "set_op_test")
+	defer tx.Commit()
+	for _, sql := range []string{
+		"create table emp (id int, name varchar(10), dept int)",
+		"create table alumni (aid int, aname varchar(20), dept int)",
+		"insert into emp (id, name, dept) values (1, 'ann', 1)",
+		"insert into emp (id, name, dept) values (2, 'ben', 1)",
+		"insert into emp (id, name, dept) values (3, 'cid', 2)",
+		"insert into emp (id, name, dept) values (4, 'dan', 3)",
+		"insert into alumni (aid, aname, dept) values (2, 'ben', 1)",
+		"insert into alumni (aid, aname, dept) values (5, 'eve', 2)",
+		"insert into alumni (aid, aname, dept) values (6, 'frances', 2)",
+	} {
+		_, err := p.ExecuteUpdate(sql, tx)
+		assert.Nil(t, err, sql)
+	}
+
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		{"select name from emp union select aname from alumni", []string{"ann", "ben", "cid", "dan", "eve", "frances"}},
+		{"select name from emp union all select aname from alumni order by name",
+			[]string{"ann", "ben", "ben", "cid", "dan", "eve", "frances"}},
+		{"select dept from emp intersect select dept from alumni", []string{"1", "2"}},
+		{"select dept from emp intersect all select dept from alumni", []string{"1", "2"}},
+		{"select dept from emp except select dept from alumni", []string{"3"}},
+		{"select dept from emp except all select dept from alumni", []string{"1", "3"}},
+		//INTERSECT的优先级比UNION高
+		{"select name from emp where id = 1 union select name from emp intersect select aname from alumni", []string{"ann", "ben"}},
+		{"select id, name from emp except select aid, aname from alumni order by name desc limit 2", []string{"4 dan", "3 cid"}},
+		//ORDER BY中使用左边的名字
+		{"select id as n from emp where dept = 2 union select aid from alumni order by n desc", []string{"6", "5", "3", "2"}},
+		//操作数中的聚合，括号中的操作数有自己的LIMIT
+		{"select count(*) from emp union all (select aid from alumni order by aid desc limit 1)", []string{"4", "6"}},
+		//子查询和FROM中的集合运算
+		{"select name from emp where id in (select aid from alumni union select 4 + 0 from emp where id = 1) order by name",
+			[]string{"ben", "dan"}},
+		{"select t.dept, count(*) from (select name, dept from emp union select aname, dept from alumni) as t group by t.dept order by t.dept",
+			[]string{"1 2", "2 3", "3 1"}},
+		//相关子查询中的集合运算，两边都引用了外层的字段
+		{"select name from emp e where exists (select aid from alumni where aid = e.id except select id from emp where id = e.id and dept = 1)",
+			[]string{}},
+		{"select name from emp e where exists (select aid from alumni where aname = e.name union select id from emp where id = e.id and dept = 3) order by name",
+			[]string{"ben", "dan"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.rows, collectRows(t, p, c.sql, tx), c.sql)
+	}
+
+	//结果中字段的长度取两边较长的
+	scan, sch, err := p.ExecuteQuery("select name from emp union select aname from alumni", tx)
+	assert.Nil(t, err)
+	scan.Close()
+	assert.Equal(t, []string{"name"}, sch.Fields())
+	assert.Equal(t, 20, sch.Length("name"))
+
+	for sql, name := range map[string]string{
+		"select name from emp union select aname from alumni order by name desc": "Union(name DESC)",
+		"select name from emp union all select aname from alumni":                "UnionAll  ",
+		"select dept from emp except all select dept from alumni":                "ExceptAll(dept)",
+	} {
+		plan, err := p.CreateQueryPlan(sql, tx)
+		if !assert.Nil(t, err, sql) {
+			continue
+		}
+		explain := Explain(plan)
+		assert.True(t, strings.Contains(explain, name), explain)
+		//ORDER BY已经由归并的顺序满足了，不需要再排序
+		assert.False(t, strings.HasPrefix(explain, "Sort"), explain)
+	}
+
+	for sql, target := range map[string]error{
+		"select id, name from emp union select aid from alumni":        ErrSetColumns,
+		"select id from emp intersect select aname from alumni":        ErrTypeMismatch,
+		"select id from emp union select aid from alumni order by aid": ErrFieldNotFound,
+		"select id from emp union select x from alumni":                ErrFieldNotFound,
+	} {
+		_, err = p.CreateQueryPlan(sql, tx)
+		assert.ErrorIs(t, err, target, sql)
+	}
+
+	//预处理语句中两边的参数槽
+	stmt, params, err := parser.Prepare("select id from emp where name = ? union select aid from alumni where dept = ? limit ?")
+	assert.Nil(t, err)
+	types, err := ParamTypes(db.mdm, stmt, params, tx)
+	assert.Nil(t, err)
+	assert.Equal(t, []rm.FIELD_TYPE{rm.VARCHAR, rm.INTEGER, rm.INTEGER}, types)
+}
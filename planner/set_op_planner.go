@@ -0,0 +1,233 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"miniSQL/parser"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+)
+
+/*
+	集合运算的查询计划：
+	1.两边的查询分别创建查询计划，它们的parent都是外层查询的scope，集合运算本身的scope只用来保存两边的scope
+	2.两边的列数必须相同，对应的列类型相同，结果中列的长度取两边中较长的，名字使用左边的名字
+	3.UNION ALL直接依次读取两边的记录；其他的运算先用SortPlan把两边按照所有的列排好序，记录太多的时候排序会写入临时表
+	  然后归并两边的记录，相同的记录排在一起，根据在两边出现的次数决定输出几次
+	4.ORDER BY只能使用结果中的字段，排序的时候把ORDER BY的字段放在最前面，这样归并的结果已经满足ORDER BY，不需要再排一次
+*/
+
+//setPlan 为集合运算创建查询计划，parent是外层查询的scope，两边的查询都可以引用外层查询的字段
+func (b *BasicQueryPlan) setPlan(data *parser.QueryData, parent *scope, tx *tx.Transaction) (Plan, *scope, error) {
+	set := data.SetOperation()
+	s := newScope(parent)
+	s.data = data
+	plans := make([]Plan, 0, 2)
+	for _, operand := range []*parser.QueryData{set.Lhs(), set.Rhs()} {
+		p, inner, err := b.createPlan(operand, parent, tx)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.addPlan("", p).derived = inner
+		s.shareOuter(inner)
+		plans = append(plans, p)
+	}
+	if err := checkSetOperands(set.Op(), plans[0].Schema(), plans[1].Schema()); err != nil {
+		return nil, nil, err
+	}
+	setPlan := NewSetOpPlan(tx, set.Op(), set.All(), plans[0], plans[1], data.OrderBy())
+	var p Plan = setPlan
+	if len(data.OrderBy()) > 0 && !setPlan.Ordered() {
+		for _, key := range data.OrderBy() {
+			if !p.Schema().HashField(key.Field()) {
+				return nil, nil, fmt.Errorf("%w: %s", ErrFieldNotFound, key.Field())
+			}
+		}
+		p = createSortPlan(p, data, tx)
+	}
+	p, err := createLimitPlan(p, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, s, nil
+}
+
+//shareOuter 两边的查询引用了外层字段的时候各自有一个OuterRow，外层的记录通过集合运算的OuterRow同时传给它们
+func (s *scope) shareOuter(inner *scope) {
+	if inner.outer == nil {
+		return
+	}
+	if s.outer == nil {
+		s.outer = query.NewOuterRow()
+	}
+	s.outer.Link(inner.outer)
+	for _, field := range inner.outerSch.Fields() {
+		if !s.outerSch.HashField(field) {
+			s.outerSch.Add(field, inner.outerSch)
+		}
+	}
+}
+
+//checkSetOperands 两边的列数必须相同，对应的列类型相同
+//结果中的值直接来自两边的记录，所以只有值的形式相同的INTEGER和BIGINT可以混合使用
+func checkSetOperands(op parser.SetOp, lhs rm.SchemaInterface, rhs rm.SchemaInterface) error {
+	lhsFields, rhsFields := lhs.Fields(), rhs.Fields()
+	if len(lhsFields) != len(rhsFields) {
+		return fmt.Errorf("%w: %d and %d", ErrSetColumns, len(lhsFields), len(rhsFields))
+	}
+	for i, field := range lhsFields {
+		lhsType, rhsType := lhs.Type(field), rhs.Type(rhsFields[i])
+		if !assignable(lhsType, rhsType) || !assignable(rhsType, lhsType) {
+			return fmt.Errorf("%w: %s column %s and %s", ErrTypeMismatch, op, field, rhsFields[i])
+		}
+	}
+	return nil
+}
+
+//SetOpPlan 集合运算，除了UNION ALL之外lhs和rhs都是按照lhsKeys和rhsKeys排好序的SortPlan
+type SetOpPlan struct {
+	op      parser.SetOp
+	all     bool
+	lhs     Plan
+	rhs     Plan
+	lhsKeys []*query.SortKey //左边的所有列，ORDER BY的字段在最前面
+	rhsKeys []*query.SortKey //右边相同位置的列，排序的方向和左边相同
+	sorted  bool             //两边是否需要排序，UNION ALL不需要
+	ordered bool             //结果是否已经满足ORDER BY
+	schema  *rm.Schema
+	records int
+	cost    float64
+}
+
+//NewSetOpPlan orderBy是集合运算的ORDER BY，可以为空
+func NewSetOpPlan(tx *tx.Transaction, op parser.SetOp, all bool, lhs Plan, rhs Plan, orderBy []*query.SortKey) *SetOpPlan {
+	setPlan := &SetOpPlan{
+		op:     op,
+		all:    all,
+		sorted: op != parser.UNION || !all,
+		schema: rm.NewSchema(),
+	}
+	lhsFields, rhsFields := lhs.Schema().Fields(), rhs.Schema().Fields()
+	for i, field := range lhsFields {
+		length := int(math.Max(float64(lhs.Schema().Length(field)), float64(rhs.Schema().Length(rhsFields[i]))))
+		setPlan.schema.AddField(field, lhs.Schema().Type(field), length)
+	}
+	setPlan.lhsKeys, setPlan.ordered = setSortKeys(lhsFields, orderBy)
+	index := make(map[string]int)
+	for i, field := range lhsFields {
+		index[field] = i
+	}
+	for _, key := range setPlan.lhsKeys {
+		setPlan.rhsKeys = append(setPlan.rhsKeys, query.NewSortKey(rhsFields[index[key.Field()]], key.Desc()))
+	}
+	if setPlan.sorted {
+		lhs, rhs = NewSortPlan(tx, lhs, setPlan.lhsKeys), NewSortPlan(tx, rhs, setPlan.rhsKeys)
+	} else {
+		setPlan.ordered = len(orderBy) == 0
+	}
+	setPlan.lhs, setPlan.rhs = lhs, rhs
+	setPlan.records = setPlan.estimateRecords()
+	setPlan.cost = lhs.Cost() + rhs.Cost() + float64(lhs.RecordsOutput()+rhs.RecordsOutput())*cpuCost
+	return setPlan
+}
+
+//setSortKeys 归并使用的排序字段，包括所有的列，ORDER BY的字段都是结果中的字段的时候把它们放在最前面
+//返回的bool表示归并的结果是否满足ORDER BY
+func setSortKeys(fields []string, orderBy []*query.SortKey) ([]*query.SortKey, bool) {
+	isField := make(map[string]bool)
+	for _, field := range fields {
+		isField[field] = true
+	}
+	keys := make([]*query.SortKey, 0, len(fields))
+	used := make(map[string]bool)
+	ordered := true
+	for _, key := range orderBy {
+		if !isField[key.Field()] {
+			ordered = false
+			break
+		}
+		if !used[key.Field()] {
+			keys = append(keys, key)
+			used[key.Field()] = true
+		}
+	}
+	if !ordered {
+		keys, used = keys[:0], make(map[string]bool)
+	}
+	for _, field := range fields {
+		if !used[field] {
+			keys = append(keys, query.NewSortKey(field, false))
+		}
+	}
+	return keys, ordered
+}
+
+//estimateRecords 没有两边记录重叠程度的统计信息，假设较小的一边有一半的记录在另一边也出现
+func (s *SetOpPlan) estimateRecords() int {
+	l, r := s.lhs.RecordsOutput(), s.rhs.RecordsOutput()
+	smaller := int(math.Min(float64(l), float64(r)))
+	switch {
+	case s.op == parser.UNION && s.all:
+		return l + r
+	case s.op == parser.UNION:
+		return l + r - smaller/2
+	case s.op == parser.INTERSECT:
+		return smaller / 2
+	}
+	return l - smaller/2
+}
+
+//Ordered 结果是否已经满足ORDER BY
+func (s *SetOpPlan) Ordered() bool {
+	return s.ordered
+}
+
+func (s *SetOpPlan) Open() (interface{}, error) {
+	lhs, err := s.lhs.Open()
+	if err != nil {
+		return nil, err
+	}
+	rhs, err := s.rhs.Open()
+	if err != nil {
+		lhs.(query.Scan).Close()
+		return nil, err
+	}
+	l, r := lhs.(query.Scan), rhs.(query.Scan)
+	switch s.op {
+	case parser.INTERSECT:
+		return query.NewIntersectScan(l, r, s.lhsKeys, s.rhsKeys, s.all), nil
+	case parser.EXCEPT:
+		return query.NewExceptScan(l, r, s.lhsKeys, s.rhsKeys, s.all), nil
+	}
+	return query.NewUnionScan(l, r, s.lhsKeys, s.rhsKeys, s.all), nil
+}
+
+func (s *SetOpPlan) BlockAccessed() int {
+	return s.lhs.BlockAccessed() + s.rhs.BlockAccessed()
+}
+
+func (s *SetOpPlan) RecordsOutput() int {
+	return s.records
+}
+
+//DistinctValues UNION中一个字段的取值来自两边，其他的运算只会来自左边
+func (s *SetOpPlan) DistinctValues(fldName string) int {
+	distinct := s.lhs.DistinctValues(fldName)
+	if s.op == parser.UNION {
+		for i, field := range s.schema.Fields() {
+			if field == fldName {
+				distinct += s.rhs.DistinctValues(s.rhs.Schema().Fields()[i])
+			}
+		}
+	}
+	return int(math.Min(float64(distinct), math.Max(float64(s.records), 1)))
+}
+
+func (s *SetOpPlan) Schema() rm.SchemaInterface {
+	return s.schema
+}
+
+func (s *SetOpPlan) Cost() float64 {
+	return s.cost
+}
@@ -0,0 +1,68 @@
+package planner
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/query"
+	"strings"
+	"testing"
+)
+
+func TestSortPlan(t *testing.T) {
+	p, tx, _ := newTestPlanner(t, "sort_test")
+	defer tx.Commit()
+	_, err := p.ExecuteUpdate("create table course (title varchar(16),deptId int)", tx)
+	assert.Nil(t, err)
+
+	//记录很少的时候直接在内存中排序
+	for i, title := range []string{"db", "os", "ml", "ai"} {
+		_, err = p.ExecuteUpdate(fmt.Sprintf("insert into course (title,deptId) values ('%s',%d)", title, 10*(i%2)), tx)
+		assert.Nil(t, err)
+	}
+	plan, err := p.CreateQueryPlan("select title from course order by deptId desc, title", tx)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(Explain(plan), "Sort(deptId DESC, title)"))
+	s, err := plan.(*ProjectPlan).p.Open()
+	assert.Nil(t, err)
+	_, ok := s.(*query.MemoryScan)
+	assert.True(t, ok)
+	s.(query.Scan).Close()
+	assert.Equal(t, []string{"ai", "os", "db", "ml"}, collectRows(t, p, "select title from course order by deptId desc, title", tx))
+	assert.Equal(t, []string{"db", "ml", "os", "ai"}, collectRows(t, p, "select title from course order by deptId", tx))
+
+	//记录超过可用缓存的时候，使用临时表进行外部排序，run的个数超过一次可以归并的个数，需要多趟归并
+	//结果和内存中排序一样是稳定的
+	_, err = p.ExecuteUpdate("delete from course where deptId = 10", tx)
+	assert.Nil(t, err)
+	_, err = p.ExecuteUpdate("delete from course where deptId = 0", tx)
+	assert.Nil(t, err)
+	const numRecords = 600
+	for i := 0; i < numRecords; i++ {
+		_, err = p.ExecuteUpdate(fmt.Sprintf("insert into course (title,deptId) values ('c%03d',%d)", i, i*37%101), tx)
+		assert.Nil(t, err)
+	}
+	plan, err = p.CreateQueryPlan("select title,deptId from course order by deptId", tx)
+	assert.Nil(t, err)
+	s, err = plan.Open()
+	assert.Nil(t, err)
+	_, ok = s.(*query.ProjectScan)
+	assert.True(t, ok)
+	scan := s.(query.Scan)
+	count, lastDept, lastTitle := 0, -1, ""
+	for scan.Next() {
+		dept, title := scan.GetInt("deptId"), scan.GetString("title")
+		assert.True(t, dept > lastDept || dept == lastDept && title > lastTitle, "%d %s after %d %s", dept, title, lastDept, lastTitle)
+		lastDept, lastTitle = dept, title
+		count++
+	}
+	scan.Close()
+	assert.Equal(t, numRecords, count)
+	sortScan, err := plan.(*ProjectPlan).p.Open()
+	assert.Nil(t, err)
+	_, ok = sortScan.(*query.SortScan)
+	assert.True(t, ok)
+	sortScan.(query.Scan).Close()
+
+	_, err = p.CreateQueryPlan("select title from course order by grade", tx)
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+}
@@ -0,0 +1,261 @@
+package planner
+
+import (
+	"math"
+	"miniSQL/comm"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+	"sort"
+)
+
+/*
+	SortPlan 实现ORDER BY
+	打开的时候先把底层的记录读取到内存中，内存的大小按照事务当前可以使用的缓存块来计算
+	1.如果所有的记录都能放在内存中，直接在内存中排好序返回
+	2.否则每当内存满了就把内存中的记录排好序写入到一个临时表中，形成一个run
+	  每一趟把fanIn个run归并成一个新的run，直到run的数量不超过fanIn，最后一趟归并交给SortScan在读取的时候完成
+	fanIn是可用的缓存块数减1，归并的时候每个run需要一个缓存块，写入的临时表还需要一个
+*/
+
+type SortPlan struct {
+	tx        *tx.Transaction
+	p         Plan
+	keys      []*query.SortKey
+	layout    *rm.Layout //写入临时表的记录的格式
+	available int        //创建计划的时候可以使用的缓存块数
+	cost      float64
+}
+
+//NewSortPlan 按照keys对p的输出进行排序
+func NewSortPlan(tx *tx.Transaction, p Plan, keys []*query.SortKey) *SortPlan {
+	sortPlan := &SortPlan{
+		tx:        tx,
+		p:         p,
+		keys:      keys,
+		layout:    rm.NewLayoutWithSchema(p.Schema()),
+		available: int(tx.AvailableBuffer()),
+	}
+	sortPlan.cost = p.Cost() + sortPlan.sortCost()
+	return sortPlan
+}
+
+//Open 读取底层的所有记录并排序，返回MemoryScan或者SortScan
+func (s *SortPlan) Open() (interface{}, error) {
+	src, err := s.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	scan := src.(query.Scan)
+	defer scan.Close()
+	fields := s.p.Schema().Fields()
+	comp := query.NewRecordComparator(s.keys)
+	capacity := s.memoryRecords()
+	rows := make([][]*comm.Constant, 0)
+	runs := make([]*TempTable, 0)
+	for scan.Next() {
+		row := make([]*comm.Constant, len(fields))
+		for i, field := range fields {
+			row[i] = scan.GetVal(field)
+		}
+		rows = append(rows, row)
+		if len(rows) < capacity {
+			continue
+		}
+		//内存已经满了，写入一个run
+		run, err := s.writeRun(fields, rows, comp)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+		rows = make([][]*comm.Constant, 0)
+	}
+	if len(runs) == 0 {
+		sortRows(fields, rows, comp)
+		return query.NewMemoryScan(fields, rows), nil
+	}
+	if len(rows) > 0 {
+		run, err := s.writeRun(fields, rows, comp)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	fanIn := s.fanIn()
+	for len(runs) > fanIn {
+		if runs, err = s.mergeIteration(runs, fanIn, comp); err != nil {
+			return nil, err
+		}
+	}
+	scans, err := openRuns(runs)
+	if err != nil {
+		return nil, err
+	}
+	return query.NewSortScan(scans, comp), nil
+}
+
+//sortRows 在内存中进行稳定排序
+func sortRows(fields []string, rows [][]*comm.Constant, comp *query.RecordComparator) {
+	index := make(map[string]int)
+	for i, field := range fields {
+		index[field] = i
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return comp.CompareValues(
+			func(field string) *comm.Constant { return rows[i][index[field]] },
+			func(field string) *comm.Constant { return rows[j][index[field]] },
+		) < 0
+	})
+}
+
+//writeRun 把内存中的记录排好序之后写入到一个新的临时表中
+func (s *SortPlan) writeRun(fields []string, rows [][]*comm.Constant, comp *query.RecordComparator) (*TempTable, error) {
+	sortRows(fields, rows, comp)
+	run := NewTempTable(s.tx, s.p.Schema())
+	dest, err := run.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer dest.Close()
+	for _, row := range rows {
+		dest.Insert()
+		for i, field := range fields {
+			dest.SetVal(field, row[i])
+		}
+	}
+	return run, nil
+}
+
+//mergeIteration 一趟归并，每fanIn个run归并成一个新的run
+func (s *SortPlan) mergeIteration(runs []*TempTable, fanIn int, comp *query.RecordComparator) ([]*TempTable, error) {
+	result := make([]*TempTable, 0, (len(runs)+fanIn-1)/fanIn)
+	for start := 0; start < len(runs); start += fanIn {
+		end := start + fanIn
+		if end > len(runs) {
+			end = len(runs)
+		}
+		if end-start == 1 {
+			result = append(result, runs[start])
+			continue
+		}
+		run, err := s.mergeRuns(runs[start:end], comp)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, run)
+	}
+	return result, nil
+}
+
+//mergeRuns 把多个run归并写入到一个新的临时表中
+func (s *SortPlan) mergeRuns(runs []*TempTable, comp *query.RecordComparator) (*TempTable, error) {
+	scans, err := openRuns(runs)
+	if err != nil {
+		return nil, err
+	}
+	src := query.NewSortScan(scans, comp)
+	defer src.Close()
+	run := NewTempTable(s.tx, s.p.Schema())
+	dest, err := run.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer dest.Close()
+	fields := s.p.Schema().Fields()
+	for src.Next() {
+		dest.Insert()
+		for _, field := range fields {
+			dest.SetVal(field, src.GetVal(field))
+		}
+	}
+	return run, nil
+}
+
+func openRuns(runs []*TempTable) ([]query.Scan, error) {
+	scans := make([]query.Scan, 0, len(runs))
+	for _, run := range runs {
+		scan, err := run.Open()
+		if err != nil {
+			for _, opened := range scans {
+				opened.Close()
+			}
+			return nil, err
+		}
+		scans = append(scans, scan)
+	}
+	return scans, nil
+}
+
+//recordsPerBlock 一个区块中可以存放多少条记录
+func (s *SortPlan) recordsPerBlock() int {
+	n := int(s.tx.BlockSize()) / s.layout.SlotSize()
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+//memoryRecords 内存中最多可以存放多少条记录，按照每个可用的缓存块存放一个区块的记录来计算
+func (s *SortPlan) memoryRecords() int {
+	available := int(s.tx.AvailableBuffer())
+	if available < 1 {
+		available = 1
+	}
+	return available * s.recordsPerBlock()
+}
+
+//fanIn 一次最多可以归并多少个run
+func (s *SortPlan) fanIn() int {
+	fanIn := int(s.tx.AvailableBuffer()) - 1
+	if fanIn < 2 {
+		return 2
+	}
+	return fanIn
+}
+
+//sortCost 排序的开销：内存中排序需要R*log(R)次比较，外部排序每一趟都需要把所有的区块读写一遍
+func (s *SortPlan) sortCost() float64 {
+	records := float64(s.p.RecordsOutput())
+	cost := records * math.Log2(records+1) * cpuCost
+	blocks := s.BlockAccessed()
+	available := s.available
+	if available < 1 {
+		available = 1
+	}
+	if blocks <= available {
+		return cost
+	}
+	fanIn := available - 1
+	if fanIn < 2 {
+		fanIn = 2
+	}
+	runs := math.Ceil(float64(blocks) / float64(available))
+	passes := math.Ceil(math.Log(runs) / math.Log(float64(fanIn)))
+	if passes < 1 {
+		passes = 1
+	}
+	return cost + 2*float64(blocks)*passes*ioCost
+}
+
+//BlockAccessed 排好序的记录写入临时表之后占用的区块数
+func (s *SortPlan) BlockAccessed() int {
+	perBlock := s.recordsPerBlock()
+	return (s.p.RecordsOutput() + perBlock - 1) / perBlock
+}
+
+//RecordsOutput 排序不会改变记录数
+func (s *SortPlan) RecordsOutput() int {
+	return s.p.RecordsOutput()
+}
+
+func (s *SortPlan) DistinctValues(fldName string) int {
+	return s.p.DistinctValues(fldName)
+}
+
+func (s *SortPlan) Schema() rm.SchemaInterface {
+	return s.p.Schema()
+}
+
+func (s *SortPlan) Cost() float64 {
+	return s.cost
+}
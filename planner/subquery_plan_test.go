@@ -0,0 +1,128 @@
+package planner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"miniSQL/comm"
+	"miniSQL/parser"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	"strings"
+	"testing"
+)
+
+func TestSubquery(t *testing.T) {
+	p, tx, db := newTestPlanner(t, "subquery_test")
+	defer tx.Commit()
+	for _, sql := range []string{
+		"create table emp (id int, name varchar(10), dept int, sal int)",
+		"create table dept (did int, title varchar(10))",
+		"insert into emp (id, name, dept, sal) values (1, 'ann', 1, 30)",
+		"insert into emp (id, name, dept, sal) values (2, 'ben', 1, 20)",
+		"insert into emp (id, name, dept, sal) values (3, 'cid', 2, 50)",
+		"insert into emp (id, name, dept, sal) values (4, 'dan', 3, 10)",
+		"insert into dept (did, title) values (1, 'sales')",
+		"insert into dept (did, title) values (2, 'tech')",
+		"insert into dept (did, title) values (4, 'hr')",
+	} {
+		_, err := p.ExecuteUpdate(sql, tx)
+		assert.Nil(t, err, sql)
+	}
+
+	cases := []struct {
+		sql  string
+		rows []string
+	}{
+		//不相关的子查询
+		{"select name from emp where dept in (select did from dept where title <> 'tech') order by name", []string{"ann", "ben"}},
+		{"select name from emp where dept not in (select did from dept) order by name", []string{"dan"}},
+		{"select name from emp where sal > (select avg(sal) from emp) order by name", []string{"ann", "cid"}},
+		{"select name, (select max(sal) from emp) - sal as diff from emp where id < 3 order by id", []string{"ann 20", "ben 30"}},
+		{"select name from emp where exists (select did from dept where did = 4) and id = 1", []string{"ann"}},
+		//没有记录的标量子查询是NULL
+		{"select name from emp where sal = (select sal from emp where id = 9)", []string{}},
+		//相关子查询
+		{"select name from emp e where sal > (select avg(sal) from emp where dept = e.dept) order by name", []string{"ann"}},
+		{"select title, (select count(*) from emp where dept = did) as n from dept order by did", []string{"sales 2", "tech 1", "hr 0"}},
+		{"select name from emp where exists (select did from dept where did = dept and (title = 'tech' or did = 4))", []string{"cid"}},
+		//可以转化成半连接的相关子查询
+		{"select name from emp where exists (select * from dept where did = dept) order by name", []string{"ann", "ben", "cid"}},
+		{"select title from dept where not exists (select id from emp where emp.dept = dept.did)", []string{"hr"}},
+		{"select name from emp e where id in (select id from emp where dept = e.dept and sal > 15) order by name", []string{"ann", "ben", "cid"}},
+		//子查询中再引用外层的外层
+		{"select title from dept d where exists (select id from emp where dept = d.did and exists " +
+			"(select * from emp x where x.sal > emp.sal and x.dept = d.did))", []string{"sales"}},
+		//FROM中的子查询
+		{"select t.title, t.total from (select title, sum(sal) as total from emp join dept on dept = did group by title) as t " +
+			"where t.total > 40 order by t.title", []string{"sales 50", "tech 50"}},
+		{"select e.name, d.title from (select name, dept from emp where sal >= 30) e join dept d on e.dept = d.did order by e.name",
+			[]string{"ann sales", "cid tech"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.rows, collectRows(t, p, c.sql, tx), c.sql)
+	}
+
+	//能转化成半连接的子查询只执行一次
+	for sql, name := range map[string]string{
+		"select name from emp where exists (select * from dept where did = dept)":               "SemiJoin(dept=did)",
+		"select title from dept where not exists (select id from emp where dept = did)":         "AntiJoin(did=dept)",
+		"select name from emp e where id in (select id from emp where dept = e.dept)":           "SemiJoin(id=id AND dept=dept)",
+		"select name from emp where sal > (select avg(sal) from emp x where x.dept = emp.dept)": "SubPlan(correlated: dept)",
+		"select name from emp where dept in (select did from dept)":                             "SubPlan\n",
+	} {
+		plan, err := p.CreateQueryPlan(sql, tx)
+		if !assert.Nil(t, err, sql) {
+			continue
+		}
+		explain := Explain(plan)
+		assert.True(t, strings.Contains(explain, name), explain)
+	}
+
+	//标量子查询返回了多条记录
+	scan, _, err := p.ExecuteQuery("select name from emp where sal = (select sal from emp)", tx)
+	assert.Nil(t, err)
+	assert.Panics(t, func() { scan.(query.Scan).Next() })
+	_, err = p.ExecuteUpdate("update emp set sal = (select sal from emp) where id = 1", tx)
+	assert.ErrorIs(t, err, query.ErrSubqueryRows)
+	for sql, target := range map[string]error{
+		"select name from emp where dept in (select did, title from dept)":   ErrSubqueryColumns,
+		"select name from emp where dept in (select title from dept)":        ErrTypeMismatch,
+		"select name from emp where exists (select * from dept where x = 1)": ErrFieldNotFound,
+		"select name from (select name from emp) as t where t.sal > 1":       ErrFieldNotFound,
+		//FROM中的子查询不能引用外层的字段
+		"select name from emp e, (select did from dept where did = e.dept) as d": ErrFieldNotFound,
+	} {
+		_, err = p.CreateQueryPlan(sql, tx)
+		assert.ErrorIs(t, err, target, sql)
+	}
+
+	//UPDATE和DELETE的条件中使用子查询
+	n, err := p.ExecuteUpdate("update emp set sal = (select max(sal) from emp x where x.dept = emp.dept) where dept in (select did from dept)", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	n, err = p.ExecuteUpdate("delete from emp where not exists (select * from dept where did = dept)", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"ann 30", "ben 30", "cid 50"}, collectRows(t, p, "select name, sal from emp order by name", tx))
+
+	//预处理语句中子查询里的参数槽
+	stmt, params, err := parser.Prepare("select name from emp where ? in (select did from dept where title = ?) and exists " +
+		"(select * from dept where did = dept and title <> ?)")
+	assert.Nil(t, err)
+	types, err := ParamTypes(db.mdm, stmt, params, tx)
+	assert.Nil(t, err)
+	assert.Equal(t, []rm.FIELD_TYPE{rm.INTEGER, rm.VARCHAR, rm.VARCHAR}, types)
+	plan, err := p.queryPlanner.CreatePlan(stmt.(*parser.QueryData), tx)
+	assert.Nil(t, err)
+	for _, bind := range []struct {
+		did     int
+		title   string
+		exclude string
+		rows    int
+	}{{1, "sales", "tech", 2}, {2, "sales", "tech", 0}, {2, "tech", "sales", 1}} {
+		*params[0] = *comm.NewConstantInt(&bind.did)
+		*params[1] = *comm.NewConstantString(&bind.title)
+		*params[2] = *comm.NewConstantString(&bind.exclude)
+		//不相关的子查询每次打开的时候重新执行
+		assert.Equal(t, bind.rows, len(planRows(t, plan)), bind)
+	}
+}
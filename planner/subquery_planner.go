@@ -0,0 +1,335 @@
+package planner
+
+import (
+	"math"
+	"miniSQL/parser"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+	"strings"
+)
+
+/*
+	子查询的查询计划：
+	1.条件和选择的表达式中的子查询使用外层查询的scope作为parent创建查询计划，然后绑定到Subquery上
+	  不相关的子查询只执行一次，相关子查询在外层的每一条记录上执行一次，外层的记录通过OuterRefPlan传进去
+	2.FROM中的子查询和视图一样，先创建它自己的查询计划，再作为一张表使用，它不能引用外层查询的字段
+	3.WHERE中最外层的相关EXISTS，NOT EXISTS和IN子查询，如果子查询只是用相等的条件和外层关联，就转化成半连接：
+	  SELECT * FROM a WHERE EXISTS (SELECT * FROM b WHERE b.x = a.y AND b.z > 1)
+	  子查询去掉和外层关联的条件之后只需要执行一次，把b.x读取到哈希集合中，a的每一条记录使用a.y查找，NOT EXISTS使用反连接
+	  x IN (SELECT c FROM ...) 相当于多了一个x = c的关联条件，NOT IN在子查询中有NULL的时候结果不同，不做转化
+*/
+
+//querySubqueries 查询中所有的子查询，不包括FROM中的子查询
+func querySubqueries(data *parser.QueryData) []*query.Subquery {
+	subs := data.Pred().Subqueries()
+	for _, column := range data.Columns() {
+		subs = append(subs, column.Subqueries()...)
+	}
+	if data.Having() != nil {
+		subs = append(subs, data.Having().Subqueries()...)
+	}
+	for _, ref := range data.From() {
+		for _, join := range ref.Joins() {
+			subs = append(subs, join.On().Subqueries()...)
+		}
+	}
+	return subs
+}
+
+//bindSubqueries 为s中的子查询创建查询计划，子查询中可以引用s中的字段
+func (b *BasicQueryPlan) bindSubqueries(s *scope, subs []*query.Subquery, tx *tx.Transaction) error {
+	for _, sub := range subs {
+		p, inner, err := b.createPlan(sub.Data().(*parser.QueryData), s, tx)
+		if err != nil {
+			return err
+		}
+		sub.Bind(p, inner.outer, inner.refs())
+		s.subs[sub] = inner
+	}
+	return nil
+}
+
+//resolveTable UPDATE和DELETE只有一张表，把条件和新的值中的字段换成规范的名字，并且为其中的子查询创建查询计划
+func (b *BasicQueryPlan) resolveTable(p Plan, table string, pred *query.Predicate, vals []*query.Expression, tx *tx.Transaction) (*scope, *query.Predicate, []*query.Expression, error) {
+	s := newScope(nil)
+	s.addPlan(table, p)
+	pred, err := pred.Rename(s.resolve)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	subs := pred.Subqueries()
+	resolved := make([]*query.Expression, len(vals))
+	for i, val := range vals {
+		if resolved[i], err = val.Rename(s.resolve); err != nil {
+			return nil, nil, nil, err
+		}
+		subs = append(subs, resolved[i].Subqueries()...)
+	}
+	if err := b.bindSubqueries(s, subs, tx); err != nil {
+		return nil, nil, nil, err
+	}
+	return s, pred, resolved, nil
+}
+
+//resetSubqueries 查询计划每次打开的时候清空不相关子查询缓存下来的结果，预处理语句的参数可能已经改变了
+func resetSubqueries(subs []*query.Subquery) {
+	for _, sub := range subs {
+		sub.Reset()
+	}
+}
+
+//exprsSubqueries 多个表达式中所有的子查询
+func exprsSubqueries(exprs []*query.Expression) []*query.Subquery {
+	subs := make([]*query.Subquery, 0)
+	for _, expr := range exprs {
+		subs = append(subs, expr.Subqueries()...)
+	}
+	return subs
+}
+
+//isOuterRef 表达式是不是一个外层查询的字段
+func isOuterRef(e *query.Expression) bool {
+	return e.IsFieldName() && strings.HasPrefix(e.AsFieldName(), "^")
+}
+
+//hasOuterRef 字段中是否有外层查询的字段
+func hasOuterRef(fields []string) bool {
+	for _, field := range fields {
+		if strings.HasPrefix(field, "^") {
+			return true
+		}
+	}
+	return false
+}
+
+//predOuterRef 条件中是否引用了外层查询的字段，包括条件中的子查询引用的
+func predOuterRef(pred *query.Predicate) bool {
+	for _, term := range pred.Comparisons() {
+		if hasOuterRef(term.Lhs().Fields()) || hasOuterRef(term.Rhs().Fields()) {
+			return true
+		}
+	}
+	for _, sub := range pred.Subqueries() {
+		if hasOuterRef(sub.OuterFields()) {
+			return true
+		}
+	}
+	return false
+}
+
+//semiJoin 转化成半连接之后的子查询
+type semiJoin struct {
+	inner     Plan
+	outerKeys []*query.Expression
+	innerKeys []*query.Expression
+	anti      bool
+}
+
+//semiJoinTerm 可以转化成半连接的条件：EXISTS，NOT EXISTS和IN，返回其中的子查询，IN的左边以及是否是反连接
+func semiJoinTerm(t *query.Term) (*query.Subquery, *query.Expression, bool) {
+	switch {
+	case t.Kind() == query.TERM_EXISTS:
+		return t.Rhs().Subquery(), nil, false
+	case t.Kind() == query.TERM_NOT:
+		terms := t.Children()[0].Terms()
+		if len(terms) == 1 && terms[0].Kind() == query.TERM_EXISTS {
+			return terms[0].Rhs().Subquery(), nil, true
+		}
+	case t.Kind() == query.TERM_COMPARE && t.Op() == query.OP_IN:
+		return t.Rhs().Subquery(), t.Lhs(), false
+	}
+	return nil, nil, false
+}
+
+//decorrelate 把pred中最外层的简单的相关子查询转化成半连接，其他的条件放在半连接下面的SelectPlan中
+func (s *scope) decorrelate(p Plan, pred *query.Predicate) (Plan, error) {
+	rest := make([]*query.Term, 0)
+	joins := make([]*semiJoin, 0)
+	for _, t := range pred.Terms() {
+		sub, lhs, anti := semiJoinTerm(t)
+		if sub != nil && sub.IsCorrelated() {
+			join, err := s.subs[sub].semiJoin(lhs, anti)
+			if err != nil {
+				return nil, err
+			}
+			if join != nil {
+				joins = append(joins, join)
+				continue
+			}
+		}
+		rest = append(rest, t)
+	}
+	p = NewSelectPlan(p, query.NewPredicateWithMultiTerms(rest))
+	for _, join := range joins {
+		p = NewSemiJoinPlan(p, join.inner, join.outerKeys, join.innerKeys, join.anti)
+	}
+	return p, nil
+}
+
+//semiJoin s是子查询的scope，子查询只通过inner = ^x这样的条件和外层关联的时候，返回去掉这些条件之后的子查询
+//column是IN的左边，EXISTS的时候为nil；子查询是集合运算，有聚合或者LIMIT的时候返回nil
+func (s *scope) semiJoin(column *query.Expression, anti bool) (*semiJoin, error) {
+	data := s.data
+	if data.SetOperation() != nil || data.IsAggregate() || data.Limit() != nil {
+		return nil, nil
+	}
+	for _, ref := range data.From() {
+		for _, join := range ref.Joins() {
+			if predOuterRef(join.On()) {
+				return nil, nil
+			}
+		}
+	}
+	join := &semiJoin{
+		anti: anti,
+	}
+	if column != nil {
+		if len(data.Columns()) != 1 || hasOuterRef(data.Columns()[0].Fields()) {
+			return nil, nil
+		}
+		join.outerKeys = append(join.outerKeys, column)
+		join.innerKeys = append(join.innerKeys, data.Columns()[0])
+	}
+	local := make([]*query.Term, 0)
+	for _, t := range data.Pred().Terms() {
+		if !predOuterRef(query.NewPredicateWithTerm(t)) {
+			local = append(local, t)
+			continue
+		}
+		if !t.IsEquality() {
+			return nil, nil
+		}
+		lhs, rhs := t.Lhs(), t.Rhs()
+		if isOuterRef(lhs) {
+			lhs, rhs = rhs, lhs
+		}
+		if !isOuterRef(rhs) || hasOuterRef(lhs.Fields()) {
+			return nil, nil
+		}
+		//^x在外层查询中的名字是x
+		join.outerKeys = append(join.outerKeys, query.NewExpressionWithFieldName(rhs.AsFieldName()[1:]))
+		join.innerKeys = append(join.innerKeys, lhs)
+	}
+	p, err := s.fromPlan(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(local) > 0 {
+		p = NewSelectPlan(p, query.NewPredicateWithMultiTerms(local))
+	}
+	join.inner = p
+	return join, nil
+}
+
+//OuterRefPlan 相关子查询中只有一条记录的计划，字段^x的值是外层查询当前记录中x的值
+type OuterRefPlan struct {
+	row    *query.OuterRow
+	names  map[string]string //子查询中的名字对应外层查询中的名字
+	schema *rm.Schema
+}
+
+func NewOuterRefPlan(row *query.OuterRow, sch *rm.Schema) *OuterRefPlan {
+	outerPlan := &OuterRefPlan{
+		row:    row,
+		names:  make(map[string]string),
+		schema: sch,
+	}
+	for _, field := range sch.Fields() {
+		outerPlan.names[field] = field[1:]
+	}
+	return outerPlan
+}
+
+func (o *OuterRefPlan) Open() (interface{}, error) {
+	return query.NewOuterScan(o.row, o.names), nil
+}
+
+func (o *OuterRefPlan) BlockAccessed() int {
+	return 0
+}
+
+func (o *OuterRefPlan) RecordsOutput() int {
+	return 1
+}
+
+func (o *OuterRefPlan) DistinctValues(fldName string) int {
+	return 1
+}
+
+func (o *OuterRefPlan) Schema() rm.SchemaInterface {
+	return o.schema
+}
+
+func (o *OuterRefPlan) Cost() float64 {
+	return 0
+}
+
+//SemiJoinPlan 半连接，输出p中在inner里能找到匹配记录的记录，anti为true的时候是反连接，输出找不到匹配的记录
+//inner只需要读取一次，放到内存中的哈希集合里
+type SemiJoinPlan struct {
+	p         Plan
+	inner     Plan
+	outerKeys []*query.Expression
+	innerKeys []*query.Expression
+	anti      bool
+	records   int
+	cost      float64
+}
+
+func NewSemiJoinPlan(p Plan, inner Plan, outerKeys []*query.Expression, innerKeys []*query.Expression, anti bool) *SemiJoinPlan {
+	semiJoinPlan := &SemiJoinPlan{
+		p:         p,
+		inner:     inner,
+		outerKeys: outerKeys,
+		innerKeys: innerKeys,
+		anti:      anti,
+	}
+	//外层的一条记录能够匹配上的概率，inner中的键和外层的键取值的范围相同的时候为inner的记录数除以外层键不同取值的个数
+	distinct := 1
+	for _, key := range outerKeys {
+		if key.IsFieldName() {
+			distinct *= int(math.Max(float64(p.DistinctValues(key.AsFieldName())), 1))
+		}
+	}
+	matchRate := math.Min(1, float64(inner.RecordsOutput())/float64(distinct))
+	if anti {
+		matchRate = 1 - matchRate
+	}
+	semiJoinPlan.records = int(float64(p.RecordsOutput()) * matchRate)
+	semiJoinPlan.cost = p.Cost() + inner.Cost() + float64(p.RecordsOutput()+inner.RecordsOutput())*cpuCost
+	return semiJoinPlan
+}
+
+func (j *SemiJoinPlan) Open() (interface{}, error) {
+	outer, err := j.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	inner, err := j.inner.Open()
+	if err != nil {
+		outer.(query.Scan).Close()
+		return nil, err
+	}
+	return query.NewSemiJoinScan(outer.(query.Scan), inner.(query.Scan), j.outerKeys, j.innerKeys, j.anti), nil
+}
+
+func (j *SemiJoinPlan) BlockAccessed() int {
+	return j.p.BlockAccessed() + j.inner.BlockAccessed()
+}
+
+func (j *SemiJoinPlan) RecordsOutput() int {
+	return j.records
+}
+
+func (j *SemiJoinPlan) DistinctValues(fldName string) int {
+	return int(math.Min(float64(j.p.DistinctValues(fldName)), math.Max(float64(j.records), 1)))
+}
+
+func (j *SemiJoinPlan) Schema() rm.SchemaInterface {
+	return j.p.Schema()
+}
+
+func (j *SemiJoinPlan) Cost() float64 {
+	return j.cost
+}
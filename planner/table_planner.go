@@ -1,6 +1,7 @@
 package planner
 
 import (
+	"fmt"
 	mm "miniSQL/metadata_manager"
 	rm "miniSQL/record_manager"
 	tx "miniSQL/transaction"
@@ -25,11 +26,15 @@ func NewTablePlan(tx *tx.Transaction, tblName string, md *mm.MetaDataManager) (*
 	}
 	//从元数据管理器中获得表结构layout信息
 	if tblPlanner.layout, err = md.GetLayout(tblName, tx); err != nil {
-		return nil, nil
+		return nil, err
+	}
+	if tblPlanner.layout.SlotSize() < 0 {
+		//tblcat中没有找到这张表
+		return nil, fmt.Errorf("%w: %s", ErrTableNotFound, tblName)
 	}
 	//从元数据管理器中获得表的统计信息
 	if tblPlanner.si, err = md.GetStatInfo(tblName, tblPlanner.layout, tx); err != nil {
-		return nil, nil
+		return nil, err
 	}
 
 	//计算查询树的成本
@@ -42,7 +47,7 @@ func NewTablePlan(tx *tx.Transaction, tblName string, md *mm.MetaDataManager) (*
 func (t *TablePlan) Open() (interface{}, error) {
 	tbleScan, err := rm.NewTableScan(t.tx, t.tblName, t.layout)
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 	return tbleScan, nil
 }
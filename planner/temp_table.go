@@ -0,0 +1,41 @@
+package planner
+
+import (
+	"fmt"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+	"sync/atomic"
+)
+
+var tempTableCount uint64 //用来给临时表生成不重复的名字
+
+//TempTable 排序等操作使用的临时表，不会记录在元数据表中
+//临时表的文件名都以temp开头，数据库启动的时候文件管理器会把它们全部删除
+type TempTable struct {
+	tx      *tx.Transaction
+	tblName string
+	layout  *rm.Layout
+}
+
+//NewTempTable 使用给定的表结构创建一个临时表
+func NewTempTable(tx *tx.Transaction, sch rm.SchemaInterface) *TempTable {
+	return &TempTable{
+		tx:      tx,
+		tblName: fmt.Sprintf("temp%d", atomic.AddUint64(&tempTableCount, 1)),
+		layout:  rm.NewLayoutWithSchema(sch),
+	}
+}
+
+//Open 打开临时表，可以读取也可以写入
+func (t *TempTable) Open() (query.UpdateScan, error) {
+	return rm.NewTableScan(t.tx, t.tblName, t.layout)
+}
+
+func (t *TempTable) TableName() string {
+	return t.tblName
+}
+
+func (t *TempTable) Layout() *rm.Layout {
+	return t.layout
+}
@@ -0,0 +1,170 @@
+package planner
+
+import (
+	"container/heap"
+	"math"
+	"miniSQL/comm"
+	"miniSQL/query"
+	rm "miniSQL/record_manager"
+	tx "miniSQL/transaction"
+	"sort"
+)
+
+/*
+	TopNPlan ORDER BY和LIMIT一起使用的时候，只需要排序结果中的前limit+offset条记录
+	读取底层记录的时候使用一个大小为n的大顶堆，堆顶是目前为止排在最后的一条，新的记录排在堆顶前面的时候替换掉堆顶
+	这样只需要n条记录的内存，比较的次数是R*log(n)，不需要把所有的记录都排序
+	n超过内存可以存放的记录数的时候，退化成普通的SortPlan
+*/
+
+type TopNPlan struct {
+	tx     *tx.Transaction
+	p      Plan
+	keys   []*query.SortKey
+	limit  *comm.Constant
+	offset *comm.Constant
+	cost   float64
+}
+
+func NewTopNPlan(tx *tx.Transaction, p Plan, keys []*query.SortKey, limit *comm.Constant, offset *comm.Constant) *TopNPlan {
+	topNPlan := &TopNPlan{
+		tx:     tx,
+		p:      p,
+		keys:   keys,
+		limit:  limit,
+		offset: offset,
+	}
+	records := float64(p.RecordsOutput())
+	n := float64(topNPlan.RecordsOutput())
+	topNPlan.cost = p.Cost() + records*math.Log2(n+1)*cpuCost
+	return topNPlan
+}
+
+//Open 只在内存中保留排在最前面的n条记录
+func (t *TopNPlan) Open() (interface{}, error) {
+	limit, offset, err := limitValues(t.limit, t.offset)
+	if err != nil {
+		return nil, err
+	}
+	n := limit + offset
+	sortPlan := NewSortPlan(t.tx, t.p, t.keys)
+	if n > sortPlan.memoryRecords() {
+		return sortPlan.Open()
+	}
+	src, err := t.p.Open()
+	if err != nil {
+		return nil, err
+	}
+	scan := src.(query.Scan)
+	defer scan.Close()
+	fields := t.p.Schema().Fields()
+	h := newTopNHeap(fields, query.NewRecordComparator(t.keys))
+	for seq := 0; n > 0 && scan.Next(); seq++ {
+		row := &topNRow{vals: make([]*comm.Constant, len(fields)), seq: seq}
+		for i, field := range fields {
+			row.vals[i] = scan.GetVal(field)
+		}
+		if h.Len() < n {
+			heap.Push(h, row)
+		} else if h.less(h.rows[0], row) {
+			h.rows[0] = row
+			heap.Fix(h, 0)
+		}
+	}
+	//堆中的记录按照排序字段从小到大排列，相等的时候先读到的排在前面
+	sort.Slice(h.rows, func(i, j int) bool {
+		return h.less(h.rows[j], h.rows[i])
+	})
+	rows := make([][]*comm.Constant, len(h.rows))
+	for i, row := range h.rows {
+		rows[i] = row.vals
+	}
+	return query.NewMemoryScan(fields, rows), nil
+}
+
+type topNRow struct {
+	vals []*comm.Constant
+	seq  int //读取的顺序，排序字段相等的时候保证结果是稳定的
+}
+
+//topNHeap 大顶堆，堆顶是排在最后的记录
+type topNHeap struct {
+	rows  []*topNRow
+	index map[string]int
+	comp  *query.RecordComparator
+}
+
+func newTopNHeap(fields []string, comp *query.RecordComparator) *topNHeap {
+	h := &topNHeap{
+		index: make(map[string]int),
+		comp:  comp,
+	}
+	for i, field := range fields {
+		h.index[field] = i
+	}
+	return h
+}
+
+//less 在堆中r1是否比r2更靠近堆顶，也就是r1在排序结果中排在r2的后面
+func (h *topNHeap) less(r1 *topNRow, r2 *topNRow) bool {
+	c := h.comp.CompareValues(
+		func(field string) *comm.Constant { return r1.vals[h.index[field]] },
+		func(field string) *comm.Constant { return r2.vals[h.index[field]] },
+	)
+	if c != 0 {
+		return c > 0
+	}
+	return r1.seq > r2.seq
+}
+
+func (h *topNHeap) Len() int {
+	return len(h.rows)
+}
+
+func (h *topNHeap) Less(i, j int) bool {
+	return h.less(h.rows[i], h.rows[j])
+}
+
+func (h *topNHeap) Swap(i, j int) {
+	h.rows[i], h.rows[j] = h.rows[j], h.rows[i]
+}
+
+func (h *topNHeap) Push(x interface{}) {
+	h.rows = append(h.rows, x.(*topNRow))
+}
+
+func (h *topNHeap) Pop() interface{} {
+	row := h.rows[len(h.rows)-1]
+	h.rows = h.rows[:len(h.rows)-1]
+	return row
+}
+
+func (t *TopNPlan) BlockAccessed() int {
+	return t.p.BlockAccessed()
+}
+
+//RecordsOutput 最多输出limit+offset条记录，预处理语句还没有绑定参数的时候按照所有的记录来估计
+func (t *TopNPlan) RecordsOutput() int {
+	records := t.p.RecordsOutput()
+	limit, ok := knownInt(t.limit)
+	if !ok {
+		return records
+	}
+	offset, _ := knownInt(t.offset)
+	if limit+offset < records {
+		return limit + offset
+	}
+	return records
+}
+
+func (t *TopNPlan) DistinctValues(fldName string) int {
+	return int(math.Min(float64(t.p.DistinctValues(fldName)), float64(t.RecordsOutput())))
+}
+
+func (t *TopNPlan) Schema() rm.SchemaInterface {
+	return t.p.Schema()
+}
+
+func (t *TopNPlan) Cost() float64 {
+	return t.cost
+}
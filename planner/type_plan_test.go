@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"miniSQL/comm"
+	rm "miniSQL/record_manager"
+	"testing"
+)
+
+func TestColumnTypes(t *testing.T) {
+	p, t1, db := newTestPlanner(t, "type_test")
+	for _, sql := range []string{
+		"create table item (id int, big bigint, price decimal(8,2), weight double, instock boolean)",
+		"create index itemprice on item (price)",
+		//整数可以写入DECIMAL和DOUBLE字段，小数位数多的时候四舍五入
+		"insert into item (id, big, price, weight, instock) values (1, 9000000000, 19.99, 1.5, true)",
+		"insert into item (id, big, price, weight, instock) values (2, -1, 5, 2, false), (3, 3, 0.125, 0.25, true)",
+		"insert into item (id, big, price, weight, instock) values (4, 4, -0.005, NULL, NULL)",
+	} {
+		_, err := p.ExecuteUpdate(sql, t1)
+		assert.Nil(t, err, sql)
+	}
+
+	for sql, want := range map[string][]string{
+		"select id, big, price, weight, instock from item where id = 1": {"1 9000000000 19.99 1.5 TRUE"},
+		"select price from item where id <> 1 order by id":              {"5.00", "0.13", "-0.01"},
+		//不同的数值类型之间可以比较和运算
+		"select id from item where price = 5 and weight = 2":   {"2"},
+		"select id from item where weight > price order by id": {"3"},
+		"select id from item where instock = true order by id": {"1", "3"},
+		"select id from item where instock = false":            {"2"},
+		//BOOLEAN字段可以单独作为条件
+		"select id from item where instock order by id":                    {"1", "3"},
+		"select id from item where not instock":                            {"2"},
+		"select id from item where (instock) and id > 1 or id = 2":         {"2", "3"},
+		"select price * 2, price + weight, big / 2 from item where id = 1": {"39.98 21.49 4500000000"},
+		"select price / 3 from item where id = 2":                          {"1.666667"},
+		"select id * 1.5 from item where id = 3":                           {"4.5"},
+		//DECIMAL的SUM是精确的，AVG多保留4位小数
+		"select sum(price), avg(price), min(price), max(weight) from item":     {"25.11 6.277500 -0.01 2"},
+		"select instock, count(*) from item group by instock order by instock": {"NULL 1", "FALSE 1", "TRUE 2"},
+		//值相同的整数和定点数属于同一组，INTEGER和BIGINT可以合并
+		"select count(*) from (select id from item union select big from item) u":  {"6"},
+		"select id from item where weight in (select id from item) order by id":    {"2"},
+		"select id from item where price in (select id + 3 from item) order by id": {"2"},
+	} {
+		assert.Equal(t, want, collectRows(t, p, sql, t1), sql)
+	}
+
+	//写入的值超过DECIMAL(8,2)的范围，或者不能隐式转换成字段的类型
+	for sql, want := range map[string]error{
+		"insert into item (id, price) values (5, 1000000)":    comm.ErrDecimalOverflow,
+		"insert into item (id, big) values (5, 1.5)":          ErrTypeMismatch,
+		"insert into item (id, instock) values (5, 1)":        ErrTypeMismatch,
+		"insert into item (id) values (5.0)":                  ErrTypeMismatch,
+		"update item set price = price * 100000 where id = 1": comm.ErrDecimalOverflow,
+		//INTEGER字段只能保存32位的整数，整数运算超出64位的时候返回错误
+		"insert into item (id) values (3000000000)":                    comm.ErrIntegerOverflow,
+		"insert into item (id) select big from item where id = 1":      comm.ErrIntegerOverflow,
+		"update item set id = big where id = 1":                        comm.ErrIntegerOverflow,
+		"update item set big = 9223372036854775807 + big where id = 1": comm.ErrIntegerOverflow,
+		"update item set big = big * 9223372036854775807 where id = 1": comm.ErrIntegerOverflow,
+	} {
+		_, err := p.ExecuteUpdate(sql, t1)
+		assert.ErrorIs(t, err, want, sql)
+	}
+	for _, sql := range []string{
+		"select id from item where instock > 1",
+		"select sum(instock) from item",
+		"select id from item where id",
+	} {
+		_, _, err := p.ExecuteQuery(sql, t1)
+		assert.ErrorIs(t, err, ErrTypeMismatch, sql)
+	}
+	for _, sql := range []string{
+		"insert into item (id, price) values (5, 123456.78)",
+		"update item set weight = price where id = 5",
+	} {
+		_, err := p.ExecuteUpdate(sql, t1)
+		assert.Nil(t, err, sql)
+	}
+	t1.Commit()
+
+	//重新打开之后，字段的类型、精度和小数位数保持不变，索引可以使用定点数查找
+	t2 := db.newTx()
+	p = db.reopen(t, t2)
+	layout, err := db.mdm.GetLayout("item", t2)
+	assert.Nil(t, err)
+	sch := layout.Schema()
+	assert.Equal(t, "DECIMAL(8,2)", rm.TypeName(sch.Type("price"), sch.Length("price")))
+	assert.Equal(t, []rm.FIELD_TYPE{rm.INTEGER, rm.BIGINT, rm.DECIMAL, rm.DOUBLE, rm.BOOLEAN},
+		[]rm.FIELD_TYPE{sch.Type("id"), sch.Type("big"), sch.Type("price"), sch.Type("weight"), sch.Type("instock")})
+	assert.Equal(t, []string{"123456.78 123456.78"}, collectRows(t, p, "select price, weight from item where id = 5", t2))
+	assert.Equal(t, []string{"1"}, collectRows(t, p, "select id from item where price = 19.990", t2))
+	assert.Equal(t, []string{"2"}, collectRows(t, p, "select id from item where price = 5", t2))
+	t2.Commit()
+}
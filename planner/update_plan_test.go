@@ -2,12 +2,15 @@ package planner
 
 import (
 	"fmt"
+	"github.com/stretchr/testify/assert"
 	bm "miniSQL/buffer_manager"
+	"miniSQL/comm"
 	fm "miniSQL/file_manager"
 	lm "miniSQL/log_manager"
 	mm "miniSQL/metadata_manager"
 	"miniSQL/parser"
 	"miniSQL/query"
+	rm "miniSQL/record_manager"
 	tx "miniSQL/transaction"
 	"os"
 	"testing"
@@ -20,7 +23,7 @@ func PrintStudentTable(tx *tx.Transaction, mdm *mm.MetaDataManager) {
 
 	testPlanner := NewBasicQueryPlan(mdm) //创建一个查询计划
 	//创建一个查询计划
-	testPlan := testPlanner.CreatePlan(queryData, tx)
+	testPlan, _ := testPlanner.CreatePlan(queryData, tx)
 	testInterface, _ := testPlan.Open()
 	testScan := testInterface.(query.Scan)
 	for testScan.Next() {
@@ -41,6 +44,8 @@ func CreateInsertUpdateByUpdatePlanner() {
 	lmgr, _ := lm.NewLogManager(fmgr, "logfile")
 	bmgr := bm.NewBufferManager(fmgr, lmgr, 3)
 	tx := tx.NewTransaction(fmgr, lmgr, bmgr)
+	//提交之后释放目录表上的锁，后面的测试还要使用它们
+	defer tx.Commit()
 	mdm, _ := mm.NewMetaDataManager(true, tx)
 
 	//使用这个update接口可以进行增加删除修改操作
@@ -103,3 +108,57 @@ func TestUpdatePlanner(t *testing.T) {
 	CreateInsertUpdateByUpdatePlanner()
 
 }
+
+func TestUpdateFields(t *testing.T) {
+	p, tx, db := newTestPlanner(t, "update_test")
+	defer tx.Commit()
+	for _, sql := range []string{
+		"create table emp (id int, name varchar(10), dept int, sal int)",
+		"create index deptidx on emp (dept)",
+		"insert into emp (id, name, dept, sal) values (1, 'ann', 1, 10), (2, 'ben', 1, 20), (3, 'cid', 2, 30)",
+	} {
+		_, err := p.ExecuteUpdate(sql, tx)
+		assert.Nil(t, err, sql)
+	}
+	//所有的新值都使用修改之前的记录计算
+	n, err := p.ExecuteUpdate("update emp set sal = dept, dept = sal + 1, name = 'x' where id < 3", tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"1 x 11 1", "2 x 21 1", "3 cid 2 30"}, collectRows(t, p, "select id, name, dept, sal from emp order by id", tx))
+
+	//被修改的字段上的索引指向新的值
+	countKey := func(key int) int {
+		idx := db.mdm.GetIndexInfo("emp", tx)["dept"].Open()
+		defer idx.Close()
+		idx.BeforeFirst(comm.NewConstantInt(&key))
+		rows := 0
+		for idx.Next() {
+			rows++
+		}
+		return rows
+	}
+	assert.Equal(t, 0, countKey(1))
+	assert.Equal(t, 1, countKey(11))
+	assert.Equal(t, 1, countKey(21))
+	assert.Equal(t, 1, countKey(2))
+
+	for sql, target := range map[string]error{
+		"update emp set sal = 1, title = 'x'":     ErrFieldNotFound,
+		"update emp set sal = 1, name = 2":        ErrTypeMismatch,
+		"update emp set sal = 1, dept = 'x' || 1": parser.ErrSyntax,
+		"update emp set sal = 1, sal = 2":         parser.ErrSyntax,
+		"update emp set name = 'toolongvalue'":    ErrValueTooLong,
+	} {
+		_, err = p.ExecuteUpdate(sql, tx)
+		assert.ErrorIs(t, err, target, sql)
+	}
+
+	//出错的语句没有修改任何记录
+	assert.Equal(t, []string{"x", "x", "cid"}, collectRows(t, p, "select name from emp order by id", tx))
+
+	stmt, params, err := parser.Prepare("update emp set name = ?, sal = ? where id = ?")
+	assert.Nil(t, err)
+	types, err := ParamTypes(db.mdm, stmt, params, tx)
+	assert.Nil(t, err)
+	assert.Equal(t, []rm.FIELD_TYPE{rm.VARCHAR, rm.INTEGER, rm.INTEGER}, types)
+}
@@ -1,11 +1,16 @@
 package planner
 
 import (
+	"errors"
+	"fmt"
+	"miniSQL/comm"
 	mm "miniSQL/metadata_manager"
 	"miniSQL/parser"
 	"miniSQL/query"
 	rm "miniSQL/record_manager"
 	tx "miniSQL/transaction"
+	"sort"
+	"strings"
 )
 
 //因为无论是修改还是删除，创建还是插入，都是进行修改，所以在这个文件进行处理即可
@@ -24,89 +29,526 @@ func NewBasicUpdatePlanner(mdm *mm.MetaDataManager) *BasicUpdatePlanner {
 }
 
 //ExecuteDelete 执行删除操作,返回删除的记录的数量
-func (b *BasicUpdatePlanner) ExecuteDelete(data *parser.DeleteData, tx *tx.Transaction) int {
+func (b *BasicUpdatePlanner) ExecuteDelete(data *parser.DeleteData, tx *tx.Transaction) (int, error) {
 	//首先要先把要删除的记录给扫描出来
 	//构造一个表查询计划
 	tablePlan, err := NewTablePlan(tx, data.TableName(), b.mdm) //这个tableplan主要是用来打开底层的数据库的
 	if err != nil {
-		return 0
+		return 0, err
 	}
-	selectPlan := NewSelectPlan(tablePlan, data.Pred()) //这个selectplan主要是用来根据查询条件进行筛选数据的
+	//条件中可以使用子查询，例如 delete from student where majorid in (select did from dept where dname = 'math')
+	_, pred, _, err := NewBasicQueryPlan(b.mdm).resolveTable(tablePlan, data.TableName(), data.Pred(), nil, tx)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkPredicate(pred, tablePlan.Schema()); err != nil {
+		return 0, err
+	}
+	selectPlan := NewSelectPlan(tablePlan, pred) //这个selectplan主要是用来根据查询条件进行筛选数据的
+	indexes := b.openIndexes(data.TableName(), tx)
+	defer indexes.Close()
 	//使用一个scan对象把记录拿出来
 	scan, err := selectPlan.Open() //把记录拿出来
 	if err != nil {
-		return 0
+		return 0, err
 	}
 	updateScan := scan.(*query.SelectScan) //进行强制类型转化成selectScan对象
-	count := 0                             //这个就是记录当前有多少条记录的
+	defer updateScan.Close()
+	count := 0 //这个就是记录当前有多少条记录的
 	//根据当前的这个updateScan对象，进行向后查找
 	for updateScan.Next() {
 		//进入到这个地方说明，他当前就是有一条符号条件的记录了
+		indexes.delete(updateScan)
 		updateScan.Delete() //删除底层的记录，就是把当前的某个slot位置设置为没有被使用就说明当前已经被删除了
 		count++
 	}
-	return count
+	return count, nil
 
 }
 
-func (b *BasicUpdatePlanner) ExecuteModify(data *parser.UpdateData, tx *tx.Transaction) int {
+//ExecuteModify 执行修改操作，返回修改的记录的数量
+//SET中所有的新值都使用修改之前的记录计算，例如 set a = b, b = a 交换两个字段的值
+//被修改的字段上有索引的时候，同时把索引中旧的值换成新的值
+func (b *BasicUpdatePlanner) ExecuteModify(data *parser.UpdateData, tx *tx.Transaction) (int, error) {
 	//把记录一条一条的取出来，
 	tablePlan, err := NewTablePlan(tx, data.TableName(), b.mdm) //这个tableplan主要是用来打开底层的数据库的
 	if err != nil {
-		return 0
+		return 0, err
+	}
+	//条件和新的值中都可以使用子查询
+	_, pred, newValues, err := NewBasicQueryPlan(b.mdm).resolveTable(tablePlan, data.TableName(), data.Pred(), data.NewValues(), tx)
+	if err != nil {
+		return 0, err
+	}
+	fields := data.Fields()
+	for i, field := range fields {
+		//新的值可以使用这条记录中的字段，例如 set age = age + 1
+		if err := checkAssign(tablePlan.Schema(), field, newValues[i], tablePlan.Schema()); err != nil {
+			return 0, err
+		}
+	}
+	if err := checkPredicate(pred, tablePlan.Schema()); err != nil {
+		return 0, err
 	}
 
-	selectPlan := NewSelectPlan(tablePlan, data.Pred()) //这个selectplan主要是用来根据查询条件进行筛选数据的
+	selectPlan := NewSelectPlan(tablePlan, pred) //这个selectplan主要是用来根据查询条件进行筛选数据的
+	indexes := b.openIndexes(data.TableName(), tx)
+	defer indexes.Close()
 	//使用一个scan对象把记录拿出来
 	scan, err := selectPlan.Open() //把记录拿出来
 	if err != nil {
-		return 0
+		return 0, err
 	}
 	updateScan := scan.(*query.SelectScan) //进行强制类型转化成selectScan对象
+	defer updateScan.Close()
 	count := 0
 	//update Student set gradyear=2020 where gradyear=2019
 	//下面的evaluate就是把这个要修改的
 	//这样的实现就是按照火山模型，把符合条件的记录一条一条的取出来
+	vals := make([]*comm.Constant, len(fields))
 	for updateScan.Next() {
-		val := data.NewValue().Evaluate(scan.(query.Scan)) //获得需要被修改成的值,看看要修改成哪些值，这个操作就是把20给拿出来
-		updateScan.SetVal(data.TargetField(), val)         //把特定的字段设置成特定的值
+		for i, newValue := range newValues {
+			if vals[i], err = evaluate(newValue, updateScan); err != nil { //获得需要被修改成的值
+				return count, err
+			}
+			if vals[i], err = convertValue(vals[i], tablePlan.Schema(), fields[i]); err != nil {
+				return count, err
+			}
+		}
+		for i, field := range fields {
+			if idx, ok := indexes[field]; ok {
+				rid := updateScan.GetRid().(*rm.RID)
+				idx.Delete(updateScan.GetVal(field), rid)
+				idx.Insert(vals[i], rid)
+			}
+			updateScan.SetVal(field, vals[i]) //把特定的字段设置成特定的值
+		}
 		count++
 	}
-	return count
+	return count, nil
 }
 
-//ExecuteInsert 执行当前的insert语句，最后返回是否执行成功
-func (b *BasicUpdatePlanner) ExecuteInsert(data *parser.InsertData, tx *tx.Transaction) bool {
+//ExecuteInsert 执行当前的insert语句，最后返回插入的记录的数量
+//VALUES中的所有行都检查和计算完之后才开始写入，这样一行出错的时候不会只写入了前面的几行
+func (b *BasicUpdatePlanner) ExecuteInsert(data *parser.InsertData, tx *tx.Transaction) (int, error) {
 	tablePlan, err := NewTablePlan(tx, data.TableName(), b.mdm) //这个tableplan主要是用来打开底层的数据库的
 	if err != nil {
-		return false
+		return 0, err
 	}
+	if data.Query() != nil {
+		return b.insertSelect(tablePlan, data, tx)
+	}
+	insertFields := data.Fields() //获得需要写入的字段
+	rows := make([][]*comm.Constant, len(data.Rows()))
+	for r, insertVal := range data.Rows() {
+		if len(insertFields) != len(insertVal) {
+			return 0, fmt.Errorf("%w: insert into %s: %d fields but %d values in row %d",
+				ErrColumnCount, data.TableName(), len(insertFields), len(insertVal), r+1)
+		}
+		//写入的值中不能使用字段，所以使用一个空的表结构来检查
+		rows[r] = make([]*comm.Constant, len(insertVal))
+		for i, field := range insertFields {
+			if err := checkAssign(tablePlan.Schema(), field, insertVal[i], rm.NewSchema()); err != nil {
+				return 0, err
+			}
+			if rows[r][i], err = evaluate(insertVal[i], nil); err != nil {
+				return 0, err
+			}
+			if rows[r][i], err = convertValue(rows[r][i], tablePlan.Schema(), field); err != nil {
+				return 0, err
+			}
+		}
+	}
+	defaults, err := b.openDefaults(data.TableName(), tablePlan.Schema(), insertFields, tx)
+	if err != nil {
+		return 0, err
+	}
+	indexes := b.openIndexes(data.TableName(), tx)
+	defer indexes.Close()
 	//因为是进行插入，所以就没有select这个操作了
-	uScan, err := tablePlan.Open()      //打开这个底层的表
+	uScan, err := tablePlan.Open() //打开这个底层的表
+	if err != nil {
+		return 0, err
+	}
 	updateScan := uScan.(*rm.TableScan) //获得这个tableScan对象
-	updateScan.Insert()                 //向后增加一个可用的空间
-	insertFields := data.Fields()       //获得需要写入的字段
-	insertVal := data.Vals()            //获得需要写入的值
-	for i := 0; i < len(insertFields); i++ {
-		//遍历这个字段名，并把记录进行写入
-		updateScan.SetVal(insertFields[i], insertVal[i]) //相应的字段插入进相应的值
+	defer updateScan.Close()            //执行完进行一个关闭
+	for _, vals := range rows {
+		updateScan.Insert() //向后增加一个可用的空间
+		defaults.set(updateScan)
+		for i := 0; i < len(insertFields); i++ {
+			//遍历这个字段名，并把记录进行写入
+			updateScan.SetVal(insertFields[i], vals[i]) //相应的字段插入进相应的值
+		}
+		indexes.insert(updateScan)
+	}
+	return len(rows), nil
+}
+
+//insertSelect INSERT ... SELECT，查询结果的列按照位置写入到对应的字段中
+//查询中读取了要写入的表的时候，先把查询结果写入临时表，否则新写入的记录可能又被查询读取到
+func (b *BasicUpdatePlanner) insertSelect(tablePlan *TablePlan, data *parser.InsertData, tx *tx.Transaction) (int, error) {
+	p, err := NewBasicQueryPlan(b.mdm).CreatePlan(data.Query(), tx)
+	if err != nil {
+		return 0, err
+	}
+	insertFields, queryFields := data.Fields(), p.Schema().Fields()
+	if len(insertFields) != len(queryFields) {
+		return 0, fmt.Errorf("%w: insert into %s: %d fields but the query returns %d columns",
+			ErrColumnCount, data.TableName(), len(insertFields), len(queryFields))
+	}
+	sch := tablePlan.Schema()
+	for i, field := range insertFields {
+		if !sch.HashField(field) {
+			return 0, fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+		}
+		if !assignable(p.Schema().Type(queryFields[i]), sch.Type(field)) {
+			return 0, fmt.Errorf("%w: %s", ErrTypeMismatch, field)
+		}
+	}
+	defaults, err := b.openDefaults(data.TableName(), sch, insertFields, tx)
+	if err != nil {
+		return 0, err
 	}
-	updateScan.Close() //执行完进行一个关闭
-	return true        //
+	indexes := b.openIndexes(data.TableName(), tx)
+	defer indexes.Close()
+	src, err := p.Open()
+	if err != nil {
+		return 0, err
+	}
+	srcScan := src.(query.Scan)
+	defer srcScan.Close()
+	if readsTable(p, data.TableName()) {
+		temp := NewTempTable(tx, p.Schema())
+		tempScan, err := temp.Open()
+		if err != nil {
+			return 0, err
+		}
+		defer tempScan.Close()
+		if _, err := copyRecords(srcScan, tempScan, p.Schema(), queryFields, queryFields, nil, nil); err != nil {
+			return 0, err
+		}
+		srcScan = tempScan
+		srcScan.BeforeFirst()
+	}
+	uScan, err := tablePlan.Open()
+	if err != nil {
+		return 0, err
+	}
+	updateScan := uScan.(*rm.TableScan)
+	defer updateScan.Close()
+	return copyRecords(srcScan, updateScan, sch, queryFields, insertFields, defaults, indexes)
 }
 
-//ExecuteCreateTable 创建一个表结构，create table
+//copyRecords 把src中的每一条记录写入到dest中，src中的srcFields[i]写入到dest的destFields[i]，返回写入的记录数
+//destSch是dest的表结构，值先转换成字段的类型再写入，destFields以外的字段写入defaults中的默认值
+//写入的记录同时加入到indexes中，写入临时表的时候defaults和indexes是nil
+func copyRecords(src query.Scan, dest query.UpdateScan, destSch rm.SchemaInterface, srcFields []string, destFields []string, defaults tableDefaults, indexes tableIndexes) (int, error) {
+	count := 0
+	vals := make([]*comm.Constant, len(srcFields))
+	for src.Next() {
+		for i, field := range srcFields {
+			val, err := convertValue(src.GetVal(field), destSch, destFields[i])
+			if err != nil {
+				return count, err
+			}
+			vals[i] = val
+		}
+		dest.Insert()
+		defaults.set(dest)
+		for i, field := range destFields {
+			dest.SetVal(field, vals[i])
+		}
+		indexes.insert(dest)
+		count++
+	}
+	return count, nil
+}
+
+//readsTable 查询计划中是否读取了表table，包括子查询和视图中的表
+func readsTable(p Plan, table string) bool {
+	if tablePlan, ok := p.(*TablePlan); ok && tablePlan.tblName == table {
+		return true
+	}
+	for _, child := range childPlans(p) {
+		if readsTable(child, table) {
+			return true
+		}
+	}
+	for _, sub := range subPlans(p) {
+		if readsTable(sub.Plan().(Plan), table) {
+			return true
+		}
+	}
+	return false
+}
+
+//tableIndexes 一张表上的所有索引，key是建立索引的字段，写入、修改和删除记录的时候同时修改索引
+type tableIndexes map[string]mm.Index
+
+//openIndexes 打开表上所有的索引，要在打开表的scan之前调用
+//GetIndexInfo会扫描表来计算统计信息，事务对同一个区块只记录一次pin，这个扫描关闭的时候会把scan正在使用的区块释放掉
+func (b *BasicUpdatePlanner) openIndexes(tblName string, tx *tx.Transaction) tableIndexes {
+	indexes := make(tableIndexes)
+	for field, info := range b.mdm.GetIndexInfo(tblName, tx) {
+		indexes[field] = info.Open()
+	}
+	return indexes
+}
+
+//insert 把scan当前的记录加入到所有的索引中，NULL也会加入，和CREATE INDEX填充已有记录的时候一致
+func (t tableIndexes) insert(scan query.UpdateScan) {
+	if len(t) == 0 {
+		return
+	}
+	rid := scan.GetRid().(*rm.RID)
+	for field, idx := range t {
+		idx.Insert(scan.GetVal(field), rid)
+	}
+}
+
+//delete 删除记录之前，把它从所有的索引中去掉
+func (t tableIndexes) delete(scan query.UpdateScan) {
+	if len(t) == 0 {
+		return
+	}
+	rid := scan.GetRid().(*rm.RID)
+	for field, idx := range t {
+		idx.Delete(scan.GetVal(field), rid)
+	}
+}
+
+//Close 关闭所有的索引
+func (t tableIndexes) Close() {
+	for _, idx := range t {
+		idx.Close()
+	}
+}
+
+//tableDefaults INSERT中没有给出的字段的默认值，key是字段名
+type tableDefaults map[string]*comm.Constant
+
+//openDefaults 读取表中insertFields以外的字段的默认值
+func (b *BasicUpdatePlanner) openDefaults(tblName string, sch rm.SchemaInterface, insertFields []string, tx *tx.Transaction) (tableDefaults, error) {
+	texts, err := b.mdm.GetDefaults(tblName, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range insertFields {
+		delete(texts, field)
+	}
+	defaults := make(tableDefaults)
+	for field, text := range texts {
+		if defaults[field], err = parseDefault(text, sch, field); err != nil {
+			return nil, err
+		}
+	}
+	return defaults, nil
+}
+
+//set 把默认值写入scan当前的记录，Insert之后所有字段都是NULL
+func (d tableDefaults) set(scan query.UpdateScan) {
+	for field, val := range d {
+		scan.SetVal(field, val)
+	}
+}
+
+//ExecuteCreateTable 创建一个表结构，create table，记录头中的NULL标志限制了字段的个数
 func (b *BasicUpdatePlanner) ExecuteCreateTable(data *parser.CreateTableData, tx *tx.Transaction) error {
+	if len(data.Schema().Fields()) > rm.MAX_FIELDS {
+		return fmt.Errorf("%w: %s has more than %d fields", ErrTooManyFields, data.TableName(), rm.MAX_FIELDS)
+	}
+	if err := b.checkNewName(data.TableName(), tx); err != nil {
+		return err
+	}
 	return b.mdm.CreateTable(data.TableName(), data.Schema(), tx)
 }
 
-//ExecuteCreateView 创建一个视图
+//ExecuteCreateView 创建一个视图，视图不能直接或者通过其他视图间接引用自己，否则查询的时候展开视图不会结束
 func (b *BasicUpdatePlanner) ExecuteCreateView(data *parser.CreateViewData, tx *tx.Transaction) error {
-	return b.mdm.CreateView(data.ViewName(), data.ViewDef(), tx) //创建一个视图
+	name := data.ViewName()
+	if err := b.checkNewName(name, tx); err != nil {
+		return err
+	}
+	//已有的视图可能引用了还不存在的name，新的视图引用它们的时候也会形成循环
+	views, err := b.dependentViews(name, tx)
+	if err != nil {
+		return err
+	}
+	cycle := map[string]bool{name: true}
+	for _, view := range views {
+		cycle[view] = true
+	}
+	for _, table := range data.Query().ReferencedTables() {
+		if cycle[table] {
+			return fmt.Errorf("%w: %s", ErrRecursiveView, name)
+		}
+	}
+	return b.mdm.CreateView(name, data.ViewDef(), tx) //创建一个视图
+}
+
+//checkNewName 新建或者改名之后的表和视图不能和已有的表或者视图重名
+func (b *BasicUpdatePlanner) checkNewName(name string, tx *tx.Transaction) error {
+	for _, kind := range []parser.DropKind{parser.DROP_TABLE, parser.DROP_VIEW} {
+		exists, err := b.exists(kind, name, tx)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w: %s", ErrTableExists, name)
+		}
+	}
+	return nil
 }
 
 //ExecuteCreateIndex 创建一个索引
+// 目前的索引只支持建立在一个字段上，创建完之后会把表中已经存在的记录都加入到索引中
 func (b *BasicUpdatePlanner) ExecuteCreateIndex(data *parser.CreateIndexData, tx *tx.Transaction) error {
-	//TODO
+	if len(data.FieldName()) != 1 {
+		return fmt.Errorf("index %s: only single field index is supported", data.IndexName())
+	}
+	fieldName := data.FieldName()[0]
+	tablePlan, err := NewTablePlan(tx, data.TableName(), b.mdm)
+	if err != nil {
+		return err
+	}
+	if !tablePlan.Schema().HashField(fieldName) {
+		return fmt.Errorf("%w: %s", ErrFieldNotFound, fieldName)
+	}
+	//索引的文件按照索引名命名，重名的索引会共用同一组文件
+	exists, err := b.mdm.IndexExists(data.IndexName(), tx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s", ErrIndexExists, data.IndexName())
+	}
+	if err := b.mdm.CreateIndex(data.IndexName(), data.TableName(), fieldName, tx); err != nil {
+		return err
+	}
+	//遍历数据表，根据数据表来填充索引表中的记录，索引要在打开数据表之前打开，原因见openIndexes
+	idx := b.mdm.GetIndexInfo(data.TableName(), tx)[fieldName].Open()
+	defer idx.Close()
+	s, err := tablePlan.Open()
+	if err != nil {
+		return err
+	}
+	tableScan := s.(*rm.TableScan)
+	defer tableScan.Close()
+	for tableScan.Next() {
+		idx.Insert(tableScan.GetVal(fieldName), tableScan.GetRid().(*rm.RID))
+	}
 	return nil
 }
+
+//catalogTables 元数据表，不能被删除
+var catalogTables = map[string]bool{"tblcat": true, "fldcat": true, "viewcat": true, "idxcat": true, "defcat": true}
+
+//ExecuteDrop 删除表，视图或者索引，IF EXISTS的时候对象不存在不报错
+//有视图依赖要删除的表或者视图的时候，使用CASCADE会同时删除这些视图，否则返回ErrDependentObjects
+//删除的元数据可以回滚，表和索引的文件在事务提交之后才删除
+func (b *BasicUpdatePlanner) ExecuteDrop(data *parser.DropData, tx *tx.Transaction) error {
+	name := data.Name()
+	exists, err := b.exists(data.Kind(), name, tx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if data.IfExists() {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", dropNotFound[data.Kind()], name)
+	}
+	if data.Kind() == parser.DROP_INDEX {
+		_, err = b.mdm.DropIndex(name, tx)
+		return err
+	}
+	if catalogTables[name] {
+		return fmt.Errorf("%w: %s", ErrCatalogTable, name)
+	}
+	views, err := b.dependentViews(name, tx)
+	if err != nil {
+		return err
+	}
+	if len(views) > 0 && !data.Cascade() {
+		return fmt.Errorf("%w: view %s depends on %s %s", ErrDependentObjects, strings.Join(views, ", "),
+			strings.ToLower(data.Kind().String()), name)
+	}
+	for _, view := range views {
+		if _, err := b.mdm.DropView(view, tx); err != nil {
+			return err
+		}
+	}
+	if data.Kind() == parser.DROP_VIEW {
+		_, err = b.mdm.DropView(name, tx)
+	} else {
+		_, err = b.mdm.DropTable(name, tx)
+	}
+	return err
+}
+
+//dropNotFound 要删除的对象不存在的时候返回的错误
+var dropNotFound = map[parser.DropKind]error{
+	parser.DROP_TABLE: ErrTableNotFound,
+	parser.DROP_VIEW:  ErrViewNotFound,
+	parser.DROP_INDEX: ErrIndexNotFound,
+}
+
+//exists 要删除的表，视图或者索引是否存在
+func (b *BasicUpdatePlanner) exists(kind parser.DropKind, name string, tx *tx.Transaction) (bool, error) {
+	switch kind {
+	case parser.DROP_VIEW:
+		viewDef, err := b.mdm.GetViewDef(name, tx)
+		return viewDef != "", err
+	case parser.DROP_INDEX:
+		return b.mdm.IndexExists(name, tx)
+	}
+	return b.mdm.TableExists(name, tx)
+}
+
+//dependentViews 直接或者间接引用了表或者视图name的所有视图，按照名字排序
+//定义已经无法解析的视图本身就不能使用了，不把它们当作依赖
+func (b *BasicUpdatePlanner) dependentViews(name string, tx *tx.Transaction) ([]string, error) {
+	defs, err := b.mdm.ViewDefs(tx)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string][]string)
+	for view, def := range defs {
+		if data, err := parser.NewSQLParser(def).Query(); err == nil {
+			refs[view] = data.ReferencedTables()
+		}
+	}
+	dropped := map[string]bool{name: true}
+	views := make([]string, 0)
+	for changed := true; changed; {
+		changed = false
+		for view, tables := range refs {
+			if dropped[view] {
+				continue
+			}
+			for _, table := range tables {
+				if dropped[table] {
+					dropped[view], changed = true, true
+					views = append(views, view)
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(views)
+	return views, nil
+}
+
+//evaluate 计算表达式的值，计算出错或者标量子查询返回了多条记录的时候返回错误而不是panic
+func evaluate(e *query.Expression, s query.Scan) (val *comm.Constant, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok && (errors.Is(e, comm.ErrArithmetic) || errors.Is(e, query.ErrSubqueryRows)) {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+	return e.Evaluate(s), nil
+}
@@ -0,0 +1,202 @@
+package query
+
+import (
+	"miniSQL/comm"
+	rm "miniSQL/record_manager"
+	"strings"
+)
+
+/*
+	聚合函数把一组记录合并成一个值，例如 SELECT deptId, COUNT(*), MAX(salary) FROM emp GROUP BY deptId
+	Aggregate是语法树中的一个聚合函数，AggregationFn在执行的时候对一组记录进行计算
+	聚合函数的结果作为一个字段输出，字段名是函数名和参数，例如count(*)，max(salary)，HAVING和ORDER BY中也使用这个名字
+*/
+
+const (
+	AGG_COUNT = "count"
+	AGG_SUM   = "sum"
+	AGG_MIN   = "min"
+	AGG_MAX   = "max"
+	AGG_AVG   = "avg"
+)
+
+//IsAggregateFn 判断name是不是一个聚合函数的名字，不区分大小写
+func IsAggregateFn(name string) bool {
+	switch strings.ToLower(name) {
+	case AGG_COUNT, AGG_SUM, AGG_MIN, AGG_MAX, AGG_AVG:
+		return true
+	}
+	return false
+}
+
+//Aggregate 语法树中的一个聚合函数，field为*的时候表示COUNT(*)
+type Aggregate struct {
+	fn    string
+	field string
+}
+
+func NewAggregate(fn string, field string) *Aggregate {
+	return &Aggregate{
+		fn:    strings.ToLower(fn),
+		field: field,
+	}
+}
+
+func (a *Aggregate) Fn() string {
+	return a.fn
+}
+
+//Field 聚合函数的参数，COUNT(*)返回*
+func (a *Aggregate) Field() string {
+	return a.field
+}
+
+//Name 聚合结果对应的字段名
+func (a *Aggregate) Name() string {
+	return a.fn + "(" + a.field + ")"
+}
+
+//ResultType 聚合结果的类型，COUNT是整数，其他的都与参数的类型相同
+func (a *Aggregate) ResultType(fieldType rm.FIELD_TYPE) rm.FIELD_TYPE {
+	if a.fn == AGG_COUNT {
+		return rm.INTEGER
+	}
+	return fieldType
+}
+
+//ResultLength 聚合结果字段的长度，length是参数字段的长度
+//定点数的SUM和AVG使用最大的精度，AVG的小数位数和定点数除法一样多DECIMAL_DIV_SCALE位
+func (a *Aggregate) ResultLength(fieldType rm.FIELD_TYPE, length int) int {
+	switch {
+	case a.fn == AGG_COUNT:
+		return 0
+	case fieldType != rm.DECIMAL || a.fn == AGG_MIN || a.fn == AGG_MAX:
+		return length
+	case a.fn == AGG_AVG:
+		scale := rm.DecimalScale(length) + comm.DECIMAL_DIV_SCALE
+		if scale > comm.MAX_DECIMAL_PRECISION {
+			scale = comm.MAX_DECIMAL_PRECISION
+		}
+		return rm.DecimalLength(comm.MAX_DECIMAL_PRECISION, scale)
+	}
+	return rm.DecimalLength(comm.MAX_DECIMAL_PRECISION, rm.DecimalScale(length))
+}
+
+//NewFn 创建一个执行聚合计算的对象，fieldType是参数字段的类型
+func (a *Aggregate) NewFn(fieldType rm.FIELD_TYPE) AggregationFn {
+	switch a.fn {
+	case AGG_COUNT:
+		return &countFn{field: a.field}
+	case AGG_SUM:
+		return &sumFn{field: a.field}
+	case AGG_AVG:
+		return &sumFn{field: a.field, avg: true}
+	case AGG_MIN:
+		return &extremeFn{field: a.field, sign: -1}
+	default:
+		return &extremeFn{field: a.field, sign: 1}
+	}
+}
+
+//AggregationFn 对一组记录进行聚合计算
+type AggregationFn interface {
+	ProcessFirst(s Scan) //s指向一组记录中的第一条
+	ProcessNext(s Scan)  //s指向同一组中后续的记录
+	Value() *comm.Constant
+}
+
+//countFn COUNT(*)统计记录的条数，COUNT(col)只统计col不是NULL的记录
+type countFn struct {
+	field string
+	count int
+}
+
+func (c *countFn) ProcessFirst(s Scan) {
+	c.count = 0
+	c.ProcessNext(s)
+}
+
+func (c *countFn) ProcessNext(s Scan) {
+	if c.field == "*" || !s.GetVal(c.field).IsNull() {
+		c.count++
+	}
+}
+
+func (c *countFn) Value() *comm.Constant {
+	count := c.count
+	return comm.NewConstantInt(&count)
+}
+
+//sumFn SUM和AVG，跳过NULL，没有不是NULL的值的时候结果是NULL
+//结果的类型和参数相同，整数的平均值向零取整，定点数的平均值和定点数除法一样保留小数
+type sumFn struct {
+	field string
+	avg   bool
+	sum   *comm.Constant
+	count int //不是NULL的值的个数
+}
+
+func (f *sumFn) ProcessFirst(s Scan) {
+	f.sum, f.count = nil, 0
+	f.ProcessNext(s)
+}
+
+//ProcessNext 整数或者定点数超出范围的时候和表达式计算出错一样panic一个包装了comm.ErrArithmetic的错误
+func (f *sumFn) ProcessNext(s Scan) {
+	val := s.GetVal(f.field)
+	if val.IsNull() {
+		return
+	}
+	f.count++
+	if f.sum == nil {
+		f.sum = val
+		return
+	}
+	sum, err := f.sum.Add(val)
+	if err != nil {
+		panic(err)
+	}
+	f.sum = sum
+}
+
+func (f *sumFn) Value() *comm.Constant {
+	if f.count == 0 {
+		return comm.NewNullConstant()
+	}
+	if !f.avg {
+		return f.sum
+	}
+	count := f.count
+	result, err := f.sum.Div(comm.NewConstantInt(&count))
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+//extremeFn MIN和MAX，sign为1的时候保留较大的值，为-1的时候保留较小的值，跳过NULL
+type extremeFn struct {
+	field string
+	sign  int
+	val   *comm.Constant
+}
+
+func (f *extremeFn) ProcessFirst(s Scan) {
+	f.val = nil
+	f.ProcessNext(s)
+}
+
+func (f *extremeFn) ProcessNext(s Scan) {
+	val := s.GetVal(f.field)
+	if !val.IsNull() && (f.val == nil || val.CompareTo(f.val)*f.sign > 0) {
+		f.val = val
+	}
+}
+
+//Value 没有不是NULL的值的时候结果是NULL
+func (f *extremeFn) Value() *comm.Constant {
+	if f.val != nil {
+		return f.val
+	}
+	return comm.NewNullConstant()
+}
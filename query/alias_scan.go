@@ -0,0 +1,48 @@
+package query
+
+import (
+	"miniSQL/comm"
+)
+
+//AliasScan 给底层的字段换一个名字，FROM中的两张表有同名的字段时，其中的字段使用t.col的名字和另一张表区分开
+//例如 SELECT s.id, t.id FROM student s, teacher t 中student的id字段在AliasScan中的名字是s.id
+type AliasScan struct {
+	s     Scan
+	names map[string]string //新的名字对应的底层字段名
+}
+
+func NewAliasScan(s Scan, names map[string]string) *AliasScan {
+	return &AliasScan{
+		s:     s,
+		names: names,
+	}
+}
+
+func (a *AliasScan) BeforeFirst() {
+	a.s.BeforeFirst()
+}
+
+func (a *AliasScan) Next() bool {
+	return a.s.Next()
+}
+
+func (a *AliasScan) GetInt(fieldName string) int {
+	return a.s.GetInt(a.names[fieldName])
+}
+
+func (a *AliasScan) GetString(fieldName string) string {
+	return a.s.GetString(a.names[fieldName])
+}
+
+func (a *AliasScan) GetVal(fieldName string) *comm.Constant {
+	return a.s.GetVal(a.names[fieldName])
+}
+
+func (a *AliasScan) HasField(fieldName string) bool {
+	_, ok := a.names[fieldName]
+	return ok
+}
+
+func (a *AliasScan) Close() {
+	a.s.Close()
+}
@@ -1,8 +1,10 @@
 package query
 
 import (
+	"fmt"
 	"miniSQL/comm"
 	rm "miniSQL/record_manager"
+	"strings"
 )
 
 //SName = 'joe' and MajorId = DId,这一整个是一个predicate
@@ -10,11 +12,26 @@ import (
 //MOD(GradYear,4)==0,由=好将其分成了左右两部分。我们给这些部分用expression来表示
 //对于MOD(GradYear,4),我们分成了MOD，GradYearpackage query
 //其中MOD指定了一个操作operation，GradYear是一个字段名用fieldname表示，4是一个常量，用constant表示
+//表达式是一棵树，叶子节点是常量或者字段，中间节点是算术运算符或者函数调用，例如 (age + 1) * 2，upper(name)
+
+//算术运算符
+const (
+	ARITH_ADD = "+"
+	ARITH_SUB = "-"
+	ARITH_MUL = "*"
+	ARITH_DIV = "/"
+	ARITH_MOD = "%"
+	ARITH_NEG = "NEG" //取负数，只有一个操作数
+)
 
 type Expression struct {
 	//age >20，age是一个字段，20是一个常量
 	val     *comm.Constant //常量
 	fldName string         //字段
+	op      string         //算术运算符
+	fn      *Function      //调用的函数
+	args    []*Expression  //运算符的操作数或者函数的参数
+	sub     *Subquery      //标量子查询，IN的右边也使用这种表达式
 }
 
 //NewExpressionWithConstant 用一个val来初始化一个expression
@@ -33,11 +50,47 @@ func NewExpressionWithFieldName(fldName string) *Expression {
 	}
 }
 
+//NewArithmeticExpression 两个表达式进行算术运算
+func NewArithmeticExpression(op string, lhs *Expression, rhs *Expression) *Expression {
+	return &Expression{
+		op:   op,
+		args: []*Expression{lhs, rhs},
+	}
+}
+
+//NewNegateExpression 对表达式取负数
+func NewNegateExpression(e *Expression) *Expression {
+	return &Expression{
+		op:   ARITH_NEG,
+		args: []*Expression{e},
+	}
+}
+
+//NewFunctionExpression 调用一个函数，参数的个数在解析的时候已经检查过了
+func NewFunctionExpression(fn *Function, args []*Expression) *Expression {
+	return &Expression{
+		fn:   fn,
+		args: args,
+	}
+}
+
+//NewSubqueryExpression 标量子查询
+func NewSubqueryExpression(sub *Subquery) *Expression {
+	return &Expression{
+		sub: sub,
+	}
+}
+
 //IsFieldName 当前表达式是否是fieldName
 func (e *Expression) IsFieldName() bool {
 	return e.fldName != ""
 }
 
+//IsConstant 当前表达式是否是一个常量，预处理语句中的参数槽也是常量
+func (e *Expression) IsConstant() bool {
+	return e.val != nil
+}
+
 func (e *Expression) AsFieldName() string {
 	return e.fldName
 }
@@ -46,30 +99,195 @@ func (e *Expression) AsConstant() *comm.Constant {
 	return e.val
 }
 
+//Op 算术运算符，不是运算的时候为空
+func (e *Expression) Op() string {
+	return e.op
+}
+
+//Function 调用的函数，不是函数调用的时候为nil
+func (e *Expression) Function() *Function {
+	return e.fn
+}
+
+//Args 运算符的操作数或者函数的参数
+func (e *Expression) Args() []*Expression {
+	return e.args
+}
+
+//Subquery 标量子查询，不是子查询的时候为nil
+func (e *Expression) Subquery() *Subquery {
+	return e.sub
+}
+
+//Subqueries 表达式中所有的子查询，不包括子查询里面嵌套的子查询
+func (e *Expression) Subqueries() []*Subquery {
+	if e.sub != nil {
+		return []*Subquery{e.sub}
+	}
+	subs := make([]*Subquery, 0)
+	for _, arg := range e.args {
+		subs = append(subs, arg.Subqueries()...)
+	}
+	return subs
+}
+
+//Fields 表达式中用到的所有字段，按照出现的顺序，不会重复，相关子查询中引用的外层字段也包括在内
+func (e *Expression) Fields() []string {
+	fields := make([]string, 0)
+	add := func(name string) {
+		for _, field := range fields {
+			if field == name {
+				return
+			}
+		}
+		fields = append(fields, name)
+	}
+	var walk func(e *Expression)
+	walk = func(e *Expression) {
+		if e.IsFieldName() {
+			add(e.fldName)
+		}
+		if e.sub != nil {
+			for _, field := range e.sub.OuterFields() {
+				add(field)
+			}
+		}
+		for _, arg := range e.args {
+			walk(arg)
+		}
+	}
+	walk(e)
+	return fields
+}
+
+//Rename 返回一个把所有字段按照rename换成新名字的表达式，常量仍然使用原来的对象，这样预处理语句的参数槽不会被复制
+func (e *Expression) Rename(rename func(string) (string, error)) (*Expression, error) {
+	result := *e
+	if e.IsFieldName() {
+		name, err := rename(e.fldName)
+		if err != nil {
+			return nil, err
+		}
+		result.fldName = name
+	}
+	if e.sub != nil {
+		//子查询中的字段在为它创建查询计划的时候解析
+		result.sub = e.sub.clone()
+	}
+	if e.args != nil {
+		result.args = make([]*Expression, len(e.args))
+		for i, arg := range e.args {
+			renamed, err := arg.Rename(rename)
+			if err != nil {
+				return nil, err
+			}
+			result.args[i] = renamed
+		}
+	}
+	return &result, nil
+}
+
+//SplitFieldName 把t.col形式的字段名拆分成表名和字段名，没有表名的时候qualifier为空
+//聚合函数的名字例如max(s.age)不进行拆分
+func SplitFieldName(name string) (string, string) {
+	if strings.Contains(name, "(") {
+		return "", name
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
 //Evaluate 如果当前是一个常量直接返回这个值，如果当前是一个字段，就需要根据这个字段查询这个值
+//运算符和函数先计算出参数的值再进行计算，计算出错的时候panic一个包装了comm.ErrArithmetic的错误
 func (e *Expression) Evaluate(s Scan) *comm.Constant {
 	//expression可能是常量也可能是一个字段，如果是后者，我们需要查询这个字段对应的具体值
 	if e.val != nil {
 		//如果是常量，直接返回这个常量
 		return e.val
 	}
-	//如果当前是字段，就需要查找这个字段对应的值
-	return s.GetVal(e.fldName)
+	if e.IsFieldName() {
+		//如果当前是字段，就需要查找这个字段对应的值
+		return s.GetVal(e.fldName)
+	}
+	if e.sub != nil {
+		return e.sub.Value(s)
+	}
+	args := make([]*comm.Constant, len(e.args))
+	for i, arg := range e.args {
+		args[i] = arg.Evaluate(s)
+	}
+	var (
+		val *comm.Constant
+		err error
+	)
+	switch e.op {
+	case ARITH_ADD:
+		val, err = args[0].Add(args[1])
+	case ARITH_SUB:
+		val, err = args[0].Sub(args[1])
+	case ARITH_MUL:
+		val, err = args[0].Mul(args[1])
+	case ARITH_DIV:
+		val, err = args[0].Div(args[1])
+	case ARITH_MOD:
+		val, err = args[0].Mod(args[1])
+	case ARITH_NEG:
+		val, err = args[0].Negate()
+	default:
+		val, err = e.fn.Call(args)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
 //AppliesTo 判断当前字段是否可以运用在该表中
 func (e *Expression) AppliesTo(sch *rm.Schema) bool {
 	//如果是一个常量的话，可以作为判断条件直接用，如果当前表没有某个字段的话，就无法使用
-	if e.val != nil {
-		return true
+	for _, field := range e.Fields() {
+		if !sch.HashField(field) {
+			return false
+		}
 	}
-	return sch.HashField(e.fldName)
+	return true
 }
 
 //ToString 将当前的常量或者是字段，都按照字符串的形式来表示
+//运算符的操作数如果也是运算，就加上括号，这样不需要考虑优先级，视图的定义也能被重新解析
 func (e *Expression) ToString() string {
 	if e.val != nil {
+		if e.val.Sval != nil {
+			//字符串常量需要带上引号，这样视图的定义才能被重新解析
+			return "\"" + e.val.ToString() + "\""
+		}
 		return e.val.ToString()
 	}
-	return e.fldName
+	if e.IsFieldName() {
+		return e.fldName
+	}
+	if e.sub != nil {
+		return e.sub.ToString()
+	}
+	if e.fn != nil {
+		args := make([]string, len(e.args))
+		for i, arg := range e.args {
+			args[i] = arg.ToString()
+		}
+		return e.fn.Name() + "(" + strings.Join(args, ", ") + ")"
+	}
+	if e.op == ARITH_NEG {
+		return "-" + e.args[0].operandString()
+	}
+	return fmt.Sprintf("%s%s%s", e.args[0].operandString(), e.op, e.args[1].operandString())
+}
+
+//operandString 作为运算符的操作数时的字符串形式
+func (e *Expression) operandString() string {
+	if e.op != "" {
+		return "(" + e.ToString() + ")"
+	}
+	return e.ToString()
 }
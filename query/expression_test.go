@@ -35,6 +35,50 @@ func TestExpression(t *testing.T) {
 	sch.AddStringField("age", 9)
 	assert.True(t, expressionFld.AppliesTo(sch))
 	assert.True(t, expressionConstant.AppliesTo(sch))
+}
+
+func TestArithmeticExpression(t *testing.T) {
+	two, three := 2, 3
+	name := "Tom"
+	s := NewMemoryScan([]string{"age", "name"}, [][]*comm.Constant{
+		{comm.NewConstantInt(&three), comm.NewConstantString(&name)},
+	})
+	assert.True(t, s.Next())
+	age := NewExpressionWithFieldName("age")
+	c2 := NewExpressionWithConstant(comm.NewConstantInt(&two))
+
+	//(age + 2) * -age % 4
+	e := NewArithmeticExpression(ARITH_MOD,
+		NewArithmeticExpression(ARITH_MUL, NewArithmeticExpression(ARITH_ADD, age, c2), NewNegateExpression(age)),
+		NewExpressionWithConstant(comm.NewConstantInt(&[]int{4}[0])))
+	assert.Equal(t, -3, e.Evaluate(s).AsInt())
+	assert.Equal(t, "((age+2)*(-age))%4", e.ToString())
+	assert.Equal(t, []string{"age"}, e.Fields())
+	assert.False(t, e.IsFieldName())
+	assert.False(t, e.IsConstant())
+
+	sch := rm.NewSchema()
+	assert.False(t, e.AppliesTo(sch))
+	sch.AddIntField("age")
+	assert.True(t, e.AppliesTo(sch))
+
+	//函数名不区分大小写
+	concat := NewFunctionExpression(LookupFunction("CONCAT"), []*Expression{
+		NewFunctionExpression(LookupFunction("upper"), []*Expression{NewExpressionWithFieldName("name")}), age})
+	assert.Equal(t, "TOM3", concat.Evaluate(s).AsString())
+	assert.Equal(t, "concat(upper(name), age)", concat.ToString())
+	assert.Equal(t, []string{"name", "age"}, concat.Fields())
+	length := NewFunctionExpression(LookupFunction("length"), []*Expression{concat})
+	assert.Equal(t, 4, length.Evaluate(s).AsInt())
+	sub := NewFunctionExpression(LookupFunction("substr"), []*Expression{NewExpressionWithFieldName("name"), c2, NewExpressionWithConstant(comm.NewConstantInt(&[]int{10}[0]))})
+	assert.Equal(t, "om", sub.Evaluate(s).AsString())
+	abs := NewFunctionExpression(LookupFunction("abs"), []*Expression{NewNegateExpression(age)})
+	assert.Equal(t, 3, abs.Evaluate(s).AsInt())
+	assert.Nil(t, LookupFunction("nothing"))
+	assert.False(t, IsFunction("count"))
 
-	//todo Add test for evaluate
+	//计算出错的时候panic一个包装了comm.ErrArithmetic的错误
+	zero := 0
+	div := NewArithmeticExpression(ARITH_DIV, age, NewExpressionWithConstant(comm.NewConstantInt(&zero)))
+	assert.PanicsWithError(t, comm.ErrDivisionByZero.Error(), func() { div.Evaluate(s) })
 }
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"miniSQL/comm"
+)
+
+//ExtendScan 在底层的记录上增加计算出来的字段，例如 SELECT age + 1 AS next FROM student 中的next
+//计算出来的字段每次读取的时候根据底层当前的记录计算，其他的字段直接从底层读取
+type ExtendScan struct {
+	s      Scan
+	fields []string      //计算出来的字段名
+	exprs  []*Expression //每个字段对应的表达式
+}
+
+func NewExtendScan(s Scan, fields []string, exprs []*Expression) *ExtendScan {
+	return &ExtendScan{
+		s:      s,
+		fields: fields,
+		exprs:  exprs,
+	}
+}
+
+func (e *ExtendScan) BeforeFirst() {
+	e.s.BeforeFirst()
+}
+
+func (e *ExtendScan) Next() bool {
+	return e.s.Next()
+}
+
+func (e *ExtendScan) GetInt(fieldName string) int {
+	return e.GetVal(fieldName).AsInt()
+}
+
+func (e *ExtendScan) GetString(fieldName string) string {
+	return e.GetVal(fieldName).AsString()
+}
+
+func (e *ExtendScan) GetVal(fieldName string) *comm.Constant {
+	for i, field := range e.fields {
+		if field == fieldName {
+			return e.exprs[i].Evaluate(e.s)
+		}
+	}
+	return e.s.GetVal(fieldName)
+}
+
+func (e *ExtendScan) HasField(fieldName string) bool {
+	for _, field := range e.fields {
+		if field == fieldName {
+			return true
+		}
+	}
+	return e.s.HasField(fieldName)
+}
+
+func (e *ExtendScan) Close() {
+	e.s.Close()
+}
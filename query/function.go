@@ -0,0 +1,128 @@
+package query
+
+import (
+	"fmt"
+	"miniSQL/comm"
+	rm "miniSQL/record_manager"
+	"strings"
+)
+
+/*
+	表达式中可以调用的标量函数，每次对一条记录计算出一个值，和对一组记录计算的聚合函数不同
+	函数名不区分大小写，也不是关键字，只有后面跟着左括号的时候才作为函数，这样upper，length等仍然可以作为字段名
+	||在词法分析中表示OR，所以字符串的拼接使用CONCAT函数，和MySQL相同
+*/
+
+//TYPE_ANY 函数的参数可以是任意的类型
+const TYPE_ANY rm.FIELD_TYPE = -1
+
+//Function 一个标量函数，variadic为true的时候最后一个参数可以出现任意多次
+type Function struct {
+	name     string
+	args     []rm.FIELD_TYPE
+	variadic bool
+	result   rm.FIELD_TYPE
+	eval     func(args []*comm.Constant) (*comm.Constant, error)
+}
+
+var functions = map[string]*Function{
+	"abs": {name: "abs", args: []rm.FIELD_TYPE{rm.INTEGER}, result: rm.INTEGER, eval: func(args []*comm.Constant) (*comm.Constant, error) {
+		if args[0].AsInt() < 0 {
+			return args[0].Negate()
+		}
+		return args[0], nil
+	}},
+	"mod": {name: "mod", args: []rm.FIELD_TYPE{rm.INTEGER, rm.INTEGER}, result: rm.INTEGER, eval: func(args []*comm.Constant) (*comm.Constant, error) {
+		return args[0].Mod(args[1])
+	}},
+	"length": {name: "length", args: []rm.FIELD_TYPE{rm.VARCHAR}, result: rm.INTEGER, eval: func(args []*comm.Constant) (*comm.Constant, error) {
+		n := len(args[0].AsString())
+		return comm.NewConstantInt(&n), nil
+	}},
+	"upper": {name: "upper", args: []rm.FIELD_TYPE{rm.VARCHAR}, result: rm.VARCHAR, eval: func(args []*comm.Constant) (*comm.Constant, error) {
+		s := strings.ToUpper(args[0].AsString())
+		return comm.NewConstantString(&s), nil
+	}},
+	"lower": {name: "lower", args: []rm.FIELD_TYPE{rm.VARCHAR}, result: rm.VARCHAR, eval: func(args []*comm.Constant) (*comm.Constant, error) {
+		s := strings.ToLower(args[0].AsString())
+		return comm.NewConstantString(&s), nil
+	}},
+	"substr": {name: "substr", args: []rm.FIELD_TYPE{rm.VARCHAR, rm.INTEGER, rm.INTEGER}, result: rm.VARCHAR, eval: substr},
+	"concat": {name: "concat", args: []rm.FIELD_TYPE{TYPE_ANY}, variadic: true, result: rm.VARCHAR, eval: func(args []*comm.Constant) (*comm.Constant, error) {
+		var sb strings.Builder
+		for _, arg := range args {
+			sb.WriteString(arg.ToString())
+		}
+		s := sb.String()
+		return comm.NewConstantString(&s), nil
+	}},
+}
+
+//substr SUBSTR(s, start, length)，start从1开始，超出字符串范围的部分被忽略
+func substr(args []*comm.Constant) (*comm.Constant, error) {
+	s, start, length := args[0].AsString(), args[1].AsInt(), args[2].AsInt()
+	if start < 1 || length < 0 {
+		return nil, fmt.Errorf("%w: substr(%q, %d, %d)", comm.ErrArithmetic, s, start, length)
+	}
+	begin := start - 1
+	if begin > len(s) {
+		begin = len(s)
+	}
+	end := begin + length
+	if end > len(s) {
+		end = len(s)
+	}
+	result := s[begin:end]
+	return comm.NewConstantString(&result), nil
+}
+
+//IsFunction 判断name是不是一个标量函数的名字，不区分大小写
+func IsFunction(name string) bool {
+	_, ok := functions[strings.ToLower(name)]
+	return ok
+}
+
+//LookupFunction 根据名字查找标量函数，不存在的时候返回nil
+func LookupFunction(name string) *Function {
+	return functions[strings.ToLower(name)]
+}
+
+func (f *Function) Name() string {
+	return f.name
+}
+
+//ArgType 第i个参数的类型，可变参数的函数后面的参数使用最后一个参数的类型
+func (f *Function) ArgType(i int) rm.FIELD_TYPE {
+	if i >= len(f.args) {
+		return f.args[len(f.args)-1]
+	}
+	return f.args[i]
+}
+
+//CheckArgCount 检查参数的个数
+func (f *Function) CheckArgCount(n int) bool {
+	if f.variadic {
+		return n >= len(f.args)
+	}
+	return n == len(f.args)
+}
+
+//ResultType 函数结果的类型
+func (f *Function) ResultType() rm.FIELD_TYPE {
+	return f.result
+}
+
+//Call 对参数调用函数，参数的类型已经在创建计划的时候检查过了
+func (f *Function) Call(args []*comm.Constant) (*comm.Constant, error) {
+	for i, arg := range args {
+		//有一个参数是NULL的时候结果也是NULL
+		if arg.IsNull() {
+			return comm.NewNullConstant(), nil
+		}
+		argType := f.ArgType(i)
+		if argType == rm.INTEGER && arg.Ival == nil || argType == rm.VARCHAR && arg.Sval == nil {
+			return nil, fmt.Errorf("%w: wrong argument type for %s", comm.ErrArithmetic, f.name)
+		}
+	}
+	return f.eval(args)
+}
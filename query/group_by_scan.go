@@ -0,0 +1,362 @@
+package query
+
+import (
+	"miniSQL/comm"
+	rm "miniSQL/record_manager"
+	"strconv"
+	"strings"
+)
+
+/*
+	GROUP BY有两种执行方式：
+	1.GroupByScan 底层的记录已经按照分组字段排好序，同一组的记录是连续的，每次读取一组记录进行聚合
+	2.HashGroupByScan 底层的记录没有顺序，使用哈希表保存每一组的聚合结果
+	  哈希表中的组数超过内存的容量之后，新出现的组的记录按照哈希值写入到多个分区临时表中，
+	  内存中的组返回完之后再依次对每个分区进行聚合，分区仍然放不下的时候继续递归的分区
+	没有GROUP BY的聚合查询所有的记录是一组，即使没有任何记录也会返回一条结果
+*/
+
+//newAggregationFns 为一组记录创建聚合对象，types是每个聚合函数参数的类型
+func newAggregationFns(aggs []*Aggregate, types []rm.FIELD_TYPE) []AggregationFn {
+	fns := make([]AggregationFn, len(aggs))
+	for i, agg := range aggs {
+		fns[i] = agg.NewFn(types[i])
+	}
+	return fns
+}
+
+//GroupByScan 对已经按照分组字段排好序的记录进行分组聚合
+type GroupByScan struct {
+	s           Scan
+	groupFields []string
+	aggs        []*Aggregate
+	types       []rm.FIELD_TYPE
+	fns         []AggregationFn
+	groupVal    map[string]*comm.Constant //当前组的分组字段的值
+	moreGroups  bool
+	emitted     bool //没有分组字段的时候是否已经返回过结果
+}
+
+func NewGroupByScan(s Scan, groupFields []string, aggs []*Aggregate, types []rm.FIELD_TYPE) *GroupByScan {
+	groupByScan := &GroupByScan{
+		s:           s,
+		groupFields: groupFields,
+		aggs:        aggs,
+		types:       types,
+	}
+	groupByScan.BeforeFirst()
+	return groupByScan
+}
+
+func (g *GroupByScan) BeforeFirst() {
+	g.s.BeforeFirst()
+	g.moreGroups = g.s.Next()
+	g.emitted = false
+	g.fns = newAggregationFns(g.aggs, g.types)
+}
+
+//Next 读取下一组记录，读取到分组字段的值发生变化为止
+func (g *GroupByScan) Next() bool {
+	if !g.moreGroups {
+		//整张表是一组的时候，没有记录也需要返回一条结果
+		if len(g.groupFields) == 0 && !g.emitted {
+			g.emitted = true
+			return true
+		}
+		return false
+	}
+	g.emitted = true
+	g.fns = newAggregationFns(g.aggs, g.types)
+	for _, fn := range g.fns {
+		fn.ProcessFirst(g.s)
+	}
+	g.groupVal = make(map[string]*comm.Constant)
+	for _, field := range g.groupFields {
+		g.groupVal[field] = g.s.GetVal(field)
+	}
+	for g.moreGroups = g.s.Next(); g.moreGroups; g.moreGroups = g.s.Next() {
+		if !g.sameGroup() {
+			break
+		}
+		for _, fn := range g.fns {
+			fn.ProcessNext(g.s)
+		}
+	}
+	return true
+}
+
+func (g *GroupByScan) sameGroup() bool {
+	for _, field := range g.groupFields {
+		//分组的时候NULL和NULL在同一组，所以不能使用Equal
+		if g.s.GetVal(field).CompareTo(g.groupVal[field]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *GroupByScan) GetInt(fieldName string) int {
+	return g.GetVal(fieldName).AsInt()
+}
+
+func (g *GroupByScan) GetString(fieldName string) string {
+	return g.GetVal(fieldName).AsString()
+}
+
+func (g *GroupByScan) GetVal(fieldName string) *comm.Constant {
+	if val, ok := g.groupVal[fieldName]; ok {
+		return val
+	}
+	for i, agg := range g.aggs {
+		if agg.Name() == fieldName {
+			return g.fns[i].Value()
+		}
+	}
+	panic("field " + fieldName + " not found")
+}
+
+func (g *GroupByScan) HasField(fieldName string) bool {
+	for _, field := range g.groupFields {
+		if field == fieldName {
+			return true
+		}
+	}
+	for _, agg := range g.aggs {
+		if agg.Name() == fieldName {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GroupByScan) Close() {
+	g.s.Close()
+}
+
+//hashGroup 哈希聚合中的一组
+type hashGroup struct {
+	vals []*comm.Constant //分组字段的值
+	fns  []AggregationFn
+}
+
+//TempTable 哈希聚合写入分区使用的临时表
+type TempTable interface {
+	Open() (UpdateScan, error)
+}
+
+//partition 哈希聚合中写入到临时表中的一个分区，depth是分区的层数，每一层使用不同的哈希函数
+//分区写完之后就关闭，轮到它的时候再重新打开，这样等待中的分区不会占用缓存块
+type partition struct {
+	table TempTable
+	depth int
+}
+
+//HashGroupByScan 使用哈希表进行分组聚合，组数超过capacity的时候把记录写入分区临时表
+type HashGroupByScan struct {
+	s           Scan
+	fields      []string //底层记录的所有字段，写入分区的时候使用
+	groupFields []string
+	aggs        []*Aggregate
+	types       []rm.FIELD_TYPE
+	capacity    int              //内存中最多保存多少组
+	sizeHint    int              //估算出来的组数，用来预先分配哈希表的空间
+	fanOut      int              //每一次最多分成多少个分区
+	newTemp     func() TempTable //创建分区使用的临时表
+	groups      []*hashGroup
+	pos         int
+	pending     []*partition //还没有聚合的分区
+}
+
+func NewHashGroupByScan(s Scan, fields []string, groupFields []string, aggs []*Aggregate, types []rm.FIELD_TYPE,
+	capacity int, sizeHint int, fanOut int, newTemp func() TempTable) *HashGroupByScan {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fanOut < 2 {
+		fanOut = 2
+	}
+	hashScan := &HashGroupByScan{
+		s:           s,
+		fields:      fields,
+		groupFields: groupFields,
+		aggs:        aggs,
+		types:       types,
+		capacity:    capacity,
+		sizeHint:    sizeHint,
+		fanOut:      fanOut,
+		newTemp:     newTemp,
+	}
+	hashScan.BeforeFirst()
+	return hashScan
+}
+
+//BeforeFirst 重新对底层的记录进行聚合
+func (h *HashGroupByScan) BeforeFirst() {
+	h.pending = nil
+	h.s.BeforeFirst()
+	h.aggregate(h.s, 0)
+}
+
+//aggregate 对src中的记录进行聚合，放不下的组写入到下一层的分区中
+func (h *HashGroupByScan) aggregate(src Scan, depth int) {
+	size := h.sizeHint
+	if size > h.capacity || size < 0 {
+		size = h.capacity
+	}
+	index := make(map[string]*hashGroup, size)
+	h.groups = make([]*hashGroup, 0, size)
+	h.pos = -1
+	var writers []UpdateScan
+	for src.Next() {
+		vals := make([]*comm.Constant, len(h.groupFields))
+		for i, field := range h.groupFields {
+			vals[i] = src.GetVal(field)
+		}
+		key := groupKey(vals)
+		if group, ok := index[key]; ok {
+			for _, fn := range group.fns {
+				fn.ProcessNext(src)
+			}
+			continue
+		}
+		if len(index) < h.capacity {
+			group := &hashGroup{vals: vals, fns: newAggregationFns(h.aggs, h.types)}
+			for _, fn := range group.fns {
+				fn.ProcessFirst(src)
+			}
+			index[key] = group
+			h.groups = append(h.groups, group)
+			continue
+		}
+		//内存已经满了，这一组的记录写入到分区中
+		if writers == nil {
+			writers = make([]UpdateScan, h.fanOut)
+		}
+		i := int(hashKey(vals, depth) % uint32(h.fanOut))
+		if writers[i] == nil {
+			table := h.newTemp()
+			scan, err := table.Open()
+			if err != nil {
+				panic(err)
+			}
+			writers[i] = scan
+			h.pending = append(h.pending, &partition{table: table, depth: depth + 1})
+		}
+		writers[i].Insert()
+		for _, field := range h.fields {
+			writers[i].SetVal(field, src.GetVal(field))
+		}
+	}
+	for _, writer := range writers {
+		if writer != nil {
+			writer.Close()
+		}
+	}
+	//整张表是一组的时候，没有记录也需要返回一条结果
+	if len(h.groupFields) == 0 && depth == 0 && len(h.groups) == 0 {
+		h.groups = append(h.groups, &hashGroup{fns: newAggregationFns(h.aggs, h.types)})
+	}
+}
+
+//groupKey 把分组字段的值编码成哈希表的键，每种类型使用不同的前缀避免冲突，所有的NULL在同一组
+//数值使用规范的形式，相等的整数，定点数和浮点数的键相同，例如3，3.00和1.50，1.5
+func groupKey(vals []*comm.Constant) string {
+	var sb strings.Builder
+	for _, val := range vals {
+		if val.IsNull() {
+			sb.WriteString("n")
+		} else if v, ok := val.Integral(); ok {
+			sb.WriteString("i")
+			sb.WriteString(strconv.FormatInt(v, 10))
+		} else if val.Dval != nil {
+			sb.WriteString("d")
+			sb.WriteString(val.Dval.Normalize().String())
+		} else if val.Fval != nil {
+			sb.WriteString("d")
+			sb.WriteString(strconv.FormatFloat(*val.Fval, 'f', -1, 64))
+		} else if val.Bval != nil {
+			sb.WriteString("b")
+			sb.WriteString(val.ToString())
+		} else {
+			sb.WriteString("s")
+			sb.WriteString(strconv.Itoa(len(*val.Sval)))
+			sb.WriteString(":")
+			sb.WriteString(*val.Sval)
+		}
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+//hashKey 每一层分区使用不同的种子，这样上一层同一个分区中的组在下一层可以被分开
+func hashKey(vals []*comm.Constant, depth int) uint32 {
+	h := uint32(2166136261) + uint32(depth)*16777619
+	for _, val := range vals {
+		h = (h ^ val.HashCode()) * 16777619
+	}
+	return h
+}
+
+//Next 内存中的组返回完之后，取出下一个分区进行聚合
+func (h *HashGroupByScan) Next() bool {
+	for {
+		if h.pos < len(h.groups)-1 {
+			h.pos++
+			return true
+		}
+		if len(h.pending) == 0 {
+			return false
+		}
+		part := h.pending[0]
+		h.pending = h.pending[1:]
+		scan, err := part.table.Open()
+		if err != nil {
+			panic(err)
+		}
+		h.aggregate(scan, part.depth)
+		scan.Close()
+	}
+}
+
+func (h *HashGroupByScan) GetInt(fieldName string) int {
+	return h.GetVal(fieldName).AsInt()
+}
+
+func (h *HashGroupByScan) GetString(fieldName string) string {
+	return h.GetVal(fieldName).AsString()
+}
+
+func (h *HashGroupByScan) GetVal(fieldName string) *comm.Constant {
+	group := h.groups[h.pos]
+	for i, field := range h.groupFields {
+		if field == fieldName {
+			return group.vals[i]
+		}
+	}
+	for i, agg := range h.aggs {
+		if agg.Name() == fieldName {
+			return group.fns[i].Value()
+		}
+	}
+	panic("field " + fieldName + " not found")
+}
+
+func (h *HashGroupByScan) HasField(fieldName string) bool {
+	for _, field := range h.groupFields {
+		if field == fieldName {
+			return true
+		}
+	}
+	for _, agg := range h.aggs {
+		if agg.Name() == fieldName {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HashGroupByScan) Close() {
+	h.pending = nil
+	h.groups = nil
+	h.s.Close()
+}
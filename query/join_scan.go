@@ -0,0 +1,148 @@
+package query
+
+import "miniSQL/comm"
+
+/*
+	JoinScan 使用嵌套循环实现内连接和外连接
+	对外层的每一条记录扫描一遍内层，满足连接条件的组合输出出来
+	1.preserveOuter为true的时候，外层的记录如果没有匹配上任何内层的记录，就和一条全是NULL的内层记录组合输出
+	2.preserveInner为true的时候，第一趟结束之后再扫描一趟内层，对每一条内层记录检查外层中是否有匹配的记录，
+	  没有的时候和一条全是NULL的外层记录组合输出，这样不需要记住哪些内层的记录被匹配过
+	LEFT JOIN只保留外层，RIGHT JOIN把右边的表作为外层，FULL JOIN两边都保留
+	USING中的字段两边都有，取没有被NULL填充的一边的值
+*/
+
+type JoinScan struct {
+	outer         Scan
+	inner         Scan
+	pred          *Predicate //ON后面的连接条件
+	using         []string   //USING中的字段，两边的值相等才能连接
+	preserveOuter bool
+	preserveInner bool
+	hasOuter      bool //外层是否指向了一条还没有处理完的记录
+	matched       bool //外层当前的记录是否已经匹配上了内层的记录
+	innerPass     bool //是否已经进入了查找没有匹配上的内层记录的第二趟
+	outerPadded   bool //当前输出的记录中外层是否使用NULL填充
+	innerPadded   bool //当前输出的记录中内层是否使用NULL填充
+}
+
+//NewJoinScan 根据连接条件连接outer和inner，pred为空的条件并且没有using的时候就是笛卡尔积
+func NewJoinScan(outer Scan, inner Scan, pred *Predicate, using []string, preserveOuter bool, preserveInner bool) *JoinScan {
+	return &JoinScan{
+		outer:         outer,
+		inner:         inner,
+		pred:          pred,
+		using:         using,
+		preserveOuter: preserveOuter,
+		preserveInner: preserveInner,
+	}
+}
+
+func (j *JoinScan) BeforeFirst() {
+	j.outer.BeforeFirst()
+	j.hasOuter, j.matched, j.innerPass = false, false, false
+	j.outerPadded, j.innerPadded = false, false
+}
+
+//Next 第一趟输出所有匹配上的记录以及需要保留的外层记录，第二趟输出需要保留的内层记录
+func (j *JoinScan) Next() bool {
+	j.outerPadded, j.innerPadded = false, false
+	if !j.innerPass {
+		if j.nextOuterRow() {
+			return true
+		}
+		if !j.preserveInner {
+			return false
+		}
+		j.innerPass = true
+		j.inner.BeforeFirst()
+	}
+	for j.inner.Next() {
+		if !j.innerMatched() {
+			j.outerPadded = true
+			return true
+		}
+	}
+	return false
+}
+
+//nextOuterRow 第一趟，对外层的每一条记录在内层中查找匹配的记录
+func (j *JoinScan) nextOuterRow() bool {
+	for {
+		if j.hasOuter {
+			for j.inner.Next() {
+				if j.matches() {
+					j.matched = true
+					return true
+				}
+			}
+			j.hasOuter = false
+			if j.preserveOuter && !j.matched {
+				j.innerPadded = true
+				return true
+			}
+		}
+		if !j.outer.Next() {
+			return false
+		}
+		j.hasOuter, j.matched = true, false
+		j.inner.BeforeFirst()
+	}
+}
+
+//innerMatched 第二趟，检查内层当前的记录在外层中是否有匹配的记录
+func (j *JoinScan) innerMatched() bool {
+	j.outer.BeforeFirst()
+	for j.outer.Next() {
+		if j.matches() {
+			return true
+		}
+	}
+	return false
+}
+
+//matches 外层和内层当前的记录是否满足连接条件
+func (j *JoinScan) matches() bool {
+	for _, field := range j.using {
+		if !Compare(j.outer.GetVal(field), OP_EQ, j.inner.GetVal(field)) {
+			return false
+		}
+	}
+	return j.pred == nil || j.pred.IsSatisfied(j)
+}
+
+func (j *JoinScan) GetInt(fieldName string) int {
+	return j.GetVal(fieldName).AsInt()
+}
+
+func (j *JoinScan) GetString(fieldName string) string {
+	return j.GetVal(fieldName).AsString()
+}
+
+//GetVal 被NULL填充的一边返回NULL
+func (j *JoinScan) GetVal(fieldName string) *comm.Constant {
+	for _, field := range j.using {
+		if field == fieldName && j.outerPadded {
+			return j.inner.GetVal(fieldName)
+		}
+	}
+	if j.outer.HasField(fieldName) {
+		if j.outerPadded {
+			return comm.NewNullConstant()
+		}
+		return j.outer.GetVal(fieldName)
+	}
+	if j.innerPadded {
+		return comm.NewNullConstant()
+	}
+	return j.inner.GetVal(fieldName)
+}
+
+func (j *JoinScan) HasField(fieldName string) bool {
+	return j.outer.HasField(fieldName) || j.inner.HasField(fieldName)
+}
+
+func (j *JoinScan) Close() {
+	j.outer.Close()
+	j.inner.Close()
+}
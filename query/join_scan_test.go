@@ -0,0 +1,81 @@
+package query
+
+import (
+	"github.com/stretchr/testify/assert"
+	"miniSQL/comm"
+	"strings"
+	"testing"
+)
+
+func intRows(fields []string, rows ...[]int) *MemoryScan {
+	vals := make([][]*comm.Constant, len(rows))
+	for i, row := range rows {
+		vals[i] = make([]*comm.Constant, len(row))
+		for j := range row {
+			vals[i][j] = comm.NewConstantInt(&row[j])
+		}
+	}
+	return NewMemoryScan(fields, vals)
+}
+
+func collectJoin(s Scan, fields ...string) []string {
+	defer s.Close()
+	rows := make([]string, 0)
+	for s.Next() {
+		vals := make([]string, len(fields))
+		for i, field := range fields {
+			vals[i] = s.GetVal(field).ToString()
+		}
+		rows = append(rows, strings.Join(vals, " "))
+	}
+	return rows
+}
+
+func TestJoinScan(t *testing.T) {
+	customers := func() Scan { return intRows([]string{"cid", "age"}, []int{1, 20}, []int{2, 30}, []int{3, 40}) }
+	orders := func() Scan { return intRows([]string{"ocid", "amount"}, []int{1, 100}, []int{1, 200}, []int{4, 400}) }
+	on := NewPredicateWithTerm(NewTerm(NewExpressionWithFieldName("cid"), NewExpressionWithFieldName("ocid")))
+
+	inner := NewJoinScan(customers(), orders(), on, nil, false, false)
+	assert.Equal(t, []string{"1 100", "1 200"}, collectJoin(inner, "cid", "amount"))
+
+	//没有匹配上的客户使用NULL填充
+	left := NewJoinScan(customers(), orders(), on, nil, true, false)
+	assert.Equal(t, []string{"1 1 100", "1 1 200", "2 NULL NULL", "3 NULL NULL"}, collectJoin(left, "cid", "ocid", "amount"))
+
+	//第二趟输出没有匹配上的订单
+	full := NewJoinScan(customers(), orders(), on, nil, true, true)
+	for full.Next() {
+	}
+	full.BeforeFirst()
+	assert.Equal(t, []string{"1 100", "1 200", "2 NULL", "3 NULL", "NULL 400"}, collectJoin(full, "cid", "amount"))
+
+	//USING中的字段取没有被NULL填充的一边的值
+	lhs := intRows([]string{"id", "a"}, []int{1, 10}, []int{2, 20})
+	rhs := intRows([]string{"id", "b"}, []int{2, 200}, []int{3, 300})
+	using := NewJoinScan(lhs, rhs, NewPredicate(), []string{"id"}, true, true)
+	assert.Equal(t, []string{"1 10 NULL", "2 20 200", "3 NULL 300"}, collectJoin(using, "id", "a", "b"))
+
+	//外层为空的时候第二趟仍然输出所有的内层记录
+	empty := NewJoinScan(intRows([]string{"id", "a"}), intRows([]string{"id", "b"}, []int{1, 2}), NewPredicate(), []string{"id"}, true, true)
+	assert.Equal(t, []string{"1 NULL 2"}, collectJoin(empty, "id", "a", "b"))
+}
+
+func TestSemiJoinScan(t *testing.T) {
+	customers := func() Scan {
+		rows := intRows([]string{"cid", "age"}, []int{1, 20}, []int{2, 30}, []int{3, 40})
+		//第三个客户的cid是NULL
+		rows.rows[2][0] = comm.NewNullConstant()
+		return rows
+	}
+	orders := func() Scan { return intRows([]string{"ocid", "amount"}, []int{1, 100}, []int{1, 200}, []int{4, 400}) }
+	outerKeys := []*Expression{NewExpressionWithFieldName("cid")}
+	innerKeys := []*Expression{NewExpressionWithFieldName("ocid")}
+
+	//匹配多条记录的客户只输出一次
+	semi := NewSemiJoinScan(customers(), orders(), outerKeys, innerKeys, false)
+	assert.Equal(t, []string{"20"}, collectJoin(semi, "age"))
+	//NULL不和任何记录匹配，反连接中会被输出
+	anti := NewSemiJoinScan(customers(), orders(), outerKeys, innerKeys, true)
+	assert.Equal(t, []string{"30", "40"}, collectJoin(anti, "age"))
+}
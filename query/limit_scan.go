@@ -0,0 +1,91 @@
+package query
+
+import (
+	"miniSQL/comm"
+)
+
+//LimitScan 实现LIMIT和OFFSET，先跳过offset条记录，再最多返回limit条记录
+//返回够了记录之后就不再从底层读取，并且立刻关闭底层的scan，释放它占用的缓存块
+type LimitScan struct {
+	s       Scan
+	open    func() (Scan, error) //底层的scan关闭之后，BeforeFirst的时候需要重新打开
+	limit   int                  //小于0表示没有限制
+	offset  int
+	count   int //已经返回的记录数
+	skipped int //已经跳过的记录数
+	closed  bool
+}
+
+func NewLimitScan(s Scan, open func() (Scan, error), limit int, offset int) *LimitScan {
+	return &LimitScan{
+		s:      s,
+		open:   open,
+		limit:  limit,
+		offset: offset,
+	}
+}
+
+//BeforeFirst 底层的scan已经关闭的时候重新打开它
+func (l *LimitScan) BeforeFirst() {
+	if l.closed {
+		s, err := l.open()
+		if err != nil {
+			panic(err)
+		}
+		l.s, l.closed = s, false
+	} else {
+		l.s.BeforeFirst()
+	}
+	l.count, l.skipped = 0, 0
+}
+
+func (l *LimitScan) Next() bool {
+	if l.closed {
+		return false
+	}
+	for l.skipped < l.offset {
+		if !l.s.Next() {
+			l.finish()
+			return false
+		}
+		l.skipped++
+	}
+	if l.limit >= 0 && l.count >= l.limit {
+		l.finish()
+		return false
+	}
+	if !l.s.Next() {
+		l.finish()
+		return false
+	}
+	l.count++
+	return true
+}
+
+//finish 不会再读取底层的记录了，提前关闭底层的scan
+func (l *LimitScan) finish() {
+	l.s.Close()
+	l.closed = true
+}
+
+func (l *LimitScan) GetInt(fieldName string) int {
+	return l.s.GetInt(fieldName)
+}
+
+func (l *LimitScan) GetString(fieldName string) string {
+	return l.s.GetString(fieldName)
+}
+
+func (l *LimitScan) GetVal(fieldName string) *comm.Constant {
+	return l.s.GetVal(fieldName)
+}
+
+func (l *LimitScan) HasField(fieldName string) bool {
+	return l.s.HasField(fieldName)
+}
+
+func (l *LimitScan) Close() {
+	if !l.closed {
+		l.finish()
+	}
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"miniSQL/comm"
+)
+
+//MemoryScan 把已经读取到内存中的记录按照顺序返回，排序等操作在数据量比较小的时候不需要使用临时表
+type MemoryScan struct {
+	fields map[string]int //字段在记录中的下标
+	rows   [][]*comm.Constant
+	pos    int //当前记录的下标，-1表示还没有开始
+}
+
+//NewMemoryScan rows中每一条记录的字段顺序和fields相同
+func NewMemoryScan(fields []string, rows [][]*comm.Constant) *MemoryScan {
+	memoryScan := &MemoryScan{
+		fields: make(map[string]int),
+		rows:   rows,
+		pos:    -1,
+	}
+	for i, field := range fields {
+		memoryScan.fields[field] = i
+	}
+	return memoryScan
+}
+
+func (m *MemoryScan) BeforeFirst() {
+	m.pos = -1
+}
+
+func (m *MemoryScan) Next() bool {
+	if m.pos < len(m.rows) {
+		m.pos++
+	}
+	return m.pos < len(m.rows)
+}
+
+func (m *MemoryScan) GetInt(fieldName string) int {
+	return m.GetVal(fieldName).AsInt()
+}
+
+func (m *MemoryScan) GetString(fieldName string) string {
+	return m.GetVal(fieldName).AsString()
+}
+
+func (m *MemoryScan) GetVal(fieldName string) *comm.Constant {
+	return m.rows[m.pos][m.fields[fieldName]]
+}
+
+func (m *MemoryScan) HasField(fieldName string) bool {
+	_, ok := m.fields[fieldName]
+	return ok
+}
+
+//Close 释放记录占用的内存
+func (m *MemoryScan) Close() {
+	m.rows = nil
+}
@@ -28,6 +28,7 @@ import (
 */
 
 //Predicate 由一个一个的term组成的逻辑式子，组成在一起的一个条件表达式
+//terms之间是AND的关系，OR和NOT是term中的节点，所以选择条件和连接条件可以按照term进行拆分
 type Predicate struct {
 	terms []*Term
 }
@@ -57,17 +58,24 @@ func (p *Predicate) ConjoinWith(pred *Predicate) {
 	p.terms = append(p.terms, pred.terms...)
 }
 
-//IsSatisfied 判断该条件表达式是否符合条件
+//IsSatisfied 判断该条件表达式是否符合条件，结果是UNKNOWN的时候不符合
 func (p *Predicate) IsSatisfied(s Scan) bool {
-	//对这个条件表达式中的每个表达式进行判断，是否符合条件
+	return p.Evaluate(s) == TRUTH_TRUE
+}
+
+//Evaluate 按照三值逻辑计算所有term的AND，有一个FALSE就是FALSE，否则有UNKNOWN的时候是UNKNOWN
+func (p *Predicate) Evaluate(s Scan) Truth {
+	result := TRUTH_TRUE
 	for _, t := range p.terms {
-		if !t.IsSatisfied(s) {
+		switch t.Evaluate(s) {
+		case TRUTH_FALSE:
 			//如果在整个表达式中有一个不满足，就不成立
-			return false
+			return TRUTH_FALSE
+		case TRUTH_UNKNOWN:
+			result = TRUTH_UNKNOWN
 		}
 	}
-	//所有表达式都满足
-	return true
+	return result
 }
 
 //SelectSubPred 从predicate的表达式中筛选出符合当前schema表的表达式集合
@@ -95,8 +103,8 @@ func (p *Predicate) JoinSubPred(sch1 *rm.Schema, sch2 *rm.Schema) *Predicate {
 	newSch.AddAll(sch2)
 	for _, t := range p.terms {
 		//遍历当前term中的所有表达式
-		if !t.AppliesTo(sch1) && t.AppliesTo(sch2) && t.AppliesTo(newSch) {
-			//如果当前表达式既符合sch1表，又符合sch2表，同时还符合两个表合并之后的表
+		if !t.AppliesTo(sch1) && !t.AppliesTo(sch2) && t.AppliesTo(newSch) {
+			//只有两个表合并之后才能计算的表达式，单独属于一个表的表达式已经在select中处理了
 			result.terms = append(result.terms, t)
 		}
 	}
@@ -136,13 +144,54 @@ func (p *Predicate) ToString() string {
 		if i == 0 {
 			result += t.ToString()
 		} else {
-			//todo 使用相应的逻辑表达式来进行处理
 			result += " AND " + t.ToString()
 		}
 	}
 	return result
 }
 
+//Terms AND连接起来的每一个条件
 func (p *Predicate) Terms() []*Term {
 	return p.terms
 }
+
+//AppliesTo 条件中所有的字段是否都在sch中
+func (p *Predicate) AppliesTo(sch *rm.Schema) bool {
+	for _, t := range p.terms {
+		if !t.AppliesTo(sch) {
+			return false
+		}
+	}
+	return true
+}
+
+//Rename 返回一个把所有字段按照rename换成新名字的条件
+func (p *Predicate) Rename(rename func(string) (string, error)) (*Predicate, error) {
+	result := NewPredicate()
+	for _, t := range p.terms {
+		renamed, err := t.Rename(rename)
+		if err != nil {
+			return nil, err
+		}
+		result.terms = append(result.terms, renamed)
+	}
+	return result, nil
+}
+
+//Subqueries 条件中所有的子查询
+func (p *Predicate) Subqueries() []*Subquery {
+	subs := make([]*Subquery, 0)
+	for _, t := range p.terms {
+		subs = append(subs, t.Subqueries()...)
+	}
+	return subs
+}
+
+//Comparisons 条件树中所有的比较节点，包括OR和NOT里面的比较
+func (p *Predicate) Comparisons() []*Term {
+	terms := make([]*Term, 0)
+	for _, t := range p.terms {
+		terms = append(terms, t.Comparisons()...)
+	}
+	return terms
+}
@@ -42,6 +42,36 @@ func TestPredicate(t *testing.T) {
 	sch1.AddIntField("MajorId")
 	sch1.AddIntField("StuId")
 	sch1.AddIntField("year")
+	//StuId=2只用到一张表，只有MajorId=DId需要两张表连接之后才能计算
 	p4 := p1.JoinSubPred(sch1, sch)
-	assert.Nil(t, p4)
+	assert.Equal(t, "MajorId=DId", p4.ToString())
+	assert.Nil(t, p1.JoinSubPred(sch, sch))
+}
+
+func TestPredicateOrNot(t *testing.T) {
+	one := 1
+	c1 := NewExpressionWithConstant(comm.NewConstantInt(&one))
+	age := NewExpressionWithFieldName("age")
+	dId := NewExpressionWithFieldName("DId")
+	majorId := NewExpressionWithFieldName("MajorId")
+
+	//age > 1 AND (age = DId OR NOT MajorId <> 1)
+	or := NewOrTerm(NewPredicateWithTerm(NewTerm(age, dId)),
+		NewPredicateWithTerm(NewNotTerm(NewPredicateWithTerm(NewTermWithOp(majorId, OP_NE, c1)))))
+	pred := NewPredicateWithTerm(NewTermWithOp(age, OP_GT, c1))
+	pred.ConjoinWith(NewPredicateWithTerm(or))
+	assert.Equal(t, "age>1 AND (age=DId OR NOT (MajorId<>1))", pred.ToString())
+	assert.Equal(t, 3, len(pred.Comparisons()))
+
+	sch1 := rm.NewSchema()
+	sch1.AddIntField("age")
+	sch2 := rm.NewSchema()
+	sch2.AddIntField("DId")
+	sch2.AddIntField("MajorId")
+	//OR节点作为一个整体，只有所有的字段都在表中的时候才能下推
+	assert.Equal(t, "age>1", pred.SelectSubPred(sch1).ToString())
+	assert.Nil(t, pred.SelectSubPred(sch2))
+	assert.Equal(t, "(age=DId OR NOT (MajorId<>1))", pred.JoinSubPred(sch1, sch2).ToString())
+	//OR节点中的相等比较不能用来选择索引
+	assert.Equal(t, "", pred.EquatesWithField("age"))
 }
@@ -1,6 +1,9 @@
 package query
 
-import "miniSQL/comm"
+import (
+	"miniSQL/comm"
+	rm "miniSQL/record_manager"
+)
 
 /*
 	select name from student where age>20,把所有age>20的记录都取出来，但是我们只要其中的name字段
@@ -67,6 +70,15 @@ func (s *SelectScan) SetVal(fieldName string, val *comm.Constant) {
 	s.scan.SetVal(fieldName, val)
 }
 
+//GetRid 当前记录所在的位置，修改和删除记录的时候用来同时修改索引
+func (s *SelectScan) GetRid() rm.RIDInterface {
+	return s.scan.GetRid()
+}
+
+func (s *SelectScan) Move2Rid(rid rm.RIDInterface) {
+	s.scan.Move2Rid(rid)
+}
+
 //HasField 判断某个字段是否存在这个表中
 func (s *SelectScan) HasField(fieldName string) bool {
 	return s.scan.HasField(fieldName)
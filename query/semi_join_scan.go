@@ -0,0 +1,83 @@
+package query
+
+import (
+	"miniSQL/comm"
+)
+
+//SemiJoinScan 输出outer中在inner里能找到匹配记录的记录，anti为true的时候输出找不到匹配的记录
+//匹配的条件是outerKeys[i]和innerKeys[i]的值都相等，第一次调用Next的时候把inner中所有的键读取到一个哈希集合中
+//键中有NULL的时候不和任何记录匹配
+type SemiJoinScan struct {
+	outer     Scan
+	inner     Scan
+	outerKeys []*Expression
+	innerKeys []*Expression
+	anti      bool
+	keys      map[string]bool //inner中所有的键，还没有读取的时候为nil
+}
+
+func NewSemiJoinScan(outer Scan, inner Scan, outerKeys []*Expression, innerKeys []*Expression, anti bool) *SemiJoinScan {
+	return &SemiJoinScan{
+		outer:     outer,
+		inner:     inner,
+		outerKeys: outerKeys,
+		innerKeys: innerKeys,
+		anti:      anti,
+	}
+}
+
+func (j *SemiJoinScan) BeforeFirst() {
+	j.outer.BeforeFirst()
+}
+
+func (j *SemiJoinScan) Next() bool {
+	if j.keys == nil {
+		j.keys = make(map[string]bool)
+		j.inner.BeforeFirst()
+		for j.inner.Next() {
+			if key, ok := keyOf(j.innerKeys, j.inner); ok {
+				j.keys[key] = true
+			}
+		}
+	}
+	for j.outer.Next() {
+		key, ok := keyOf(j.outerKeys, j.outer)
+		if (ok && j.keys[key]) != j.anti {
+			return true
+		}
+	}
+	return false
+}
+
+//keyOf 计算s当前记录的键，有NULL的时候返回false
+func keyOf(exprs []*Expression, s Scan) (string, bool) {
+	vals := make([]*comm.Constant, len(exprs))
+	for i, expr := range exprs {
+		if vals[i] = expr.Evaluate(s); vals[i].IsNull() {
+			return "", false
+		}
+	}
+	return groupKey(vals), true
+}
+
+func (j *SemiJoinScan) GetInt(fieldName string) int {
+	return j.outer.GetInt(fieldName)
+}
+
+func (j *SemiJoinScan) GetString(fieldName string) string {
+	return j.outer.GetString(fieldName)
+}
+
+func (j *SemiJoinScan) GetVal(fieldName string) *comm.Constant {
+	return j.outer.GetVal(fieldName)
+}
+
+func (j *SemiJoinScan) HasField(fieldName string) bool {
+	return j.outer.HasField(fieldName)
+}
+
+func (j *SemiJoinScan) Close() {
+	j.outer.Close()
+	j.inner.Close()
+	j.keys = nil
+}
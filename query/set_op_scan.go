@@ -0,0 +1,283 @@
+package query
+
+import (
+	"miniSQL/comm"
+)
+
+/*
+	集合运算的扫描，UNION ALL直接依次读取两边的记录，其他的运算要求两边都已经按照所有的字段排好了序：
+	lhsKeys和rhsKeys按照位置一一对应，两边的记录按照相同的顺序排列，相同的记录排在一起
+	每次从两边各取出当前一组相同的记录，比较之后较小的一组先处理，根据这一组在左边出现了m次，右边出现了n次决定输出几次
+	结果中字段的名字使用左边的名字
+*/
+
+//sortedGroups 从排好序的scan中依次读取每一组相同的记录
+type sortedGroups struct {
+	s     Scan
+	keys  []string
+	vals  []*comm.Constant //当前这一组的值，已经读取完的时候为nil
+	count int              //当前这一组的记录数
+	more  bool             //s是否还指向一条没有读取的记录
+}
+
+func newSortedGroups(s Scan, keys []string) *sortedGroups {
+	return &sortedGroups{
+		s:    s,
+		keys: keys,
+	}
+}
+
+func (g *sortedGroups) beforeFirst() {
+	g.s.BeforeFirst()
+	g.more = g.s.Next()
+	g.next()
+}
+
+//next 读取下一组相同的记录
+func (g *sortedGroups) next() {
+	g.vals, g.count = nil, 0
+	if !g.more {
+		return
+	}
+	g.vals = g.current()
+	for g.more && compareRows(g.current(), g.vals, nil) == 0 {
+		g.count++
+		g.more = g.s.Next()
+	}
+}
+
+func (g *sortedGroups) current() []*comm.Constant {
+	vals := make([]*comm.Constant, len(g.keys))
+	for i, key := range g.keys {
+		vals[i] = g.s.GetVal(key)
+	}
+	return vals
+}
+
+//compareRows 按照每个字段的排序方向比较两组值，nil表示已经读取完了，排在最后面
+func compareRows(a []*comm.Constant, b []*comm.Constant, desc []bool) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	for i := range a {
+		c := a[i].CompareTo(b[i])
+		if c == 0 {
+			continue
+		}
+		if desc != nil && desc[i] {
+			return -c
+		}
+		return c
+	}
+	return 0
+}
+
+//mergeScan 归并两边排好序的记录，count根据一组记录在左边和右边出现的次数决定输出几次
+type mergeScan struct {
+	lhs       *sortedGroups
+	rhs       *sortedGroups
+	desc      []bool
+	names     map[string]int //左边的字段在每一组的值中的下标
+	vals      []*comm.Constant
+	remaining int //当前这一组还需要输出几次
+	count     func(m int, n int) int
+}
+
+func newMergeScan(lhs Scan, rhs Scan, lhsKeys []*SortKey, rhsKeys []*SortKey, count func(m int, n int) int) *mergeScan {
+	m := &mergeScan{
+		desc:  make([]bool, len(lhsKeys)),
+		names: make(map[string]int),
+		count: count,
+	}
+	lhsFields, rhsFields := make([]string, len(lhsKeys)), make([]string, len(rhsKeys))
+	for i, key := range lhsKeys {
+		lhsFields[i], rhsFields[i] = key.Field(), rhsKeys[i].Field()
+		m.desc[i] = key.Desc()
+		m.names[key.Field()] = i
+	}
+	m.lhs, m.rhs = newSortedGroups(lhs, lhsFields), newSortedGroups(rhs, rhsFields)
+	m.BeforeFirst()
+	return m
+}
+
+func (m *mergeScan) BeforeFirst() {
+	m.lhs.beforeFirst()
+	m.rhs.beforeFirst()
+	m.remaining = 0
+}
+
+func (m *mergeScan) Next() bool {
+	for m.remaining == 0 {
+		if m.lhs.vals == nil && m.rhs.vals == nil {
+			return false
+		}
+		c := compareRows(m.lhs.vals, m.rhs.vals, m.desc)
+		l, r := 0, 0
+		if c <= 0 {
+			l, m.vals = m.lhs.count, m.lhs.vals
+			m.lhs.next()
+		}
+		if c >= 0 {
+			r, m.vals = m.rhs.count, m.rhs.vals
+			m.rhs.next()
+		}
+		m.remaining = m.count(l, r)
+	}
+	m.remaining--
+	return true
+}
+
+func (m *mergeScan) GetInt(fieldName string) int {
+	return m.GetVal(fieldName).AsInt()
+}
+
+func (m *mergeScan) GetString(fieldName string) string {
+	return m.GetVal(fieldName).AsString()
+}
+
+func (m *mergeScan) GetVal(fieldName string) *comm.Constant {
+	return m.vals[m.names[fieldName]]
+}
+
+func (m *mergeScan) HasField(fieldName string) bool {
+	_, ok := m.names[fieldName]
+	return ok
+}
+
+func (m *mergeScan) Close() {
+	m.lhs.s.Close()
+	m.rhs.s.Close()
+}
+
+//UnionScan UNION去掉重复的记录，每一组只输出一次；UNION ALL依次输出两边的所有记录，不需要排序
+type UnionScan struct {
+	merge *mergeScan //UNION归并两边的记录，UNION ALL的时候为nil
+	lhs   Scan
+	rhs   Scan
+	names map[string]string //UNION ALL中左边的字段对应右边的字段
+	onRhs bool              //UNION ALL已经读取完了左边，正在读取右边
+}
+
+func NewUnionScan(lhs Scan, rhs Scan, lhsKeys []*SortKey, rhsKeys []*SortKey, all bool) *UnionScan {
+	u := &UnionScan{
+		lhs:   lhs,
+		rhs:   rhs,
+		names: make(map[string]string),
+	}
+	if !all {
+		u.merge = newMergeScan(lhs, rhs, lhsKeys, rhsKeys, func(m int, n int) int { return 1 })
+		return u
+	}
+	for i, key := range lhsKeys {
+		u.names[key.Field()] = rhsKeys[i].Field()
+	}
+	return u
+}
+
+func (u *UnionScan) BeforeFirst() {
+	if u.merge != nil {
+		u.merge.BeforeFirst()
+		return
+	}
+	u.lhs.BeforeFirst()
+	u.rhs.BeforeFirst()
+	u.onRhs = false
+}
+
+func (u *UnionScan) Next() bool {
+	if u.merge != nil {
+		return u.merge.Next()
+	}
+	if !u.onRhs {
+		if u.lhs.Next() {
+			return true
+		}
+		u.onRhs = true
+	}
+	return u.rhs.Next()
+}
+
+func (u *UnionScan) GetInt(fieldName string) int {
+	return u.GetVal(fieldName).AsInt()
+}
+
+func (u *UnionScan) GetString(fieldName string) string {
+	return u.GetVal(fieldName).AsString()
+}
+
+func (u *UnionScan) GetVal(fieldName string) *comm.Constant {
+	if u.merge != nil {
+		return u.merge.GetVal(fieldName)
+	}
+	if u.onRhs {
+		return u.rhs.GetVal(u.names[fieldName])
+	}
+	return u.lhs.GetVal(fieldName)
+}
+
+func (u *UnionScan) HasField(fieldName string) bool {
+	if u.merge != nil {
+		return u.merge.HasField(fieldName)
+	}
+	_, ok := u.names[fieldName]
+	return ok
+}
+
+func (u *UnionScan) Close() {
+	u.lhs.Close()
+	u.rhs.Close()
+}
+
+//IntersectScan INTERSECT输出两边都有的记录，INTERSECT ALL输出min(m, n)次
+type IntersectScan struct {
+	*mergeScan
+}
+
+func NewIntersectScan(lhs Scan, rhs Scan, lhsKeys []*SortKey, rhsKeys []*SortKey, all bool) *IntersectScan {
+	return &IntersectScan{
+		mergeScan: newMergeScan(lhs, rhs, lhsKeys, rhsKeys, func(m int, n int) int {
+			if all {
+				return minCount(m, n)
+			}
+			return minCount(minCount(m, n), 1)
+		}),
+	}
+}
+
+//ExceptScan EXCEPT输出左边有右边没有的记录，EXCEPT ALL输出max(m - n, 0)次
+type ExceptScan struct {
+	*mergeScan
+}
+
+func NewExceptScan(lhs Scan, rhs Scan, lhsKeys []*SortKey, rhsKeys []*SortKey, all bool) *ExceptScan {
+	return &ExceptScan{
+		mergeScan: newMergeScan(lhs, rhs, lhsKeys, rhsKeys, func(m int, n int) int {
+			if all {
+				return maxCount(m-n, 0)
+			}
+			if m > 0 && n == 0 {
+				return 1
+			}
+			return 0
+		}),
+	}
+}
+
+func minCount(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxCount(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
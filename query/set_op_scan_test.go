@@ -0,0 +1,38 @@
+package query
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSetOpScan(t *testing.T) {
+	//两边都已经按照(a, b)排好了序，右边的字段名字不同
+	lhs := func() Scan {
+		return intRows([]string{"a", "b"}, []int{1, 1}, []int{1, 1}, []int{1, 1}, []int{2, 2}, []int{3, 3})
+	}
+	rhs := func() Scan { return intRows([]string{"x", "y"}, []int{1, 1}, []int{1, 1}, []int{3, 3}, []int{4, 4}) }
+	lhsKeys := []*SortKey{NewSortKey("a", false), NewSortKey("b", false)}
+	rhsKeys := []*SortKey{NewSortKey("x", false), NewSortKey("y", false)}
+
+	assert.Equal(t, []string{"1", "2", "3", "4"}, collectJoin(NewUnionScan(lhs(), rhs(), lhsKeys, rhsKeys, false), "a"))
+	assert.Equal(t, []string{"1 1", "1 1", "1 1", "2 2", "3 3", "1 1", "1 1", "3 3", "4 4"},
+		collectJoin(NewUnionScan(lhs(), rhs(), lhsKeys, rhsKeys, true), "a", "b"))
+	assert.Equal(t, []string{"1", "3"}, collectJoin(NewIntersectScan(lhs(), rhs(), lhsKeys, rhsKeys, false), "a"))
+	assert.Equal(t, []string{"1", "1", "3"}, collectJoin(NewIntersectScan(lhs(), rhs(), lhsKeys, rhsKeys, true), "a"))
+	assert.Equal(t, []string{"2"}, collectJoin(NewExceptScan(lhs(), rhs(), lhsKeys, rhsKeys, false), "a"))
+	assert.Equal(t, []string{"1", "2"}, collectJoin(NewExceptScan(lhs(), rhs(), lhsKeys, rhsKeys, true), "a"))
+
+	//按照降序排列的时候归并的方向也相反
+	desc := func() Scan { return intRows([]string{"a", "b"}, []int{3, 3}, []int{2, 2}, []int{1, 1}) }
+	descRhs := func() Scan { return intRows([]string{"x", "y"}, []int{4, 4}, []int{2, 2}) }
+	lhsKeys = []*SortKey{NewSortKey("a", true), NewSortKey("b", false)}
+	rhsKeys = []*SortKey{NewSortKey("x", true), NewSortKey("y", false)}
+	union := NewUnionScan(desc(), descRhs(), lhsKeys, rhsKeys, false)
+	for union.Next() {
+	}
+	assert.True(t, union.HasField("b"))
+	assert.False(t, union.HasField("y"))
+	//BeforeFirst之后可以重新读取
+	union.BeforeFirst()
+	assert.Equal(t, []string{"4", "3", "2", "1"}, collectJoin(union, "a"))
+}
@@ -0,0 +1,150 @@
+package query
+
+import (
+	"miniSQL/comm"
+	"strings"
+)
+
+/*
+	ORDER BY的执行分成两种情况：
+	1.所有的记录都能放在内存中，直接在内存中排序，然后使用MemoryScan把结果一条一条的返回
+	2.记录太多放不下，先把记录分成多个有序的run写入到临时表中，再把多个run归并，SortScan负责最后一趟的归并
+	SortScan每次从所有run的当前记录中挑选出最小的一条返回，相等的时候优先返回前面的run，这样排序是稳定的
+*/
+
+//SortKey ORDER BY中的一个排序字段
+type SortKey struct {
+	field string
+	desc  bool //是否按照降序排列
+}
+
+func NewSortKey(field string, desc bool) *SortKey {
+	return &SortKey{
+		field: field,
+		desc:  desc,
+	}
+}
+
+func (k *SortKey) Field() string {
+	return k.field
+}
+
+func (k *SortKey) Desc() bool {
+	return k.desc
+}
+
+//ToString 转化成SQL语句中的形式，例如 gradyear DESC
+func (k *SortKey) ToString() string {
+	if k.desc {
+		return k.field + " DESC"
+	}
+	return k.field
+}
+
+//SortKeysToString 把多个排序字段使用逗号连接起来
+func SortKeysToString(keys []*SortKey) string {
+	strs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		strs = append(strs, key.ToString())
+	}
+	return strings.Join(strs, ", ")
+}
+
+//RecordComparator 按照排序字段依次比较两条记录
+type RecordComparator struct {
+	keys []*SortKey
+}
+
+func NewRecordComparator(keys []*SortKey) *RecordComparator {
+	return &RecordComparator{
+		keys: keys,
+	}
+}
+
+//Compare 比较两个scan当前指向的记录，s1排在前面返回负数，相等返回0，s1排在后面返回正数
+func (r *RecordComparator) Compare(s1 Scan, s2 Scan) int {
+	return r.CompareValues(s1.GetVal, s2.GetVal)
+}
+
+//CompareValues 比较两条记录，记录中字段的值通过get1和get2获得
+func (r *RecordComparator) CompareValues(get1 func(string) *comm.Constant, get2 func(string) *comm.Constant) int {
+	for _, key := range r.keys {
+		c := get1(key.field).CompareTo(get2(key.field))
+		if c == 0 {
+			continue
+		}
+		if key.desc {
+			return -c
+		}
+		return c
+	}
+	return 0
+}
+
+//SortScan 对多个已经排好序的scan进行归并
+type SortScan struct {
+	scans   []Scan
+	hasMore []bool //每个scan是否还有记录
+	current int    //当前记录来自哪一个scan，-1表示还没有开始或者已经结束
+	comp    *RecordComparator
+}
+
+//NewSortScan 传入的每个scan都必须已经按照comp排好序了
+func NewSortScan(scans []Scan, comp *RecordComparator) *SortScan {
+	sortScan := &SortScan{
+		scans:   scans,
+		hasMore: make([]bool, len(scans)),
+		comp:    comp,
+	}
+	sortScan.BeforeFirst()
+	return sortScan
+}
+
+//BeforeFirst 每个scan都指向自己的第一条记录
+func (s *SortScan) BeforeFirst() {
+	s.current = -1
+	for i, scan := range s.scans {
+		scan.BeforeFirst()
+		s.hasMore[i] = scan.Next()
+	}
+}
+
+//Next 把上一次返回的scan往后移动一条，然后在所有scan的当前记录中挑选出最小的一条
+//run的数量不会超过可用的缓存块数，所以这里直接线性的查找
+func (s *SortScan) Next() bool {
+	if s.current >= 0 {
+		s.hasMore[s.current] = s.scans[s.current].Next()
+	}
+	s.current = -1
+	for i, scan := range s.scans {
+		if !s.hasMore[i] {
+			continue
+		}
+		if s.current < 0 || s.comp.Compare(scan, s.scans[s.current]) < 0 {
+			s.current = i
+		}
+	}
+	return s.current >= 0
+}
+
+func (s *SortScan) GetInt(fieldName string) int {
+	return s.scans[s.current].GetInt(fieldName)
+}
+
+func (s *SortScan) GetString(fieldName string) string {
+	return s.scans[s.current].GetString(fieldName)
+}
+
+func (s *SortScan) GetVal(fieldName string) *comm.Constant {
+	return s.scans[s.current].GetVal(fieldName)
+}
+
+func (s *SortScan) HasField(fieldName string) bool {
+	return len(s.scans) > 0 && s.scans[0].HasField(fieldName)
+}
+
+func (s *SortScan) Close() {
+	for _, scan := range s.scans {
+		scan.Close()
+	}
+}
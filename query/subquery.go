@@ -0,0 +1,266 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"miniSQL/comm"
+	rm "miniSQL/record_manager"
+)
+
+/*
+	条件和表达式中的子查询：
+	1.x IN (SELECT y FROM ...)，x等于子查询结果中的某一个值
+	2.EXISTS (SELECT ...)，子查询至少有一条记录
+	3.标量子查询，例如 age > (SELECT avg(age) FROM student)，子查询最多只能有一条记录，没有记录的时候是NULL
+	解析的时候只保存子查询的语法树，planner为子查询创建查询计划之后通过Bind绑定上去
+	子查询中引用了外层查询的字段的时候是相关子查询，外层的每一条记录都需要重新执行一遍，外层的记录通过OuterRow传进去
+	不相关的子查询只执行一次，结果缓存下来，查询计划每次打开的时候调用Reset清空缓存
+*/
+
+//ErrSubqueryRows 标量子查询返回了多条记录，和计算出错一样只是这一条语句失败
+var ErrSubqueryRows = errors.New("more than one row returned by a subquery used as an expression")
+
+//SubqueryData 子查询的语法树，由parser中的QueryData实现
+type SubqueryData interface {
+	ToString() string
+}
+
+//SubqueryPlan 子查询的查询计划，由planner中的Plan实现
+type SubqueryPlan interface {
+	Open() (interface{}, error)
+	Schema() rm.SchemaInterface
+}
+
+//OuterRow 相关子查询执行的时候外层查询当前的记录
+type OuterRow struct {
+	s      Scan
+	linked []*OuterRow //子查询是集合运算的时候，每一个操作数有自己的OuterRow，外层的记录需要同时传给它们
+}
+
+func NewOuterRow() *OuterRow {
+	return &OuterRow{}
+}
+
+//Link 设置外层记录的时候同时设置row
+func (r *OuterRow) Link(row *OuterRow) {
+	r.linked = append(r.linked, row)
+}
+
+func (r *OuterRow) set(s Scan) {
+	r.s = s
+	for _, row := range r.linked {
+		row.set(s)
+	}
+}
+
+type Subquery struct {
+	data   SubqueryData
+	plan   SubqueryPlan
+	outer  *OuterRow      //相关子查询从这里读取外层的记录，不相关的时候为nil
+	fields []string       //子查询中引用的外层查询的字段
+	cached bool           //不相关的子查询是否已经执行过了
+	exists bool           //EXISTS缓存下来的结果
+	values *inSet         //IN缓存下来的子查询结果
+	value  *comm.Constant //标量子查询缓存下来的结果
+}
+
+func NewSubquery(data SubqueryData) *Subquery {
+	return &Subquery{
+		data: data,
+	}
+}
+
+//Bind 绑定子查询的查询计划，fields是子查询中引用的外层查询的字段，外层查询当前的记录通过outer传给子查询
+func (q *Subquery) Bind(plan SubqueryPlan, outer *OuterRow, fields []string) {
+	q.plan = plan
+	q.outer = outer
+	q.fields = fields
+	q.Reset()
+}
+
+//clone 复制一个还没有绑定查询计划的子查询，同一个语法树可以多次创建查询计划
+func (q *Subquery) clone() *Subquery {
+	return NewSubquery(q.data)
+}
+
+func (q *Subquery) Data() SubqueryData {
+	return q.data
+}
+
+func (q *Subquery) Plan() SubqueryPlan {
+	return q.plan
+}
+
+//OuterFields 子查询中引用的外层查询的字段，外层查询需要提供这些字段
+func (q *Subquery) OuterFields() []string {
+	return q.fields
+}
+
+//IsCorrelated 是否是相关子查询
+func (q *Subquery) IsCorrelated() bool {
+	return len(q.fields) > 0
+}
+
+//Reset 清空不相关子查询缓存下来的结果，下一次使用的时候重新执行
+func (q *Subquery) Reset() {
+	q.cached = false
+	q.values = nil
+	q.value = nil
+}
+
+//Exists 子查询是否至少有一条记录
+func (q *Subquery) Exists(s Scan) bool {
+	if q.cached {
+		return q.exists
+	}
+	exists := false
+	q.run(s, func(scan Scan, field string) bool {
+		exists = true
+		return false
+	})
+	q.cache(func() { q.exists = exists })
+	return exists
+}
+
+//inSet IN子查询的结果，保存所有不是NULL的值，以及结果中有没有NULL
+type inSet struct {
+	values  map[string]bool
+	hasNull bool
+}
+
+//contains 按照三值逻辑判断val是否在结果中：结果为空的时候是FALSE，val是NULL或者没有找到但是结果中有NULL的时候是UNKNOWN
+func (in *inSet) contains(val *comm.Constant) Truth {
+	switch {
+	case len(in.values) == 0 && !in.hasNull:
+		return TRUTH_FALSE
+	case val.IsNull():
+		return TRUTH_UNKNOWN
+	case in.values[groupKey([]*comm.Constant{val})]:
+		return TRUTH_TRUE
+	case in.hasNull:
+		return TRUTH_UNKNOWN
+	}
+	return TRUTH_FALSE
+}
+
+//Contains 子查询的结果中是否有和val相等的值，和CompareTruth一样NULL和任何值比较都是UNKNOWN
+func (q *Subquery) Contains(val *comm.Constant, s Scan) Truth {
+	if q.cached {
+		return q.values.contains(val)
+	}
+	values := &inSet{values: make(map[string]bool)}
+	q.run(s, func(scan Scan, field string) bool {
+		if v := scan.GetVal(field); v.IsNull() {
+			values.hasNull = true
+		} else {
+			values.values[groupKey([]*comm.Constant{v})] = true
+		}
+		return true
+	})
+	q.cache(func() { q.values = values })
+	return values.contains(val)
+}
+
+//Value 标量子查询的值，没有记录的时候是NULL，有多条记录的时候panic一个ErrSubqueryRows
+func (q *Subquery) Value(s Scan) *comm.Constant {
+	if q.cached {
+		return q.value
+	}
+	value := comm.NewNullConstant()
+	rows := 0
+	q.run(s, func(scan Scan, field string) bool {
+		rows++
+		if rows > 1 {
+			panic(ErrSubqueryRows)
+		}
+		value = scan.GetVal(field)
+		return true
+	})
+	q.cache(func() { q.value = value })
+	return value
+}
+
+//cache 不相关的子查询把结果缓存下来
+func (q *Subquery) cache(save func()) {
+	if !q.IsCorrelated() {
+		save()
+		q.cached = true
+	}
+}
+
+//run 以s作为外层的记录执行子查询，对结果中的每一条记录调用visit，visit返回false的时候停止
+func (q *Subquery) run(s Scan, visit func(scan Scan, field string) bool) {
+	if q.plan == nil {
+		panic(fmt.Sprintf("subquery %s is not planned", q.ToString()))
+	}
+	if q.outer != nil {
+		q.outer.set(s)
+	}
+	opened, err := q.plan.Open()
+	if err != nil {
+		panic(err)
+	}
+	scan := opened.(Scan)
+	defer scan.Close()
+	//IN和标量子查询使用结果中唯一的字段
+	field := ""
+	if fields := q.plan.Schema().Fields(); len(fields) > 0 {
+		field = fields[0]
+	}
+	for scan.Next() {
+		if !visit(scan, field) {
+			return
+		}
+	}
+}
+
+//ToString 子查询带上括号，这样视图的定义可以被重新解析
+func (q *Subquery) ToString() string {
+	return "(" + q.data.ToString() + ")"
+}
+
+//OuterScan 相关子查询中只有一条记录的扫描，字段的值是外层查询当前记录中对应字段的值
+type OuterScan struct {
+	row   *OuterRow
+	names map[string]string //子查询中使用的名字对应外层查询中的字段名
+	done  bool
+}
+
+func NewOuterScan(row *OuterRow, names map[string]string) *OuterScan {
+	return &OuterScan{
+		row:   row,
+		names: names,
+	}
+}
+
+func (o *OuterScan) BeforeFirst() {
+	o.done = false
+}
+
+func (o *OuterScan) Next() bool {
+	if o.done {
+		return false
+	}
+	o.done = true
+	return true
+}
+
+func (o *OuterScan) GetInt(fieldName string) int {
+	return o.GetVal(fieldName).AsInt()
+}
+
+func (o *OuterScan) GetString(fieldName string) string {
+	return o.GetVal(fieldName).AsString()
+}
+
+func (o *OuterScan) GetVal(fieldName string) *comm.Constant {
+	return o.row.s.GetVal(o.names[fieldName])
+}
+
+func (o *OuterScan) HasField(fieldName string) bool {
+	_, ok := o.names[fieldName]
+	return ok
+}
+
+func (o *OuterScan) Close() {
+}
@@ -3,44 +3,275 @@ package query
 import (
 	"miniSQL/comm"
 	rm "miniSQL/record_manager"
+	"strings"
+)
+
+//比较运算符
+const (
+	OP_EQ = "="
+	OP_NE = "<>"
+	OP_LT = "<"
+	OP_LE = "<="
+	OP_GT = ">"
+	OP_GE = ">="
+	OP_IN = "IN" //右边是子查询，左边的值在子查询的结果中
+
+	OP_IS_NULL     = "IS NULL"     //右边固定是NULL常量，左边的值是NULL
+	OP_IS_NOT_NULL = "IS NOT NULL" //右边固定是NULL常量，左边的值不是NULL
+)
+
+//Truth SQL的三值逻辑，和NULL比较的结果是UNKNOWN，WHERE，HAVING和ON只保留结果是TRUE的记录
+type Truth int
+
+const (
+	TRUTH_FALSE Truth = iota
+	TRUTH_TRUE
+	TRUTH_UNKNOWN
+)
+
+func truthOf(b bool) Truth {
+	if b {
+		return TRUTH_TRUE
+	}
+	return TRUTH_FALSE
+}
+
+//Not TRUE和FALSE互换，UNKNOWN还是UNKNOWN
+func (t Truth) Not() Truth {
+	switch t {
+	case TRUTH_TRUE:
+		return TRUTH_FALSE
+	case TRUTH_FALSE:
+		return TRUTH_TRUE
+	}
+	return TRUTH_UNKNOWN
+}
+
+//布尔运算的节点类型
+const (
+	TERM_COMPARE = iota //lhs op rhs
+	TERM_OR             //children中的条件至少有一个成立
+	TERM_NOT            //children中唯一的条件不成立
+	TERM_EXISTS         //rhs中的子查询至少有一条记录
 )
 
 //Term MOD(GradYear,4)==0这个式子用term表示,表达式
 //MajorId = DId
+//Term是条件表达式树中的一个节点，Predicate是多个Term的AND，Term可以是一个比较，也可以是多个Predicate的OR或者一个Predicate的NOT
+//例如 a = 1 AND (b > 2 OR NOT (c = 3 AND d = 4))，最外层的Predicate中有两个Term，第二个Term是OR节点
 type Term struct {
-	lhs *Expression //左表达式,对于上面的例子，这个就是MajorId
-	rhs *Expression //右表达式,对于上面的例子，这个就是DId
+	kind     int
+	op       string       //比较运算符
+	lhs      *Expression  //左表达式,对于上面的例子，这个就是MajorId
+	rhs      *Expression  //右表达式,对于上面的例子，这个就是DId
+	children []*Predicate //OR和NOT节点的子条件
 }
 
+//NewTerm 构造一个相等比较
 func NewTerm(lhs *Expression, rhs *Expression) *Term {
+	return NewTermWithOp(lhs, OP_EQ, rhs)
+}
+
+//NewTermWithOp 构造一个使用op进行比较的Term
+func NewTermWithOp(lhs *Expression, op string, rhs *Expression) *Term {
 	return &Term{
-		lhs: lhs,
-		rhs: rhs,
+		kind: TERM_COMPARE,
+		op:   op,
+		lhs:  lhs,
+		rhs:  rhs,
+	}
+}
+
+//NewInTerm lhs的值在子查询的结果中
+func NewInTerm(lhs *Expression, sub *Subquery) *Term {
+	return NewTermWithOp(lhs, OP_IN, NewSubqueryExpression(sub))
+}
+
+//NewIsNullTerm lhs IS NULL，not为true的时候是lhs IS NOT NULL
+func NewIsNullTerm(lhs *Expression, not bool) *Term {
+	op := OP_IS_NULL
+	if not {
+		op = OP_IS_NOT_NULL
+	}
+	return NewTermWithOp(lhs, op, NewExpressionWithConstant(comm.NewNullConstant()))
+}
+
+//NewExistsTerm 子查询至少有一条记录
+func NewExistsTerm(sub *Subquery) *Term {
+	return &Term{
+		kind: TERM_EXISTS,
+		rhs:  NewSubqueryExpression(sub),
+	}
+}
+
+//NewOrTerm 多个条件中至少有一个成立
+func NewOrTerm(preds ...*Predicate) *Term {
+	return &Term{
+		kind:     TERM_OR,
+		children: preds,
+	}
+}
+
+//NewNotTerm 条件不成立
+func NewNotTerm(pred *Predicate) *Term {
+	return &Term{
+		kind:     TERM_NOT,
+		children: []*Predicate{pred},
 	}
 }
 
 //IsSatisfied 如果是字段就查表拿到这个字段的值，如果是常量就直接获得这个值，判读这两个对应的值是否相同,判断是否符合条件
+//结果是UNKNOWN的时候不成立
 func (t *Term) IsSatisfied(s Scan) bool {
+	return t.Evaluate(s) == TRUTH_TRUE
+}
+
+//Evaluate 按照三值逻辑计算条件的结果，OR中有一个TRUE就是TRUE，NOT UNKNOWN还是UNKNOWN
+func (t *Term) Evaluate(s Scan) Truth {
+	switch t.kind {
+	case TERM_OR:
+		result := TRUTH_FALSE
+		for _, child := range t.children {
+			switch child.Evaluate(s) {
+			case TRUTH_TRUE:
+				return TRUTH_TRUE
+			case TRUTH_UNKNOWN:
+				result = TRUTH_UNKNOWN
+			}
+		}
+		return result
+	case TERM_NOT:
+		return t.children[0].Evaluate(s).Not()
+	case TERM_EXISTS:
+		return truthOf(t.rhs.sub.Exists(s))
+	}
+	if t.op == OP_IN {
+		return t.rhs.sub.Contains(t.lhs.Evaluate(s), s)
+	}
 	//evaluate获得的是一个常量对象，所以可以直接比较
 	lhsVal := t.lhs.Evaluate(s)
 	rhsVal := t.rhs.Evaluate(s)
-	return lhsVal.Equal(rhsVal) //判读两个字段是否相同
+	return CompareTruth(lhsVal, t.op, rhsVal)
+}
+
+//Compare 使用op比较两个常量，结果是TRUE的时候成立，类型不能比较的时候都不成立，例如整数和字符串
+func Compare(lhs *comm.Constant, op string, rhs *comm.Constant) bool {
+	return CompareTruth(lhs, op, rhs) == TRUTH_TRUE
+}
+
+//CompareTruth 使用op比较两个常量，除了IS NULL和IS NOT NULL，和NULL比较的结果都是UNKNOWN
+func CompareTruth(lhs *comm.Constant, op string, rhs *comm.Constant) Truth {
+	switch op {
+	case OP_IS_NULL:
+		return truthOf(lhs.IsNull())
+	case OP_IS_NOT_NULL:
+		return truthOf(!lhs.IsNull())
+	}
+	if lhs.IsNull() || rhs.IsNull() {
+		return TRUTH_UNKNOWN
+	}
+	if !lhs.Comparable(rhs) {
+		return TRUTH_FALSE
+	}
+	c := lhs.CompareTo(rhs)
+	switch op {
+	case OP_EQ:
+		return truthOf(c == 0)
+	case OP_NE:
+		return truthOf(c != 0)
+	case OP_LT:
+		return truthOf(c < 0)
+	case OP_LE:
+		return truthOf(c <= 0)
+	case OP_GT:
+		return truthOf(c > 0)
+	case OP_GE:
+		return truthOf(c >= 0)
+	}
+	return TRUTH_FALSE
 }
 
 //AppliesTo 判读这两个字段是否可以使用在对于这张表达的操作
 func (t *Term) AppliesTo(sch *rm.Schema) bool {
+	if t.kind == TERM_EXISTS {
+		return t.rhs.AppliesTo(sch)
+	}
+	if t.kind != TERM_COMPARE {
+		for _, child := range t.children {
+			if !child.AppliesTo(sch) {
+				return false
+			}
+		}
+		return true
+	}
 	return t.lhs.AppliesTo(sch) && t.rhs.AppliesTo(sch)
 }
 
-//ToString 把这个表达式转化成字符串的形式
-//TODO 操作符后续我们需要处理
+//ToString 把这个表达式转化成字符串的形式，OR和NOT会加上括号，这样视图的定义可以被重新解析
 func (t *Term) ToString() string {
-	return t.lhs.ToString() + "=" + t.rhs.ToString()
+	switch t.kind {
+	case TERM_OR:
+		strs := make([]string, 0, len(t.children))
+		for _, child := range t.children {
+			strs = append(strs, child.ToString())
+		}
+		return "(" + strings.Join(strs, " OR ") + ")"
+	case TERM_NOT:
+		return "NOT (" + t.children[0].ToString() + ")"
+	case TERM_EXISTS:
+		return "EXISTS " + t.rhs.ToString()
+	}
+	if t.op == OP_IN {
+		return t.lhs.ToString() + " IN " + t.rhs.ToString()
+	}
+	if t.IsNullTest() {
+		return t.lhs.ToString() + " " + t.op
+	}
+	return t.lhs.ToString() + t.op + t.rhs.ToString()
+}
+
+func (t *Term) Kind() int {
+	return t.kind
+}
+
+//Op 比较运算符，只有比较节点才有
+func (t *Term) Op() string {
+	return t.op
+}
+
+//Children OR和NOT节点的子条件
+func (t *Term) Children() []*Predicate {
+	return t.children
+}
+
+//IsEquality 是否是一个相等比较
+func (t *Term) IsEquality() bool {
+	return t.kind == TERM_COMPARE && t.op == OP_EQ
+}
+
+//IsNullTest 是否是IS NULL或者IS NOT NULL，右边的NULL常量只是占位
+func (t *Term) IsNullTest() bool {
+	return t.kind == TERM_COMPARE && (t.op == OP_IS_NULL || t.op == OP_IS_NOT_NULL)
+}
+
+//Comparisons 条件树中所有的比较节点
+func (t *Term) Comparisons() []*Term {
+	if t.kind == TERM_COMPARE {
+		return []*Term{t}
+	}
+	terms := make([]*Term, 0)
+	for _, child := range t.children {
+		terms = append(terms, child.Comparisons()...)
+	}
+	return terms
 }
 
 //EquatesWithField 检查是否存在与给定字段相等的另一字段名字
 //MajorId = DId”这两个都是字段，给定的fieldName := "DId"，得到MajorId
 func (t *Term) EquatesWithField(fieldName string) string {
+	if !t.IsEquality() {
+		return ""
+	}
 	if t.lhs.IsFieldName() && t.lhs.AsFieldName() == fieldName && t.rhs.IsFieldName() {
 		return t.rhs.AsFieldName() //如果左右两边都是字段，同时左边的字段和给定的字段相同，那么我们得到和这个相同的右边字段
 	} else if t.rhs.IsFieldName() && t.rhs.AsFieldName() == fieldName && t.lhs.IsFieldName() {
@@ -52,9 +283,12 @@ func (t *Term) EquatesWithField(fieldName string) string {
 //EquatesWithConstant 查询给定的字段相等的一个常数
 //pid=20,fieldName=pid，返回=20
 func (t *Term) EquatesWithConstant(fieldName string) *comm.Constant {
-	if t.lhs.IsFieldName() && t.lhs.AsFieldName() == fieldName && !t.rhs.IsFieldName() {
+	if !t.IsEquality() {
+		return nil
+	}
+	if t.lhs.IsFieldName() && t.lhs.AsFieldName() == fieldName && t.rhs.IsConstant() {
 		return t.rhs.AsConstant() //左边是字段，右边是一个常量，左边字段和给定的字段相同，我们返回右边的常量
-	} else if t.rhs.IsFieldName() && t.rhs.AsFieldName() == fieldName && t.lhs.IsFieldName() {
+	} else if t.rhs.IsFieldName() && t.rhs.AsFieldName() == fieldName && t.lhs.IsConstant() {
 		//右边是字段，左边是一个常量，左边字段和给定的字段相同，我们返回左边的常量
 		return t.lhs.AsConstant()
 	} else {
@@ -62,6 +296,56 @@ func (t *Term) EquatesWithConstant(fieldName string) *comm.Constant {
 	}
 }
 
+//Rename 返回一个把所有字段按照rename换成新名字的Term
+func (t *Term) Rename(rename func(string) (string, error)) (*Term, error) {
+	result := *t
+	if t.kind == TERM_EXISTS {
+		rhs, err := t.rhs.Rename(rename)
+		if err != nil {
+			return nil, err
+		}
+		result.rhs = rhs
+		return &result, nil
+	}
+	if t.kind == TERM_COMPARE {
+		lhs, err := t.lhs.Rename(rename)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := t.rhs.Rename(rename)
+		if err != nil {
+			return nil, err
+		}
+		result.lhs, result.rhs = lhs, rhs
+		return &result, nil
+	}
+	result.children = make([]*Predicate, len(t.children))
+	for i, child := range t.children {
+		renamed, err := child.Rename(rename)
+		if err != nil {
+			return nil, err
+		}
+		result.children[i] = renamed
+	}
+	return &result, nil
+}
+
+//Subqueries 条件树中所有的子查询，不包括子查询里面嵌套的子查询
+func (t *Term) Subqueries() []*Subquery {
+	switch t.kind {
+	case TERM_COMPARE:
+		return append(t.lhs.Subqueries(), t.rhs.Subqueries()...)
+	case TERM_EXISTS:
+		return t.rhs.Subqueries()
+	}
+	subs := make([]*Subquery, 0)
+	for _, child := range t.children {
+		subs = append(subs, child.Subqueries()...)
+	}
+	return subs
+}
+
+//Lhs 比较的左边，EXISTS没有左边
 func (t *Term) Lhs() *Expression {
 	return t.lhs
 }
@@ -37,3 +37,75 @@ func TestTerm(t *testing.T) {
 	assert.True(t, term2.AppliesTo(sch))
 
 }
+
+func TestTermOperators(t *testing.T) {
+	one, two := 1, 2
+	c1 := NewExpressionWithConstant(comm.NewConstantInt(&one))
+	c2 := NewExpressionWithConstant(comm.NewConstantInt(&two))
+	name := "tom"
+	str := NewExpressionWithConstant(comm.NewConstantString(&name))
+	cases := []struct {
+		op    string
+		lhs   *Expression
+		rhs   *Expression
+		match bool
+	}{
+		{OP_EQ, c1, c1, true},
+		{OP_NE, c1, c2, true},
+		{OP_NE, c1, c1, false},
+		{OP_LT, c1, c2, true},
+		{OP_LE, c2, c2, true},
+		{OP_GT, c1, c2, false},
+		{OP_GE, c2, c1, true},
+		{OP_NE, c1, str, false}, //整数和字符串之间的比较都不成立
+	}
+	for _, c := range cases {
+		term := NewTermWithOp(c.lhs, c.op, c.rhs)
+		assert.Equal(t, c.match, term.IsSatisfied(nil), term.ToString())
+	}
+
+	//只有相等比较才能用来查找相等的字段和常量
+	age := NewExpressionWithFieldName("age")
+	gt := NewTermWithOp(age, OP_GT, c1)
+	assert.False(t, gt.IsEquality())
+	assert.Nil(t, gt.EquatesWithConstant("age"))
+	assert.Equal(t, "age>1", gt.ToString())
+	assert.NotNil(t, NewTerm(age, c1).EquatesWithConstant("age"))
+
+	or := NewOrTerm(NewPredicateWithTerm(NewTermWithOp(c1, OP_GT, c2)), NewPredicateWithTerm(NewTerm(c2, c2)))
+	assert.True(t, or.IsSatisfied(nil))
+	assert.Equal(t, "(1>2 OR 2=2)", or.ToString())
+	not := NewNotTerm(NewPredicateWithTerm(or))
+	assert.False(t, not.IsSatisfied(nil))
+	assert.Equal(t, "NOT ((1>2 OR 2=2))", not.ToString())
+	assert.Equal(t, 2, len(not.Comparisons()))
+}
+
+func TestTermNull(t *testing.T) {
+	one := 1
+	c1 := NewExpressionWithConstant(comm.NewConstantInt(&one))
+	null := NewExpressionWithConstant(comm.NewNullConstant())
+	isTrue := NewPredicateWithTerm(NewTerm(c1, c1))
+	isFalse := NewPredicateWithTerm(NewTermWithOp(c1, OP_NE, c1))
+	unknown := NewPredicateWithTerm(NewTerm(c1, null))
+
+	//和NULL比较的结果是UNKNOWN，NOT UNKNOWN还是UNKNOWN
+	assert.Equal(t, TRUTH_UNKNOWN, unknown.Evaluate(nil))
+	assert.Equal(t, TRUTH_UNKNOWN, NewTermWithOp(null, OP_NE, null).Evaluate(nil))
+	assert.Equal(t, TRUTH_UNKNOWN, NewNotTerm(unknown).Evaluate(nil))
+	assert.False(t, NewNotTerm(unknown).IsSatisfied(nil))
+	//OR中有TRUE就是TRUE，AND中有FALSE就是FALSE
+	assert.Equal(t, TRUTH_TRUE, NewOrTerm(unknown, isTrue).Evaluate(nil))
+	assert.Equal(t, TRUTH_UNKNOWN, NewOrTerm(unknown, isFalse).Evaluate(nil))
+	and := NewPredicateWithMultiTerms([]*Term{NewTerm(c1, null), NewTermWithOp(c1, OP_NE, c1)})
+	assert.Equal(t, TRUTH_FALSE, and.Evaluate(nil))
+	and = NewPredicateWithMultiTerms([]*Term{NewTerm(c1, null), NewTerm(c1, c1)})
+	assert.Equal(t, TRUTH_UNKNOWN, and.Evaluate(nil))
+
+	//IS NULL的结果只有TRUE和FALSE
+	assert.Equal(t, TRUTH_TRUE, NewIsNullTerm(null, false).Evaluate(nil))
+	assert.Equal(t, TRUTH_FALSE, NewIsNullTerm(c1, false).Evaluate(nil))
+	assert.Equal(t, TRUTH_TRUE, NewIsNullTerm(c1, true).Evaluate(nil))
+	assert.Equal(t, "age IS NOT NULL", NewIsNullTerm(NewExpressionWithFieldName("age"), true).ToString())
+	assert.Equal(t, "age=NULL", NewTerm(NewExpressionWithFieldName("age"), null).ToString())
+}
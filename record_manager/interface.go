@@ -1,6 +1,9 @@
 package record_manager
 
-import fm "miniSQL/file_manager"
+import (
+	"miniSQL/comm"
+	fm "miniSQL/file_manager"
+)
 
 //SchemaInterface 某个表的描述
 type SchemaInterface interface {
@@ -24,19 +27,30 @@ type SchemaInterface interface {
 //用来描述某个具体的字段
 type LayoutInterface interface {
 	Schema() SchemaInterface
-	Offset(fieldName string) int //返回这个字段在这个表中的偏移
-	SlotSize() int               //返回某个记录占用了多少个字节
+	Offset(fieldName string) int  //返回这个字段在这个表中的偏移
+	SlotSize() int                //返回某个记录占用了多少个字节
+	NullBit(fieldName string) int //字段的NULL标志在记录头中的位置
 }
 
 //RecordManagerInterface 记录管理器
 type RecordManagerInterface interface {
-	Block() *fm.BlockId                               //当前记录处在的哪个文件块中
-	GetInt(slot int, fieldName string) int            //返回该字段的值,给定记录所在的编号和记录的field
-	SetInt(slot int, fieldName string, val int)       //给某个字段设置数据
-	GetString(slot int, fieldName string) string      //返回该字段的值,给定记录所在的编号和记录的field
-	SetString(slot int, fieldName string, val string) //给某个字段设置string类型数据
-	Format()                                          //将所有页面内的记录设置为默认值
-	Delete(slot int)                                  //删除给定编号的记录,只需要把这个占位符设置为无效即可,设置成0
+	Block() *fm.BlockId                                      //当前记录处在的哪个文件块中
+	GetInt(slot int, fieldName string) int                   //返回该字段的值,给定记录所在的编号和记录的field
+	SetInt(slot int, fieldName string, val int)              //给某个字段设置数据
+	GetString(slot int, fieldName string) string             //返回该字段的值,给定记录所在的编号和记录的field
+	SetString(slot int, fieldName string, val string)        //给某个字段设置string类型数据
+	GetBool(slot int, fieldName string) bool                 //返回布尔类型字段的值
+	SetBool(slot int, fieldName string, val bool)            //给某个字段设置布尔类型数据
+	GetFloat(slot int, fieldName string) float64             //返回浮点数字段的值
+	SetFloat(slot int, fieldName string, val float64)        //给某个字段设置浮点数
+	GetDecimal(slot int, fieldName string) comm.Decimal      //返回定点数字段的值
+	SetDecimal(slot int, fieldName string, val comm.Decimal) //给某个字段设置定点数
+	Format()                                                 //将所有页面内的记录设置为默认值
+	Delete(slot int)                                         //删除给定编号的记录,只需要把这个占位符设置为无效即可,设置成0
+	Clear()                                                  //把所有slot都设置成无效，和Format不同的是会写日志，可以回滚
+	IsNull(slot int, fieldName string) bool                  //字段的值是不是NULL
+	SetNull(slot int, fieldName string)                      //把字段设置成NULL
+	DropNullBit(slot int, fieldName string)                  //删除字段之前去掉它在记录头中的NULL标志，后面字段的标志往前移动一位
 	//某一条记录都有一个占位符来表示这个记录是否有效
 	NextAfter(slot int) int   //给出从给定编号之后，flag标志位被设置成1(有效的)的记录的编号
 	InsertAfter(slot int) int //查找给定编号在之后，flag标志设置成0（无效）记录的编号,可以使用该位置进行设置记录
@@ -7,6 +7,8 @@ import (
 
 const (
 	BYTES_OF_INT = 8 //一个INT占用的字节大小
+	//MAX_FIELDS 记录头8个字节的最低位表示slot是否被使用，剩下的63位是每个字段的NULL标志，所以一张表最多63个字段
+	MAX_FIELDS = 63
 )
 
 // Layout 和Schema就是数据库表的元数据
@@ -64,11 +66,59 @@ func (l *Layout) SlotSize() int {
 	return l.slotSize
 }
 
+//NullBit 字段的NULL标志在记录头中的位置，按照字段的偏移从小到大排列，第一个字段使用第1位
+//ADD COLUMN新增的字段在记录的末尾，所以已有字段的位置不变，DROP COLUMN之后后面字段的位置会减一
+func (l *Layout) NullBit(fieldName string) int {
+	offset := l.Offset(fieldName)
+	bit := 1
+	for _, field := range l.schema.Fields() {
+		if l.offsets[field] < offset {
+			bit++
+		}
+	}
+	return bit
+}
+
+//AddField 在记录的末尾增加一个字段，已有字段的偏移不变，sch中包含这个字段的类型和长度
+func (l *Layout) AddField(fieldName string, sch SchemaInterface) *Layout {
+	schema := NewSchema()
+	schema.AddAll(l.schema)
+	schema.Add(fieldName, sch)
+	layout := &Layout{
+		schema:   schema,
+		offsets:  make(map[string]int),
+		slotSize: l.slotSize,
+	}
+	for field, offset := range l.offsets {
+		layout.offsets[field] = offset
+	}
+	layout.offsets[fieldName] = l.slotSize
+	layout.slotSize += layout.lengthInBytes(fieldName)
+	return layout
+}
+
+//DropField 去掉一个字段，记录的大小和其他字段的偏移都不变，这个字段占用的字节不再使用
+func (l *Layout) DropField(fieldName string) *Layout {
+	schema := NewSchema()
+	offsets := make(map[string]int)
+	for _, field := range l.schema.Fields() {
+		if field != fieldName {
+			schema.Add(field, l.schema)
+			offsets[field] = l.offsets[field]
+		}
+	}
+	return NewLayout(schema, offsets, l.slotSize)
+}
+
 //lengthInBytes 某个field占用的字节大小
 func (l *Layout) lengthInBytes(fieldName string) int {
 	fieldType := l.schema.Type(fieldName) //从表中获得该field的类型
 	p := fm.NewPageBySize(1)
-	if fieldType == INTEGER {
+	if fieldType == DECIMAL {
+		//定点数保存去掉小数点之后的整数和小数位数，这样临时表中计算出来的值不会因为小数位数不同而丢失精度
+		return 2 * BYTES_OF_INT
+	} else if !isString(fieldType) {
+		//布尔值和浮点数也按照8个字节的整数写入，和整数一样写日志
 		return BYTES_OF_INT
 	} else {
 		fieldLen := l.schema.Length(fieldName) //获得某个field的长度
@@ -80,3 +130,8 @@ func (l *Layout) lengthInBytes(fieldName string) int {
 		return int(p.MaxLengthForString(dummyStr)) //返回他在page中管理的实际长度8+length
 	}
 }
+
+//isString 字符串类型的字段前面8个字节是长度，其他类型的字段都是固定的长度
+func isString(fieldType FIELD_TYPE) bool {
+	return fieldType == VARCHAR || fieldType == BLOB
+}
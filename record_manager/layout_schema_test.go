@@ -40,3 +40,25 @@ func TestLayout_Offset(t *testing.T) {
 	assert.Equal(t, 33, offsetHeight)        //height
 	assert.Equal(t, 41, layout.SlotSize())
 }
+
+//TestLayout_AddDropField 新增的字段放在记录末尾，删除字段之后其他字段的偏移和记录大小都不变
+func TestLayout_AddDropField(t *testing.T) {
+	sch := NewSchema()
+	sch.AddIntField("age")
+	sch.AddStringField("name", 9)
+	layout := NewLayoutWithSchema(sch)
+	add := NewSchema()
+	add.AddStringField("email", 4)
+	added := layout.AddField("email", add)
+	assert.Equal(t, []string{"age", "name", "email"}, added.Schema().Fields())
+	assert.Equal(t, 33, added.Offset("email"))
+	assert.Equal(t, 45, added.SlotSize())
+	assert.Equal(t, 16, added.Offset("name"))
+	assert.Equal(t, 33, layout.SlotSize())
+
+	dropped := added.DropField("name")
+	assert.Equal(t, []string{"age", "email"}, dropped.Schema().Fields())
+	assert.Equal(t, -1, dropped.Offset("name"))
+	assert.Equal(t, 33, dropped.Offset("email"))
+	assert.Equal(t, 45, dropped.SlotSize())
+}
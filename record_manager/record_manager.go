@@ -1,6 +1,8 @@
 package record_manager
 
 import (
+	"math"
+	"miniSQL/comm"
 	fm "miniSQL/file_manager"
 	tx "miniSQL/transaction"
 )
@@ -20,19 +22,26 @@ const (
 	USED                   //描述当前slot已经被使用了
 )
 
+//记录头8个字节的最低位是SLOT_FLAG，其余的位是字段的NULL标志，位置由Layout.NullBit决定
+//插入一条记录的时候所有字段都是NULL，写入了值的字段清除NULL标志，这样INSERT中没有给出的字段就是NULL
+//插入记录时写入记录头的日志在回滚的时候会把整个记录头恢复成EMPTY，所以之后修改这条记录的NULL标志不需要再写日志，一条记录只有一次记录头的日志
+const slotFlagMask = 1
+
 //RecordPage 使用recordManager来管理记录在页面中的存储,对一条一条记录进行读取
 type RecordPage struct {
-	tx     *tx.Transaction //使用一个事务，保证数据的原子性和可恢复性
-	blk    *fm.BlockId     //管理的是哪个页面
-	layout LayoutInterface //当前管理的某个表，每个字段的管理
+	tx       *tx.Transaction //使用一个事务，保证数据的原子性和可恢复性
+	blk      *fm.BlockId     //管理的是哪个页面
+	layout   LayoutInterface //当前管理的某个表，每个字段的管理
+	inserted int             //最近一次InsertAfter得到的slot，它的NULL标志修改的时候不写日志，没有的时候是-1
 }
 
 //NewRecordPage 构造一个RecordPage对象来管理日志块
 func NewRecordPage(tx *tx.Transaction, blk *fm.BlockId, layout LayoutInterface) *RecordPage {
 	rp := &RecordPage{
-		tx:     tx,
-		blk:    blk,
-		layout: layout,
+		tx:       tx,
+		blk:      blk,
+		layout:   layout,
+		inserted: -1,
 	}
 	//把当前的blk给占用
 	tx.Pin(blk)
@@ -64,6 +73,7 @@ func (r *RecordPage) GetInt(slot int, fieldName string) int {
 func (r *RecordPage) SetInt(slot int, fieldName string, val int) {
 	fieldPos := r.offset(slot) + uint64(r.layout.Offset(fieldName)) //获得某个recored中的某个field的实际的存储的偏移位置
 	r.tx.SetInt(r.blk, fieldPos, int64(val), true)                  //写入事务中，并且保证生成日志
+	r.setNullFlag(slot, fieldName, false)
 }
 
 //GetString 返回该字段的值,给定记录所在的编号和记录的field
@@ -77,6 +87,102 @@ func (r *RecordPage) GetString(slot int, fieldName string) string {
 func (r *RecordPage) SetString(slot int, fieldName string, val string) {
 	fieldPos := r.offset(slot) + uint64(r.layout.Offset(fieldName)) //获得某个recored中的某个field的实际的存储的偏移位置
 	r.tx.SetString(r.blk, fieldPos, val, true)                      //生成日志信息
+	r.setNullFlag(slot, fieldName, false)
+}
+
+//GetBool 布尔值按照整数0和1保存
+func (r *RecordPage) GetBool(slot int, fieldName string) bool {
+	return r.GetInt(slot, fieldName) != 0
+}
+
+//SetBool 给某个字段设置布尔类型数据
+func (r *RecordPage) SetBool(slot int, fieldName string, val bool) {
+	v := 0
+	if val {
+		v = 1
+	}
+	r.SetInt(slot, fieldName, v)
+}
+
+//GetFloat 浮点数按照IEEE 754的二进制形式作为整数保存
+func (r *RecordPage) GetFloat(slot int, fieldName string) float64 {
+	return math.Float64frombits(uint64(r.GetInt(slot, fieldName)))
+}
+
+//SetFloat 给某个字段设置浮点数
+func (r *RecordPage) SetFloat(slot int, fieldName string, val float64) {
+	r.SetInt(slot, fieldName, int(math.Float64bits(val)))
+}
+
+//GetDecimal 定点数的前8个字节是去掉小数点之后的整数，后8个字节是小数位数
+func (r *RecordPage) GetDecimal(slot int, fieldName string) comm.Decimal {
+	fieldPos := r.offset(slot) + uint64(r.layout.Offset(fieldName))
+	unscaled, _ := r.tx.GetInt(r.blk, fieldPos)
+	scale, _ := r.tx.GetInt(r.blk, fieldPos+BYTES_OF_INT)
+	return comm.NewDecimal(unscaled, int(scale))
+}
+
+//SetDecimal 给某个字段设置定点数
+func (r *RecordPage) SetDecimal(slot int, fieldName string, val comm.Decimal) {
+	fieldPos := r.offset(slot) + uint64(r.layout.Offset(fieldName))
+	r.tx.SetInt(r.blk, fieldPos, val.Unscaled, true)
+	r.tx.SetInt(r.blk, fieldPos+BYTES_OF_INT, int64(val.Scale), true)
+	r.setNullFlag(slot, fieldName, false)
+}
+
+//IsNull 字段的值是不是NULL
+func (r *RecordPage) IsNull(slot int, fieldName string) bool {
+	header, _ := r.tx.GetInt(r.blk, r.offset(slot))
+	return header&r.nullMask(fieldName) != 0
+}
+
+//SetNull 把字段设置成NULL，字段原来的字节写成类型的零值，这样直接读取的时候结果是确定的
+func (r *RecordPage) SetNull(slot int, fieldName string) {
+	r.setZero(slot, fieldName, true)
+	r.setNullFlag(slot, fieldName, true)
+}
+
+//DropNullBit 删除字段之前去掉它在记录头中的NULL标志，偏移在它后面的字段的NULL标志都往低位移动一位
+func (r *RecordPage) DropNullBit(slot int, fieldName string) {
+	header, _ := r.tx.GetInt(r.blk, r.offset(slot))
+	bit := uint(r.layout.NullBit(fieldName))
+	low := header & (int64(1)<<bit - 1)
+	high := int64(uint64(header) >> (bit + 1) << bit)
+	if low|high != header {
+		r.tx.SetInt(r.blk, r.offset(slot), low|high, true)
+	}
+}
+
+//setZero 把字段写成类型的零值，整数是0，字符串是空字符串
+func (r *RecordPage) setZero(slot int, fieldName string, okToLog bool) {
+	fieldPos := r.offset(slot) + uint64(r.layout.Offset(fieldName))
+	fieldType := r.layout.Schema().Type(fieldName)
+	if isString(fieldType) {
+		r.tx.SetString(r.blk, fieldPos, "", okToLog)
+		return
+	}
+	r.tx.SetInt(r.blk, fieldPos, 0, okToLog)
+	if fieldType == DECIMAL {
+		r.tx.SetInt(r.blk, fieldPos+BYTES_OF_INT, 0, okToLog)
+	}
+}
+
+//nullMask 字段的NULL标志在记录头中对应的位
+func (r *RecordPage) nullMask(fieldName string) int64 {
+	return int64(1) << uint(r.layout.NullBit(fieldName))
+}
+
+//setNullFlag 修改字段的NULL标志，标志没有变化的时候，或者是刚刚插入的记录的时候不写日志
+func (r *RecordPage) setNullFlag(slot int, fieldName string, isNull bool) {
+	header, _ := r.tx.GetInt(r.blk, r.offset(slot))
+	mask := r.nullMask(fieldName)
+	newHeader := header &^ mask
+	if isNull {
+		newHeader |= mask
+	}
+	if newHeader != header {
+		r.tx.SetInt(r.blk, r.offset(slot), newHeader, slot != r.inserted)
+	}
 }
 
 //Format 将所有页面内的记录设置为默认值,将记录设置成默认的值，int类型就设置成0,string类型就设置成“”
@@ -88,20 +194,35 @@ func (r *RecordPage) Format() {
 		sch := r.layout.Schema()                                //获得当前schema，并从中获得他的每个fieldname
 		for _, fieldName := range sch.Fields() {
 			//遍历每个字段
-			fieldPos := r.offset(slot) + uint64(r.layout.Offset(fieldName))
-			if sch.Type(fieldName) == INTEGER {
-				r.tx.SetInt(r.blk, fieldPos, 0, false)
-			} else {
-				r.tx.SetString(r.blk, fieldPos, "", false)
-			}
+			r.setZero(slot, fieldName, false)
 		}
 		slot += 1 //处理完一个record就移动到下一个slot
 	}
 
 }
 
+//Clear 把当前区块里所有的slot都设置成无效，所有的修改都写日志
+//表结构修改之后用新的layout来清空区块，旧的记录所在的字节在新的layout下面不再有意义
+func (r *RecordPage) Clear() {
+	sch := r.layout.Schema()
+	r.inserted = -1
+	for slot := 0; r.isValidSlot(slot); slot++ {
+		r.setFlag(slot, EMPTY)
+		//字符串前面8个字节是它的长度，写日志的时候要读取旧的字符串，所以先把长度设置成0
+		for _, fieldName := range sch.Fields() {
+			if isString(sch.Type(fieldName)) {
+				fieldPos := r.offset(slot) + uint64(r.layout.Offset(fieldName))
+				r.tx.SetInt(r.blk, fieldPos, 0, true)
+			}
+		}
+	}
+}
+
 //Delete 删除给定编号的记录,只需要把这个占位符设置为无效即可,设置成0
 func (r *RecordPage) Delete(slot int) {
+	if slot == r.inserted {
+		r.inserted = -1
+	}
 	r.setFlag(slot, EMPTY) //将该槽位设置成无效的
 }
 
@@ -116,8 +237,11 @@ func (r *RecordPage) NextAfter(slot int) int {
 func (r *RecordPage) InsertAfter(slot int) int {
 	newSlot := r.searchAfter(slot, EMPTY)
 	if newSlot >= 0 {
-		//如果找到，就设置，没找到就不设置
-		r.setFlag(newSlot, USED)
+		//如果找到，就设置，没找到就不设置，新的记录中所有的字段都是NULL
+		fields := uint(len(r.layout.Schema().Fields()))
+		allNull := uint64(1)<<(fields+1) - 2
+		r.tx.SetInt(r.blk, r.offset(newSlot), int64(allNull)|int64(USED), true)
+		r.inserted = newSlot
 	}
 	return newSlot
 }
@@ -139,7 +263,7 @@ func (r *RecordPage) searchAfter(slot int, flag SLOT_FLAG) int {
 	for r.isValidSlot(slot) {
 		//一个一个slot往后面遍历
 		val, _ := r.tx.GetInt(r.blk, r.offset(slot)) //得到某个slot的占位符，判断有效还是无效
-		if SLOT_FLAG(val) == flag {
+		if SLOT_FLAG(val&slotFlagMask) == flag {
 			return slot
 		}
 		slot += 1
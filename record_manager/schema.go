@@ -1,14 +1,59 @@
 package record_manager
 
+import "strconv"
+
 type FIELD_TYPE int
 
 const (
 	INTEGER FIELD_TYPE = iota //整形类型
 	VARCHAR                   //字读串的可变长度,最大不能超过65535
 	BLOB                      //二进制类型
+	BOOLEAN                   //布尔类型
+	BIGINT                    //64位整数
+	DOUBLE                    //双精度浮点数
+	DECIMAL                   //定点数，长度中保存了精度和小数位数
 
 )
 
+//DecimalLength DECIMAL(p,s)的精度和小数位数合并成一个长度保存在fldcat中，精度在高位，小数位数在低8位
+func DecimalLength(precision int, scale int) int {
+	return precision<<8 | scale
+}
+
+//DecimalPrecision 从DECIMAL字段的长度中取出精度
+func DecimalPrecision(length int) int {
+	return length >> 8
+}
+
+//DecimalScale 从DECIMAL字段的长度中取出小数位数
+func DecimalScale(length int) int {
+	return length & 0xff
+}
+
+//IsNumeric 整数，浮点数和定点数都是数值类型，数值类型之间可以互相运算和比较
+func IsNumeric(fieldType FIELD_TYPE) bool {
+	return fieldType == INTEGER || fieldType == BIGINT || fieldType == DOUBLE || fieldType == DECIMAL
+}
+
+//TypeName 字段类型在SQL中的写法，例如VARCHAR(20)，DECIMAL(10,2)
+func TypeName(fieldType FIELD_TYPE, length int) string {
+	switch fieldType {
+	case INTEGER:
+		return "INT"
+	case VARCHAR:
+		return "VARCHAR(" + strconv.Itoa(length) + ")"
+	case BOOLEAN:
+		return "BOOLEAN"
+	case BIGINT:
+		return "BIGINT"
+	case DOUBLE:
+		return "DOUBLE"
+	case DECIMAL:
+		return "DECIMAL(" + strconv.Itoa(DecimalPrecision(length)) + "," + strconv.Itoa(DecimalScale(length)) + ")"
+	}
+	return "BLOB"
+}
+
 //FieldInfo 某个字段的类型
 type FieldInfo struct {
 	fieldType FIELD_TYPE //该字段的类型
@@ -54,6 +99,11 @@ func (s *Schema) AddStringField(fileName string, length int) {
 	s.AddField(fileName, VARCHAR, length)
 }
 
+//AddDecimalField 添加一个DECIMAL(precision,scale)类型的字段
+func (s *Schema) AddDecimalField(fieldName string, precision int, scale int) {
+	s.AddField(fieldName, DECIMAL, DecimalLength(precision, scale))
+}
+
 //Add 整形类型或字符串类型都能添加
 func (s *Schema) Add(fieldName string, sch SchemaInterface) {
 	filedType := sch.Type(fieldName)         //获得fieldName在当前的表中的类型
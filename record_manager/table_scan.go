@@ -15,12 +15,17 @@ type TableScan struct {
 	currentSlot int                    //当前表处在的槽位
 }
 
+//TableFileName 存储一张表的文件名
+func TableFileName(tableName string) string {
+	return tableName + ".tbl"
+}
+
 //NewTableScan 构造该表的记录扫描器
 func NewTableScan(tx *tx.Transaction, tableName string, layout LayoutInterface) (*TableScan, error) {
 	tableScan := &TableScan{
 		tx:       tx,
 		layout:   layout,
-		fileName: tableName + ".tbl", //一个表都存储在".tbl"文件中
+		fileName: TableFileName(tableName), //一个表都存储在".tbl"文件中
 	}
 	size, err := tx.Size(tableScan.fileName) //获得当前文件占用了多少个区块,在这个函数里面，如果某个表不存在的话，就会传建出来
 	if err != nil {
@@ -143,12 +148,54 @@ func (t *TableScan) Delete() {
 	t.rp.Delete(t.currentSlot)
 }
 
-//GetVal 获得当前slot的数据（不管是int还是string都能正确得到）
+//Clear 删除表中所有的记录，文件里的区块按照当前的layout重新清空，之后从第一个区块开始插入
+func (t *TableScan) Clear() error {
+	size, err := t.tx.Size(t.fileName)
+	if err != nil {
+		return err
+	}
+	for blkNum := 0; blkNum < int(size); blkNum++ {
+		t.Move2Block(blkNum)
+		t.rp.Clear()
+	}
+	t.BeforeFirst()
+	return nil
+}
+
+//IsNull 当前slot的字段是不是NULL
+func (t *TableScan) IsNull(fieldName string) bool {
+	return t.rp.IsNull(t.currentSlot, fieldName)
+}
+
+//SetNull 把当前slot的字段设置成NULL
+func (t *TableScan) SetNull(fieldName string) {
+	t.rp.SetNull(t.currentSlot, fieldName)
+}
+
+//DropNullBit 删除字段之前对每条记录调用，去掉这个字段在记录头中的NULL标志
+func (t *TableScan) DropNullBit(fieldName string) {
+	t.rp.DropNullBit(t.currentSlot, fieldName)
+}
+
+//GetVal 获得当前slot的数据（不管是什么类型都能正确得到），字段是NULL的时候返回NULL
 func (t *TableScan) GetVal(fieldName string) *comm.Constant {
-	if t.layout.Schema().Type(fieldName) == INTEGER {
+	if t.IsNull(fieldName) {
+		return comm.NewNullConstant()
+	}
+	switch t.layout.Schema().Type(fieldName) {
+	case INTEGER, BIGINT:
 		//当前这个字段是int类型
 		val := t.GetInt(fieldName)
 		return comm.NewConstantInt(&val) //将当前
+	case BOOLEAN:
+		val := t.rp.GetBool(t.currentSlot, fieldName)
+		return comm.NewConstantBool(&val)
+	case DOUBLE:
+		val := t.rp.GetFloat(t.currentSlot, fieldName)
+		return comm.NewConstantFloat(&val)
+	case DECIMAL:
+		val := t.rp.GetDecimal(t.currentSlot, fieldName)
+		return comm.NewConstantDecimal(&val)
 	}
 	//否则就是一个string类型的变量
 	val := t.GetString(fieldName)
@@ -160,11 +207,23 @@ func (t *TableScan) HasField(fieldName string) bool {
 	return t.layout.Schema().HashField(fieldName)
 }
 
-//SetVal 往当前slot中添加数据，不管是int还是string都能正确添加
+//SetVal 往当前slot中添加数据，不管是什么类型都能正确添加，val是NULL的时候设置NULL标志
+//整数可以写入浮点数和定点数字段，定点数可以写入浮点数字段，定点数的小数位数由调用者调整成字段的小数位数
 func (t *TableScan) SetVal(fieldName string, val *comm.Constant) {
-	if t.layout.Schema().Type(fieldName) == INTEGER {
+	if val.IsNull() {
+		t.SetNull(fieldName)
+		return
+	}
+	switch t.layout.Schema().Type(fieldName) {
+	case INTEGER, BIGINT:
 		t.SetInt(fieldName, *val.Ival) //插入当前对象的int类型数据
-	} else {
+	case BOOLEAN:
+		t.rp.SetBool(t.currentSlot, fieldName, *val.Bval)
+	case DOUBLE:
+		t.rp.SetFloat(t.currentSlot, fieldName, val.AsFloat())
+	case DECIMAL:
+		t.rp.SetDecimal(t.currentSlot, fieldName, val.AsDecimal())
+	default:
 		t.SetString(fieldName, *val.Sval) //插入当前对象的string类型的数据
 	}
 }
@@ -5,9 +5,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"math/rand"
 	bm "miniSQL/buffer_manager"
+	"miniSQL/comm"
 	fm "miniSQL/file_manager"
 	lm "miniSQL/log_manager"
 	tx "miniSQL/transaction"
+	"path/filepath"
 	"testing"
 )
 
@@ -80,3 +82,147 @@ func TestTableScanInsertAndDelete(t *testing.T) {
 	ts.Close()
 	tx.Commit()
 }
+
+func TestTableScanNull(t *testing.T) {
+	fmgr, err := fm.NewFileManager(filepath.Join(t.TempDir(), "tablescan_null_test"), 400)
+	assert.Nil(t, err)
+	lmgr, _ := lm.NewLogManager(fmgr, "logfile")
+	bmgr := bm.NewBufferManager(fmgr, lmgr, 3)
+	tx1 := tx.NewTransaction(fmgr, lmgr, bmgr)
+	sch := NewSchema()
+	sch.AddIntField("A")
+	sch.AddStringField("B", 9)
+	sch.AddIntField("C")
+	layout := NewLayoutWithSchema(sch)
+	assert.Equal(t, 1, layout.NullBit("A"))
+	assert.Equal(t, 3, layout.NullBit("C"))
+
+	ts, err := NewTableScan(tx1, "T", layout)
+	assert.Nil(t, err)
+	for i := 0; i < 20; i++ {
+		ts.Insert()
+		ts.SetInt("A", i)
+		if i%2 == 0 {
+			ts.SetNull("B")
+		} else {
+			ts.SetString("B", fmt.Sprintf("rec%d", i))
+		}
+		ts.SetVal("C", comm.NewNullConstant())
+	}
+	//NULL标志不影响slot是否被使用，设置了值之后NULL标志被清除
+	ts.BeforeFirst()
+	count := 0
+	for ts.Next() {
+		a := ts.GetInt("A")
+		assert.Equal(t, a%2 == 0, ts.GetVal("B").IsNull(), a)
+		assert.True(t, ts.IsNull("C"))
+		assert.False(t, ts.IsNull("A"))
+		if a == 3 {
+			ts.SetInt("C", 7)
+		}
+		count++
+	}
+	assert.Equal(t, 20, count)
+	tx1.Commit()
+
+	//去掉B的NULL标志之后，按照新的layout读取C的标志不变，回滚之后恢复原样
+	tx2 := tx.NewTransaction(fmgr, lmgr, bmgr)
+	ts, _ = NewTableScan(tx2, "T", layout)
+	for ts.Next() {
+		ts.DropNullBit("B")
+	}
+	ts.Close()
+	dropped := layout.DropField("B")
+	ts, _ = NewTableScan(tx2, "T", dropped)
+	for ts.Next() {
+		assert.Equal(t, ts.GetInt("A") != 3, ts.IsNull("C"))
+	}
+	ts.Close()
+	assert.Nil(t, tx2.RollBack())
+
+	tx3 := tx.NewTransaction(fmgr, lmgr, bmgr)
+	ts, _ = NewTableScan(tx3, "T", layout)
+	for ts.Next() {
+		a := ts.GetInt("A")
+		assert.Equal(t, a%2 == 0, ts.IsNull("B"), a)
+		assert.Equal(t, a != 3, ts.IsNull("C"), a)
+		if a == 4 {
+			ts.Delete()
+		}
+	}
+	//插入的记录中没有写入值的字段都是NULL，不会读到删除的记录留下来的值
+	//插入一条记录只写一次记录头的日志，写入字段的时候清除NULL标志不再写日志
+	ts.BeforeFirst()
+	lsn := tx3.Savepoint()
+	ts.Insert()
+	ts.SetInt("A", 100)
+	assert.Equal(t, lsn+2, tx3.Savepoint())
+	assert.True(t, ts.GetRid().Equals(NewRID(0, 4)))
+	assert.False(t, ts.IsNull("A"))
+	assert.True(t, ts.GetVal("B").IsNull())
+	assert.True(t, ts.IsNull("C"))
+	ts.Close()
+	tx3.Commit()
+}
+
+func TestTableScanTypes(t *testing.T) {
+	fmgr, err := fm.NewFileManager(filepath.Join(t.TempDir(), "tablescan_types_test"), 400)
+	assert.Nil(t, err)
+	lmgr, _ := lm.NewLogManager(fmgr, "logfile")
+	bmgr := bm.NewBufferManager(fmgr, lmgr, 3)
+	tx1 := tx.NewTransaction(fmgr, lmgr, bmgr)
+	sch := NewSchema()
+	sch.AddField("FLAG", BOOLEAN, 0)
+	sch.AddField("BIG", BIGINT, 0)
+	sch.AddField("RATE", DOUBLE, 0)
+	sch.AddDecimalField("PRICE", 10, 2)
+	layout := NewLayoutWithSchema(sch)
+	//定点数保存整数和小数位数两个部分
+	assert.Equal(t, 8+8*3+16, layout.SlotSize())
+	assert.Equal(t, "DECIMAL(10,2)", TypeName(sch.Type("PRICE"), sch.Length("PRICE")))
+	assert.Equal(t, 10, DecimalPrecision(sch.Length("PRICE")))
+	assert.Equal(t, 2, DecimalScale(sch.Length("PRICE")))
+
+	ts, err := NewTableScan(tx1, "T", layout)
+	assert.Nil(t, err)
+	for i := 0; i < 30; i++ {
+		flag, big, rate := i%2 == 0, 1<<40+i, float64(i)/4
+		price := comm.NewDecimal(int64(-i*101), 2)
+		ts.Insert()
+		ts.SetVal("FLAG", comm.NewConstantBool(&flag))
+		ts.SetVal("BIG", comm.NewConstantInt(&big))
+		ts.SetVal("RATE", comm.NewConstantFloat(&rate))
+		ts.SetVal("PRICE", comm.NewConstantDecimal(&price))
+	}
+	ts.Close()
+	tx1.Commit()
+
+	tx2 := tx.NewTransaction(fmgr, lmgr, bmgr)
+	ts, _ = NewTableScan(tx2, "T", layout)
+	i := 0
+	for ts.Next() {
+		assert.Equal(t, i%2 == 0, ts.GetVal("FLAG").AsBool())
+		assert.Equal(t, 1<<40+i, ts.GetVal("BIG").AsInt())
+		assert.Equal(t, float64(i)/4, ts.GetVal("RATE").AsFloat())
+		assert.Equal(t, comm.NewDecimal(int64(-i*101), 2), ts.GetVal("PRICE").AsDecimal())
+		//整数写入浮点数和定点数字段的时候自动转换
+		one := 1
+		ts.SetVal("RATE", comm.NewConstantInt(&one))
+		ts.SetVal("PRICE", comm.NewNullConstant())
+		assert.Equal(t, "1", ts.GetVal("RATE").ToString())
+		assert.True(t, ts.GetVal("PRICE").IsNull())
+		i++
+	}
+	assert.Equal(t, 30, i)
+	ts.Close()
+	assert.Nil(t, tx2.RollBack())
+
+	tx3 := tx.NewTransaction(fmgr, lmgr, bmgr)
+	ts, _ = NewTableScan(tx3, "T", layout)
+	assert.True(t, ts.Next())
+	assert.True(t, ts.Next())
+	assert.Equal(t, "0.25", ts.GetVal("RATE").ToString())
+	assert.Equal(t, "-1.01", ts.GetVal("PRICE").ToString())
+	ts.Close()
+	tx3.Commit()
+}
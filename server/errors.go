@@ -0,0 +1,176 @@
+package server
+
+import (
+	"errors"
+	"miniSQL/db"
+	"miniSQL/parser"
+	"miniSQL/planner"
+	"miniSQL/query"
+	"net/http"
+)
+
+var (
+	errFeatureNotSupported = errors.New("feature not supported")
+	errAccessDenied        = errors.New("access denied")
+	errBadRequest          = errors.New("bad request")
+	errTxNotFound          = errors.New("transaction not found")
+)
+
+//pgErrorCode 把错误转化成PostgreSQL的SQLSTATE错误码
+func pgErrorCode(err error) string {
+	switch {
+	case errors.Is(err, parser.ErrSyntax):
+		return "42601" //syntax_error
+	case errors.Is(err, parser.ErrArgCount):
+		return "08P01" //protocol_violation
+	case errors.Is(err, planner.ErrTableNotFound), errors.Is(err, planner.ErrViewNotFound):
+		return "42P01" //undefined_table
+	case errors.Is(err, planner.ErrIndexNotFound):
+		return "42704" //undefined_object
+	case errors.Is(err, planner.ErrDependentObjects):
+		return "2BP01" //dependent_objects_still_exist
+	case errors.Is(err, planner.ErrCatalogTable):
+		return "42501" //insufficient_privilege
+	case errors.Is(err, planner.ErrDuplicateField):
+		return "42701" //duplicate_column
+	case errors.Is(err, planner.ErrTableExists):
+		return "42P07" //duplicate_table
+	case errors.Is(err, planner.ErrIndexExists):
+		return "42P07" //duplicate_table，PostgreSQL中索引也是一种relation
+	case errors.Is(err, planner.ErrRecursiveView):
+		return "42P17" //invalid_object_definition
+	case errors.Is(err, planner.ErrLastField):
+		return "42P16" //invalid_table_definition
+	case errors.Is(err, planner.ErrTooManyFields):
+		return "54011" //too_many_columns
+	case errors.Is(err, planner.ErrFieldNotFound):
+		return "42703" //undefined_column
+	case errors.Is(err, planner.ErrAmbiguousField):
+		return "42702" //ambiguous_column
+	case errors.Is(err, planner.ErrDuplicateAlias):
+		return "42712" //duplicate_alias
+	case errors.Is(err, planner.ErrSubqueryColumns):
+		return "42601" //syntax_error，和PostgreSQL相同
+	case errors.Is(err, planner.ErrSetColumns), errors.Is(err, planner.ErrColumnCount):
+		return "42601" //syntax_error，和PostgreSQL相同
+	case errors.Is(err, query.ErrSubqueryRows):
+		return "21000" //cardinality_violation
+	case errors.Is(err, planner.ErrTypeMismatch):
+		return "42804" //datatype_mismatch
+	case errors.Is(err, planner.ErrValueTooLong):
+		return "22001" //string_data_right_truncation
+	case errors.Is(err, planner.ErrNotQuery), errors.Is(err, planner.ErrNotUpdate):
+		return "42809" //wrong_object_type
+	case errors.Is(err, ErrAborted):
+		return "40001" //serialization_failure，客户端可以重试
+	case errors.Is(err, db.ErrInTransaction):
+		return "25001" //active_sql_transaction
+	case errors.Is(err, db.ErrNoTransaction):
+		return "25P01" //no_active_sql_transaction
+	case errors.Is(err, db.ErrReadOnly):
+		return "25006" //read_only_sql_transaction
+	case errors.Is(err, db.ErrSavepointNotFound):
+		return "3B001" //invalid_savepoint_specification
+	case errors.Is(err, errFeatureNotSupported):
+		return "0A000" //feature_not_supported
+	}
+	return "XX000" //internal_error
+}
+
+//mysqlErrorCode 把错误转化成MySQL的错误号以及SQLSTATE
+func mysqlErrorCode(err error) (uint16, string) {
+	switch {
+	case errors.Is(err, parser.ErrSyntax):
+		return 1064, "42000" //ER_PARSE_ERROR
+	case errors.Is(err, parser.ErrArgCount):
+		return 1210, "HY000" //ER_WRONG_ARGUMENTS
+	case errors.Is(err, planner.ErrTableNotFound):
+		return 1146, "42S02" //ER_NO_SUCH_TABLE
+	case errors.Is(err, planner.ErrViewNotFound):
+		return 1051, "42S02" //ER_BAD_TABLE_ERROR
+	case errors.Is(err, planner.ErrIndexNotFound):
+		return 1091, "42000" //ER_CANT_DROP_FIELD_OR_KEY
+	case errors.Is(err, planner.ErrDependentObjects):
+		return 3730, "HY000" //ER_FK_CANNOT_DROP_PARENT
+	case errors.Is(err, planner.ErrCatalogTable):
+		return 1044, "42000" //ER_DBACCESS_DENIED_ERROR
+	case errors.Is(err, planner.ErrDuplicateField):
+		return 1060, "42S21" //ER_DUP_FIELDNAME
+	case errors.Is(err, planner.ErrTableExists):
+		return 1050, "42S01" //ER_TABLE_EXISTS_ERROR
+	case errors.Is(err, planner.ErrIndexExists):
+		return 1061, "42000" //ER_DUP_KEYNAME
+	case errors.Is(err, planner.ErrRecursiveView):
+		return 1462, "HY000" //ER_VIEW_RECURSIVE
+	case errors.Is(err, planner.ErrLastField):
+		return 1090, "42000" //ER_CANT_REMOVE_ALL_FIELDS
+	case errors.Is(err, planner.ErrTooManyFields):
+		return 1117, "HY000" //ER_TOO_MANY_FIELDS
+	case errors.Is(err, planner.ErrFieldNotFound):
+		return 1054, "42S22" //ER_BAD_FIELD_ERROR
+	case errors.Is(err, planner.ErrAmbiguousField):
+		return 1052, "23000" //ER_NON_UNIQ_ERROR
+	case errors.Is(err, planner.ErrDuplicateAlias):
+		return 1066, "42000" //ER_NONUNIQ_TABLE
+	case errors.Is(err, planner.ErrSubqueryColumns):
+		return 1241, "21000" //ER_OPERAND_COLUMNS
+	case errors.Is(err, planner.ErrSetColumns):
+		return 1222, "21000" //ER_WRONG_NUMBER_OF_COLUMNS_IN_SELECT
+	case errors.Is(err, planner.ErrColumnCount):
+		return 1136, "21S01" //ER_WRONG_VALUE_COUNT_ON_ROW
+	case errors.Is(err, query.ErrSubqueryRows):
+		return 1242, "21000" //ER_SUBQUERY_NO_1_ROW
+	case errors.Is(err, planner.ErrTypeMismatch):
+		return 1366, "HY000" //ER_TRUNCATED_WRONG_VALUE_FOR_FIELD
+	case errors.Is(err, planner.ErrValueTooLong):
+		return 1406, "22001" //ER_DATA_TOO_LONG
+	case errors.Is(err, planner.ErrNotQuery), errors.Is(err, planner.ErrNotUpdate):
+		return 1347, "HY000" //ER_WRONG_OBJECT
+	case errors.Is(err, ErrAborted):
+		return 1213, "40001" //ER_LOCK_DEADLOCK，客户端可以重试
+	case errors.Is(err, db.ErrInTransaction), errors.Is(err, db.ErrNoTransaction):
+		return 1399, "XAE07" //ER_XAER_RMFAIL
+	case errors.Is(err, db.ErrReadOnly):
+		return 1792, "25006" //ER_CANT_EXECUTE_IN_READ_ONLY_TRANSACTION
+	case errors.Is(err, db.ErrSavepointNotFound):
+		return 1305, "42000" //ER_SP_DOES_NOT_EXIST
+	case errors.Is(err, errFeatureNotSupported):
+		return 1235, "42000" //ER_NOT_SUPPORTED_YET
+	case errors.Is(err, errAccessDenied):
+		return 1045, "28000" //ER_ACCESS_DENIED_ERROR
+	}
+	return 1105, "HY000" //ER_UNKNOWN_ERROR
+}
+
+//httpStatus 把错误转化成HTTP的状态码
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, parser.ErrSyntax), errors.Is(err, parser.ErrArgCount),
+		errors.Is(err, planner.ErrTypeMismatch), errors.Is(err, planner.ErrNotQuery),
+		errors.Is(err, planner.ErrNotUpdate), errors.Is(err, errBadRequest),
+		errors.Is(err, planner.ErrAmbiguousField), errors.Is(err, planner.ErrDuplicateAlias),
+		errors.Is(err, planner.ErrSubqueryColumns), errors.Is(err, query.ErrSubqueryRows),
+		errors.Is(err, planner.ErrSetColumns), errors.Is(err, planner.ErrColumnCount),
+		errors.Is(err, db.ErrInTransaction), errors.Is(err, db.ErrNoTransaction),
+		errors.Is(err, db.ErrReadOnly), errors.Is(err, db.ErrSavepointNotFound),
+		errors.Is(err, planner.ErrLastField), errors.Is(err, planner.ErrTooManyFields),
+		errors.Is(err, planner.ErrValueTooLong), errors.Is(err, planner.ErrRecursiveView):
+		return http.StatusBadRequest
+	case errors.Is(err, planner.ErrTableNotFound), errors.Is(err, planner.ErrFieldNotFound),
+		errors.Is(err, planner.ErrViewNotFound), errors.Is(err, planner.ErrIndexNotFound),
+		errors.Is(err, errTxNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, planner.ErrDependentObjects), errors.Is(err, planner.ErrDuplicateField),
+		errors.Is(err, planner.ErrTableExists), errors.Is(err, planner.ErrIndexExists):
+		return http.StatusConflict
+	case errors.Is(err, planner.ErrCatalogTable):
+		return http.StatusForbidden
+	case errors.Is(err, ErrAborted):
+		return http.StatusConflict //客户端可以重试
+	case errors.Is(err, errFeatureNotSupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, ErrServerClosed):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,456 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"miniSQL/comm"
+	"miniSQL/db"
+	"miniSQL/parser"
+	"miniSQL/planner"
+	rm "miniSQL/record_manager"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	HTTP/JSON接口
+	POST /query  {"sql": "...", "params": [...], "tx": "..."} 执行查询语句，结果以NDJSON的形式流式返回
+	             第一行是列的描述{"columns":[{"name":..,"type":..}]}，之后每一行是一条记录，
+	             最后一行是{"count":N}，中途出错的时候最后一行是{"error":"..."}
+	POST /exec   {"sql": "...", "params": [...], "tx": "..."} 执行修改语句，返回{"count":N}
+	POST /tx                 开启一个事务，返回{"tx":"ID"}，请求体可以是{"read_only":true}
+	POST /tx/ID/commit       提交事务
+	POST /tx/ID/rollback     回滚事务
+	没有指定tx的时候语句在自己的事务中执行，执行完自动提交；显式开启的事务空闲超过idleTimeout之后会被回滚
+	事务中可以使用SAVEPOINT以及ROLLBACK TO，BEGIN/COMMIT/ROLLBACK需要使用/tx接口
+*/
+
+const (
+	DEFAULT_IDLE_TIMEOUT = 30 * time.Second
+	MAX_REQUEST_SIZE     = 1 << 20
+	FLUSH_ROWS           = 100 //每输出多少条记录刷新一次
+)
+
+//HTTPHandler 处理HTTP/JSON请求，可以直接作为http.Server的Handler
+type HTTPHandler struct {
+	db          *db.DB
+	idleTimeout time.Duration
+	mux         *http.ServeMux
+	mu          sync.Mutex
+	txs         map[string]*httpTx //显式开启的事务
+	closed      bool
+}
+
+//httpTx 一个显式开启的事务对应的会话，同一时刻只能有一个请求使用
+type httpTx struct {
+	id       string
+	mu       sync.Mutex
+	sess     *db.Session
+	timer    *time.Timer //空闲超时的定时器
+	lastUsed time.Time
+	done     bool //已经提交或者回滚
+	explicit bool
+}
+
+//httpBeginRequest /tx的请求体
+type httpBeginRequest struct {
+	ReadOnly bool `json:"read_only"`
+}
+
+//httpRequest /query以及/exec的请求体
+type httpRequest struct {
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params"`
+	Tx     string        `json:"tx"`
+}
+
+//httpColumn 结果集中一列的描述
+type httpColumn struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Length    int    `json:"length,omitempty"`
+	Precision int    `json:"precision,omitempty"`
+	Scale     int    `json:"scale,omitempty"`
+}
+
+//NewHTTPHandler 创建HTTP接口，idleTimeout<=0的时候使用DEFAULT_IDLE_TIMEOUT
+func NewHTTPHandler(d *db.DB, idleTimeout time.Duration) *HTTPHandler {
+	if idleTimeout <= 0 {
+		idleTimeout = DEFAULT_IDLE_TIMEOUT
+	}
+	h := &HTTPHandler{
+		db:          d,
+		idleTimeout: idleTimeout,
+		mux:         http.NewServeMux(),
+		txs:         make(map[string]*httpTx),
+	}
+	h.mux.HandleFunc("/query", h.post(h.handleQuery))
+	h.mux.HandleFunc("/exec", h.post(h.handleExec))
+	h.mux.HandleFunc("/tx", h.post(h.handleBegin))
+	h.mux.HandleFunc("/tx/", h.post(h.handleEnd))
+	return h
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+//Close 回滚所有还没有结束的事务，之后的请求都会返回503
+func (h *HTTPHandler) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	txs := h.txs
+	h.txs = make(map[string]*httpTx)
+	h.mu.Unlock()
+	for _, ht := range txs {
+		ht.mu.Lock()
+		ht.end(false)
+		ht.mu.Unlock()
+	}
+	return nil
+}
+
+//post 只允许POST请求
+func (h *HTTPHandler) post(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		h.mu.Lock()
+		closed := h.closed
+		h.mu.Unlock()
+		if closed {
+			writeHTTPError(w, ErrServerClosed)
+			return
+		}
+		f(w, r)
+	}
+}
+
+func (h *HTTPHandler) handleBegin(w http.ResponseWriter, r *http.Request) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	var req httpBeginRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_REQUEST_SIZE)).Decode(&req); err != nil && err != io.EOF {
+			writeHTTPError(w, fmt.Errorf("%w: %v", errBadRequest, err))
+			return
+		}
+	}
+	ht := &httpTx{id: hex.EncodeToString(buf), sess: h.db.NewSession(), lastUsed: time.Now(), explicit: true}
+	if err := ht.sess.Begin(req.ReadOnly); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		ht.end(false)
+		writeHTTPError(w, ErrServerClosed)
+		return
+	}
+	h.txs[ht.id] = ht
+	ht.timer = time.AfterFunc(h.idleTimeout, func() { h.expire(ht) })
+	h.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{"tx": ht.id})
+}
+
+//handleEnd 处理/tx/ID/commit以及/tx/ID/rollback
+func (h *HTTPHandler) handleEnd(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/tx/"), "/")
+	if len(parts) != 2 || (parts[1] != "commit" && parts[1] != "rollback") {
+		http.NotFound(w, r)
+		return
+	}
+	h.mu.Lock()
+	ht, ok := h.txs[parts[0]]
+	delete(h.txs, parts[0])
+	h.mu.Unlock()
+	if !ok {
+		writeHTTPError(w, fmt.Errorf("%w: %s", errTxNotFound, parts[0]))
+		return
+	}
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	if ht.done {
+		writeHTTPError(w, fmt.Errorf("%w: %s", errTxNotFound, parts[0]))
+		return
+	}
+	ht.timer.Stop()
+	if err := ht.end(parts[1] == "commit"); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	status := "committed"
+	if parts[1] == "rollback" {
+		status = "rolled back"
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"tx": ht.id, "status": status})
+}
+
+//expire 空闲超时之后回滚事务
+func (h *HTTPHandler) expire(ht *httpTx) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	if ht.done || time.Since(ht.lastUsed) < h.idleTimeout {
+		//定时器触发的时候事务正在被使用，使用完之后定时器会重新开始计时
+		return
+	}
+	h.mu.Lock()
+	delete(h.txs, ht.id)
+	h.mu.Unlock()
+	ht.end(false)
+}
+
+//acquire 获取请求所使用的事务，返回的事务已经加锁，使用完之后需要调用release
+func (h *HTTPHandler) acquire(id string) (*httpTx, error) {
+	if id == "" {
+		return &httpTx{sess: h.db.NewSession()}, nil
+	}
+	h.mu.Lock()
+	ht, ok := h.txs[id]
+	h.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errTxNotFound, id)
+	}
+	ht.mu.Lock()
+	if ht.done {
+		ht.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", errTxNotFound, id)
+	}
+	ht.timer.Stop()
+	return ht, nil
+}
+
+//release 语句执行结束，没有显式开启的事务已经由会话提交或者回滚了
+//语句被中止的时候会话会回滚整个事务，这里把它删除
+func (h *HTTPHandler) release(ht *httpTx) {
+	if !ht.explicit {
+		return
+	}
+	defer ht.mu.Unlock()
+	if !ht.sess.InTransaction() {
+		h.mu.Lock()
+		delete(h.txs, ht.id)
+		h.mu.Unlock()
+		ht.done = true
+		return
+	}
+	ht.lastUsed = time.Now()
+	ht.timer.Reset(h.idleTimeout)
+}
+
+//end 提交或者回滚事务
+func (ht *httpTx) end(commit bool) error {
+	ht.done = true
+	if commit {
+		return ht.sess.Commit()
+	}
+	return ht.sess.Rollback()
+}
+
+//prepare 解析请求并获取事务
+func (h *HTTPHandler) prepare(w http.ResponseWriter, r *http.Request) (parser.Statement, *httpTx, error) {
+	var req httpRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_REQUEST_SIZE))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	args, err := toArgs(req.Params)
+	if err != nil {
+		return nil, nil, err
+	}
+	stmt, err := parser.NewSQLParserWithArgs(req.SQL, args).ParseStatement()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch data := stmt.(type) {
+	case *parser.BeginData, *parser.CommitData:
+		return nil, nil, fmt.Errorf("%w: use the /tx endpoints to begin and end transactions", errBadRequest)
+	case *parser.RollbackData:
+		if data.Savepoint() == "" {
+			return nil, nil, fmt.Errorf("%w: use the /tx endpoints to begin and end transactions", errBadRequest)
+		}
+	}
+	ht, err := h.acquire(req.Tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stmt, ht, nil
+}
+
+func (h *HTTPHandler) handleExec(w http.ResponseWriter, r *http.Request) {
+	stmt, ht, err := h.prepare(w, r)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	count, err := ht.sess.Execute(stmt)
+	h.release(ht)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"count": count})
+}
+
+func (h *HTTPHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	stmt, ht, err := h.prepare(w, r)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	data, ok := stmt.(*parser.QueryData)
+	if !ok {
+		h.release(ht)
+		writeHTTPError(w, planner.ErrNotQuery)
+		return
+	}
+	rows, err := ht.sess.Query(data)
+	if err != nil {
+		h.release(ht)
+		writeHTTPError(w, err)
+		return
+	}
+
+	//表结构确定之后开始流式输出，之后的错误只能写在最后一行
+	sch := rows.Schema()
+	fields := rows.Fields()
+	columns := make([]httpColumn, len(fields))
+	for i, field := range fields {
+		columns[i] = httpColumnOf(sch, field)
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	count := 0
+	err = enc.Encode(map[string][]httpColumn{"columns": columns})
+	row := make([]interface{}, len(fields))
+	for err == nil && rows.Next() {
+		for i, val := range rows.Row() {
+			row[i] = fromConstant(val)
+		}
+		if err = enc.Encode(row); err != nil {
+			break
+		}
+		count++
+		if count%FLUSH_ROWS == 0 && flusher != nil {
+			flusher.Flush()
+		}
+	}
+	err = rows.CloseWithError(err)
+	h.release(ht)
+	if err != nil {
+		enc.Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	enc.Encode(map[string]int{"count": count})
+}
+
+//httpColumnOf 结果中一列的描述，VARCHAR有长度，DECIMAL有精度和小数位数
+func httpColumnOf(sch rm.SchemaInterface, field string) httpColumn {
+	length := sch.Length(field)
+	switch sch.Type(field) {
+	case rm.VARCHAR:
+		return httpColumn{Name: field, Type: "VARCHAR", Length: length}
+	case rm.BOOLEAN:
+		return httpColumn{Name: field, Type: "BOOLEAN"}
+	case rm.BIGINT:
+		return httpColumn{Name: field, Type: "BIGINT"}
+	case rm.DOUBLE:
+		return httpColumn{Name: field, Type: "DOUBLE"}
+	case rm.DECIMAL:
+		return httpColumn{Name: field, Type: "DECIMAL", Precision: rm.DecimalPrecision(length), Scale: rm.DecimalScale(length)}
+	}
+	return httpColumn{Name: field, Type: "INT"}
+}
+
+//toArgs 把JSON中的参数转化成常量，支持数字，布尔值，字符串和null
+//整数是INTEGER，12.5这样的小数是DECIMAL，使用指数形式的数字是DOUBLE
+func toArgs(params []interface{}) ([]*comm.Constant, error) {
+	args := make([]*comm.Constant, len(params))
+	for i, param := range params {
+		switch v := param.(type) {
+		case nil:
+			args[i] = comm.NewNullConstant()
+		case json.Number:
+			val, err := numberConstant(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parameter %d is not a valid number: %s", planner.ErrTypeMismatch, i+1, v)
+			}
+			args[i] = val
+		case bool:
+			bval := v
+			args[i] = comm.NewConstantBool(&bval)
+		case string:
+			sval := v
+			args[i] = comm.NewConstantString(&sval)
+		default:
+			return nil, fmt.Errorf("%w: unsupported type %T of parameter %d", planner.ErrTypeMismatch, param, i+1)
+		}
+	}
+	return args, nil
+}
+
+//numberConstant 把JSON中的数字转化成常量
+func numberConstant(v json.Number) (*comm.Constant, error) {
+	if n, err := v.Int64(); err == nil {
+		ival := int(n)
+		return comm.NewConstantInt(&ival), nil
+	}
+	if !strings.ContainsAny(v.String(), "eE") {
+		d, err := comm.ParseDecimal(v.String())
+		if err != nil {
+			return nil, err
+		}
+		return comm.NewConstantDecimal(&d), nil
+	}
+	f, err := v.Float64()
+	if err != nil {
+		return nil, err
+	}
+	return comm.NewConstantFloat(&f), nil
+}
+
+//fromConstant 把常量转化成JSON中的值，DECIMAL直接输出十进制的数字，不经过浮点数
+func fromConstant(val *comm.Constant) interface{} {
+	switch {
+	case val.IsNull():
+		return nil
+	case val.Ival != nil:
+		return *val.Ival
+	case val.Bval != nil:
+		return *val.Bval
+	case val.Fval != nil:
+		return *val.Fval
+	case val.Dval != nil:
+		return json.Number(val.Dval.String())
+	}
+	return *val.Sval
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeHTTPError(w http.ResponseWriter, err error) {
+	writeJSONError(w, httpStatus(err), err)
+}
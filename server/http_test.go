@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/db"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func startHTTPServer(t *testing.T, idleTimeout time.Duration) *httptest.Server {
+	d, err := db.Open(filepath.Join(t.TempDir(), "http_test"), db.Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	h := NewHTTPHandler(d, idleTimeout)
+	s := httptest.NewServer(h)
+	t.Cleanup(func() {
+		s.Close()
+		assert.Nil(t, h.Close())
+		assert.Nil(t, d.Close())
+	})
+	return s
+}
+
+//post 发送请求，返回状态码以及按行解析的JSON
+func post(t *testing.T, s *httptest.Server, path string, body interface{}) (int, []interface{}) {
+	buf, err := json.Marshal(body)
+	assert.Nil(t, err)
+	resp, err := http.Post(s.URL+path, "application/json", bytes.NewReader(buf))
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	lines := make([]interface{}, 0)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var v interface{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &v))
+		lines = append(lines, v)
+	}
+	return resp.StatusCode, lines
+}
+
+func TestHTTPQuery(t *testing.T) {
+	s := startHTTPServer(t, 0)
+	status, lines := post(t, s, "/exec", httpRequest{SQL: "create table student (name varchar(16),gradyear int)"})
+	assert.Equal(t, http.StatusOK, status)
+	for i, name := range []string{"tom", "jerry"} {
+		status, lines = post(t, s, "/exec", httpRequest{SQL: "insert into student (name,gradyear) values (?,?)", Params: []interface{}{name, 2020 + i}})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, map[string]interface{}{"count": float64(1)}, lines[0])
+	}
+
+	status, lines = post(t, s, "/query", httpRequest{SQL: "select name,gradyear from student where gradyear=?", Params: []interface{}{2021}})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"columns": []interface{}{
+			map[string]interface{}{"name": "name", "type": "VARCHAR", "length": float64(16)},
+			map[string]interface{}{"name": "gradyear", "type": "INT"},
+		}},
+		[]interface{}{"jerry", float64(2021)},
+		map[string]interface{}{"count": float64(1)},
+	}, lines)
+
+	status, _ = post(t, s, "/query", httpRequest{SQL: "select name from nosuchtable"})
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = post(t, s, "/exec", httpRequest{SQL: "select name from student"})
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = post(t, s, "/exec", httpRequest{SQL: "insert into student (name,gradyear) values (?,?)", Params: []interface{}{"bob", 1.5}})
+	assert.Equal(t, http.StatusBadRequest, status)
+	//JSON中的null是NULL
+	status, _ = post(t, s, "/exec", httpRequest{SQL: "insert into student (name,gradyear) values (?,?)", Params: []interface{}{"bob", nil}})
+	assert.Equal(t, http.StatusOK, status)
+	_, lines = post(t, s, "/query", httpRequest{SQL: "select gradyear from student where gradyear is null"})
+	assert.Equal(t, []interface{}{nil}, lines[1])
+	resp, err := http.Get(s.URL + "/query")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestHTTPTransaction(t *testing.T) {
+	s := startHTTPServer(t, 0)
+	status, _ := post(t, s, "/exec", httpRequest{SQL: "create table student (name varchar(16),gradyear int)"})
+	assert.Equal(t, http.StatusOK, status)
+
+	begin := func() string {
+		status, lines := post(t, s, "/tx", nil)
+		assert.Equal(t, http.StatusOK, status)
+		return lines[0].(map[string]interface{})["tx"].(string)
+	}
+	id := begin()
+	status, _ = post(t, s, "/exec", httpRequest{SQL: "insert into student (name,gradyear) values ('tom',2020)", Tx: id})
+	assert.Equal(t, http.StatusOK, status)
+	status, lines := post(t, s, "/query", httpRequest{SQL: "select name from student", Tx: id})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"count": float64(1)}, lines[len(lines)-1])
+	status, _ = post(t, s, "/tx/"+id+"/rollback", nil)
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = post(t, s, "/tx/"+id+"/commit", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	id = begin()
+	status, _ = post(t, s, "/exec", httpRequest{SQL: "insert into student (name,gradyear) values ('jerry',2021)", Tx: id})
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = post(t, s, "/tx/"+id+"/commit", nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, lines = post(t, s, "/query", httpRequest{SQL: "select name from student"})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []interface{}{"jerry"}, lines[1])
+	assert.Equal(t, map[string]interface{}{"count": float64(1)}, lines[2])
+}
+
+func TestHTTPIdleTimeout(t *testing.T) {
+	s := startHTTPServer(t, 50*time.Millisecond)
+	status, lines := post(t, s, "/tx", nil)
+	assert.Equal(t, http.StatusOK, status)
+	id := lines[0].(map[string]interface{})["tx"].(string)
+	time.Sleep(200 * time.Millisecond)
+	status, _ = post(t, s, "/exec", httpRequest{SQL: "create table student (name varchar(16))", Tx: id})
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = post(t, s, "/tx/"+id+"/commit", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,502 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"miniSQL/comm"
+	"miniSQL/db"
+	"miniSQL/parser"
+	rm "miniSQL/record_manager"
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+	MySQL客户端/服务端协议
+	握手：服务端发送Handshake v10，客户端使用mysql_native_password返回认证数据，认证成功返回OK包
+	COM_QUERY使用文本协议返回结果集，COM_STMT_PREPARE/COM_STMT_EXECUTE使用二进制协议返回结果集
+	出错的时候返回带有SQLSTATE的ERR包
+*/
+
+const (
+	mysqlServerVersion = "8.0.0-miniSQL"
+	mysqlCapabilities  = mysqlClientLongPassword | mysqlClientFoundRows | mysqlClientLongFlag |
+		mysqlClientConnectWithDB | mysqlClientProtocol41 | mysqlClientTransactions |
+		mysqlClientSecureConn | mysqlClientPluginAuth | mysqlClientConnectAttrs | mysqlClientPluginAuthLenenc
+)
+
+var (
+	mysqlConnID uint32 //握手时分配给连接的编号
+)
+
+//NewMySQLServer 创建一个使用MySQL协议的服务，users是用户名到密码的映射，为nil的时候不做认证
+func NewMySQLServer(d *db.DB, users map[string]string) *Server {
+	return newServer(d, func(d *db.DB, conn net.Conn) {
+		serveMySQL(d, conn, users)
+	})
+}
+
+//mysqlStmt COM_STMT_PREPARE创建的预处理语句
+type mysqlStmt struct {
+	id         uint32
+	sql        string
+	numParams  int
+	stmt       parser.Statement //使用占位值解析得到的语法树
+	schema     rm.SchemaInterface
+	paramTypes []byte //上一次执行时客户端发送的参数类型
+}
+
+type mysqlConn struct {
+	mysqlPacketIO
+	sess       *session
+	stmts      map[uint32]*mysqlStmt
+	nextStmtID uint32
+}
+
+func serveMySQL(d *db.DB, conn net.Conn, users map[string]string) {
+	c := &mysqlConn{
+		mysqlPacketIO: mysqlPacketIO{r: bufio.NewReader(conn), w: bufio.NewWriter(conn)},
+		sess:          newSession(d),
+		stmts:         make(map[uint32]*mysqlStmt),
+	}
+	defer c.sess.close() //连接断开的时候回滚没有提交的事务
+	if err := c.handshake(users); err != nil {
+		return
+	}
+	for {
+		data, err := c.readPacket()
+		if err != nil || len(data) == 0 {
+			return
+		}
+		if data[0] == mysqlComQuit {
+			return
+		}
+		c.dispatch(data[0], &mysqlReader{buf: data[1:]})
+		if err := c.w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+//handshake 发送握手包并完成认证
+func (c *mysqlConn) handshake(users map[string]string) error {
+	scramble := make([]byte, 20)
+	if _, err := rand.Read(scramble); err != nil {
+		return err
+	}
+	for i := range scramble {
+		//认证数据中不能出现0
+		scramble[i] = scramble[i]&0x7f | 1
+	}
+	c.seq = 0
+	pkt := []byte{10}
+	pkt = append(pkt, mysqlServerVersion...)
+	pkt = append(pkt, 0)
+	pkt = appendUint32(pkt, atomic.AddUint32(&mysqlConnID, 1))
+	pkt = append(pkt, scramble[:8]...)
+	pkt = append(pkt, 0)
+	pkt = appendUint16(pkt, uint16(mysqlCapabilities&0xffff))
+	pkt = append(pkt, mysqlCharsetUTF8)
+	pkt = appendUint16(pkt, mysqlStatusAutocommit)
+	pkt = appendUint16(pkt, uint16(mysqlCapabilities>>16))
+	pkt = append(pkt, byte(len(scramble)+1))
+	pkt = append(pkt, make([]byte, 10)...)
+	pkt = append(pkt, scramble[8:]...)
+	pkt = append(pkt, 0)
+	pkt = append(pkt, mysqlNativePassword...)
+	pkt = append(pkt, 0)
+	if err := c.writePacket(pkt); err != nil {
+		return err
+	}
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+
+	//HandshakeResponse41
+	data, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	r := &mysqlReader{buf: data}
+	capability := r.uint32()
+	r.uint32() //max packet size
+	r.byte1()  //charset
+	r.bytes(23)
+	user := r.cstring()
+	var token []byte
+	switch {
+	case capability&mysqlClientPluginAuthLenenc != 0:
+		token = r.lenencString()
+	case capability&mysqlClientSecureConn != 0:
+		token = r.bytes(int(r.byte1()))
+	default:
+		token = []byte(r.cstring())
+	}
+	if capability&mysqlClientConnectWithDB != 0 {
+		r.cstring()
+	}
+	plugin := mysqlNativePassword
+	if capability&mysqlClientPluginAuth != 0 {
+		plugin = r.cstring()
+	}
+	if r.err != nil || capability&mysqlClientProtocol41 == 0 {
+		c.writeError(fmt.Errorf("%w: client must support protocol 4.1", errFeatureNotSupported))
+		c.w.Flush()
+		return errMySQLPacket
+	}
+	if plugin != mysqlNativePassword {
+		//客户端使用了别的认证插件，要求它换成mysql_native_password
+		pkt := []byte{0xfe}
+		pkt = append(pkt, mysqlNativePassword...)
+		pkt = append(pkt, 0)
+		pkt = append(pkt, scramble...)
+		pkt = append(pkt, 0)
+		if err := c.writePacket(pkt); err != nil {
+			return err
+		}
+		if err := c.w.Flush(); err != nil {
+			return err
+		}
+		if token, err = c.readPacket(); err != nil {
+			return err
+		}
+	}
+	if users != nil {
+		password, ok := users[user]
+		expected := nativePasswordToken(password, scramble)
+		if !ok || len(token) != len(expected) || subtle.ConstantTimeCompare(token, expected) != 1 {
+			c.writeError(fmt.Errorf("%w for user '%s'", errAccessDenied, user))
+			c.w.Flush()
+			return errAccessDenied
+		}
+	}
+	c.writeOK(0)
+	return c.w.Flush()
+}
+
+//dispatch 根据命令的类型进行处理
+func (c *mysqlConn) dispatch(cmd byte, r *mysqlReader) {
+	switch cmd {
+	case mysqlComPing, mysqlComInitDB, mysqlComStmtReset:
+		c.writeOK(0)
+	case mysqlComQuery:
+		c.handleQuery(string(r.buf))
+	case mysqlComStmtPrepare:
+		c.handlePrepare(string(r.buf))
+	case mysqlComStmtExecute:
+		c.handleExecute(r)
+	case mysqlComStmtClose:
+		//COM_STMT_CLOSE不需要回复
+		delete(c.stmts, r.uint32())
+	default:
+		c.writeError(fmt.Errorf("%w: command 0x%02x", errFeatureNotSupported, cmd))
+	}
+}
+
+//handleQuery 使用文本协议执行一条语句
+func (c *mysqlConn) handleQuery(sql string) {
+	stmts, rest := parser.SplitStatements(sql)
+	if rest = strings.TrimSpace(rest); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	if len(stmts) != 1 {
+		c.writeError(fmt.Errorf("%w: expected exactly one statement, got %d", parser.ErrSyntax, len(stmts)))
+		return
+	}
+	res, err := c.sess.execute(stmts[0], nil)
+	if err != nil {
+		c.writeError(err)
+		return
+	}
+	if !res.isQuery() {
+		c.writeOK(res.count)
+		return
+	}
+	c.writeColumns(res.schema)
+	for _, row := range res.rows {
+		pkt := make([]byte, 0)
+		for _, val := range row {
+			if val.IsNull() {
+				//文本协议中NULL使用0xfb表示
+				pkt = append(pkt, 0xfb)
+				continue
+			}
+			pkt = appendLenencString(pkt, mysqlText(val))
+		}
+		c.writePacket(pkt)
+	}
+	c.writeEOF()
+}
+
+//handlePrepare 解析语句，返回参数以及结果列的描述
+func (c *mysqlConn) handlePrepare(sql string) {
+	numParams := countPlaceholders(sql)
+	//参数槽还没有绑定值，类型未知，描述结果的时候不会因为参数的类型出错
+	stmt, _, err := parser.Prepare(sql)
+	if err != nil {
+		c.writeError(err)
+		return
+	}
+	sch, err := c.sess.describe(stmt)
+	if err != nil {
+		c.writeError(err)
+		return
+	}
+	c.nextStmtID++
+	ps := &mysqlStmt{id: c.nextStmtID, sql: sql, numParams: numParams, stmt: stmt, schema: sch}
+	c.stmts[ps.id] = ps
+	numColumns := 0
+	if sch != nil {
+		numColumns = len(sch.Fields())
+	}
+	pkt := []byte{0}
+	pkt = appendUint32(pkt, ps.id)
+	pkt = appendUint16(pkt, uint16(numColumns))
+	pkt = appendUint16(pkt, uint16(numParams))
+	pkt = append(pkt, 0)
+	pkt = appendUint16(pkt, 0) //warnings
+	c.writePacket(pkt)
+	if numParams > 0 {
+		for i := 0; i < numParams; i++ {
+			c.writePacket(columnDefinition("?", mysqlTypeVarString, mysqlCharsetBinary, 0, 0))
+		}
+		c.writeEOF()
+	}
+	if numColumns > 0 {
+		for _, field := range sch.Fields() {
+			c.writePacket(fieldDefinition(sch, field))
+		}
+		c.writeEOF()
+	}
+}
+
+//handleExecute 绑定参数执行预处理语句，查询结果使用二进制协议返回
+func (c *mysqlConn) handleExecute(r *mysqlReader) {
+	id := r.uint32()
+	r.byte1()  //flags，不支持游标
+	r.uint32() //iteration count，总是1
+	ps, ok := c.stmts[id]
+	if !ok {
+		c.writeError(fmt.Errorf("unknown prepared statement handler %d", id))
+		return
+	}
+	args := make([]*comm.Constant, ps.numParams)
+	if ps.numParams > 0 {
+		nullBitmap := r.bytes((ps.numParams + 7) / 8)
+		if r.byte1() == 1 {
+			//客户端发送了新的参数类型
+			ps.paramTypes = make([]byte, ps.numParams)
+			for i := range ps.paramTypes {
+				ps.paramTypes[i] = r.byte1()
+				r.byte1() //unsigned标志
+			}
+		}
+		if r.err != nil || len(ps.paramTypes) != ps.numParams {
+			c.writeError(fmt.Errorf("%w: malformed COM_STMT_EXECUTE", parser.ErrArgCount))
+			return
+		}
+		for i := 0; i < ps.numParams; i++ {
+			if nullBitmap[i/8]&(1<<(i%8)) != 0 {
+				//NULL参数后面没有数据
+				args[i] = comm.NewNullConstant()
+				continue
+			}
+			val, err := decodeMySQLParam(r, ps.paramTypes[i])
+			if err != nil {
+				c.writeError(err)
+				return
+			}
+			args[i] = val
+		}
+	}
+	if r.err != nil {
+		c.writeError(fmt.Errorf("%w: malformed COM_STMT_EXECUTE", parser.ErrArgCount))
+		return
+	}
+	res, err := c.sess.execute(ps.sql, args)
+	if err != nil {
+		c.writeError(err)
+		return
+	}
+	if !res.isQuery() {
+		c.writeOK(res.count)
+		return
+	}
+	c.writeColumns(res.schema)
+	bitmapLen := (len(res.fields) + 7 + 2) / 8
+	for _, row := range res.rows {
+		pkt := append([]byte{0}, make([]byte, bitmapLen)...)
+		for i, val := range row {
+			if val.IsNull() {
+				//二进制协议中NULL只在位图中标记，位图的前两位是保留的
+				pkt[1+(i+2)/8] |= 1 << ((i + 2) % 8)
+				continue
+			}
+			switch {
+			case val.Ival != nil:
+				pkt = appendUint64(pkt, uint64(int64(*val.Ival)))
+			case val.Bval != nil:
+				b := byte(0)
+				if *val.Bval {
+					b = 1
+				}
+				pkt = append(pkt, b)
+			case val.Fval != nil:
+				pkt = appendUint64(pkt, math.Float64bits(*val.Fval))
+			default:
+				//DECIMAL在二进制协议中也是字符串
+				pkt = appendLenencString(pkt, mysqlText(val))
+			}
+		}
+		c.writePacket(pkt)
+	}
+	c.writeEOF()
+}
+
+//decodeMySQLParam 按照二进制协议读取一个参数
+func decodeMySQLParam(r *mysqlReader, typ byte) (*comm.Constant, error) {
+	var v int
+	switch typ {
+	case mysqlTypeTiny:
+		v = int(int8(r.byte1()))
+	case mysqlTypeShort, mysqlTypeYear:
+		v = int(int16(r.uint16()))
+	case mysqlTypeLong, mysqlTypeInt24:
+		v = int(int32(r.uint32()))
+	case mysqlTypeLongLong:
+		v = int(int64(r.uint64()))
+	case mysqlTypeFloat:
+		f := float64(math.Float32frombits(r.uint32()))
+		return comm.NewConstantFloat(&f), nil
+	case mysqlTypeDouble:
+		f := math.Float64frombits(r.uint64())
+		return comm.NewConstantFloat(&f), nil
+	case mysqlTypeDecimal, mysqlTypeNewDecimal:
+		d, err := comm.ParseDecimal(string(r.lenencString()))
+		if err != nil {
+			return nil, err
+		}
+		return comm.NewConstantDecimal(&d), nil
+	case mysqlTypeVarchar, mysqlTypeVarString, mysqlTypeString, mysqlTypeBlob:
+		s := string(r.lenencString())
+		return comm.NewConstantString(&s), nil
+	default:
+		return nil, fmt.Errorf("%w: parameter type 0x%02x", errFeatureNotSupported, typ)
+	}
+	return comm.NewConstantInt(&v), nil
+}
+
+//writeColumns 发送结果集的列数以及每一列的描述
+func (c *mysqlConn) writeColumns(sch rm.SchemaInterface) {
+	fields := sch.Fields()
+	c.writePacket(appendLenencInt(nil, uint64(len(fields))))
+	for _, field := range fields {
+		c.writePacket(fieldDefinition(sch, field))
+	}
+	c.writeEOF()
+}
+
+func fieldDefinition(sch rm.SchemaInterface, field string) []byte {
+	length := sch.Length(field)
+	switch sch.Type(field) {
+	case rm.INTEGER, rm.BIGINT:
+		return columnDefinition(field, mysqlTypeLongLong, mysqlCharsetBinary, 20, 0)
+	case rm.BOOLEAN:
+		return columnDefinition(field, mysqlTypeTiny, mysqlCharsetBinary, 1, 0)
+	case rm.DOUBLE:
+		//小数位数是0x1f表示浮点数的小数位数不固定
+		return columnDefinition(field, mysqlTypeDouble, mysqlCharsetBinary, 22, 0x1f)
+	case rm.DECIMAL:
+		//显示宽度包括符号和小数点
+		precision, scale := rm.DecimalPrecision(length), rm.DecimalScale(length)
+		return columnDefinition(field, mysqlTypeNewDecimal, mysqlCharsetBinary, uint32(precision+2), byte(scale))
+	}
+	return columnDefinition(field, mysqlTypeVarString, mysqlCharsetUTF8, uint32(length*3), 0)
+}
+
+//mysqlText 值在文本协议中的形式，布尔值使用1和0
+func mysqlText(val *comm.Constant) string {
+	if val.Bval != nil {
+		if *val.Bval {
+			return "1"
+		}
+		return "0"
+	}
+	return val.ToString()
+}
+
+//columnDefinition 构造ColumnDefinition41，decimals是小数位数
+func columnDefinition(name string, typ byte, charset uint16, length uint32, decimals byte) []byte {
+	pkt := appendLenencString(nil, "def")
+	pkt = appendLenencString(pkt, "") //schema
+	pkt = appendLenencString(pkt, "") //table
+	pkt = appendLenencString(pkt, "") //org_table
+	pkt = appendLenencString(pkt, name)
+	pkt = appendLenencString(pkt, name)
+	pkt = append(pkt, 0x0c)
+	pkt = appendUint16(pkt, charset)
+	pkt = appendUint32(pkt, length)
+	pkt = append(pkt, typ)
+	pkt = appendUint16(pkt, 0) //flags
+	pkt = append(pkt, decimals)
+	return appendUint16(pkt, 0)
+}
+
+//status 服务端的状态标志
+func (c *mysqlConn) status() uint16 {
+	if c.sess.inTransaction() {
+		return mysqlStatusInTrans
+	}
+	return mysqlStatusAutocommit
+}
+
+func (c *mysqlConn) writeOK(affectedRows int) {
+	pkt := []byte{0}
+	pkt = appendLenencInt(pkt, uint64(affectedRows))
+	pkt = appendLenencInt(pkt, 0) //last insert id
+	pkt = appendUint16(pkt, c.status())
+	pkt = appendUint16(pkt, 0)
+	c.writePacket(pkt)
+}
+
+func (c *mysqlConn) writeEOF() {
+	pkt := []byte{0xfe}
+	pkt = appendUint16(pkt, 0)
+	pkt = appendUint16(pkt, c.status())
+	c.writePacket(pkt)
+}
+
+func (c *mysqlConn) writeError(err error) {
+	code, state := mysqlErrorCode(err)
+	pkt := []byte{0xff}
+	pkt = appendUint16(pkt, code)
+	pkt = append(pkt, '#')
+	pkt = append(pkt, state...)
+	pkt = append(pkt, err.Error()...)
+	c.writePacket(pkt)
+}
+
+//countPlaceholders 统计语句中?占位符的个数，字符串中的?不算
+func countPlaceholders(sql string) int {
+	count := 0
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '?':
+			count++
+		}
+	}
+	return count
+}
+
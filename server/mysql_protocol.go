@@ -0,0 +1,256 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+/*
+	MySQL客户端/服务端协议
+	每个数据包的格式：3字节小端序的长度 + 1字节的序号 + 数据，超过16M的数据需要拆分成多个包
+	每条命令开始的时候序号从0开始，之后每发送或者接收一个包序号加一
+*/
+
+const (
+	mysqlMaxPacketSize = 1<<24 - 1
+
+	//客户端和服务端的能力标志
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientFoundRows        = 0x00000002
+	mysqlClientLongFlag         = 0x00000004
+	mysqlClientConnectWithDB    = 0x00000008
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientTransactions     = 0x00002000
+	mysqlClientSecureConn       = 0x00008000
+	mysqlClientPluginAuth       = 0x00080000
+	mysqlClientConnectAttrs     = 0x00100000
+	mysqlClientPluginAuthLenenc = 0x00200000
+
+	//服务端的状态标志
+	mysqlStatusInTrans    = 0x0001
+	mysqlStatusAutocommit = 0x0002
+
+	//命令
+	mysqlComQuit        = 0x01
+	mysqlComInitDB      = 0x02
+	mysqlComQuery       = 0x03
+	mysqlComPing        = 0x0e
+	mysqlComStmtPrepare = 0x16
+	mysqlComStmtExecute = 0x17
+	mysqlComStmtClose   = 0x19
+	mysqlComStmtReset   = 0x1a
+
+	//列的类型
+	mysqlTypeDecimal    = 0x00
+	mysqlTypeTiny       = 0x01
+	mysqlTypeShort      = 0x02
+	mysqlTypeLong       = 0x03
+	mysqlTypeFloat      = 0x04
+	mysqlTypeDouble     = 0x05
+	mysqlTypeLongLong   = 0x08
+	mysqlTypeInt24      = 0x09
+	mysqlTypeYear       = 0x0d
+	mysqlTypeVarchar    = 0x0f
+	mysqlTypeNewDecimal = 0xf6
+	mysqlTypeBlob       = 0xfc
+	mysqlTypeVarString  = 0xfd
+	mysqlTypeString     = 0xfe
+	mysqlTypeNull       = 0x06
+	mysqlCharsetUTF8    = 33 //utf8_general_ci
+	mysqlCharsetBinary  = 63
+	mysqlNativePassword = "mysql_native_password"
+)
+
+var (
+	errMySQLPacket = errors.New("invalid packet")
+)
+
+//mysqlPacketIO 负责数据包的读写以及序号的维护
+type mysqlPacketIO struct {
+	r   *bufio.Reader
+	w   *bufio.Writer
+	seq byte
+}
+
+//readPacket 读取一个完整的数据包，被拆分的数据包会被重新拼接起来
+func (p *mysqlPacketIO) readPacket() ([]byte, error) {
+	var data []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(p.r, header[:]); err != nil {
+			return nil, err
+		}
+		size := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		p.seq = header[3] + 1
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(p.r, buf); err != nil {
+			return nil, err
+		}
+		data = append(data, buf...)
+		if size < mysqlMaxPacketSize {
+			return data, nil
+		}
+	}
+}
+
+//writePacket 写入一个数据包，数据太长的时候拆分成多个包
+func (p *mysqlPacketIO) writePacket(data []byte) error {
+	for {
+		size := len(data)
+		if size > mysqlMaxPacketSize {
+			size = mysqlMaxPacketSize
+		}
+		header := [4]byte{byte(size), byte(size >> 8), byte(size >> 16), p.seq}
+		p.seq++
+		if _, err := p.w.Write(header[:]); err != nil {
+			return err
+		}
+		if _, err := p.w.Write(data[:size]); err != nil {
+			return err
+		}
+		data = data[size:]
+		if size < mysqlMaxPacketSize {
+			return nil
+		}
+	}
+}
+
+//mysqlReader 解析数据包，遇到错误之后后面的读取都返回零值，最后统一检查err
+type mysqlReader struct {
+	buf []byte
+	err error
+}
+
+func (r *mysqlReader) byte1() byte {
+	if r.err != nil || len(r.buf) < 1 {
+		r.err = errMySQLPacket
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *mysqlReader) uint16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (r *mysqlReader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *mysqlReader) uint64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (r *mysqlReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || len(r.buf) < n {
+		r.err = errMySQLPacket
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+//cstring 读取一个以0结尾的字符串，没有0的时候读取剩下所有的内容
+func (r *mysqlReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, b := range r.buf {
+		if b == 0 {
+			s := string(r.buf[:i])
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+	s := string(r.buf)
+	r.buf = nil
+	return s
+}
+
+//lenencInt 读取一个长度编码的整数
+func (r *mysqlReader) lenencInt() uint64 {
+	first := r.byte1()
+	switch first {
+	case 0xfc:
+		return uint64(r.uint16())
+	case 0xfd:
+		b := r.bytes(3)
+		if b == nil {
+			return 0
+		}
+		return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16
+	case 0xfe:
+		return r.uint64()
+	}
+	return uint64(first)
+}
+
+func (r *mysqlReader) lenencString() []byte {
+	n := r.lenencInt()
+	return r.bytes(int(n))
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
+
+//appendLenencInt 写入一个长度编码的整数
+func appendLenencInt(b []byte, v uint64) []byte {
+	switch {
+	case v < 0xfb:
+		return append(b, byte(v))
+	case v < 1<<16:
+		return appendUint16(append(b, 0xfc), uint16(v))
+	case v < 1<<24:
+		return append(b, 0xfd, byte(v), byte(v>>8), byte(v>>16))
+	}
+	return appendUint64(append(b, 0xfe), v)
+}
+
+func appendLenencString(b []byte, s string) []byte {
+	return append(appendLenencInt(b, uint64(len(s))), s...)
+}
+
+//nativePasswordToken 计算mysql_native_password的认证数据
+//SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+func nativePasswordToken(password string, scramble []byte) []byte {
+	if password == "" {
+		return nil
+	}
+	stage1 := sha1.Sum([]byte(password))
+	stage2 := sha1.Sum(stage1[:])
+	h := sha1.New()
+	h.Write(scramble)
+	h.Write(stage2[:])
+	token := h.Sum(nil)
+	for i := range token {
+		token[i] ^= stage1[i]
+	}
+	return token
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/db"
+	"net"
+	"testing"
+)
+
+func openMySQL(t *testing.T, users map[string]string, user string, password string) *sql.DB {
+	addr := startTestServer(t, func(d *db.DB) *Server {
+		return NewMySQLServer(d, users)
+	}).(*net.TCPAddr)
+	dsn := fmt.Sprintf("%s:%s@tcp(127.0.0.1:%d)/test", user, password, addr.Port)
+	conn, err := sql.Open("mysql", dsn)
+	assert.Nil(t, err)
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestMySQLTextProtocol(t *testing.T) {
+	conn := openMySQL(t, map[string]string{"root": "secret"}, "root", "secret")
+	assert.Nil(t, conn.Ping())
+	_, err := conn.Exec("create table student (name varchar(16),gradyear int)")
+	assert.Nil(t, err)
+	res, err := conn.Exec("insert into student (name,gradyear) values ('tom',2020)")
+	assert.Nil(t, err)
+	n, _ := res.RowsAffected()
+	assert.Equal(t, int64(1), n)
+
+	rows, err := conn.Query("select name,gradyear from student")
+	assert.Nil(t, err)
+	types, _ := rows.ColumnTypes()
+	assert.Equal(t, "VARCHAR", types[0].DatabaseTypeName())
+	assert.Equal(t, "BIGINT", types[1].DatabaseTypeName())
+	assert.True(t, rows.Next())
+	var name string
+	var year int
+	assert.Nil(t, rows.Scan(&name, &year))
+	assert.Equal(t, "tom", name)
+	assert.Equal(t, 2020, year)
+	assert.False(t, rows.Next())
+	assert.Nil(t, rows.Close())
+
+	//外连接中没有匹配上的字段是NULL
+	_, err = conn.Exec("create table club (member varchar(16))")
+	assert.Nil(t, err)
+	var member sql.NullString
+	assert.Nil(t, conn.QueryRow("select name,member from student left join club on name = member").Scan(&name, &member))
+	assert.Equal(t, "tom", name)
+	assert.False(t, member.Valid)
+
+	_, err = conn.Exec("select name from nosuchtable")
+	var merr *mysql.MySQLError
+	assert.True(t, errors.As(err, &merr))
+	assert.Equal(t, uint16(1146), merr.Number)
+	assert.Equal(t, "42S02", string(merr.SQLState[:]))
+
+	_, err = conn.Exec("selec name from student")
+	assert.True(t, errors.As(err, &merr))
+	assert.Equal(t, uint16(1064), merr.Number)
+}
+
+func TestMySQLPreparedStatement(t *testing.T) {
+	conn := openMySQL(t, nil, "test", "")
+	_, err := conn.Exec("create table student (name varchar(16),gradyear int)")
+	assert.Nil(t, err)
+	stmt, err := conn.Prepare("insert into student (name,gradyear) values (?,?)")
+	assert.Nil(t, err)
+	for i, name := range []string{"tom", "jerry", "what?"} {
+		res, err := stmt.Exec(name, 2020+i)
+		assert.Nil(t, err)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(1), n)
+	}
+	assert.Nil(t, stmt.Close())
+
+	var name string
+	assert.Nil(t, conn.QueryRow("select name from student where gradyear=?", 2022).Scan(&name))
+	assert.Equal(t, "what?", name)
+	var year int64
+	assert.Nil(t, conn.QueryRow("select gradyear from student where name=?", "jerry").Scan(&year))
+	assert.Equal(t, int64(2021), year)
+	//二进制协议中NULL在位图中标记
+	_, err = conn.Exec("create table club (member varchar(16),since int)")
+	assert.Nil(t, err)
+	var member sql.NullString
+	var since sql.NullInt64
+	assert.Nil(t, conn.QueryRow("select name,member,since from student left join club on name = member where gradyear=?", 2021).Scan(&name, &member, &since))
+	assert.Equal(t, "jerry", name)
+	assert.False(t, member.Valid)
+	assert.False(t, since.Valid)
+	//NULL参数
+	_, err = conn.Exec("insert into club (member,since) values (?,?)", "tom", nil)
+	assert.Nil(t, err)
+	assert.Nil(t, conn.QueryRow("select member,since from club where since is null and member=?", "tom").Scan(&member, &since))
+	assert.True(t, member.Valid)
+	assert.False(t, since.Valid)
+
+	_, err = conn.Exec("insert into student (name,gradyear) values (?,?)", 2023, 2023)
+	var merr *mysql.MySQLError
+	assert.True(t, errors.As(err, &merr))
+	assert.Equal(t, uint16(1366), merr.Number)
+}
+
+func TestMySQLAccessDenied(t *testing.T) {
+	conn := openMySQL(t, map[string]string{"root": "secret"}, "root", "wrong")
+	err := conn.Ping()
+	var merr *mysql.MySQLError
+	assert.True(t, errors.As(err, &merr))
+	assert.Equal(t, uint16(1045), merr.Number)
+	assert.Equal(t, "28000", string(merr.SQLState[:]))
+}
+
+func TestCountPlaceholders(t *testing.T) {
+	assert.Equal(t, 2, countPlaceholders("select a from t where a=? and b=?"))
+	assert.Equal(t, 1, countPlaceholders("select a from t where a='?' and b=? and c=\"?\""))
+}
@@ -0,0 +1,644 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"miniSQL/comm"
+	"miniSQL/db"
+	"miniSQL/parser"
+	rm "miniSQL/record_manager"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+	PostgreSQL v3 前后端协议
+	启动：客户端发送StartupMessage，服务端不做认证，直接返回AuthenticationOk，一些ParameterStatus，BackendKeyData和ReadyForQuery
+	简单查询：Query消息中可能包含多条以;分隔的语句，每条语句返回RowDescription/DataRow/CommandComplete，最后返回ReadyForQuery
+	扩展查询：Parse->Bind->Describe->Execute->Sync，出错之后丢弃所有的消息直到Sync
+	语句中的$n参数会被替换成?，Parse的时候解析成带有参数槽的语法树，Execute的时候把参数写入参数槽再执行
+	Execute指定了最多返回的记录数的时候，剩下的记录留在portal中，返回PortalSuspended，下一次Execute继续发送
+*/
+
+var (
+	pgBackendPID int32 //BackendKeyData中使用的连接编号
+)
+
+//NewPGServer 创建一个使用PostgreSQL协议的服务
+func NewPGServer(d *db.DB) *Server {
+	return newServer(d, servePG)
+}
+
+//pgStatement Parse消息创建的预处理语句
+type pgStatement struct {
+	order     []int            //第i个?使用的参数下标
+	numParams int              //参数的个数
+	paramOIDs []uint32         //客户端指定的参数类型，0表示没有指定
+	stmt      parser.Statement //$n替换成?之后解析得到的语法树
+	params    []*comm.Constant //语法树中第i个?对应的参数槽
+}
+
+//pgPortal Bind消息创建的portal，已经绑定好了参数
+type pgPortal struct {
+	stmt          *pgStatement
+	args          []*comm.Constant //按照?的顺序排列的参数
+	resultFormats []int16
+	res           *result //第一次Execute的结果，之后的Execute从sent开始继续发送剩下的记录
+	sent          int
+}
+
+type pgConn struct {
+	conn       net.Conn
+	r          *bufio.Reader
+	w          *bufio.Writer
+	sess       *session
+	stmts      map[string]*pgStatement
+	portals    map[string]*pgPortal
+	skipToSync bool //扩展查询出错之后，丢弃消息直到Sync
+}
+
+func servePG(d *db.DB, conn net.Conn) {
+	c := &pgConn{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		w:       bufio.NewWriter(conn),
+		sess:    newSession(d),
+		stmts:   make(map[string]*pgStatement),
+		portals: make(map[string]*pgPortal),
+	}
+	defer c.sess.close() //连接断开的时候回滚没有提交的事务
+	if err := c.startup(); err != nil {
+		return
+	}
+	c.serve()
+}
+
+//startup 处理启动阶段的消息
+func (c *pgConn) startup() error {
+	for {
+		code, _, err := readPGStartup(c.r)
+		if err != nil {
+			return err
+		}
+		switch code {
+		case pgSSLRequest, pgGSSENCRequest:
+			//不支持加密连接，客户端收到N之后会使用明文重新发送启动消息
+			if err := c.w.WriteByte('N'); err != nil {
+				return err
+			}
+			if err := c.w.Flush(); err != nil {
+				return err
+			}
+			continue
+		case pgCancelRequest:
+			return errFeatureNotSupported
+		case pgProtocolVersion:
+		default:
+			c.sendError(fmt.Errorf("%w: protocol version %d", errFeatureNotSupported, code))
+			c.w.Flush()
+			return errFeatureNotSupported
+		}
+		break
+	}
+	newPGMessage('R').int32(0).writeTo(c.w) //AuthenticationOk
+	for _, p := range [][2]string{
+		{"server_version", "14.0 (miniSQL)"},
+		{"server_encoding", "UTF8"},
+		{"client_encoding", "UTF8"},
+		{"DateStyle", "ISO, MDY"},
+		{"integer_datetimes", "on"},
+		{"standard_conforming_strings", "on"},
+	} {
+		newPGMessage('S').cstring(p[0]).cstring(p[1]).writeTo(c.w)
+	}
+	pid := atomic.AddInt32(&pgBackendPID, 1)
+	newPGMessage('K').int32(pid).int32(0).writeTo(c.w)
+	c.readyForQuery()
+	return c.w.Flush()
+}
+
+//serve 循环处理客户端发送过来的消息
+func (c *pgConn) serve() {
+	for {
+		typ, body, err := readPGMessage(c.r)
+		if err != nil {
+			return
+		}
+		if typ == 'X' {
+			return //Terminate
+		}
+		if c.skipToSync && typ != 'S' {
+			continue
+		}
+		r := &pgReader{buf: body}
+		switch typ {
+		case 'Q':
+			c.handleQuery(r)
+		case 'P':
+			c.handleParse(r)
+		case 'B':
+			c.handleBind(r)
+		case 'D':
+			c.handleDescribe(r)
+		case 'E':
+			c.handleExecute(r)
+		case 'C':
+			c.handleClose(r)
+		case 'S':
+			c.skipToSync = false
+			c.readyForQuery()
+			err = c.w.Flush()
+		case 'H':
+			err = c.w.Flush()
+		default:
+			c.extendedError(fmt.Errorf("%w: message type %q", errFeatureNotSupported, typ))
+		}
+		if err != nil {
+			return
+		}
+		//简单查询的结果在handleQuery中已经发送了，扩展查询的结果等到Sync或者Flush的时候再发送
+		if typ == 'Q' {
+			if err := c.w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+//readyForQuery 告诉客户端可以发送下一个查询了，I表示当前不在事务中，T表示在事务中
+func (c *pgConn) readyForQuery() {
+	status := byte('I')
+	if c.sess.inTransaction() {
+		status = 'T'
+	}
+	newPGMessage('Z').bytes([]byte{status}).writeTo(c.w)
+}
+
+func (c *pgConn) sendError(err error) {
+	newPGMessage('E').
+		bytes([]byte{'S'}).cstring("ERROR").
+		bytes([]byte{'V'}).cstring("ERROR").
+		bytes([]byte{'C'}).cstring(pgErrorCode(err)).
+		bytes([]byte{'M'}).cstring(err.Error()).
+		bytes([]byte{0}).
+		writeTo(c.w)
+}
+
+//extendedError 扩展查询中出错，发送错误之后丢弃消息直到Sync
+func (c *pgConn) extendedError(err error) {
+	c.sendError(err)
+	c.skipToSync = true
+}
+
+//handleQuery 简单查询，依次执行每一条语句，遇到错误就停止
+func (c *pgConn) handleQuery(r *pgReader) {
+	sql := r.cstring()
+	if r.err != nil {
+		c.sendError(r.err)
+		c.readyForQuery()
+		return
+	}
+	stmts, rest := parser.SplitStatements(sql)
+	if rest = strings.TrimSpace(rest); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	if len(stmts) == 0 {
+		newPGMessage('I').writeTo(c.w) //EmptyQueryResponse
+	}
+	for _, sql := range stmts {
+		res, err := c.sess.execute(sql, nil)
+		if err != nil {
+			c.sendError(err)
+			break
+		}
+		if res.isQuery() {
+			c.sendRowDescription(res.schema, nil)
+		}
+		c.sendResult(res, nil)
+	}
+	c.readyForQuery()
+}
+
+//handleParse 创建一个预处理语句
+func (c *pgConn) handleParse(r *pgReader) {
+	name := r.cstring()
+	sql := r.cstring()
+	numTypes := int(r.int16())
+	oids := make([]uint32, 0, numTypes)
+	for i := 0; i < numTypes; i++ {
+		oids = append(oids, uint32(r.int32()))
+	}
+	if r.err != nil {
+		c.extendedError(r.err)
+		return
+	}
+	rewritten, order, numParams, err := rewriteDollarParams(sql)
+	if err != nil {
+		c.extendedError(err)
+		return
+	}
+	//使用参数槽解析，提前发现语法错误，并知道语句的类型，参数槽的类型未知，描述结果的时候不会因为参数的类型出错
+	stmt, params, err := parser.Prepare(rewritten)
+	if err != nil {
+		c.extendedError(err)
+		return
+	}
+	c.stmts[name] = &pgStatement{
+		order:     order,
+		numParams: numParams,
+		paramOIDs: oids,
+		stmt:      stmt,
+		params:    params,
+	}
+	newPGMessage('1').writeTo(c.w) //ParseComplete
+}
+
+//handleBind 把参数绑定到预处理语句上，创建一个portal
+func (c *pgConn) handleBind(r *pgReader) {
+	portalName := r.cstring()
+	stmtName := r.cstring()
+	paramFormats := make([]int16, r.int16())
+	for i := range paramFormats {
+		paramFormats[i] = r.int16()
+	}
+	numParams := int(r.int16())
+	rawParams := make([][]byte, numParams)
+	isNull := make([]bool, numParams)
+	for i := 0; i < numParams; i++ {
+		size := r.int32()
+		if size < 0 {
+			isNull[i] = true
+			continue
+		}
+		rawParams[i] = r.bytes(int(size))
+	}
+	resultFormats := make([]int16, r.int16())
+	for i := range resultFormats {
+		resultFormats[i] = r.int16()
+	}
+	if r.err != nil {
+		c.extendedError(r.err)
+		return
+	}
+	ps, ok := c.stmts[stmtName]
+	if !ok {
+		c.extendedError(fmt.Errorf("prepared statement %q does not exist", stmtName))
+		return
+	}
+	if numParams != ps.numParams {
+		c.extendedError(fmt.Errorf("%w: expected %d parameters, got %d", parser.ErrArgCount, ps.numParams, numParams))
+		return
+	}
+	params := make([]*comm.Constant, numParams)
+	for i := 0; i < numParams; i++ {
+		if isNull[i] {
+			params[i] = comm.NewNullConstant()
+			continue
+		}
+		var oid uint32
+		if i < len(ps.paramOIDs) {
+			oid = ps.paramOIDs[i]
+		}
+		val, err := decodePGParam(rawParams[i], formatCode(paramFormats, i), oid)
+		if err != nil {
+			c.extendedError(err)
+			return
+		}
+		params[i] = val
+	}
+	args := make([]*comm.Constant, len(ps.order))
+	for i, idx := range ps.order {
+		args[i] = params[idx]
+	}
+	c.portals[portalName] = &pgPortal{stmt: ps, args: args, resultFormats: resultFormats}
+	newPGMessage('2').writeTo(c.w) //BindComplete
+}
+
+//handleDescribe 返回预处理语句或者portal的参数以及结果的描述
+func (c *pgConn) handleDescribe(r *pgReader) {
+	kind := r.bytes(1)
+	name := r.cstring()
+	if r.err != nil {
+		c.extendedError(r.err)
+		return
+	}
+	var ps *pgStatement
+	var formats []int16
+	if kind[0] == 'S' {
+		s, ok := c.stmts[name]
+		if !ok {
+			c.extendedError(fmt.Errorf("prepared statement %q does not exist", name))
+			return
+		}
+		ps = s
+		//ParameterDescription，没有指定类型的参数当作text
+		msg := newPGMessage('t').int16(int16(ps.numParams))
+		for i := 0; i < ps.numParams; i++ {
+			oid := uint32(pgOIDText)
+			if i < len(ps.paramOIDs) && ps.paramOIDs[i] != 0 {
+				oid = ps.paramOIDs[i]
+			}
+			msg.int32(int32(oid))
+		}
+		msg.writeTo(c.w)
+	} else {
+		p, ok := c.portals[name]
+		if !ok {
+			c.extendedError(fmt.Errorf("portal %q does not exist", name))
+			return
+		}
+		ps, formats = p.stmt, p.resultFormats
+	}
+	sch, err := c.sess.describe(ps.stmt)
+	if err != nil {
+		c.extendedError(err)
+		return
+	}
+	if sch == nil {
+		newPGMessage('n').writeTo(c.w) //NoData
+		return
+	}
+	c.sendRowDescription(sch, formats)
+}
+
+//handleExecute 执行portal，结果的描述已经在Describe的时候发送过了
+//语句只在第一次Execute的时候执行，之后的Execute继续发送上一次没有发送完的记录
+func (c *pgConn) handleExecute(r *pgReader) {
+	name := r.cstring()
+	maxRows := int(r.int32()) //最多返回的记录数，0表示没有限制
+	if r.err != nil {
+		c.extendedError(r.err)
+		return
+	}
+	p, ok := c.portals[name]
+	if !ok {
+		c.extendedError(fmt.Errorf("portal %q does not exist", name))
+		return
+	}
+	if p.res == nil {
+		//同一个预处理语句的portal共用参数槽，执行之前写入这个portal的参数
+		for i, param := range p.stmt.params {
+			param.Set(p.args[i])
+		}
+		res, err := c.sess.executeStatement(p.stmt.stmt)
+		if err != nil {
+			c.extendedError(err)
+			return
+		}
+		p.res = res
+	}
+	rows := p.res.rows[p.sent:]
+	if maxRows > 0 && len(rows) > maxRows {
+		rows = rows[:maxRows]
+	}
+	p.sent += len(rows)
+	if p.sent < len(p.res.rows) {
+		c.sendRows(rows, p.resultFormats)
+		newPGMessage('s').writeTo(c.w) //PortalSuspended
+		return
+	}
+	//CommandComplete中的记录数是这一次Execute发送的记录数
+	part := *p.res
+	part.rows = rows
+	if part.isQuery() {
+		part.count = len(rows)
+	}
+	c.sendResult(&part, p.resultFormats)
+}
+
+//handleClose 关闭预处理语句或者portal
+func (c *pgConn) handleClose(r *pgReader) {
+	kind := r.bytes(1)
+	name := r.cstring()
+	if r.err != nil {
+		c.extendedError(r.err)
+		return
+	}
+	if kind[0] == 'S' {
+		delete(c.stmts, name)
+	} else {
+		delete(c.portals, name)
+	}
+	newPGMessage('3').writeTo(c.w) //CloseComplete
+}
+
+//sendRowDescription 根据表结构发送每一列的描述
+func (c *pgConn) sendRowDescription(sch rm.SchemaInterface, formats []int16) {
+	fields := sch.Fields()
+	msg := newPGMessage('T').int16(int16(len(fields)))
+	for i, field := range fields {
+		oid, size, typmod := int32(pgOIDInt8), int16(8), int32(-1)
+		switch sch.Type(field) {
+		case rm.VARCHAR:
+			oid, size, typmod = pgOIDVarchar, -1, int32(sch.Length(field)+4)
+		case rm.BOOLEAN:
+			oid, size = pgOIDBool, 1
+		case rm.DOUBLE:
+			oid = pgOIDFloat8
+		case rm.DECIMAL:
+			//numeric的typmod是(精度<<16 | 小数位数) + 4
+			length := sch.Length(field)
+			oid, size, typmod = pgOIDNumeric, -1, int32(rm.DecimalPrecision(length)<<16|rm.DecimalScale(length))+4
+		}
+		msg.cstring(field).int32(0).int16(0).int32(oid).int16(size).int32(typmod).int16(formatCode(formats, i))
+	}
+	msg.writeTo(c.w)
+}
+
+//sendResult 发送查询得到的记录以及CommandComplete
+func (c *pgConn) sendResult(res *result, formats []int16) {
+	c.sendRows(res.rows, formats)
+	newPGMessage('C').cstring(pgCommandTag(res)).writeTo(c.w)
+}
+
+//sendRows 每一条记录发送一个DataRow
+func (c *pgConn) sendRows(rows [][]*comm.Constant, formats []int16) {
+	for _, row := range rows {
+		msg := newPGMessage('D').int16(int16(len(row)))
+		for i, val := range row {
+			if val.IsNull() {
+				//NULL的长度是-1，后面没有数据
+				msg.int32(-1)
+				continue
+			}
+			b := encodePGValue(val, formatCode(formats, i))
+			msg.int32(int32(len(b))).bytes(b)
+		}
+		msg.writeTo(c.w)
+	}
+}
+
+//pgCommandTag CommandComplete中的命令标签
+func pgCommandTag(res *result) string {
+	switch data := res.stmt.(type) {
+	case *parser.QueryData:
+		return fmt.Sprintf("SELECT %d", res.count)
+	case *parser.InsertData:
+		return fmt.Sprintf("INSERT 0 %d", res.count)
+	case *parser.DeleteData:
+		return fmt.Sprintf("DELETE %d", res.count)
+	case *parser.UpdateData:
+		return fmt.Sprintf("UPDATE %d", res.count)
+	case *parser.CreateTableData:
+		return "CREATE TABLE"
+	case *parser.CreateViewData:
+		return "CREATE VIEW"
+	case *parser.CreateIndexData:
+		return "CREATE INDEX"
+	case *parser.DropData:
+		return "DROP " + data.Kind().String()
+	case *parser.AlterData:
+		return "ALTER TABLE"
+	case *parser.BeginData:
+		return "BEGIN"
+	case *parser.CommitData:
+		return "COMMIT"
+	case *parser.RollbackData:
+		return "ROLLBACK" //ROLLBACK TO的命令标签也是ROLLBACK
+	case *parser.SavepointData:
+		return "SAVEPOINT"
+	}
+	return ""
+}
+
+//formatCode 获得第i列使用的格式，0是文本，1是二进制；只有一个格式的时候所有列都使用这个格式
+func formatCode(formats []int16, i int) int16 {
+	switch len(formats) {
+	case 0:
+		return 0
+	case 1:
+		return formats[0]
+	}
+	if i < len(formats) {
+		return formats[i]
+	}
+	return 0
+}
+
+//encodePGValue 按照指定的格式编码一个值
+func encodePGValue(val *comm.Constant, format int16) []byte {
+	if val.Ival != nil {
+		if format == 1 {
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint64(b, uint64(int64(*val.Ival)))
+			return b
+		}
+		return []byte(strconv.Itoa(*val.Ival))
+	}
+	if val.Bval != nil {
+		if format == 1 {
+			if *val.Bval {
+				return []byte{1}
+			}
+			return []byte{0}
+		}
+		if *val.Bval {
+			return []byte("t")
+		}
+		return []byte("f")
+	}
+	if val.Fval != nil {
+		if format == 1 {
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint64(b, math.Float64bits(*val.Fval))
+			return b
+		}
+		return []byte(strconv.FormatFloat(*val.Fval, 'g', -1, 64))
+	}
+	if val.Dval != nil {
+		if format == 1 {
+			return encodePGNumeric(*val.Dval)
+		}
+		return []byte(val.Dval.String())
+	}
+	return []byte(*val.Sval)
+}
+
+//decodePGParam 把客户端传入的参数转化成Constant，没有指定类型的文本参数，能转化成整数的就当作整数，12.5这样的就当作定点数
+func decodePGParam(raw []byte, format int16, oid uint32) (*comm.Constant, error) {
+	switch oid {
+	case pgOIDInt2, pgOIDInt4, pgOIDInt8:
+		var v int
+		if format == 1 {
+			switch len(raw) {
+			case 2:
+				v = int(int16(binary.BigEndian.Uint16(raw)))
+			case 4:
+				v = int(int32(binary.BigEndian.Uint32(raw)))
+			case 8:
+				v = int(int64(binary.BigEndian.Uint64(raw)))
+			default:
+				return nil, fmt.Errorf("%w: invalid integer parameter", parser.ErrSyntax)
+			}
+		} else {
+			var err error
+			if v, err = strconv.Atoi(string(raw)); err != nil {
+				return nil, fmt.Errorf("%w: invalid integer parameter %q", parser.ErrSyntax, raw)
+			}
+		}
+		return comm.NewConstantInt(&v), nil
+	case pgOIDBool:
+		var v bool
+		if format == 1 {
+			if len(raw) != 1 {
+				return nil, fmt.Errorf("%w: invalid boolean parameter", parser.ErrSyntax)
+			}
+			v = raw[0] != 0
+		} else {
+			var err error
+			if v, err = strconv.ParseBool(string(raw)); err != nil {
+				return nil, fmt.Errorf("%w: invalid boolean parameter %q", parser.ErrSyntax, raw)
+			}
+		}
+		return comm.NewConstantBool(&v), nil
+	case pgOIDFloat4, pgOIDFloat8:
+		var v float64
+		if format == 1 {
+			switch len(raw) {
+			case 4:
+				v = float64(math.Float32frombits(binary.BigEndian.Uint32(raw)))
+			case 8:
+				v = math.Float64frombits(binary.BigEndian.Uint64(raw))
+			default:
+				return nil, fmt.Errorf("%w: invalid float parameter", parser.ErrSyntax)
+			}
+		} else {
+			var err error
+			if v, err = strconv.ParseFloat(string(raw), 64); err != nil {
+				return nil, fmt.Errorf("%w: invalid float parameter %q", parser.ErrSyntax, raw)
+			}
+		}
+		return comm.NewConstantFloat(&v), nil
+	case pgOIDNumeric:
+		var v comm.Decimal
+		var err error
+		if format == 1 {
+			v, err = decodePGNumeric(raw)
+		} else {
+			v, err = comm.ParseDecimal(string(raw))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid numeric parameter: %v", parser.ErrSyntax, err)
+		}
+		return comm.NewConstantDecimal(&v), nil
+	case pgOIDText, pgOIDVarchar, pgOIDBpchar:
+		s := string(raw)
+		return comm.NewConstantString(&s), nil
+	case 0:
+		if format == 0 {
+			if v, err := strconv.Atoi(string(raw)); err == nil {
+				return comm.NewConstantInt(&v), nil
+			}
+			if v, err := comm.ParseDecimal(string(raw)); err == nil && strings.Contains(string(raw), ".") {
+				return comm.NewConstantDecimal(&v), nil
+			}
+		}
+		s := string(raw)
+		return comm.NewConstantString(&s), nil
+	}
+	return nil, fmt.Errorf("%w: parameter type %d", errFeatureNotSupported, oid)
+}
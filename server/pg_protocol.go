@@ -0,0 +1,304 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"miniSQL/comm"
+	"strconv"
+	"strings"
+)
+
+/*
+	PostgreSQL v3 协议的消息格式
+	启动消息：int32长度 + int32协议号 + 参数
+	其他消息：1字节的类型 + int32长度(包含长度自己) + 消息体
+*/
+
+const (
+	pgProtocolVersion = 196608   //3.0
+	pgSSLRequest      = 80877103 //客户端请求使用SSL
+	pgGSSENCRequest   = 80877104 //客户端请求使用GSSAPI加密
+	pgCancelRequest   = 80877102 //取消正在执行的查询
+	pgMaxMessageSize  = 1 << 24
+
+	pgOIDBool    = 16
+	pgOIDInt2    = 21
+	pgOIDInt4    = 23
+	pgOIDInt8    = 20
+	pgOIDText    = 25
+	pgOIDFloat4  = 700
+	pgOIDFloat8  = 701
+	pgOIDBpchar  = 1042
+	pgOIDVarchar = 1043
+	pgOIDNumeric = 1700
+
+	pgNumericNeg = 0x4000 //numeric二进制格式中表示负数的符号
+)
+
+var (
+	errPGMessage = errors.New("invalid message format")
+)
+
+//readPGStartup 读取启动消息，返回协议号以及后面的内容
+func readPGStartup(r *bufio.Reader) (uint32, []byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size < 8 || size > pgMaxMessageSize {
+		return 0, nil, errPGMessage
+	}
+	body := make([]byte, size-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return binary.BigEndian.Uint32(body[:4]), body[4:], nil
+}
+
+//readPGMessage 读取一个普通的消息
+func readPGMessage(r *bufio.Reader) (byte, []byte, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size < 4 || size > pgMaxMessageSize {
+		return 0, nil, errPGMessage
+	}
+	body := make([]byte, size-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return typ, body, nil
+}
+
+//pgReader 解析消息体，遇到错误之后后面的读取都返回零值，最后统一检查err
+type pgReader struct {
+	buf []byte
+	err error
+}
+
+func (r *pgReader) int16() int16 {
+	if r.err != nil || len(r.buf) < 2 {
+		r.err = errPGMessage
+		return 0
+	}
+	v := int16(binary.BigEndian.Uint16(r.buf))
+	r.buf = r.buf[2:]
+	return v
+}
+
+func (r *pgReader) int32() int32 {
+	if r.err != nil || len(r.buf) < 4 {
+		r.err = errPGMessage
+		return 0
+	}
+	v := int32(binary.BigEndian.Uint32(r.buf))
+	r.buf = r.buf[4:]
+	return v
+}
+
+//cstring 读取一个以0结尾的字符串
+func (r *pgReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, b := range r.buf {
+		if b == 0 {
+			s := string(r.buf[:i])
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+	r.err = errPGMessage
+	return ""
+}
+
+func (r *pgReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || len(r.buf) < n {
+		r.err = errPGMessage
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+//pgMessage 构造一个要发送给客户端的消息
+type pgMessage struct {
+	typ byte
+	buf []byte
+}
+
+func newPGMessage(typ byte) *pgMessage {
+	return &pgMessage{typ: typ}
+}
+
+func (m *pgMessage) int16(v int16) *pgMessage {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(v))
+	m.buf = append(m.buf, b[:]...)
+	return m
+}
+
+func (m *pgMessage) int32(v int32) *pgMessage {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	m.buf = append(m.buf, b[:]...)
+	return m
+}
+
+func (m *pgMessage) cstring(s string) *pgMessage {
+	m.buf = append(m.buf, s...)
+	m.buf = append(m.buf, 0)
+	return m
+}
+
+func (m *pgMessage) bytes(b []byte) *pgMessage {
+	m.buf = append(m.buf, b...)
+	return m
+}
+
+func (m *pgMessage) writeTo(w *bufio.Writer) error {
+	if err := w.WriteByte(m.typ); err != nil {
+		return err
+	}
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(m.buf)+4))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(m.buf)
+	return err
+}
+
+//rewriteDollarParams 把PostgreSQL风格的$n占位符替换成?，返回替换之后的语句，
+//第i个?对应的是第order[i]个参数（从0开始），以及一共有多少个参数
+func rewriteDollarParams(sql string) (string, []int, int, error) {
+	out := make([]byte, 0, len(sql))
+	order := make([]int, 0)
+	numParams := 0
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '?':
+			return "", nil, 0, fmt.Errorf("%w: use $n for parameters", errFeatureNotSupported)
+		case c == '$':
+			j := i + 1
+			n := 0
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				n = n*10 + int(sql[j]-'0')
+				j++
+			}
+			if j == i+1 || n == 0 {
+				break
+			}
+			out = append(out, '?')
+			order = append(order, n-1)
+			if n > numParams {
+				numParams = n
+			}
+			i = j - 1
+			continue
+		}
+		out = append(out, c)
+	}
+	return string(out), order, numParams, nil
+}
+
+/*
+	numeric的二进制格式：int16数字个数 + int16权重 + int16符号 + int16小数位数 + 每个int16是一个0~9999的数字
+	值是 digit[i] * 10000^(weight-i) 的和，例如12.5的数字是[12, 5000]，权重是0
+*/
+
+//encodePGNumeric 把定点数编码成numeric的二进制格式
+func encodePGNumeric(d comm.Decimal) []byte {
+	digits := strconv.FormatInt(d.Unscaled, 10)
+	sign := 0
+	if d.Unscaled < 0 {
+		sign, digits = pgNumericNeg, digits[1:]
+	}
+	if len(digits) <= d.Scale {
+		digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+	}
+	//整数部分在前面补0，小数部分在后面补0，都补成4的倍数个数字
+	intPart, fracPart := digits[:len(digits)-d.Scale], digits[len(digits)-d.Scale:]
+	intPart = strings.Repeat("0", (4-len(intPart)%4)%4) + intPart
+	fracPart += strings.Repeat("0", (4-len(fracPart)%4)%4)
+	all := intPart + fracPart
+	groups := make([]uint16, 0, len(all)/4)
+	for i := 0; i < len(all); i += 4 {
+		n, _ := strconv.Atoi(all[i : i+4])
+		groups = append(groups, uint16(n))
+	}
+	weight := len(intPart)/4 - 1
+	for len(groups) > 0 && groups[0] == 0 {
+		groups, weight = groups[1:], weight-1
+	}
+	for len(groups) > 0 && groups[len(groups)-1] == 0 {
+		groups = groups[:len(groups)-1]
+	}
+	if len(groups) == 0 {
+		weight, sign = 0, 0
+	}
+	b := make([]byte, 8+2*len(groups))
+	binary.BigEndian.PutUint16(b[0:], uint16(len(groups)))
+	binary.BigEndian.PutUint16(b[2:], uint16(int16(weight)))
+	binary.BigEndian.PutUint16(b[4:], uint16(sign))
+	binary.BigEndian.PutUint16(b[6:], uint16(d.Scale))
+	for i, g := range groups {
+		binary.BigEndian.PutUint16(b[8+2*i:], g)
+	}
+	return b
+}
+
+//decodePGNumeric 解析numeric的二进制格式，NaN和无穷大不能表示成定点数
+func decodePGNumeric(raw []byte) (comm.Decimal, error) {
+	if len(raw) < 8 || len(raw) != 8+2*int(binary.BigEndian.Uint16(raw)) {
+		return comm.Decimal{}, errPGMessage
+	}
+	n := int(binary.BigEndian.Uint16(raw))
+	weight := int(int16(binary.BigEndian.Uint16(raw[2:])))
+	sign := binary.BigEndian.Uint16(raw[4:])
+	dscale := int(binary.BigEndian.Uint16(raw[6:]))
+	if sign != 0 && sign != pgNumericNeg {
+		return comm.Decimal{}, fmt.Errorf("%w: numeric NaN or infinity", errFeatureNotSupported)
+	}
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "%04d", binary.BigEndian.Uint16(raw[8+2*i:]))
+	}
+	s := sb.String()
+	//最后一个数字的权重是weight-n+1，也就是字符串中的整数要乘上10^exp
+	if exp := 4 * (weight - n + 1); exp >= 0 {
+		s += strings.Repeat("0", exp)
+	} else {
+		if len(s) < -exp {
+			s = strings.Repeat("0", -exp-len(s)) + s
+		}
+		s = strings.TrimRight(s[:len(s)+exp]+"."+s[len(s)+exp:], "0")
+	}
+	d, err := comm.ParseDecimal("0" + strings.TrimSuffix(s, "."))
+	if err != nil {
+		return comm.Decimal{}, err
+	}
+	if sign == pgNumericNeg {
+		d = d.Negate()
+	}
+	return d.Rescale(dscale)
+}
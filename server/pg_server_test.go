@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"miniSQL/comm"
+	"miniSQL/db"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+//startTestServer 在本地随机端口上启动一个服务，测试结束的时候关闭
+func startTestServer(t *testing.T, newServer func(d *db.DB) *Server) net.Addr {
+	d, err := db.Open(filepath.Join(t.TempDir(), "server_test"), db.Options{BlockSize: 400, NumBuffers: 16})
+	assert.Nil(t, err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := newServer(d)
+	done := make(chan error)
+	go func() {
+		done <- s.Serve(l)
+	}()
+	t.Cleanup(func() {
+		assert.Nil(t, s.Close())
+		assert.Equal(t, ErrServerClosed, <-done)
+		assert.Nil(t, d.Close())
+	})
+	return l.Addr()
+}
+
+func openPG(t *testing.T) *sql.DB {
+	addr := startTestServer(t, NewPGServer).(*net.TCPAddr)
+	dsn := fmt.Sprintf("host=127.0.0.1 port=%d user=test dbname=test sslmode=disable", addr.Port)
+	conn, err := sql.Open("postgres", dsn)
+	assert.Nil(t, err)
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestPGSimpleQuery(t *testing.T) {
+	conn := openPG(t)
+	//没有参数的时候lib/pq使用简单查询
+	_, err := conn.Exec("create table student (name varchar(16),gradyear int); insert into student (name,gradyear) values ('tom',2020)")
+	assert.Nil(t, err)
+	res, err := conn.Exec("insert into student (name,gradyear) values ('it''s',2021)")
+	assert.Nil(t, err)
+	n, _ := res.RowsAffected()
+	assert.Equal(t, int64(1), n)
+
+	rows, err := conn.Query("select name,gradyear from student")
+	assert.Nil(t, err)
+	cols, _ := rows.Columns()
+	assert.Equal(t, []string{"name", "gradyear"}, cols)
+	types, _ := rows.ColumnTypes()
+	assert.Equal(t, "VARCHAR", types[0].DatabaseTypeName())
+	assert.Equal(t, "INT8", types[1].DatabaseTypeName())
+	got := make([]string, 0)
+	for rows.Next() {
+		var name string
+		var year int
+		assert.Nil(t, rows.Scan(&name, &year))
+		got = append(got, fmt.Sprintf("%s:%d", name, year))
+	}
+	assert.Nil(t, rows.Err())
+	assert.Equal(t, []string{"tom:2020", "it's:2021"}, got)
+
+	_, err = conn.Exec("select name from teacher")
+	pqErr, ok := err.(*pq.Error)
+	assert.True(t, ok)
+	assert.Equal(t, pq.ErrorCode("42P01"), pqErr.Code)
+	_, err = conn.Exec("selec name from student")
+	assert.Equal(t, pq.ErrorCode("42601"), err.(*pq.Error).Code)
+	//出错之后连接仍然可以继续使用
+	var count int
+	assert.Nil(t, conn.QueryRow("select gradyear from student where name = 'tom'").Scan(&count))
+	assert.Equal(t, 2020, count)
+
+	//外连接中没有匹配上的字段是NULL
+	_, err = conn.Exec("create table club (member varchar(16))")
+	assert.Nil(t, err)
+	var member sql.NullString
+	assert.Nil(t, conn.QueryRow("select member from student left join club on name = member where name = 'tom'").Scan(&member))
+	assert.False(t, member.Valid)
+}
+
+func TestPGExtendedQuery(t *testing.T) {
+	conn := openPG(t)
+	_, err := conn.Exec("create table student (name varchar(16),gradyear int)")
+	assert.Nil(t, err)
+	//带参数的时候lib/pq使用Parse/Bind/Execute
+	stmt, err := conn.Prepare("insert into student (name,gradyear) values ($1,$2)")
+	assert.Nil(t, err)
+	for i, name := range []string{"tom", "jim", "amy"} {
+		res, err := stmt.Exec(name, 2020+i)
+		assert.Nil(t, err)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(1), n)
+	}
+	assert.Nil(t, stmt.Close())
+
+	res, err := conn.Exec("update student set gradyear = $2 where name = $1", "jim", 2030)
+	assert.Nil(t, err)
+	n, _ := res.RowsAffected()
+	assert.Equal(t, int64(1), n)
+
+	var name string
+	assert.Nil(t, conn.QueryRow("select name,gradyear from student where gradyear = $1", 2030).Scan(&name, new(int)))
+	assert.Equal(t, "jim", name)
+
+	_, err = conn.Exec("insert into student (name,gradyear) values ($1,$2)", 2040, "bob")
+	assert.Equal(t, pq.ErrorCode("42804"), err.(*pq.Error).Code)
+	_, err = conn.Query("select name from student where gradyear = $1", 1, 2)
+	assert.NotNil(t, err)
+	var year int
+	assert.Nil(t, conn.QueryRow("select gradyear from student where name = $1", "amy").Scan(&year))
+	assert.Equal(t, 2022, year)
+
+	//NULL参数
+	_, err = conn.Exec("insert into student (name,gradyear) values ($1,$2)", "bob", nil)
+	assert.Nil(t, err)
+	var nullYear sql.NullInt64
+	assert.Nil(t, conn.QueryRow("select gradyear from student where name = $1", "bob").Scan(&nullYear))
+	assert.False(t, nullYear.Valid)
+}
+
+//TestPGExecuteMaxRows lib/pq不会限制Execute返回的记录数，这里直接发送协议消息
+func TestPGExecuteMaxRows(t *testing.T) {
+	addr := startTestServer(t, NewPGServer)
+	conn, err := net.Dial("tcp", addr.String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+	startup := newPGMessage(0).int32(pgProtocolVersion).cstring("user").cstring("test").bytes([]byte{0}).buf
+	w.Write(newPGMessage(0).int32(int32(len(startup) + 4)).buf)
+	w.Write(startup)
+	//依次读取消息直到ReadyForQuery，返回消息类型以及CommandComplete中的标签
+	receive := func() (string, []string) {
+		assert.Nil(t, w.Flush())
+		types, tags := "", make([]string, 0)
+		for {
+			typ, body, err := readPGMessage(r)
+			assert.Nil(t, err)
+			types += string(typ)
+			if typ == 'C' {
+				tags = append(tags, (&pgReader{buf: body}).cstring())
+			}
+			if typ == 'Z' {
+				return types, tags
+			}
+		}
+	}
+	receive()
+	newPGMessage('Q').cstring("create table item (id int); insert into item (id) values (1), (2), (3), (4), (5)").writeTo(w)
+	types, _ := receive()
+	assert.Equal(t, "CCZ", types)
+
+	newPGMessage('P').cstring("").cstring("select id from item where id > $1 order by id").int16(0).writeTo(w)
+	newPGMessage('B').cstring("").cstring("").int16(0).int16(1).int32(1).bytes([]byte("0")).int16(0).writeTo(w)
+	for _, maxRows := range []int32{2, 2, 2, 0} {
+		newPGMessage('E').cstring("").int32(maxRows).writeTo(w)
+	}
+	newPGMessage('S').writeTo(w)
+	types, tags := receive()
+	assert.Equal(t, "12DDsDDsDCCZ", types)
+	assert.Equal(t, []string{"SELECT 1", "SELECT 0"}, tags)
+
+	//修改语句只执行一次
+	newPGMessage('P').cstring("del").cstring("delete from item where id <= $1").int16(0).writeTo(w)
+	newPGMessage('B').cstring("p").cstring("del").int16(0).int16(1).int32(1).bytes([]byte("2")).int16(0).writeTo(w)
+	newPGMessage('E').cstring("p").int32(1).writeTo(w)
+	newPGMessage('E').cstring("p").int32(0).writeTo(w)
+	newPGMessage('Q').cstring("select id from item").writeTo(w)
+	types, tags = receive()
+	assert.Equal(t, "12CCTDDDCZ", types)
+	assert.Equal(t, []string{"DELETE 2", "DELETE 2", "SELECT 3"}, tags)
+}
+
+func TestRewriteDollarParams(t *testing.T) {
+	sql, order, n, err := rewriteDollarParams("update t set a = $2 where b = '$1' and c = $1")
+	assert.Nil(t, err)
+	assert.Equal(t, "update t set a = ? where b = '$1' and c = ?", sql)
+	assert.Equal(t, []int{1, 0}, order)
+	assert.Equal(t, 2, n)
+}
+
+func TestPGColumnTypes(t *testing.T) {
+	conn := openPG(t)
+	_, err := conn.Exec("create table item (price decimal(8,2), weight double, instock boolean)")
+	assert.Nil(t, err)
+	//lib/pq使用文本格式发送没有类型的参数，2.5被当作定点数
+	_, err = conn.Exec("insert into item (price, weight, instock) values ($1, $2, true)", 2.5, 1.25)
+	assert.Nil(t, err)
+	var price string
+	var weight float64
+	var instock bool
+	assert.Nil(t, conn.QueryRow("select price, weight, instock from item where price = $1", "2.50").Scan(&price, &weight, &instock))
+	assert.Equal(t, "2.50", price)
+	assert.Equal(t, 1.25, weight)
+	assert.True(t, instock)
+}
+
+func TestPGNumeric(t *testing.T) {
+	for _, s := range []string{"0", "12.5", "-0.0001", "123456789.12345", "10000", "-99999999.990"} {
+		d, err := comm.ParseDecimal(s)
+		assert.Nil(t, err)
+		got, err := decodePGNumeric(encodePGNumeric(d))
+		assert.Nil(t, err, s)
+		assert.Equal(t, s, got.String())
+	}
+	//12.5的数字是[12, 5000]，权重是0，小数位数是1
+	d, _ := comm.ParseDecimal("12.5")
+	assert.Equal(t, []byte{0, 2, 0, 0, 0, 0, 0, 1, 0, 12, 0x13, 0x88}, encodePGNumeric(d))
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"errors"
+	"miniSQL/db"
+	"net"
+	"sync"
+)
+
+/*
+	server 包让客户端可以通过网络协议来访问数据库
+	Server 负责监听端口并为每一个连接启动一个协程，具体使用哪一种协议由handler决定
+	每一个连接对应一个session，连接之间共享同一个db.DB
+*/
+
+var (
+	ErrServerClosed = errors.New("server: server closed")
+)
+
+//handler 处理一个客户端连接，返回的时候连接会被关闭
+type handler func(d *db.DB, conn net.Conn)
+
+//Server 一个监听TCP端口的服务
+type Server struct {
+	db       *db.DB
+	handle   handler
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{} //当前所有的客户端连接，关闭服务的时候需要全部关闭
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+func newServer(d *db.DB, handle handler) *Server {
+	return &Server{
+		db:     d,
+		handle: handle,
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+//ListenAndServe 监听addr并开始处理客户端连接，直到服务被关闭
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+//Serve 在给定的listener上处理客户端连接，服务关闭之后返回ErrServerClosed
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listener = l
+	s.mu.Unlock()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			s.handle(s.db, conn)
+		}()
+	}
+}
+
+//Addr 返回监听的地址，还没有开始监听的时候返回nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+//Close 停止监听，关闭所有的客户端连接，并等待所有的连接处理完
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
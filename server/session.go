@@ -0,0 +1,94 @@
+package server
+
+import (
+	"miniSQL/comm"
+	"miniSQL/db"
+	"miniSQL/parser"
+	rm "miniSQL/record_manager"
+)
+
+var (
+	ErrAborted = db.ErrAborted
+)
+
+//session 一个客户端连接对应的会话，连接上的语句都通过db.Session来执行
+//没有显式开启事务，每条语句都在自己的事务中执行，执行完就自动提交
+type session struct {
+	db   *db.DB
+	sess *db.Session
+}
+
+func newSession(d *db.DB) *session {
+	return &session{db: d, sess: d.NewSession()}
+}
+
+//inTransaction 当前是否处在显式开启的事务中
+func (s *session) inTransaction() bool {
+	return s.sess.InTransaction()
+}
+
+//close 连接断开的时候回滚没有提交的事务
+func (s *session) close() {
+	s.sess.Close()
+}
+
+//result 一条语句的执行结果
+type result struct {
+	stmt   parser.Statement
+	fields []string
+	schema rm.SchemaInterface //查询语句结果的表结构，修改语句为nil
+	rows   [][]*comm.Constant
+	count  int //修改语句影响的记录数，或者查询语句返回的记录数
+}
+
+//isQuery 是否是查询语句的结果
+func (r *result) isQuery() bool {
+	return r.schema != nil
+}
+
+//execute 解析并执行一条语句，args会依次绑定到?占位符上
+func (s *session) execute(sql string, args []*comm.Constant) (*result, error) {
+	stmt, err := parser.NewSQLParserWithArgs(sql, args).ParseStatement()
+	if err != nil {
+		return nil, err
+	}
+	return s.executeStatement(stmt)
+}
+
+//executeStatement 执行一条已经解析好的语句，查询语句会把所有的记录都读取出来
+func (s *session) executeStatement(stmt parser.Statement) (*result, error) {
+	res := &result{stmt: stmt}
+	data, ok := stmt.(*parser.QueryData)
+	if !ok {
+		count, err := s.sess.Execute(stmt)
+		if err != nil {
+			return nil, err
+		}
+		res.count = count
+		return res, nil
+	}
+	rows, err := s.sess.Query(data)
+	if err != nil {
+		return nil, err
+	}
+	res.schema = rows.Schema()
+	res.fields = rows.Fields()
+	res.rows = make([][]*comm.Constant, 0)
+	for rows.Next() {
+		res.rows = append(res.rows, rows.Row())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	res.count = len(res.rows)
+	return res, nil
+}
+
+//describe 不执行语句，只返回查询语句结果的表结构，修改语句返回nil
+func (s *session) describe(stmt parser.Statement) (rm.SchemaInterface, error) {
+	data, ok := stmt.(*parser.QueryData)
+	if !ok {
+		return nil, nil
+	}
+	return s.sess.Describe(data)
+}
@@ -25,7 +25,7 @@ func PrintStudentTable(tx *tx.Transaction, mdm *mdm.MetaDataManager) {
 
 	testPlanner := planner.NewBasicQueryPlan(mdm) //创建一个查询计划
 	//创建一个查询计划
-	testPlan := testPlanner.CreatePlan(queryData, tx)
+	testPlan, _ := testPlanner.CreatePlan(queryData, tx)
 	testInterface, _ := testPlan.Open()
 	testScan := testInterface.(query.Scan)
 	for testScan.Next() {
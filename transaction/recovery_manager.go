@@ -63,6 +63,28 @@ func (r *RecoveryManager) RollBack() error {
 	return nil
 }
 
+//Savepoint 返回当前最新的日志编号，之后回滚到保存点的时候，只需要撤销比它新的日志
+func (r *RecoveryManager) Savepoint() uint64 {
+	return r.logManager.LatestLSN()
+}
+
+//RollBackTo 撤销当前事务在lsn之后写入的日志，事务本身不会结束，之后还可以继续执行或者提交
+//撤销的时候不会生成新的日志，如果之后整个事务回滚或者系统崩溃，这些日志还会被再撤销一次，结果是一样的
+func (r *RecoveryManager) RollBackTo(lsn uint64) {
+	iter := r.logManager.Iterator()
+	for iter.Valid() {
+		rec := iter.Next()
+		if iter.LSN() <= lsn {
+			//已经到了保存点之前的日志
+			return
+		}
+		logRecord := r.CreateRecord(rec)
+		if logRecord.TxNumber() == uint64(r.txNum) {
+			logRecord.Undo(r.tx)
+		}
+	}
+}
+
 //Recover 是系统执行的，发现由START，而没有找到commit，所以我们就需要执行数据的恢复,恢复到事务执行之前的状态4
 //把每commit和rollback的事务都回滚
 func (r *RecoveryManager) Recover() error {
@@ -147,14 +169,17 @@ func (r *RecoveryManager) doRecover() {
 		rec := iter.Next()
 		logRecord := r.CreateRecord(rec)
 		//由于是从最新的日志开始进行迭代，所以肯定事务肯定是先找到commit或者rollabck，其他的就是没完成
-
+		if logRecord.Op() == CHECKPOINT {
+			//checkpoint之前的数据都已经落盘了，恢复到这里就可以结束了
+			return
+		}
 		if logRecord.Op() == COMMIT || logRecord.Op() == ROLLBACK {
 			//记录当前有commit或者有rollback的日志（表示当前的日志已经完成了操作不需要进行恢复，要门已经commit提交刷新到磁盘，要门回滚恢复到了原来的状态，同样也刷新到磁盘了）
 			finishedTxs[logRecord.TxNumber()] = true
+			continue
 		}
-
-		exitst, ok := finishedTxs[uint64(r.txNum)]
-		if !ok || !exitst {
+		//必须要看的是这条日志所属的事务，而不是当前执行恢复的事务
+		if !finishedTxs[logRecord.TxNumber()] {
 			//走到这里这个说明他只有start，而没有commit和rollback，有头无尾的，就需要进行一个undo
 			//把数据进行恢复
 			logRecord.Undo(r.tx)
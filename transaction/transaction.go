@@ -29,6 +29,18 @@ type Transaction struct {
 	txNum          int32               //当前的事务序列号
 	bufferManager  *bm.BufferManager   //缓存管理器,管理当前事务使用缓存
 	concurrentMgr  *ConcurrencyManager //管理并发请求
+	removed        []*removedFile      //事务提交之后需要删除的文件
+	onFinish       []func()            //事务提交或者回滚之后调用
+	onRollBack     []func()            //事务回滚或者回滚到保存点之后调用
+}
+
+//removedFile 登记的时候最新的日志号，回滚到这之前的保存点的时候文件不再删除
+//同一个事务中重新创建了这个文件的时候取消删除，回滚到取消之前的保存点的时候恢复删除
+type removedFile struct {
+	name      string
+	lsn       uint64
+	canceled  bool
+	cancelLSN uint64
 }
 
 //NewTransaction 构造一个事务对象，传入的是文件管理器，缓存管理器，日志管理器
@@ -53,6 +65,7 @@ func NewTransaction(fileManager *fm.FileManager, logManager *lm.LogManager, buff
 
 //Commit 将当前的事务进行提交,并把当前数据刷盘
 func (t *Transaction) Commit() {
+	defer t.finish()
 	//在commit之前把锁给释放掉，收缩阶段(事务必须锁，事务不能在获得锁)的，严格两阶段锁
 	t.concurrentMgr.Release()
 	err := t.recoverManager.Commit()
@@ -63,15 +76,82 @@ func (t *Transaction) Commit() {
 	fmt.Println(r)
 	//执行commit之后，当前事务就全部完成了，所有的数据都会写入到磁盘中去，将当前用于存储当前缓存页全部进行解锁，解引用
 	t.myBuffers.UnpinAll()
+	t.removeFiles()
+}
+
+//OnFinish 登记一个在事务提交或者回滚之后调用的函数，DB用它统计还没有结束的事务
+func (t *Transaction) OnFinish(f func()) {
+	t.onFinish = append(t.onFinish, f)
+}
+
+//finish 事务结束，调用登记的函数，每个函数只调用一次
+func (t *Transaction) finish() {
+	fns := t.onFinish
+	t.onFinish = nil
+	for _, f := range fns {
+		f()
+	}
+}
+
+//OnRollBack 登记一个在事务回滚或者回滚到保存点之后调用的函数，元数据管理器用它让根据回滚之前的表结构生成的查询计划失效
+func (t *Transaction) OnRollBack(f func()) {
+	t.onRollBack = append(t.onRollBack, f)
+}
+
+//rolledBack 回滚之后调用登记的函数，回滚到保存点之后事务还会继续使用，所以不清空
+func (t *Transaction) rolledBack() {
+	for _, f := range t.onRollBack {
+		f()
+	}
+}
+
+//RemoveOnCommit 事务提交之后删除文件，回滚的时候文件保留，DROP TABLE和DROP INDEX删除表和索引的文件时使用
+//先在文件上加排他锁，其他事务正在使用这个文件的时候需要等待
+func (t *Transaction) RemoveOnCommit(filename string) error {
+	dummyBlk := fm.NewBlockId(filename, END_OF_FILE)
+	if err := t.concurrentMgr.XLock(*dummyBlk); err != nil {
+		return err
+	}
+	t.removed = append(t.removed, &removedFile{name: filename, lsn: t.Savepoint()})
+	return nil
+}
+
+//CancelRemove 登记了提交之后删除的文件在同一个事务中又被重新创建的时候调用，取消删除，返回这个文件是否在等待删除
+//文件中还保留着原来的数据，调用者需要在事务中把它清空
+func (t *Transaction) CancelRemove(filename string) bool {
+	for _, file := range t.removed {
+		if file.name == filename && !file.canceled {
+			file.canceled, file.cancelLSN = true, t.Savepoint()
+			return true
+		}
+	}
+	return false
+}
+
+//removeFiles 丢弃缓存中这些文件的区块，然后删除文件
+func (t *Transaction) removeFiles() {
+	for _, file := range t.removed {
+		if file.canceled {
+			continue
+		}
+		t.bufferManager.Discard(file.name)
+		if err := t.fileManager.Remove(file.name); err != nil {
+			fmt.Printf("remove file %s: %v\n", file.name, err)
+		}
+	}
+	t.removed = nil
 }
 
 //RollBack 执行一个回滚操作,好像当前的所有事务没有发生一样,丢弃当前事务，恢复到事务发生之前的状态
 func (t *Transaction) RollBack() error {
+	defer t.finish()
+	defer t.rolledBack()
 	err := t.recoverManager.RollBack()
 	if err != nil {
 		return err
 	}
 	t.concurrentMgr.Release() //回滚的时候也需要释放锁
+	t.removed = nil
 	r := fmt.Sprintf("transaction %d roll back", t.txNum)
 	fmt.Println(r)
 
@@ -80,6 +160,28 @@ func (t *Transaction) RollBack() error {
 	return nil
 }
 
+//Savepoint 设置一个保存点，返回的日志编号可以传给RollBackTo
+func (t *Transaction) Savepoint() uint64 {
+	return t.recoverManager.Savepoint()
+}
+
+//RollBackTo 把当前事务在保存点之后的修改全部撤销，已经获得的锁不会释放
+func (t *Transaction) RollBackTo(lsn uint64) {
+	defer t.rolledBack()
+	t.recoverManager.RollBackTo(lsn)
+	//保存点之后登记的文件不再删除，保存点之后取消的删除重新生效
+	kept := t.removed[:0]
+	for _, file := range t.removed {
+		if file.lsn <= lsn {
+			if file.canceled && file.cancelLSN > lsn {
+				file.canceled = false
+			}
+			kept = append(kept, file)
+		}
+	}
+	t.removed = kept
+}
+
 //Recover 系统启动的时候，会在所有事务执行前，运行该函数
 //系统启动的时候发现上一次的事务在执行到一半的时候，发生崩溃或者断电了，数据写到一半，启动之后，就要将写到一半的数据给抹掉，恢复到写入之前的状态
 func (t *Transaction) Recover() error {
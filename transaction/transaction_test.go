@@ -7,6 +7,7 @@ import (
 	fm "miniSQL/file_manager"
 	lm "miniSQL/log_manager"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -87,3 +88,48 @@ func TestTransaction_AvailableBuffer(t *testing.T) {
 
 	tx1.Commit()
 }
+
+func TestSavepoint(t *testing.T) {
+	fmgr, err := fm.NewFileManager(filepath.Join(t.TempDir(), "savepoint_test"), 400)
+	assert.Nil(t, err)
+	lmgr, err := lm.NewLogManager(fmgr, "logfile")
+	assert.Nil(t, err)
+	bmgr := bm.NewBufferManager(fmgr, lmgr, 3)
+	blk := fm.NewBlockId("testfile", 1)
+
+	tx1 := NewTransaction(fmgr, lmgr, bmgr)
+	tx1.Pin(blk)
+	tx1.SetInt(blk, 80, 1, true)
+	tx1.SetString(blk, 40, "one", true)
+	sp := tx1.Savepoint()
+	tx1.SetInt(blk, 80, 2, true)
+	tx1.SetString(blk, 40, "two", true)
+	tx1.RollBackTo(sp) //回滚到保存点，保存点之前的修改还在
+	ival, _ := tx1.GetInt(blk, 80)
+	sval, _ := tx1.GetString(blk, 40)
+	assert.Equal(t, int64(1), ival)
+	assert.Equal(t, "one", sval)
+
+	tx1.SetInt(blk, 80, 3, true)
+	tx1.Commit()
+
+	tx2 := NewTransaction(fmgr, lmgr, bmgr)
+	tx2.Pin(blk)
+	ival, _ = tx2.GetInt(blk, 80)
+	sval, _ = tx2.GetString(blk, 40)
+	assert.Equal(t, int64(3), ival)
+	assert.Equal(t, "one", sval)
+	sp = tx2.Savepoint()
+	tx2.SetString(blk, 40, "four", true)
+	tx2.RollBackTo(sp)
+	tx2.SetInt(blk, 80, 5, true)
+	tx2.RollBack() //整个事务回滚，保存点前后的修改都会被撤销
+
+	tx3 := NewTransaction(fmgr, lmgr, bmgr)
+	tx3.Pin(blk)
+	ival, _ = tx3.GetInt(blk, 80)
+	sval, _ = tx3.GetString(blk, 40)
+	assert.Equal(t, int64(3), ival)
+	assert.Equal(t, "one", sval)
+	tx3.Commit()
+}